@@ -0,0 +1,116 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Auth adds credentials to a request.
+type Auth interface {
+	Authorize(ctx context.Context, req *http.Request) error
+}
+
+// AuthFunc adapts a function to Auth.
+type AuthFunc func(ctx context.Context, req *http.Request) error
+
+func (f AuthFunc) Authorize(ctx context.Context, req *http.Request) error {
+	return f(ctx, req)
+}
+
+// APIKey sends key in the X-Api-Key header.
+func APIKey(key string) Auth {
+	return AuthFunc(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("X-Api-Key", key)
+		return nil
+	})
+}
+
+// BearerToken sends a token obtained elsewhere, such as a JWT issued by
+// another service, as a bearer token.
+func BearerToken(token string) Auth {
+	return AuthFunc(func(_ context.Context, req *http.Request) error {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	})
+}
+
+// JWTConfig configures JWT. Issuer and Audience must match the server's
+// settings when it has them.
+type JWTConfig struct {
+	Secret   []byte
+	Subject  string
+	Issuer   string
+	Audience string
+	// TTL is the lifetime of each token; 15 minutes by default.
+	TTL time.Duration
+}
+
+// jwtRefreshMargin is how long before expiry a token is replaced, so that it
+// does not expire in flight or during retries.
+const jwtRefreshMargin = time.Minute
+
+type jwtAuth struct {
+	cfg JWTConfig
+	now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// JWT signs HS256 tokens with the secret shared with the server and sends
+// them as bearer tokens. A token is reused until shortly before it expires.
+func JWT(cfg JWTConfig) (Auth, error) {
+	if len(cfg.Secret) == 0 {
+		return nil, errors.New("JWT secret must not be empty")
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Minute
+	}
+	if cfg.TTL <= jwtRefreshMargin {
+		return nil, fmt.Errorf("JWT TTL must be longer than %s", jwtRefreshMargin)
+	}
+	return &jwtAuth{cfg: cfg, now: time.Now}, nil
+}
+
+func (a *jwtAuth) Authorize(_ context.Context, req *http.Request) error {
+	token, err := a.current()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+func (a *jwtAuth) current() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	if a.token != "" && now.Add(jwtRefreshMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	expires := now.Add(a.cfg.TTL)
+	claims := jwt.RegisteredClaims{
+		Subject:   a.cfg.Subject,
+		Issuer:    a.cfg.Issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expires),
+	}
+	if a.cfg.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{a.cfg.Audience}
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.cfg.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign JWT: %w", err)
+	}
+	a.token, a.expires = token, expires
+	return token, nil
+}
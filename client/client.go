@@ -0,0 +1,273 @@
+// Package client is a typed Go client for the blog API's /v1 routes. Calls
+// take a context, are retried with backoff on 429 and 5xx responses where that
+// is safe, and fail with an *Error that matches the sentinel errors of this
+// package with errors.Is.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/models"
+	"blog-api/internal/sanitize"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Post is the post resource of the API.
+type Post = models.Post
+
+// SanitizationReport lists the markup the server stripped from a post.
+type SanitizationReport = sanitize.Report
+
+// Removal is one entry of a SanitizationReport.
+type Removal = sanitize.Removal
+
+const (
+	apiPrefix        = "/v1"
+	defaultUserAgent = "blog-api-client"
+	// maxErrorBodySize bounds how much of an error response is read.
+	maxErrorBodySize = 64 << 10
+)
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	// BaseURL is the API's origin, such as https://blog.example.com, without
+	// the /v1 prefix.
+	BaseURL string
+	// HTTPClient sends the requests; http.DefaultClient when nil.
+	HTTPClient *http.Client
+	// Auth authorizes every request when set. The API only requires
+	// credentials for the requests that change posts, and for the export.
+	Auth      Auth
+	Retry     RetryPolicy
+	UserAgent string
+}
+
+// RetryPolicy controls retries of failed requests. Requests are retried on
+// 429 and 503, and on other 5xx responses and network errors only when they
+// are safe to repeat. The Retry-After header is honoured up to MaxBackoff.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts; 1 disables retries and
+	// zero means 3.
+	MaxAttempts int
+	// MinBackoff and MaxBackoff bound the exponential backoff, with full
+	// jitter. They default to 100ms and 5s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	auth       Auth
+	retry      RetryPolicy
+	userAgent  string
+	// sleep waits between attempts; replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a client for the API at cfg.BaseURL.
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("base URL %q must be an absolute http or https URL", cfg.BaseURL)
+	}
+
+	c := &Client{
+		baseURL:    strings.TrimSuffix(base.String(), "/"),
+		httpClient: cfg.HTTPClient,
+		auth:       cfg.Auth,
+		retry:      cfg.Retry,
+		userAgent:  cfg.UserAgent,
+		sleep:      sleep,
+	}
+	if c.httpClient == nil {
+		c.httpClient = http.DefaultClient
+	}
+	if c.userAgent == "" {
+		c.userAgent = defaultUserAgent
+	}
+	if c.retry.MaxAttempts <= 0 {
+		c.retry.MaxAttempts = 3
+	}
+	if c.retry.MinBackoff <= 0 {
+		c.retry.MinBackoff = 100 * time.Millisecond
+	}
+	if c.retry.MaxBackoff <= 0 {
+		c.retry.MaxBackoff = 5 * time.Second
+	}
+	return c, nil
+}
+
+// request describes one API call. The body is kept in memory so that the
+// request can be sent again.
+type request struct {
+	method string
+	// path follows the /v1 prefix and has its variables escaped.
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	accept      string
+	// idempotent requests are also retried on 5xx and network errors.
+	idempotent bool
+}
+
+func jsonRequest(method, path string, body interface{}, idempotent bool) (*request, error) {
+	req := &request{method: method, path: path, accept: "application/json", idempotent: idempotent}
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request body: %w", err)
+		}
+		req.body, req.contentType = data, "application/json"
+	}
+	return req, nil
+}
+
+// do sends req, retrying as the policy allows, and returns the response when
+// its status is one of ok. Any other status is returned as an *Error. The
+// caller closes the response body.
+func (c *Client) do(ctx context.Context, req *request, ok ...int) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req)
+		last := attempt >= c.retry.MaxAttempts
+		if err != nil {
+			if ctx.Err() != nil || !req.idempotent || last {
+				return nil, fmt.Errorf("failed to send %s %s: %w", req.method, req.path, err)
+			}
+			if err := c.sleep(ctx, c.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, status := range ok {
+			if resp.StatusCode == status {
+				return resp, nil
+			}
+		}
+		apiErr := decodeError(resp)
+		if last || !retryable(resp.StatusCode, req.idempotent) {
+			return nil, apiErr
+		}
+		wait := c.backoff(attempt)
+		if apiErr.RetryAfter > 0 {
+			wait = min(apiErr.RetryAfter, c.retry.MaxBackoff)
+		}
+		if err := c.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	target := c.baseURL + apiPrefix + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if req.accept != "" {
+		httpReq.Header.Set("Accept", req.accept)
+	}
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+	if c.auth != nil {
+		if err := c.auth.Authorize(ctx, httpReq); err != nil {
+			return nil, fmt.Errorf("failed to authorize request: %w", err)
+		}
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// retryable reports whether a response with status may be retried. 429 and
+// 503 mean the request was not processed; other 5xx may follow a partial
+// write and are only retried for idempotent requests.
+func retryable(status int, idempotent bool) bool {
+	switch {
+	case status == http.StatusTooManyRequests, status == http.StatusServiceUnavailable:
+		return true
+	case status >= 500:
+		return idempotent
+	default:
+		return false
+	}
+}
+
+// backoff returns the delay before attempt+1: a random duration up to
+// MinBackoff doubled for every attempt so far, capped at MaxBackoff.
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.retry.MinBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > c.retry.MaxBackoff {
+		ceiling = c.retry.MaxBackoff
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// decodeJSON decodes and closes the response body.
+func decodeJSON(resp *http.Response, v interface{}) error {
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(h http.Header, now time.Time) time.Duration {
+	value := h.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// drain reads what is left of a response body so the connection can be reused.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBodySize))
+	resp.Body.Close()
+}
+
+var errEmptyID = errors.New("post ID must not be empty")
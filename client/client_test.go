@@ -0,0 +1,437 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-api/internal/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient serves handler and returns a client for it that records the
+// waits between attempts instead of sleeping.
+func newTestClient(t *testing.T, handler http.HandlerFunc, cfg Config) (*Client, *[]time.Duration) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	cfg.BaseURL = server.URL
+	c, err := New(cfg)
+	require.NoError(t, err)
+	var waits []time.Duration
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return ctx.Err()
+	}
+	return c, &waits
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func apiError(w http.ResponseWriter, status int, description string) {
+	writeJSON(w, status, map[string]string{"error": http.StatusText(status), "description": description, "requestId": "req-1"})
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://example.com", "http://"} {
+		_, err := New(Config{BaseURL: baseURL})
+		assert.Error(t, err, baseURL)
+	}
+
+	c, err := New(Config{BaseURL: "https://blog.example.com/api/"})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://blog.example.com/api", c.baseURL)
+}
+
+func TestGetPost(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/v1/posts/a%2Fb":
+			writeJSON(w, http.StatusOK, Post{ID: "a/b", Title: "Hello"})
+		default:
+			apiError(w, http.StatusNotFound, "post not found")
+		}
+	}, Config{})
+
+	t.Run("Found", func(t *testing.T) {
+		post, err := c.GetPost(context.Background(), "a/b")
+		assert.NoError(t, err)
+		assert.Equal(t, &Post{ID: "a/b", Title: "Hello"}, post)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		_, err := c.GetPost(context.Background(), "missing")
+
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NotErrorIs(t, err, ErrServer)
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, &Error{StatusCode: 404, Title: "Not Found", Description: "post not found", RequestID: "req-1"}, apiErr)
+		assert.EqualError(t, err, "404 Not Found: post not found (request ID req-1)")
+	})
+
+	t.Run("Empty ID", func(t *testing.T) {
+		_, err := c.GetPost(context.Background(), "")
+		assert.Error(t, err)
+	})
+}
+
+func TestWritePosts(t *testing.T) {
+	var (
+		gotMethod string
+		gotBody   map[string]interface{}
+	)
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotBody = nil
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		status := http.StatusOK
+		if r.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, map[string]interface{}{
+			"id":    "1",
+			"title": "Hello",
+			"meta":  map[string]interface{}{"sanitization": map[string]interface{}{"removed": []interface{}{map[string]interface{}{"kind": "element", "name": "script", "count": 1}}}},
+		})
+	}, Config{})
+	ctx := context.Background()
+
+	t.Run("Create", func(t *testing.T) {
+		post, report, err := c.CreatePost(ctx, &Post{Title: "Hello", Content: "<script></script>", Author: "ann"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPost, gotMethod)
+		assert.Equal(t, "Hello", gotBody["title"])
+		assert.Equal(t, "1", post.ID)
+		assert.Equal(t, &SanitizationReport{Removed: []Removal{{Kind: "element", Name: "script", Count: 1}}}, report)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		_, _, err := c.UpdatePost(ctx, "1", &Post{Title: "Hello"})
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPut, gotMethod)
+	})
+
+	t.Run("Patch Sends Only Set Fields", func(t *testing.T) {
+		title, tags := "Hello", []string{}
+		_, _, err := c.PatchPost(ctx, "1", PostPatch{Title: &title, Tags: &tags})
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodPatch, gotMethod)
+		assert.Equal(t, map[string]interface{}{"title": "Hello", "tags": []interface{}{}}, gotBody)
+	})
+}
+
+func TestRetries(t *testing.T) {
+	t.Run("Idempotent Request Retried On 5xx", func(t *testing.T) {
+		attempts := 0
+		c, waits := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts < 3 {
+				apiError(w, http.StatusBadGateway, "upstream failed")
+				return
+			}
+			writeJSON(w, http.StatusOK, Post{ID: "1"})
+		}, Config{Retry: RetryPolicy{MinBackoff: 10 * time.Millisecond, MaxBackoff: time.Second}})
+
+		_, err := c.GetPost(context.Background(), "1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		require.Len(t, *waits, 2)
+		assert.LessOrEqual(t, (*waits)[0], 10*time.Millisecond)
+		assert.LessOrEqual(t, (*waits)[1], 20*time.Millisecond)
+	})
+
+	t.Run("Retry-After Honoured", func(t *testing.T) {
+		attempts := 0
+		c, waits := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts == 1 {
+				w.Header().Set("Retry-After", "2")
+				apiError(w, http.StatusTooManyRequests, "slow down")
+				return
+			}
+			writeJSON(w, http.StatusCreated, Post{ID: "1"})
+		}, Config{})
+
+		_, _, err := c.CreatePost(context.Background(), &Post{Title: "Hello"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Duration{2 * time.Second}, *waits)
+	})
+
+	t.Run("Create Not Retried On 500", func(t *testing.T) {
+		attempts := 0
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			apiError(w, http.StatusInternalServerError, "boom")
+		}, Config{})
+
+		_, _, err := c.CreatePost(context.Background(), &Post{Title: "Hello"})
+
+		assert.ErrorIs(t, err, ErrServer)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Gives Up After Max Attempts", func(t *testing.T) {
+		attempts := 0
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.Header().Set("Retry-After", "1")
+			apiError(w, http.StatusTooManyRequests, "slow down")
+		}, Config{Retry: RetryPolicy{MaxAttempts: 4}})
+
+		_, err := c.ListPosts(context.Background(), 1, 10)
+
+		assert.ErrorIs(t, err, ErrRateLimited)
+		var apiErr *Error
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, time.Second, apiErr.RetryAfter)
+		assert.Equal(t, 4, attempts)
+	})
+
+	t.Run("Client Errors Not Retried", func(t *testing.T) {
+		attempts := 0
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			apiError(w, http.StatusBadRequest, "post validation failed")
+		}, Config{})
+
+		_, _, err := c.UpdatePost(context.Background(), "1", &Post{})
+
+		assert.ErrorIs(t, err, ErrBadRequest)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("Non-JSON Error Body", func(t *testing.T) {
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "upstream timed out", http.StatusGatewayTimeout)
+		}, Config{Retry: RetryPolicy{MaxAttempts: 1}})
+
+		_, err := c.GetPost(context.Background(), "1")
+
+		assert.EqualError(t, err, "504 Gateway Timeout: upstream timed out")
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	header := func(v string) http.Header { return http.Header{"Retry-After": {v}} }
+
+	assert.Equal(t, 3*time.Second, retryAfter(header("3"), now))
+	assert.Equal(t, 30*time.Second, retryAfter(header("Wed, 01 May 2024 12:00:30 GMT"), now))
+	assert.Zero(t, retryAfter(header("Wed, 01 May 2024 11:00:00 GMT"), now))
+	assert.Zero(t, retryAfter(header("soon"), now))
+	assert.Zero(t, retryAfter(http.Header{}, now))
+}
+
+func TestAuth(t *testing.T) {
+	authenticator := auth.New(auth.Config{
+		APIKeys:     []string{"key-1"},
+		JWTSecret:   "secret",
+		JWTIssuer:   "publisher",
+		JWTAudience: "blog-api",
+	})
+	var principals []auth.Principal
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			apiError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		principals = append(principals, principal)
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	t.Run("API Key", func(t *testing.T) {
+		principals = nil
+		c, _ := newTestClient(t, handler, Config{Auth: APIKey("key-1")})
+		assert.NoError(t, c.DeletePost(context.Background(), "1"))
+		assert.Equal(t, auth.MethodAPIKey, principals[0].Method)
+	})
+
+	t.Run("Signed JWT", func(t *testing.T) {
+		principals = nil
+		jwtAuth, err := JWT(JWTConfig{Secret: []byte("secret"), Subject: "importer", Issuer: "publisher", Audience: "blog-api"})
+		require.NoError(t, err)
+		c, _ := newTestClient(t, handler, Config{Auth: jwtAuth})
+
+		assert.NoError(t, c.DeletePost(context.Background(), "1"))
+		assert.NoError(t, c.DeletePost(context.Background(), "2"))
+		assert.Equal(t, []auth.Principal{{Subject: "importer", Method: auth.MethodJWT}, {Subject: "importer", Method: auth.MethodJWT}}, principals)
+	})
+
+	t.Run("Wrong Credentials", func(t *testing.T) {
+		c, _ := newTestClient(t, handler, Config{Auth: BearerToken("not-a-key")})
+		assert.ErrorIs(t, c.DeletePost(context.Background(), "1"), ErrUnauthorized)
+	})
+
+	t.Run("JWT Reused Until Near Expiry", func(t *testing.T) {
+		a, err := JWT(JWTConfig{Secret: []byte("secret"), TTL: 10 * time.Minute})
+		require.NoError(t, err)
+		signer := a.(*jwtAuth)
+		now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+		signer.now = func() time.Time { return now }
+
+		first, _ := signer.current()
+		now = now.Add(8 * time.Minute)
+		second, _ := signer.current()
+		now = now.Add(time.Minute + time.Second)
+		third, _ := signer.current()
+
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, second, third)
+	})
+
+	t.Run("JWT Config", func(t *testing.T) {
+		_, err := JWT(JWTConfig{})
+		assert.Error(t, err)
+		_, err = JWT(JWTConfig{Secret: []byte("secret"), TTL: time.Second})
+		assert.Error(t, err)
+	})
+}
+
+func TestPostIterator(t *testing.T) {
+	var mu sync.Mutex
+	var pages []string
+	posts := make([]Post, 25)
+	for i := range posts {
+		posts[i] = Post{ID: strconv.Itoa(i)}
+	}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pages = append(pages, r.URL.RawQuery)
+		mu.Unlock()
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		start := min((page-1)*limit, len(posts))
+		writeJSON(w, http.StatusOK, posts[start:min(start+limit, len(posts))])
+	}
+
+	t.Run("Walks All Pages", func(t *testing.T) {
+		pages = nil
+		c, _ := newTestClient(t, handler, Config{})
+
+		var ids []string
+		it := c.Posts(10)
+		for it.Next(context.Background()) {
+			ids = append(ids, it.Post().ID)
+		}
+
+		assert.NoError(t, it.Err())
+		assert.Len(t, ids, 25)
+		assert.Equal(t, "24", ids[24])
+		assert.Equal(t, []string{"limit=10&page=1", "limit=10&page=2", "limit=10&page=3"}, pages)
+		assert.False(t, it.Next(context.Background()))
+	})
+
+	t.Run("Stops On Error", func(t *testing.T) {
+		c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("page") == "2" {
+				apiError(w, http.StatusInternalServerError, "failed to fetch posts")
+				return
+			}
+			handler(w, r)
+		}, Config{Retry: RetryPolicy{MaxAttempts: 1}})
+
+		count := 0
+		it := c.Posts(10)
+		for it.Next(context.Background()) {
+			count++
+		}
+
+		assert.Equal(t, 10, count)
+		assert.ErrorIs(t, it.Err(), ErrServer)
+		assert.Nil(t, it.Post())
+	})
+}
+
+func TestBatchPosts(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/posts:batchGet":
+			writeJSON(w, http.StatusOK, map[string]interface{}{"posts": []Post{{ID: "1"}}, "missing": []string{"2"}})
+		case "/v1/posts:batchDelete":
+			writeJSON(w, http.StatusConflict, BatchDeleteResponse{
+				Mode:    BatchModeTransactional,
+				Failed:  2,
+				Results: []BatchDeleteResult{{ID: "1", Status: "failed"}, {ID: "2", Status: "notFound"}},
+			})
+		}
+	}, Config{})
+
+	posts, missing, err := c.BatchGetPosts(context.Background(), []string{"1", "2"})
+	assert.NoError(t, err)
+	assert.Equal(t, []*Post{{ID: "1"}}, posts)
+	assert.Equal(t, []string{"2"}, missing)
+
+	result, err := c.BatchDeletePosts(context.Background(), []string{"1", "2"}, "")
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.Equal(t, "notFound", result.Results[1].Status)
+}
+
+func TestImportExport(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/posts:import":
+			assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, 2, strings.Count(string(body), "\n"))
+			assert.True(t, strings.HasPrefix(string(body), `{"id":"1","title":"One"`))
+			writeJSON(w, http.StatusOK, ImportReport{Imported: 2, Results: []ImportLineResult{{Line: 1, ID: "1", Status: "imported"}, {Line: 2, ID: "2", Status: "imported"}}})
+		case "/v1/posts:export":
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, "{\"id\":\"%d\"}\n", i)
+			}
+		}
+	}, Config{})
+
+	report, err := c.ImportPosts(context.Background(), []*Post{{ID: "1", Title: "One"}, {ID: "2", Title: "Two"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Imported)
+
+	var ids []string
+	assert.NoError(t, c.ExportPosts(context.Background(), func(post *Post) error {
+		ids = append(ids, post.ID)
+		return nil
+	}))
+	assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+	stop := errors.New("stop")
+	assert.Equal(t, stop, c.ExportPosts(context.Background(), func(*Post) error { return stop }))
+}
+
+func TestGetFeed(t *testing.T) {
+	c, _ := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/authors/ann%20lee/feed.atom", r.URL.EscapedPath())
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprint(w, "<feed/>")
+	}, Config{})
+
+	feed, err := c.GetFeed(context.Background(), FeedRequest{Format: FeedAtom, Author: "ann lee"})
+	assert.NoError(t, err)
+	assert.Equal(t, &Feed{ContentType: "application/atom+xml", Body: []byte("<feed/>")}, feed)
+
+	_, err = c.GetFeed(context.Background(), FeedRequest{Format: "xml"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors matched by an *Error with errors.Is, one per kind of failure the
+// API reports.
+var (
+	ErrBadRequest           = errors.New("bad request")
+	ErrUnauthorized         = errors.New("unauthorized")
+	ErrNotFound             = errors.New("not found")
+	ErrConflict             = errors.New("conflict")
+	ErrTooLarge             = errors.New("request body too large")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrServer               = errors.New("server error")
+)
+
+// Error is a response with an unexpected status. It carries the API's JSON
+// error body when there is one.
+type Error struct {
+	StatusCode int
+	// Title is the error field of the body, the status text of StatusCode.
+	Title       string
+	Description string
+	RequestID   string
+	// RetryAfter is the wait the server asked for, if any.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%d %s", e.StatusCode, e.Title)
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	if e.RequestID != "" {
+		msg += " (request ID " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// Is matches the sentinel error of the status code's kind.
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return target == ErrBadRequest
+	case http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case http.StatusNotFound:
+		return target == ErrNotFound
+	case http.StatusConflict:
+		return target == ErrConflict
+	case http.StatusRequestEntityTooLarge:
+		return target == ErrTooLarge
+	case http.StatusUnsupportedMediaType:
+		return target == ErrUnsupportedMediaType
+	case http.StatusTooManyRequests:
+		return target == ErrRateLimited
+	}
+	return e.StatusCode >= 500 && target == ErrServer
+}
+
+// errorBody is the JSON body of the API's error responses.
+type errorBody struct {
+	Error       string `json:"error"`
+	Description string `json:"description"`
+	RequestID   string `json:"requestId"`
+}
+
+// decodeError turns a failed response into an *Error and closes its body.
+// Bodies that are not the API's JSON errors, such as those of a proxy, are
+// kept as the description.
+func decodeError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		Title:      http.StatusText(resp.StatusCode),
+		RequestID:  resp.Header.Get("X-Request-ID"),
+		RetryAfter: retryAfter(resp.Header, time.Now()),
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	var body errorBody
+	if err := json.Unmarshal(data, &body); err == nil && body.Error != "" {
+		apiErr.Title, apiErr.Description = body.Error, body.Description
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+	} else {
+		apiErr.Description = strings.TrimSpace(string(data))
+	}
+	return apiErr
+}
@@ -0,0 +1,466 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"blog-api/internal/render"
+)
+
+// Batch delete modes.
+const (
+	BatchModeTransactional = "transactional"
+	BatchModeBestEffort    = "bestEffort"
+)
+
+// defaultPageSize is the server's page size when no limit is given.
+const defaultPageSize = 10
+
+// Feed formats.
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+// RenderedPost is a post with its content rendered to HTML.
+type RenderedPost struct {
+	*Post
+	HTML string      `json:"contentHtml"`
+	TOC  []*TOCEntry `json:"toc,omitempty"`
+	Meta *Meta       `json:"meta,omitempty"`
+}
+
+// TOCEntry is a heading of a rendered post.
+type TOCEntry = render.TOCEntry
+
+// Meta is how the server changed the stored or rendered content.
+type Meta struct {
+	Sanitization *SanitizationReport `json:"sanitization,omitempty"`
+}
+
+// postResponse is the body of the responses carrying a single post.
+type postResponse struct {
+	*Post
+	Meta *Meta `json:"meta,omitempty"`
+}
+
+// PostPatch lists the fields to change with PatchPost; nil fields are left as
+// they are.
+type PostPatch struct {
+	Title         *string   `json:"title,omitempty"`
+	Content       *string   `json:"content,omitempty"`
+	ContentFormat *string   `json:"contentFormat,omitempty"`
+	Author        *string   `json:"author,omitempty"`
+	Status        *string   `json:"status,omitempty"`
+	Tags          *[]string `json:"tags,omitempty"`
+}
+
+// BatchDeleteResult is the outcome of deleting one post of a batch.
+type BatchDeleteResult struct {
+	ID string `json:"id"`
+	// Status is "deleted", "notFound" or "failed".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchDeleteResponse struct {
+	Mode    string              `json:"mode"`
+	Deleted int                 `json:"deleted"`
+	Failed  int                 `json:"failed"`
+	Results []BatchDeleteResult `json:"results"`
+}
+
+// ImportLineResult is the outcome of importing one post; Line counts from 1.
+type ImportLineResult struct {
+	Line int    `json:"line"`
+	ID   string `json:"id,omitempty"`
+	// Status is "imported" or "failed".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Imported int                `json:"imported"`
+	Failed   int                `json:"failed"`
+	Results  []ImportLineResult `json:"results"`
+}
+
+// FeedRequest selects a feed. Author and Tag are exclusive; when both are
+// empty the site feed is returned.
+type FeedRequest struct {
+	Format string
+	Author string
+	Tag    string
+}
+
+// Feed is a feed document as served.
+type Feed struct {
+	ContentType string
+	Body        []byte
+}
+
+func postPath(id string) string {
+	return "/posts/" + url.PathEscape(id)
+}
+
+// ListPosts returns one page of posts, counting pages from 1. A limit of zero
+// uses the server's default of 10.
+func (c *Client) ListPosts(ctx context.Context, page, limit int) ([]*Post, error) {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	req := &request{method: http.MethodGet, path: "/posts", query: query, accept: "application/json", idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var posts []*Post
+	if err := decodeJSON(resp, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+// GetPost returns the post with id. It fails with ErrNotFound when there is none.
+func (c *Client) GetPost(ctx context.Context, id string) (*Post, error) {
+	if id == "" {
+		return nil, errEmptyID
+	}
+	req := &request{method: http.MethodGet, path: postPath(id), accept: "application/json", idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var post Post
+	if err := decodeJSON(resp, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// GetRenderedPost returns the post with id and its content rendered to HTML.
+func (c *Client) GetRenderedPost(ctx context.Context, id string) (*RenderedPost, error) {
+	if id == "" {
+		return nil, errEmptyID
+	}
+	req := &request{
+		method:     http.MethodGet,
+		path:       postPath(id),
+		query:      url.Values{"render": {"html"}},
+		accept:     "application/json",
+		idempotent: true,
+	}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var post RenderedPost
+	if err := decodeJSON(resp, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// CreatePost stores a new post and returns it as stored, with what the
+// server's sanitizer stripped from it, if anything. It is not retried on
+// errors that may follow a write, as that could create the post twice.
+func (c *Client) CreatePost(ctx context.Context, post *Post) (*Post, *SanitizationReport, error) {
+	req, err := jsonRequest(http.MethodPost, "/posts", post, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.writePost(ctx, req, http.StatusCreated)
+}
+
+// UpdatePost replaces the post with id.
+func (c *Client) UpdatePost(ctx context.Context, id string, post *Post) (*Post, *SanitizationReport, error) {
+	if id == "" {
+		return nil, nil, errEmptyID
+	}
+	req, err := jsonRequest(http.MethodPut, postPath(id), post, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.writePost(ctx, req, http.StatusOK)
+}
+
+// PatchPost changes the fields set in patch of the post with id.
+func (c *Client) PatchPost(ctx context.Context, id string, patch PostPatch) (*Post, *SanitizationReport, error) {
+	if id == "" {
+		return nil, nil, errEmptyID
+	}
+	req, err := jsonRequest(http.MethodPatch, postPath(id), patch, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.writePost(ctx, req, http.StatusOK)
+}
+
+func (c *Client) writePost(ctx context.Context, req *request, status int) (*Post, *SanitizationReport, error) {
+	resp, err := c.do(ctx, req, status)
+	if err != nil {
+		return nil, nil, err
+	}
+	var body postResponse
+	if err := decodeJSON(resp, &body); err != nil {
+		return nil, nil, err
+	}
+	if body.Post == nil {
+		return nil, nil, errors.New("failed to decode response: no post in body")
+	}
+	var report *SanitizationReport
+	if body.Meta != nil {
+		report = body.Meta.Sanitization
+	}
+	return body.Post, report, nil
+}
+
+// DeletePost deletes the post with id. A retried delete whose first attempt
+// succeeded fails with ErrNotFound.
+func (c *Client) DeletePost(ctx context.Context, id string) error {
+	if id == "" {
+		return errEmptyID
+	}
+	req := &request{method: http.MethodDelete, path: postPath(id), idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	drain(resp)
+	return nil
+}
+
+// BatchGetPosts returns up to 100 posts in the order of ids, followed by the
+// IDs that do not exist.
+func (c *Client) BatchGetPosts(ctx context.Context, ids []string) ([]*Post, []string, error) {
+	req, err := jsonRequest(http.MethodPost, "/posts:batchGet", map[string][]string{"ids": ids}, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, nil, err
+	}
+	var body struct {
+		Posts   []*Post  `json:"posts"`
+		Missing []string `json:"missing"`
+	}
+	if err := decodeJSON(resp, &body); err != nil {
+		return nil, nil, err
+	}
+	return body.Posts, body.Missing, nil
+}
+
+// BatchDeletePosts deletes up to 100 posts in mode, BatchModeTransactional
+// when empty. An aborted transaction returns the per-post results together
+// with an error matching ErrConflict.
+func (c *Client) BatchDeletePosts(ctx context.Context, ids []string, mode string) (*BatchDeleteResponse, error) {
+	body := struct {
+		IDs  []string `json:"ids"`
+		Mode string   `json:"mode,omitempty"`
+	}{IDs: ids, Mode: mode}
+	req, err := jsonRequest(http.MethodPost, "/posts:batchDelete", body, false)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(ctx, req, http.StatusOK, http.StatusConflict)
+	if err != nil {
+		return nil, err
+	}
+	status, requestID := resp.StatusCode, resp.Header.Get("X-Request-ID")
+
+	var result BatchDeleteResponse
+	if err := decodeJSON(resp, &result); err != nil {
+		return nil, err
+	}
+	if status == http.StatusConflict {
+		return &result, &Error{
+			StatusCode:  status,
+			Title:       http.StatusText(status),
+			Description: fmt.Sprintf("transaction aborted, %d of %d posts could not be deleted", result.Failed, len(result.Results)),
+			RequestID:   requestID,
+		}
+	}
+	return &result, nil
+}
+
+// ImportPosts stores posts in bulk, keeping their IDs when set, and reports
+// the outcome of each. A post without an ID may be created twice if the
+// import is repeated, so it is not retried on errors that may follow a write.
+func (c *Client) ImportPosts(ctx context.Context, posts []*Post) (*ImportReport, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, post := range posts {
+		if err := encoder.Encode(post); err != nil {
+			return nil, fmt.Errorf("failed to encode post: %w", err)
+		}
+	}
+	req := &request{
+		method:      http.MethodPost,
+		path:        "/posts:import",
+		body:        buf.Bytes(),
+		contentType: "application/x-ndjson",
+		accept:      "application/json",
+	}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var report ImportReport
+	if err := decodeJSON(resp, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ExportPosts streams every stored post to fn, stopping at the first error
+// fn returns. An export that fails part-way is not retried, as fn has
+// already seen some posts.
+func (c *Client) ExportPosts(ctx context.Context, fn func(*Post) error) error {
+	req := &request{method: http.MethodGet, path: "/posts:export", accept: "application/x-ndjson", idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var post Post
+		err := decoder.Decode(&post)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decode exported post: %w", err)
+		}
+		if err := fn(&post); err != nil {
+			return err
+		}
+	}
+}
+
+// GetFeed returns the site, author or tag feed in req.Format.
+func (c *Client) GetFeed(ctx context.Context, feed FeedRequest) (*Feed, error) {
+	switch feed.Format {
+	case FeedRSS, FeedAtom, FeedJSON:
+	default:
+		return nil, fmt.Errorf("unknown feed format %q", feed.Format)
+	}
+	path := "/feed." + feed.Format
+	switch {
+	case feed.Author != "" && feed.Tag != "":
+		return nil, errors.New("a feed is selected by author or tag, not both")
+	case feed.Author != "":
+		path = "/authors/" + url.PathEscape(feed.Author) + path
+	case feed.Tag != "":
+		path = "/tags/" + url.PathEscape(feed.Tag) + path
+	}
+	req := &request{method: http.MethodGet, path: path, idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	return &Feed{ContentType: resp.Header.Get("Content-Type"), Body: body}, nil
+}
+
+// GetOpenAPIDocument returns the API's OpenAPI document.
+func (c *Client) GetOpenAPIDocument(ctx context.Context) (json.RawMessage, error) {
+	req := &request{method: http.MethodGet, path: "/openapi.json", accept: "application/json", idempotent: true}
+
+	resp, err := c.do(ctx, req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	var doc json.RawMessage
+	if err := decodeJSON(resp, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// PostIterator walks the pages of ListPosts. Posts written while it runs may
+// be skipped or seen twice, since pages are counted by offset.
+type PostIterator struct {
+	client   *Client
+	pageSize int
+	page     int
+	buf      []*Post
+	current  *Post
+	done     bool
+	err      error
+}
+
+// Posts returns an iterator over all posts, fetching pageSize posts per
+// request; zero uses the server's default.
+//
+//	it := c.Posts(10)
+//	for it.Next(ctx) {
+//		post := it.Post()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+func (c *Client) Posts(pageSize int) *PostIterator {
+	return &PostIterator{client: c, pageSize: pageSize}
+}
+
+// Next advances to the next post, fetching the next page when needed. It
+// returns false when there are no more posts or a request failed.
+func (it *PostIterator) Next(ctx context.Context) bool {
+	for len(it.buf) == 0 {
+		if it.done || it.err != nil {
+			it.current = nil
+			return false
+		}
+		it.page++
+		posts, err := it.client.ListPosts(ctx, it.page, it.pageSize)
+		if err != nil {
+			it.err = err
+			continue
+		}
+		pageSize := it.pageSize
+		if pageSize <= 0 {
+			pageSize = defaultPageSize
+		}
+		it.done = len(posts) < pageSize
+		it.buf = posts
+	}
+	it.current, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Post returns the post Next advanced to.
+func (it *PostIterator) Post() *Post {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *PostIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/sanitize"
+	"blog-api/internal/services"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	// importBatchSize is the number of posts handed to the service at once.
+	importBatchSize = 100
+	// maxImportLineSize bounds the size of a single NDJSON line.
+	maxImportLineSize = 4 << 20
+)
+
+// postService is the part of services.PostService used by the commands.
+type postService interface {
+	GetPostByID(ctx context.Context, id string) (*models.Post, error)
+	CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error)
+	UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error)
+	DeletePost(ctx context.Context, id string) error
+	ImportPosts(ctx context.Context, posts []*models.Post) []error
+	ExportPosts(ctx context.Context, fn func(*models.Post) error) error
+	ReindexPosts(ctx context.Context, dryRun bool, fn func(services.ReindexResult)) error
+}
+
+var _ postService = (*services.PostService)(nil)
+
+// usageError reports a command line that cannot be run.
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// errLimitReached stops an export once enough posts have been listed.
+var errLimitReached = errors.New("limit reached")
+
+type cli struct {
+	service postService
+	in      io.Reader
+	out     io.Writer
+	errOut  io.Writer
+	format  string
+	// editor opens path in the user's editor and returns once it is closed.
+	editor func(path string) error
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	switch c.format {
+	case formatTable, formatJSON, formatYAML:
+	default:
+		return usagef("unknown output format %q, expected table, json or yaml", c.format)
+	}
+
+	commands := map[string]func(context.Context, []string) error{
+		"list":    c.list,
+		"get":     c.get,
+		"create":  c.create,
+		"edit":    c.edit,
+		"delete":  c.delete,
+		"import":  c.importPosts,
+		"export":  c.export,
+		"reindex": c.reindex,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		return usagef("unknown command %q", args[0])
+	}
+	return command(ctx, args[1:])
+}
+
+// flags returns the flag set of a command, printing usage to errOut.
+func (c *cli) flags(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	fs.Usage = func() {
+		fmt.Fprintf(c.errOut, "Usage: blogctl %s %s\n", name, synopsis)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func (c *cli) list(ctx context.Context, args []string) error {
+	fs := c.flags("list", "[flags]")
+	status := fs.String("status", "", "only posts with this status (draft or published)")
+	author := fs.String("author", "", "only posts by this author")
+	tag := fs.String("tag", "", "only posts with this tag")
+	limit := fs.Int("limit", 50, "maximum number of posts, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("list takes no arguments")
+	}
+	if *status != "" && *status != models.StatusDraft && *status != models.StatusPublished {
+		return usagef("unknown status %q, expected draft or published", *status)
+	}
+
+	posts := []*models.Post{}
+	err := c.service.ExportPosts(ctx, func(post *models.Post) error {
+		switch {
+		case *status != "" && post.IsPublished() != (*status == models.StatusPublished),
+			*author != "" && post.Author != *author,
+			*tag != "" && !hasTag(post, *tag):
+			return nil
+		}
+		posts = append(posts, post)
+		if *limit > 0 && len(posts) == *limit {
+			return errLimitReached
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errLimitReached) {
+		return err
+	}
+	return c.printPosts(posts)
+}
+
+func hasTag(post *models.Post, tag string) bool {
+	for _, t := range post.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *cli) get(ctx context.Context, args []string) error {
+	fs := c.flags("get", "<id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("get takes exactly one post ID")
+	}
+
+	post, err := c.service.GetPostByID(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return c.printPost(post)
+}
+
+// postTemplate is the document opened in the editor by create.
+const postTemplate = `# Fill in the new post. Lines starting with # are ignored.
+title: ""
+author: ""
+contentFormat: markdown
+status: draft
+tags: []
+content: |
+
+`
+
+func (c *cli) create(ctx context.Context, args []string) error {
+	fs := c.flags("create", "[-f file]")
+	file := fs.String("f", "", `read the post as JSON or YAML from file, or "-" for stdin, instead of opening $EDITOR`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("create takes no arguments")
+	}
+
+	var (
+		data []byte
+		err  error
+		path string
+	)
+	switch *file {
+	case "":
+		path, data, err = c.editDocument("blogctl-new-*.yaml", []byte(postTemplate))
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(data, []byte(postTemplate)) {
+			os.Remove(path)
+			fmt.Fprintln(c.errOut, "post unchanged, nothing created")
+			return nil
+		}
+	case "-":
+		data, err = io.ReadAll(c.in)
+	default:
+		data, err = os.ReadFile(*file)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read post: %w", err)
+	}
+
+	post, err := decodePost(data)
+	if err == nil {
+		var report *sanitize.Report
+		post, report, err = c.service.CreatePost(ctx, post)
+		c.reportSanitized(report)
+	}
+	if err != nil {
+		return keepEdits(err, path)
+	}
+	if path != "" {
+		os.Remove(path)
+	}
+	return c.printPost(post)
+}
+
+func (c *cli) edit(ctx context.Context, args []string) error {
+	fs := c.flags("edit", "<id>")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usagef("edit takes exactly one post ID")
+	}
+	id := fs.Arg(0)
+
+	post, err := c.service.GetPostByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	doc, err := toYAML(post)
+	if err != nil {
+		return err
+	}
+	original := append([]byte("# Editing post "+id+". Changes to id, version, createdAt and updatedAt are ignored.\n"), doc...)
+
+	path, data, err := c.editDocument("blogctl-"+id+"-*.yaml", original)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(data, original) {
+		os.Remove(path)
+		fmt.Fprintln(c.errOut, "post unchanged, nothing updated")
+		return nil
+	}
+
+	edited, err := decodePost(data)
+	if err == nil {
+		var report *sanitize.Report
+		edited, report, err = c.service.UpdatePost(ctx, id, edited)
+		c.reportSanitized(report)
+	}
+	if err != nil {
+		return keepEdits(err, path)
+	}
+	os.Remove(path)
+	return c.printPost(edited)
+}
+
+// editDocument writes doc to a temporary file, opens it in the editor and
+// returns the file's path and edited content.
+func (c *cli) editDocument(pattern string, doc []byte) (string, []byte, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	_, err = f.Write(doc)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", nil, fmt.Errorf("failed to write temporary file: %w", err)
+	}
+
+	if err := c.editor(path); err != nil {
+		os.Remove(path)
+		return "", nil, fmt.Errorf("failed to run editor: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read edited post: %w", err)
+	}
+	return path, data, nil
+}
+
+// keepEdits points at the file holding the user's edits, if any, so that a
+// rejected post does not have to be typed again.
+func keepEdits(err error, path string) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nyour edits are saved in %s", err, path)
+}
+
+func (c *cli) reportSanitized(report *sanitize.Report) {
+	if !report.Changed() {
+		return
+	}
+	for _, removal := range report.Removed {
+		fmt.Fprintf(c.errOut, "sanitized: removed %s %s (%d)\n", removal.Kind, removal.Name, removal.Count)
+	}
+}
+
+func (c *cli) delete(ctx context.Context, args []string) error {
+	fs := c.flags("delete", "[-yes] <id>...")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usagef("delete takes at least one post ID")
+	}
+
+	if !*yes {
+		fmt.Fprintf(c.errOut, "Delete %d post(s) %s? [y/N] ", fs.NArg(), strings.Join(fs.Args(), ", "))
+		answer, _ := bufio.NewReader(c.in).ReadString('\n')
+		if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+			return errors.New("aborted")
+		}
+	}
+
+	failed := 0
+	for _, id := range fs.Args() {
+		if err := c.service.DeletePost(ctx, id); err != nil {
+			fmt.Fprintf(c.errOut, "%s: %v\n", id, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(c.out, "deleted %s\n", id)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d posts", failed, fs.NArg())
+	}
+	return nil
+}
+
+// importResult is the outcome of importing one NDJSON line.
+type importResult struct {
+	Line   int    `json:"line"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c *cli) importPosts(ctx context.Context, args []string) error {
+	fs := c.flags("import", "[-f file]")
+	file := fs.String("f", "-", `NDJSON file, as written by export, or "-" for stdin`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("import takes no arguments")
+	}
+
+	in := c.in
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("failed to open import file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	results := []importResult{}
+	failed := 0
+	add := func(line int, id string, err error) {
+		result := importResult{Line: line, ID: id, Status: "imported"}
+		if err != nil {
+			result.Status, result.Error = "failed", err.Error()
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	var (
+		batch      []*models.Post
+		batchLines []int
+	)
+	flush := func() {
+		for i, err := range c.service.ImportPosts(ctx, batch) {
+			add(batchLines[i], batch[i].ID, err)
+		}
+		batch, batchLines = batch[:0], batchLines[:0]
+	}
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var post models.Post
+		if err := json.Unmarshal(raw, &post); err != nil {
+			add(line, "", fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+		batch = append(batch, &post)
+		batchLines = append(batchLines, line)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read import file: %w", err)
+	}
+	// Lines that are not JSON are reported before their batch is imported.
+	sort.Slice(results, func(i, j int) bool { return results[i].Line < results[j].Line })
+
+	err := c.print(results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "LINE\tID\tSTATUS\tERROR")
+		for _, r := range results {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", r.Line, r.ID, r.Status, r.Error)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to import %d of %d posts", failed, len(results))
+	}
+	return nil
+}
+
+func (c *cli) export(ctx context.Context, args []string) error {
+	fs := c.flags("export", "[-f file]")
+	file := fs.String("f", "-", `NDJSON file to write, or "-" for stdout`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("export takes no arguments")
+	}
+
+	out := c.out
+	var f *os.File
+	if *file != "-" {
+		var err error
+		if f, err = os.Create(*file); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+	count := 0
+	err := c.service.ExportPosts(ctx, func(post *models.Post) error {
+		count++
+		return encoder.Encode(post)
+	})
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if f != nil {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export posts: %w", err)
+	}
+	fmt.Fprintf(c.errOut, "exported %d posts\n", count)
+	return nil
+}
+
+// reindexResult is the outcome of reindexing one post as printed.
+type reindexResult struct {
+	ID     string `json:"id"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c *cli) reindex(ctx context.Context, args []string) error {
+	fs := c.flags("reindex", "[-dry-run] [-all]")
+	dryRun := fs.Bool("dry-run", false, "report the posts that would be rewritten without writing them")
+	all := fs.Bool("all", false, "also list posts that are already up to date")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return usagef("reindex takes no arguments")
+	}
+
+	started := time.Now()
+	results := []reindexResult{}
+	failed, total := 0, 0
+	err := c.service.ReindexPosts(ctx, *dryRun, func(r services.ReindexResult) {
+		total++
+		result := reindexResult{ID: r.ID, Result: "unchanged"}
+		switch {
+		case r.Err != nil:
+			result.Result, result.Error = "failed", r.Err.Error()
+			failed++
+		case r.Changed && *dryRun:
+			result.Result = "would rewrite"
+		case r.Changed:
+			result.Result = "rewritten"
+		case !*all:
+			return
+		}
+		results = append(results, result)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.print(results, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tRESULT\tERROR")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.ID, r.Result, r.Error)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.errOut, "checked %d posts in %s\n", total, time.Since(started).Round(time.Millisecond))
+	if failed > 0 {
+		return fmt.Errorf("failed to reindex %d of %d posts", failed, total)
+	}
+	return nil
+}
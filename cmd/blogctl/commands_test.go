@@ -0,0 +1,304 @@
+package main
+
+import (
+	"blog-api/internal/models"
+	"blog-api/internal/sanitize"
+	"blog-api/internal/services"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPostService struct {
+	mock.Mock
+	posts []*models.Post
+}
+
+func (m *MockPostService) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	args := m.Called(ctx, id)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+func (m *MockPostService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, post)
+	created, _ := args.Get(0).(*models.Post)
+	report, _ := args.Get(1).(*sanitize.Report)
+	return created, report, args.Error(2)
+}
+
+func (m *MockPostService) UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, id, post)
+	updated, _ := args.Get(0).(*models.Post)
+	report, _ := args.Get(1).(*sanitize.Report)
+	return updated, report, args.Error(2)
+}
+
+func (m *MockPostService) DeletePost(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockPostService) ImportPosts(ctx context.Context, posts []*models.Post) []error {
+	errs, _ := m.Called(ctx, posts).Get(0).([]error)
+	return errs
+}
+
+// ExportPosts feeds the posts field to fn, like the service paging through the table.
+func (m *MockPostService) ExportPosts(_ context.Context, fn func(*models.Post) error) error {
+	for _, post := range m.posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MockPostService) ReindexPosts(ctx context.Context, dryRun bool, fn func(services.ReindexResult)) error {
+	args := m.Called(ctx, dryRun)
+	results, _ := args.Get(0).([]services.ReindexResult)
+	for _, r := range results {
+		fn(r)
+	}
+	return args.Error(1)
+}
+
+var updatedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestCLI(service postService, format, stdin string) (*cli, *bytes.Buffer, *bytes.Buffer) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	return &cli{
+		service: service,
+		in:      strings.NewReader(stdin),
+		out:     out,
+		errOut:  errOut,
+		format:  format,
+		editor:  func(string) error { return nil },
+	}, out, errOut
+}
+
+func TestList(t *testing.T) {
+	service := &MockPostService{posts: []*models.Post{
+		{ID: "1", Title: "First", Author: "ann", Status: models.StatusPublished, Tags: []string{"go"}, UpdatedAt: updatedAt},
+		{ID: "2", Title: "Second", Author: "bob", Status: models.StatusDraft, UpdatedAt: updatedAt},
+		{ID: "3", Title: "Legacy", Author: "ann", UpdatedAt: updatedAt},
+	}}
+
+	t.Run("Table", func(t *testing.T) {
+		c, out, _ := newTestCLI(service, formatTable, "")
+		assert.NoError(t, c.run(context.Background(), []string{"list"}))
+		assert.Equal(t, ""+
+			"ID  TITLE   AUTHOR  STATUS       UPDATED\n"+
+			"1   First   ann     published    2024-05-01T12:00:00Z\n"+
+			"2   Second  bob     draft        2024-05-01T12:00:00Z\n"+
+			"3   Legacy  ann     (published)  2024-05-01T12:00:00Z\n", out.String())
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		c, out, _ := newTestCLI(service, formatJSON, "")
+		assert.NoError(t, c.run(context.Background(), []string{"list", "-status", "published", "-author", "ann"}))
+		assert.Contains(t, out.String(), `"id": "1"`)
+		assert.Contains(t, out.String(), `"id": "3"`)
+		assert.NotContains(t, out.String(), `"id": "2"`)
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		c, out, _ := newTestCLI(service, formatJSON, "")
+		assert.NoError(t, c.run(context.Background(), []string{"list", "-limit", "1"}))
+		assert.Contains(t, out.String(), `"id": "1"`)
+		assert.NotContains(t, out.String(), `"id": "2"`)
+	})
+
+	t.Run("Unknown Status", func(t *testing.T) {
+		c, _, _ := newTestCLI(service, formatTable, "")
+		err := c.run(context.Background(), []string{"list", "-status", "archived"})
+		assert.IsType(t, &usageError{}, err)
+	})
+}
+
+func TestGet(t *testing.T) {
+	post := &models.Post{ID: "1", Title: "Hello", Content: "line one\nline two", Author: "ann", Tags: []string{"go"}, Status: models.StatusPublished, Version: 2, CreatedAt: updatedAt, UpdatedAt: updatedAt}
+	service := new(MockPostService)
+	service.On("GetPostByID", mock.Anything, "1").Return(post, nil)
+	service.On("GetPostByID", mock.Anything, "2").Return(nil, &services.NotFoundError{Resource: "Post", ID: "2"})
+
+	t.Run("YAML", func(t *testing.T) {
+		c, out, _ := newTestCLI(service, formatYAML, "")
+		assert.NoError(t, c.run(context.Background(), []string{"get", "1"}))
+		assert.Equal(t, `id: "1"
+title: Hello
+content: |-
+  line one
+  line two
+author: ann
+tags:
+  - go
+status: published
+version: 2
+createdAt: "2024-05-01T12:00:00Z"
+updatedAt: "2024-05-01T12:00:00Z"
+`, out.String())
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		c, _, _ := newTestCLI(service, formatJSON, "")
+		assert.EqualError(t, c.run(context.Background(), []string{"get", "2"}), "Post with ID 2 not found")
+	})
+
+	t.Run("Missing ID", func(t *testing.T) {
+		c, _, _ := newTestCLI(service, formatJSON, "")
+		assert.IsType(t, &usageError{}, c.run(context.Background(), []string{"get"}))
+	})
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("From Stdin", func(t *testing.T) {
+		service := new(MockPostService)
+		service.On("CreatePost", mock.Anything, &models.Post{Title: "Hello", Content: "Body", Author: "ann", Tags: []string{"go"}}).
+			Return(&models.Post{ID: "new", Title: "Hello"}, &sanitize.Report{Removed: []sanitize.Removal{{Kind: "element", Name: "script", Count: 1}}}, nil)
+
+		c, out, errOut := newTestCLI(service, formatJSON, "title: Hello\ncontent: Body\nauthor: ann\ntags: [go]\n")
+		assert.NoError(t, c.run(context.Background(), []string{"create", "-f", "-"}))
+		assert.Contains(t, out.String(), `"id": "new"`)
+		assert.Equal(t, "sanitized: removed element script (1)\n", errOut.String())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		c, _, _ := newTestCLI(new(MockPostService), formatJSON, `{"titel": "Hello"}`)
+		assert.ErrorContains(t, c.run(context.Background(), []string{"create", "-f", "-"}), `unknown field "titel"`)
+	})
+
+	t.Run("Editor Left Unchanged", func(t *testing.T) {
+		service := new(MockPostService)
+		c, _, errOut := newTestCLI(service, formatJSON, "")
+		assert.NoError(t, c.run(context.Background(), []string{"create"}))
+		assert.Equal(t, "post unchanged, nothing created\n", errOut.String())
+		service.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+}
+
+func TestEdit(t *testing.T) {
+	stored := &models.Post{ID: "1", Title: "Hello", Content: "Body", Author: "ann", Status: models.StatusDraft, Version: 1}
+
+	t.Run("Updates Post", func(t *testing.T) {
+		service := new(MockPostService)
+		service.On("GetPostByID", mock.Anything, "1").Return(stored, nil)
+		service.On("UpdatePost", mock.Anything, "1", mock.MatchedBy(func(p *models.Post) bool {
+			return p.Title == "Hello again" && p.Content == "Body" && p.Status == models.StatusDraft
+		})).Return(&models.Post{ID: "1", Title: "Hello again", Version: 2}, nil, nil)
+
+		c, out, _ := newTestCLI(service, formatJSON, "")
+		c.editor = func(path string) error {
+			data, _ := os.ReadFile(path)
+			return os.WriteFile(path, bytes.Replace(data, []byte("title: Hello"), []byte("title: Hello again"), 1), 0o600)
+		}
+
+		assert.NoError(t, c.run(context.Background(), []string{"edit", "1"}))
+		assert.Contains(t, out.String(), `"version": 2`)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Rejected Edit Is Kept", func(t *testing.T) {
+		service := new(MockPostService)
+		service.On("GetPostByID", mock.Anything, "1").Return(stored, nil)
+		service.On("UpdatePost", mock.Anything, "1", mock.Anything).Return(nil, nil, errors.New("updated post validation failed"))
+
+		c, _, _ := newTestCLI(service, formatJSON, "")
+		var edited string
+		c.editor = func(path string) error {
+			edited = path
+			return os.WriteFile(path, []byte("title: x\ncontent: Body\nauthor: ann\n"), 0o600)
+		}
+
+		err := c.run(context.Background(), []string{"edit", "1"})
+		assert.EqualError(t, err, "updated post validation failed\nyour edits are saved in "+edited)
+		assert.FileExists(t, edited)
+		os.Remove(edited)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("Confirmed", func(t *testing.T) {
+		service := new(MockPostService)
+		service.On("DeletePost", mock.Anything, "1").Return(nil)
+		service.On("DeletePost", mock.Anything, "2").Return(&services.NotFoundError{Resource: "Post", ID: "2"})
+
+		c, out, errOut := newTestCLI(service, formatTable, "y\n")
+		err := c.run(context.Background(), []string{"delete", "1", "2"})
+
+		assert.EqualError(t, err, "failed to delete 1 of 2 posts")
+		assert.Equal(t, "deleted 1\n", out.String())
+		assert.Contains(t, errOut.String(), "2: Post with ID 2 not found")
+	})
+
+	t.Run("Declined", func(t *testing.T) {
+		service := new(MockPostService)
+		c, _, _ := newTestCLI(service, formatTable, "n\n")
+		assert.EqualError(t, c.run(context.Background(), []string{"delete", "1"}), "aborted")
+		service.AssertNotCalled(t, "DeletePost", mock.Anything, mock.Anything)
+	})
+}
+
+func TestImport(t *testing.T) {
+	service := new(MockPostService)
+	service.On("ImportPosts", mock.Anything, mock.MatchedBy(func(posts []*models.Post) bool {
+		return len(posts) == 2 && posts[0].ID == "a" && posts[1].ID == "b"
+	})).Return([]error{nil, errors.New("post validation failed")})
+
+	c, out, _ := newTestCLI(service, formatTable, `{"id":"a","title":"One","content":"x","author":"ann"}
+
+not json
+{"id":"b","title":"","content":"x","author":"ann"}
+`)
+	err := c.run(context.Background(), []string{"import"})
+
+	assert.EqualError(t, err, "failed to import 2 of 3 posts")
+	assert.Equal(t, ""+
+		"LINE  ID  STATUS    ERROR\n"+
+		"1     a   imported  \n"+
+		"3         failed    invalid JSON: invalid character 'o' in literal null (expecting 'u')\n"+
+		"4     b   failed    post validation failed\n", out.String())
+}
+
+func TestExport(t *testing.T) {
+	service := &MockPostService{posts: []*models.Post{{ID: "1", Title: "One"}, {ID: "2", Title: "Two"}}}
+	c, out, errOut := newTestCLI(service, formatTable, "")
+
+	assert.NoError(t, c.run(context.Background(), []string{"export"}))
+	assert.Equal(t, 2, strings.Count(out.String(), "\n"))
+	assert.True(t, strings.HasPrefix(out.String(), `{"id":"1","title":"One"`))
+	assert.Equal(t, "exported 2 posts\n", errOut.String())
+}
+
+func TestReindex(t *testing.T) {
+	service := new(MockPostService)
+	service.On("ReindexPosts", mock.Anything, true).Return([]services.ReindexResult{
+		{ID: "1"},
+		{ID: "2", Changed: true},
+		{ID: "3", Err: errors.New("post validation failed")},
+	}, nil)
+
+	c, out, _ := newTestCLI(service, formatJSON, "")
+	err := c.run(context.Background(), []string{"reindex", "-dry-run"})
+
+	assert.EqualError(t, err, "failed to reindex 1 of 3 posts")
+	assert.JSONEq(t, `[
+		{"id": "2", "result": "would rewrite"},
+		{"id": "3", "result": "failed", "error": "post validation failed"}
+	]`, out.String())
+}
+
+func TestRun(t *testing.T) {
+	c, _, _ := newTestCLI(new(MockPostService), "xml", "")
+	assert.EqualError(t, c.run(context.Background(), []string{"list"}), `unknown output format "xml", expected table, json or yaml`)
+
+	c.format = formatTable
+	assert.EqualError(t, c.run(context.Background(), []string{"publish"}), `unknown command "publish"`)
+}
@@ -0,0 +1,128 @@
+// Command blogctl manages the posts of a blog-api table from the command
+// line. Every write goes through the same validation and sanitization as the
+// API.
+package main
+
+import (
+	"blog-api/internal/config"
+	"blog-api/internal/logging"
+	"blog-api/internal/render"
+	"blog-api/internal/repository"
+	"blog-api/internal/services"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+)
+
+// renderCacheSize is small: blogctl renders nothing, but the service needs a renderer.
+const renderCacheSize = 16
+
+const usage = `Usage: blogctl [global flags] <command> [flags] [args]
+
+Commands:
+  list      list posts
+  get       print a post
+  create    create a post from a file, stdin or $EDITOR
+  edit      edit a post in $EDITOR
+  delete    delete posts
+  import    import posts from NDJSON
+  export    export every post as NDJSON
+  reindex   rewrite posts whose stored form is out of date
+
+Run "blogctl <command> -h" for the flags of a command.
+
+Global flags:
+`
+
+func main() {
+	fs := flag.NewFlagSet("blogctl", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	configFile := fs.String("config", "", "YAML or JSON config file (or $"+config.FileEnv+")")
+	table := fs.String("table", "", "DynamoDB table name (or $DYNAMODB_TABLE)")
+	output := fs.String("o", formatTable, "output format: table, json or yaml")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var cfgArgs []string
+	if *configFile != "" {
+		cfgArgs = append(cfgArgs, "--config", *configFile)
+	}
+	if *table != "" {
+		cfgArgs = append(cfgArgs, "--storage.table", *table)
+	}
+	cfg, _, err := config.Load(cfgArgs, os.LookupEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "blogctl: invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	logger := logging.New(os.Stderr, cfg.LoggingConfig())
+	slog.SetDefault(logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = logging.WithLogger(ctx, logger)
+
+	service, err := newPostService(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "blogctl: %v\n", err)
+		os.Exit(1)
+	}
+
+	c := &cli{
+		service: service,
+		in:      os.Stdin,
+		out:     os.Stdout,
+		errOut:  os.Stderr,
+		format:  *output,
+		editor:  runEditor,
+	}
+	if err := c.run(ctx, fs.Args()); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "blogctl: %v\n", err)
+		}
+		var usageErr *usageError
+		if errors.As(err, &usageErr) || errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// newPostService wires the service to the configured table the way the API
+// does, writing through the outbox when one is configured.
+func newPostService(ctx context.Context, cfg config.Config) (*services.PostService, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Storage.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	client := dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		if cfg.Storage.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Storage.Endpoint)
+		}
+	})
+
+	repo := repository.NewDynamoPostRepository(client, cfg.Storage.Table)
+	repo.OutboxTable = cfg.Outbox.Table
+	policy := cfg.SanitizePolicy()
+	return services.NewPostService(repo, render.NewRenderer(renderCacheSize, policy), policy), nil
+}
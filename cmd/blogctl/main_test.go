@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-api/internal/config"
+	"blog-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPostService(t *testing.T) {
+	t.Run("Writes Through The Outbox", func(t *testing.T) {
+		var targets, bodies []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			targets = append(targets, r.Header.Get("X-Amz-Target"))
+			bodies = append(bodies, string(body))
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			io.WriteString(w, `{}`)
+		}))
+		defer server.Close()
+		t.Setenv("AWS_ACCESS_KEY_ID", "key")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+		t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+		cfg := config.Default(func(string) (string, bool) { return "", false })
+		cfg.Storage.Endpoint = server.URL
+		cfg.Storage.Table = "Posts"
+		cfg.Outbox.Table = "Outbox"
+		service, err := newPostService(context.Background(), cfg)
+		require.NoError(t, err)
+
+		_, _, err = service.CreatePost(context.Background(), &models.Post{Title: "Hello", Content: "Body", Author: "ann"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"DynamoDB_20120810.TransactWriteItems"}, targets)
+		assert.Contains(t, bodies[0], `"TableName":"Posts"`)
+		assert.Contains(t, bodies[0], `"TableName":"Outbox"`)
+	})
+}
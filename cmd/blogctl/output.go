@@ -0,0 +1,158 @@
+package main
+
+import (
+	"blog-api/internal/models"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats selected with -o.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+)
+
+// print writes v as JSON or YAML, or calls table to write it as a table.
+func (c *cli) print(v interface{}, table func(w *tabwriter.Writer)) error {
+	switch c.format {
+	case formatJSON:
+		encoder := json.NewEncoder(c.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case formatYAML:
+		doc, err := toYAML(v)
+		if err != nil {
+			return err
+		}
+		_, err = c.out.Write(doc)
+		return err
+	default:
+		w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	}
+}
+
+func (c *cli) printPosts(posts []*models.Post) error {
+	return c.print(posts, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tTITLE\tAUTHOR\tSTATUS\tUPDATED")
+		for _, post := range posts {
+			status := post.Status
+			if status == "" {
+				status = "(" + models.StatusPublished + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", post.ID, truncate(post.Title, 50), post.Author, status, post.UpdatedAt.Format(time.RFC3339))
+		}
+	})
+}
+
+func (c *cli) printPost(post *models.Post) error {
+	return c.print(post, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "ID:\t%s\n", post.ID)
+		fmt.Fprintf(w, "Title:\t%s\n", post.Title)
+		fmt.Fprintf(w, "Author:\t%s\n", post.Author)
+		fmt.Fprintf(w, "Status:\t%s\n", post.Status)
+		fmt.Fprintf(w, "Format:\t%s\n", post.Format())
+		fmt.Fprintf(w, "Tags:\t%s\n", strings.Join(post.Tags, ", "))
+		fmt.Fprintf(w, "Version:\t%d\n", post.Version)
+		fmt.Fprintf(w, "Created:\t%s\n", post.CreatedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "Updated:\t%s\n", post.UpdatedAt.Format(time.RFC3339))
+		fmt.Fprintf(w, "\n%s\n", post.Content)
+	})
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// toYAML writes v as YAML with the field names and order of its JSON form,
+// so that both formats read the same and edited YAML decodes like the API's
+// JSON.
+func toYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode: %w", err)
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to convert to YAML: %w", err)
+	}
+	blockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle drops the flow style and quoting JSON input comes with. Multi-line
+// strings become literal blocks, which are far easier to edit.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" && strings.Contains(node.Value, "\n") {
+		node.Style = yaml.LiteralStyle
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// decodePost reads a post given as JSON or YAML. Unknown fields are rejected
+// so that a misspelt field is not silently dropped.
+func decodePost(data []byte) (*models.Post, error) {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid post: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("invalid post: document is empty")
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var post models.Post
+	if err := decoder.Decode(&post); err != nil {
+		return nil, fmt.Errorf("invalid post: %w", err)
+	}
+	return &post, nil
+}
+
+// runEditor opens path in $VISUAL or $EDITOR, falling back to vi. The
+// variable may hold arguments, such as "code --wait".
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,140 @@
+// Package auth authenticates API clients by static API key or by HS256 JWT.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// APIKeyHeader is the header carrying an API key. Keys are also accepted as
+// bearer tokens.
+const APIKeyHeader = "X-Api-Key"
+
+// Authentication methods.
+const (
+	MethodAPIKey = "apikey"
+	MethodJWT    = "jwt"
+)
+
+var (
+	// ErrMissingCredentials is returned when a request carries no credentials.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the credentials are not accepted.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Config lists the accepted credentials. Authentication is disabled when
+// neither API keys nor a JWT secret are set.
+type Config struct {
+	APIKeys     []string
+	JWTSecret   string
+	JWTIssuer   string
+	JWTAudience string
+}
+
+// Principal is the authenticated caller.
+type Principal struct {
+	// Subject is the JWT "sub" claim, or "apikey:<n>" naming the matching key.
+	Subject string
+	Method  string
+}
+
+// Authenticator checks request credentials against a Config.
+type Authenticator struct {
+	keyHashes [][sha256.Size]byte
+	secret    []byte
+	parser    *jwt.Parser
+}
+
+// New creates an authenticator for cfg.
+func New(cfg Config) *Authenticator {
+	a := &Authenticator{}
+	for _, key := range cfg.APIKeys {
+		a.keyHashes = append(a.keyHashes, sha256.Sum256([]byte(key)))
+	}
+	if cfg.JWTSecret != "" {
+		a.secret = []byte(cfg.JWTSecret)
+		opts := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()}
+		if cfg.JWTIssuer != "" {
+			opts = append(opts, jwt.WithIssuer(cfg.JWTIssuer))
+		}
+		if cfg.JWTAudience != "" {
+			opts = append(opts, jwt.WithAudience(cfg.JWTAudience))
+		}
+		a.parser = jwt.NewParser(opts...)
+	}
+	return a
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.keyHashes) > 0 || a.parser != nil
+}
+
+// Authenticate accepts "X-Api-Key: <key>" or "Authorization: Bearer <token>",
+// where the token is an API key or a JWT.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	return a.AuthenticateCredentials(r.Header.Get(APIKeyHeader), r.Header.Get("Authorization"))
+}
+
+// AuthenticateCredentials is Authenticate for the values of the two headers
+// taken from elsewhere, such as gRPC metadata.
+func (a *Authenticator) AuthenticateCredentials(key, header string) (Principal, error) {
+	if key != "" {
+		return a.apiKey(key)
+	}
+	if header == "" {
+		return Principal{}, ErrMissingCredentials
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, ErrInvalidCredentials
+	}
+	if principal, err := a.apiKey(token); err == nil {
+		return principal, nil
+	}
+	return a.jwt(token)
+}
+
+func (a *Authenticator) apiKey(key string) (Principal, error) {
+	hash := sha256.Sum256([]byte(key))
+	for i, known := range a.keyHashes {
+		if subtle.ConstantTimeCompare(hash[:], known[:]) == 1 {
+			return Principal{Subject: fmt.Sprintf("apikey:%d", i), Method: MethodAPIKey}, nil
+		}
+	}
+	return Principal{}, ErrInvalidCredentials
+}
+
+func (a *Authenticator) jwt(token string) (Principal, error) {
+	if a.parser == nil {
+		return Principal{}, ErrInvalidCredentials
+	}
+	var claims jwt.RegisteredClaims
+	if _, err := a.parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return Principal{Subject: claims.Subject, Method: MethodJWT}, nil
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal carried by ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(Principal)
+	return p, ok
+}
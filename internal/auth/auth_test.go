@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func signedToken(t *testing.T, claims jwt.RegisteredClaims, method jwt.SigningMethod, key interface{}) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	assert.NoError(t, err)
+	return token
+}
+
+func TestAuthenticate(t *testing.T) {
+	a := New(Config{APIKeys: []string{"key-one", "key-two"}, JWTSecret: testSecret, JWTIssuer: "blog", JWTAudience: "blog-api"})
+	valid := jwt.RegisteredClaims{
+		Subject:   "editor-7",
+		Issuer:    "blog",
+		Audience:  jwt.ClaimStrings{"blog-api"},
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	authenticate := func(header, value string) (Principal, error) {
+		req := httptest.NewRequest("POST", "/v1/posts", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		return a.Authenticate(req)
+	}
+
+	t.Run("API Key Header", func(t *testing.T) {
+		p, err := authenticate(APIKeyHeader, "key-two")
+		assert.NoError(t, err)
+		assert.Equal(t, Principal{Subject: "apikey:1", Method: MethodAPIKey}, p)
+	})
+
+	t.Run("API Key As Bearer", func(t *testing.T) {
+		p, err := authenticate("Authorization", "Bearer key-one")
+		assert.NoError(t, err)
+		assert.Equal(t, MethodAPIKey, p.Method)
+	})
+
+	t.Run("Valid JWT", func(t *testing.T) {
+		p, err := authenticate("Authorization", "Bearer "+signedToken(t, valid, jwt.SigningMethodHS256, []byte(testSecret)))
+		assert.NoError(t, err)
+		assert.Equal(t, Principal{Subject: "editor-7", Method: MethodJWT}, p)
+	})
+
+	t.Run("Expired JWT", func(t *testing.T) {
+		claims := valid
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		_, err := authenticate("Authorization", "Bearer "+signedToken(t, claims, jwt.SigningMethodHS256, []byte(testSecret)))
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+
+	t.Run("Wrong Audience", func(t *testing.T) {
+		claims := valid
+		claims.Audience = jwt.ClaimStrings{"other"}
+		_, err := authenticate("Authorization", "Bearer "+signedToken(t, claims, jwt.SigningMethodHS256, []byte(testSecret)))
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+
+	t.Run("Wrong Secret", func(t *testing.T) {
+		_, err := authenticate("Authorization", "Bearer "+signedToken(t, valid, jwt.SigningMethodHS256, []byte("another-secret-of-enough-length!")))
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+
+	t.Run("Unsigned JWT", func(t *testing.T) {
+		_, err := authenticate("Authorization", "Bearer "+signedToken(t, valid, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType))
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+
+	t.Run("Unknown Key", func(t *testing.T) {
+		_, err := authenticate(APIKeyHeader, "nope")
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		_, err := authenticate("", "")
+		assert.True(t, errors.Is(err, ErrMissingCredentials))
+	})
+
+	t.Run("Basic Scheme", func(t *testing.T) {
+		_, err := authenticate("Authorization", "Basic dXNlcjpwYXNz")
+		assert.True(t, errors.Is(err, ErrInvalidCredentials))
+	})
+}
+
+func TestEnabled(t *testing.T) {
+	assert.False(t, New(Config{}).Enabled())
+	assert.True(t, New(Config{APIKeys: []string{"k"}}).Enabled())
+	assert.True(t, New(Config{JWTSecret: testSecret}).Enabled())
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	p := Principal{Subject: "s", Method: MethodJWT}
+	got, ok := FromContext(WithPrincipal(context.Background(), p))
+	assert.True(t, ok)
+	assert.Equal(t, p, got)
+}
@@ -0,0 +1,161 @@
+package cdc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	postevents "blog-api/internal/events"
+	"blog-api/internal/models"
+	"blog-api/internal/repository"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Change is a domain event decoded from one stream record of the posts table.
+type Change struct {
+	// ID is the stream record's event ID, unique to this change.
+	ID string
+	// Type is postevents.TypeCreated, TypeUpdated or TypeDeleted.
+	Type   string
+	PostID string
+	// Old is the post before the change and New after it; Old is nil for
+	// created posts and New for deleted ones.
+	Old *models.Post
+	New *models.Post
+	// Fields lists the JSON names of the fields an update changed.
+	Fields []string
+	Time   time.Time
+}
+
+var changeTypes = map[string]string{
+	"INSERT": postevents.TypeCreated,
+	"MODIFY": postevents.TypeUpdated,
+	"REMOVE": postevents.TypeDeleted,
+}
+
+// bookkeepingFields change on every write and are left out of Change.Fields.
+var bookkeepingFields = map[string]bool{"version": true, "updatedAt": true}
+
+// decode turns a stream record into a change. It reports false for records
+// of items that are not posts, such as the migrations' schema item.
+func decode(record events.DynamoDBEventRecord) (Change, bool, error) {
+	id := record.Change.Keys["ID"]
+	if id.DataType() != events.DataTypeString {
+		return Change{}, false, fmt.Errorf("record %s has no string ID key", record.EventID)
+	}
+	if id.String() == repository.SchemaItemID {
+		return Change{}, false, nil
+	}
+	changeType, ok := changeTypes[record.EventName]
+	if !ok {
+		return Change{}, false, fmt.Errorf("record %s has unknown event name %q", record.EventID, record.EventName)
+	}
+
+	old, err := decodePost(record.Change.OldImage)
+	if err != nil {
+		return Change{}, false, fmt.Errorf("failed to decode old image of record %s: %w", record.EventID, err)
+	}
+	current, err := decodePost(record.Change.NewImage)
+	if err != nil {
+		return Change{}, false, fmt.Errorf("failed to decode new image of record %s: %w", record.EventID, err)
+	}
+
+	change := Change{
+		ID:     record.EventID,
+		Type:   changeType,
+		PostID: id.String(),
+		Old:    old,
+		New:    current,
+		Time:   record.Change.ApproximateCreationDateTime.UTC(),
+	}
+	if changeType == postevents.TypeUpdated {
+		if old == nil || current == nil {
+			return Change{}, false, fmt.Errorf("record %s lacks an image; the stream must use NEW_AND_OLD_IMAGES", record.EventID)
+		}
+		change.Fields = diff(old, current)
+	}
+	return change, true, nil
+}
+
+// decodePost decodes a stream image, returning nil for an absent one.
+func decodePost(image map[string]events.DynamoDBAttributeValue) (*models.Post, error) {
+	if len(image) == 0 {
+		return nil, nil
+	}
+	item := make(map[string]types.AttributeValue, len(image))
+	for name, value := range image {
+		converted, err := attributeValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %w", name, err)
+		}
+		item[name] = converted
+	}
+	var post models.Post
+	if err := attributevalue.UnmarshalMap(item, &post); err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
+// attributeValue converts a stream attribute to its SDK counterpart.
+func attributeValue(v events.DynamoDBAttributeValue) (types.AttributeValue, error) {
+	switch v.DataType() {
+	case events.DataTypeString:
+		return &types.AttributeValueMemberS{Value: v.String()}, nil
+	case events.DataTypeNumber:
+		return &types.AttributeValueMemberN{Value: v.Number()}, nil
+	case events.DataTypeBinary:
+		return &types.AttributeValueMemberB{Value: v.Binary()}, nil
+	case events.DataTypeBoolean:
+		return &types.AttributeValueMemberBOOL{Value: v.Boolean()}, nil
+	case events.DataTypeNull:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case events.DataTypeStringSet:
+		return &types.AttributeValueMemberSS{Value: v.StringSet()}, nil
+	case events.DataTypeNumberSet:
+		return &types.AttributeValueMemberNS{Value: v.NumberSet()}, nil
+	case events.DataTypeBinarySet:
+		return &types.AttributeValueMemberBS{Value: v.BinarySet()}, nil
+	case events.DataTypeList:
+		list := make([]types.AttributeValue, 0, len(v.List()))
+		for _, element := range v.List() {
+			converted, err := attributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case events.DataTypeMap:
+		m := make(map[string]types.AttributeValue, len(v.Map()))
+		for name, element := range v.Map() {
+			converted, err := attributeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = converted
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	}
+	return nil, fmt.Errorf("unsupported data type %d", v.DataType())
+}
+
+// diff returns the JSON names of the fields that differ between old and
+// current, in declaration order.
+func diff(old, current *models.Post) []string {
+	var fields []string
+	before, after := reflect.ValueOf(*old), reflect.ValueOf(*current)
+	for i := 0; i < before.NumField(); i++ {
+		name, _, _ := strings.Cut(before.Type().Field(i).Tag.Get("json"), ",")
+		if bookkeepingFields[name] {
+			continue
+		}
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,101 @@
+// Package cdc consumes the DynamoDB stream of the posts table (change data
+// capture). Every record becomes a Change that is handed to each sink, so
+// side effects run outside the request path and survive a failed request
+// handler.
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// Sink receives the changes of the posts table.
+type Sink interface {
+	// Name identifies the sink in idempotency keys, so it must stay the
+	// same across deployments.
+	Name() string
+	Handle(ctx context.Context, change Change) error
+}
+
+// IdempotencyStore remembers which sinks have handled which changes.
+type IdempotencyStore interface {
+	Seen(ctx context.Context, key string) (bool, error)
+	Mark(ctx context.Context, key string) error
+}
+
+// Config holds the consumer settings. A nil Store remembers handled changes
+// only in memory, which does not survive a cold start.
+type Config struct {
+	Store  IdempotencyStore
+	Logger *slog.Logger
+}
+
+type Consumer struct {
+	sinks  []Sink
+	store  IdempotencyStore
+	logger *slog.Logger
+}
+
+func NewConsumer(sinks []Sink, cfg Config) *Consumer {
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryStore(memoryStoreSize)
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Consumer{sinks: sinks, store: store, logger: logger}
+}
+
+// Handle processes the records of a batch in order. It stops at the first
+// record a sink fails on and reports it as the batch item failure, so Lambda
+// retries the batch from there and changes to a post are never reordered.
+// Sinks that already handled a retried record are skipped. Records that
+// cannot be decoded are logged and skipped, as retrying cannot fix them.
+func (c *Consumer) Handle(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	var response events.DynamoDBEventResponse
+	for _, record := range event.Records {
+		change, ok, err := decode(record)
+		if err != nil {
+			c.logger.Error("skipped undecodable stream record", "event_id", record.EventID, "error", err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := c.dispatch(ctx, change); err != nil {
+			c.logger.Error("failed to handle post change", "event_id", change.ID, "post_id", change.PostID,
+				"type", change.Type, "sequence_number", record.Change.SequenceNumber, "error", err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.DynamoDBBatchItemFailure{
+				ItemIdentifier: record.Change.SequenceNumber,
+			})
+			return response, nil
+		}
+	}
+	return response, nil
+}
+
+// dispatch hands change to every sink that has not handled it yet.
+func (c *Consumer) dispatch(ctx context.Context, change Change) error {
+	for _, sink := range c.sinks {
+		key := change.ID + "#" + sink.Name()
+		seen, err := c.store.Seen(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check whether %s handled the change: %w", sink.Name(), err)
+		}
+		if seen {
+			continue
+		}
+		if err := sink.Handle(ctx, change); err != nil {
+			return fmt.Errorf("sink %s failed: %w", sink.Name(), err)
+		}
+		if err := c.store.Mark(ctx, key); err != nil {
+			return fmt.Errorf("failed to record that %s handled the change: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
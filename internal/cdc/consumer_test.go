@@ -0,0 +1,284 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	postevents "blog-api/internal/events"
+	"blog-api/internal/models"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink records the changes it handles and fails on the event IDs
+// in fail.
+type recordingSink struct {
+	name    string
+	fail    map[string]bool
+	handled []Change
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Handle(ctx context.Context, change Change) error {
+	if s.fail[change.ID] {
+		return errors.New("unavailable")
+	}
+	s.handled = append(s.handled, change)
+	return nil
+}
+
+func (s *recordingSink) eventIDs() []string {
+	ids := make([]string, len(s.handled))
+	for i, change := range s.handled {
+		ids[i] = change.ID
+	}
+	return ids
+}
+
+func image(id, title, status string, tags ...string) map[string]events.DynamoDBAttributeValue {
+	item := map[string]events.DynamoDBAttributeValue{
+		"ID":        events.NewStringAttribute(id),
+		"Title":     events.NewStringAttribute(title),
+		"Content":   events.NewStringAttribute("Body"),
+		"Author":    events.NewStringAttribute("ann"),
+		"Status":    events.NewStringAttribute(status),
+		"Version":   events.NewNumberAttribute("1"),
+		"CreatedAt": events.NewNumberAttribute("1760788800"),
+		"UpdatedAt": events.NewNumberAttribute("1760788800"),
+	}
+	if len(tags) > 0 {
+		list := make([]events.DynamoDBAttributeValue, len(tags))
+		for i, tag := range tags {
+			list[i] = events.NewStringAttribute(tag)
+		}
+		item["Tags"] = events.NewListAttribute(list)
+	}
+	return item
+}
+
+func record(eventID, name, sequence string, old, current map[string]events.DynamoDBAttributeValue) events.DynamoDBEventRecord {
+	id := current["ID"]
+	if current == nil {
+		id = old["ID"]
+	}
+	return events.DynamoDBEventRecord{
+		EventID:   eventID,
+		EventName: name,
+		Change: events.DynamoDBStreamRecord{
+			ApproximateCreationDateTime: events.SecondsEpochTime{Time: time.Unix(1760788800, 0)},
+			Keys:                        map[string]events.DynamoDBAttributeValue{"ID": id},
+			OldImage:                    old,
+			NewImage:                    current,
+			SequenceNumber:              sequence,
+		},
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("Created", func(t *testing.T) {
+		change, ok, err := decode(record("e1", "INSERT", "100", nil, image("p1", "Hello", models.StatusDraft, "go")))
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "e1", change.ID)
+		assert.Equal(t, postevents.TypeCreated, change.Type)
+		assert.Equal(t, "p1", change.PostID)
+		assert.Nil(t, change.Old)
+		assert.Equal(t, "Hello", change.New.Title)
+		assert.Equal(t, []string{"go"}, change.New.Tags)
+		assert.Equal(t, time.Unix(1760788800, 0).UTC(), change.Time)
+	})
+
+	t.Run("Updated With Changed Fields", func(t *testing.T) {
+		old := image("p1", "Hello", models.StatusDraft, "go")
+		current := image("p1", "Hello again", models.StatusPublished, "go")
+		current["Version"] = events.NewNumberAttribute("2")
+		current["UpdatedAt"] = events.NewNumberAttribute("1760792400")
+
+		change, ok, err := decode(record("e2", "MODIFY", "101", old, current))
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, postevents.TypeUpdated, change.Type)
+		assert.Equal(t, []string{"title", "status"}, change.Fields)
+		assert.Equal(t, "Hello", change.Old.Title)
+		assert.Equal(t, "Hello again", change.New.Title)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		change, ok, err := decode(record("e3", "REMOVE", "102", image("p1", "Hello", models.StatusPublished), nil))
+
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, postevents.TypeDeleted, change.Type)
+		assert.Equal(t, "p1", change.PostID)
+		assert.Nil(t, change.New)
+	})
+
+	t.Run("Skips Schema Item", func(t *testing.T) {
+		schema := map[string]events.DynamoDBAttributeValue{"ID": events.NewStringAttribute("__schema__")}
+
+		_, ok, err := decode(record("e4", "MODIFY", "103", schema, schema))
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("Update Without Old Image", func(t *testing.T) {
+		_, _, err := decode(record("e5", "MODIFY", "104", nil, image("p1", "Hello", models.StatusDraft)))
+
+		assert.ErrorContains(t, err, "NEW_AND_OLD_IMAGES")
+	})
+}
+
+func TestConsumer(t *testing.T) {
+	batch := events.DynamoDBEvent{Records: []events.DynamoDBEventRecord{
+		record("e1", "INSERT", "100", nil, image("p1", "First", models.StatusPublished)),
+		record("e2", "INSERT", "101", nil, image("p2", "Second", models.StatusPublished)),
+		record("e3", "INSERT", "102", nil, image("p3", "Third", models.StatusPublished)),
+	}}
+
+	t.Run("Handles Every Record", func(t *testing.T) {
+		sink := &recordingSink{name: "a"}
+		consumer := NewConsumer([]Sink{sink}, Config{})
+
+		response, err := consumer.Handle(context.Background(), batch)
+
+		require.NoError(t, err)
+		assert.Empty(t, response.BatchItemFailures)
+		assert.Equal(t, []string{"e1", "e2", "e3"}, sink.eventIDs())
+	})
+
+	t.Run("Stops At First Failure", func(t *testing.T) {
+		first := &recordingSink{name: "a"}
+		second := &recordingSink{name: "b", fail: map[string]bool{"e2": true}}
+		consumer := NewConsumer([]Sink{first, second}, Config{})
+
+		response, err := consumer.Handle(context.Background(), batch)
+
+		require.NoError(t, err)
+		assert.Equal(t, []events.DynamoDBBatchItemFailure{{ItemIdentifier: "101"}}, response.BatchItemFailures)
+		assert.Equal(t, []string{"e1", "e2"}, first.eventIDs())
+		assert.Equal(t, []string{"e1"}, second.eventIDs())
+
+		t.Run("Retry Skips Handled Sinks", func(t *testing.T) {
+			second.fail = nil
+			retry := events.DynamoDBEvent{Records: batch.Records[1:]}
+
+			response, err := consumer.Handle(context.Background(), retry)
+
+			require.NoError(t, err)
+			assert.Empty(t, response.BatchItemFailures)
+			assert.Equal(t, []string{"e1", "e2", "e3"}, first.eventIDs())
+			assert.Equal(t, []string{"e1", "e2", "e3"}, second.eventIDs())
+		})
+	})
+
+	t.Run("Skips Undecodable Records", func(t *testing.T) {
+		sink := &recordingSink{name: "a"}
+		consumer := NewConsumer([]Sink{sink}, Config{})
+		broken := record("e0", "MODIFY", "99", nil, image("p0", "Broken", models.StatusDraft))
+
+		response, err := consumer.Handle(context.Background(), events.DynamoDBEvent{
+			Records: append([]events.DynamoDBEventRecord{broken}, batch.Records...),
+		})
+
+		require.NoError(t, err)
+		assert.Empty(t, response.BatchItemFailures)
+		assert.Equal(t, []string{"e1", "e2", "e3"}, sink.eventIDs())
+	})
+}
+
+type eventRecorder struct {
+	events []postevents.Event
+}
+
+func (r *eventRecorder) Publish(ctx context.Context, event postevents.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestWebhookSink(t *testing.T) {
+	recorder := &eventRecorder{}
+	at := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	post := &models.Post{ID: "p1", Title: "Renamed"}
+
+	err := NewWebhookSink(recorder).Handle(context.Background(), Change{
+		ID:     "e1",
+		Type:   postevents.TypeUpdated,
+		PostID: "p1",
+		New:    post,
+		Fields: []string{"title"},
+		Time:   at,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []postevents.Event{{
+		Type:     postevents.TypeUpdated,
+		ID:       "p1",
+		Post:     post,
+		Fields:   []string{"title"},
+		Time:     at,
+		DedupeID: "e1",
+	}}, recorder.events)
+}
+
+type recordingIndexer struct {
+	indexed []string
+	removed []string
+}
+
+func (i *recordingIndexer) IndexPost(ctx context.Context, post *models.Post) error {
+	i.indexed = append(i.indexed, post.ID)
+	return nil
+}
+
+func (i *recordingIndexer) RemovePost(ctx context.Context, id string) error {
+	i.removed = append(i.removed, id)
+	return nil
+}
+
+func TestSearchSink(t *testing.T) {
+	published := &models.Post{ID: "p1", Status: models.StatusPublished}
+	draft := &models.Post{ID: "p1", Status: models.StatusDraft}
+	tests := []struct {
+		name             string
+		change           Change
+		indexed, removed []string
+	}{
+		{"Indexes Published Post", Change{PostID: "p1", New: published}, []string{"p1"}, nil},
+		{"Ignores Draft", Change{PostID: "p1", New: draft}, nil, nil},
+		{"Removes Unpublished Post", Change{PostID: "p1", Old: published, New: draft}, nil, []string{"p1"}},
+		{"Removes Deleted Post", Change{PostID: "p1", Old: published}, nil, []string{"p1"}},
+		{"Ignores Deleted Draft", Change{PostID: "p1", Old: draft}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			indexer := &recordingIndexer{}
+
+			err := NewSearchSink(indexer).Handle(context.Background(), tt.change)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.indexed, indexer.indexed)
+			assert.Equal(t, tt.removed, indexer.removed)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+
+	require.NoError(t, store.Mark(ctx, "a"))
+	require.NoError(t, store.Mark(ctx, "b"))
+	require.NoError(t, store.Mark(ctx, "c"))
+
+	seen, _ := store.Seen(ctx, "a")
+	assert.False(t, seen, "oldest key is forgotten")
+	seen, _ = store.Seen(ctx, "c")
+	assert.True(t, seen)
+}
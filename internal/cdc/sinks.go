@@ -0,0 +1,130 @@
+package cdc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	postevents "blog-api/internal/events"
+	"blog-api/internal/models"
+)
+
+// LogSink logs every change.
+type LogSink struct {
+	logger *slog.Logger
+}
+
+func NewLogSink(logger *slog.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Name() string { return "log" }
+
+func (s *LogSink) Handle(ctx context.Context, change Change) error {
+	s.logger.InfoContext(ctx, "post changed", "type", change.Type, "post_id", change.PostID,
+		"fields", change.Fields, "event_id", change.ID)
+	return nil
+}
+
+// Publisher records post events for delivery, such as webhooks.Dispatcher.
+type Publisher interface {
+	Publish(ctx context.Context, event postevents.Event) error
+}
+
+// WebhookSink records a webhook delivery of every change. The event ID is the
+// stream record's, so a record handled again yields the same event.
+type WebhookSink struct {
+	publisher Publisher
+}
+
+func NewWebhookSink(publisher Publisher) *WebhookSink {
+	return &WebhookSink{publisher: publisher}
+}
+
+func (s *WebhookSink) Name() string { return "webhooks" }
+
+func (s *WebhookSink) Handle(ctx context.Context, change Change) error {
+	return s.publisher.Publish(ctx, postevents.Event{
+		Type:     change.Type,
+		ID:       change.PostID,
+		Post:     change.New,
+		Fields:   change.Fields,
+		Time:     change.Time,
+		DedupeID: change.ID,
+	})
+}
+
+// Indexer maintains a search index of published posts, such as
+// search.Indexer.
+type Indexer interface {
+	IndexPost(ctx context.Context, post *models.Post) error
+	RemovePost(ctx context.Context, id string) error
+}
+
+// SearchSink keeps a search index in step with the published posts: posts
+// are indexed when they are published and removed when they are deleted or
+// unpublished.
+type SearchSink struct {
+	indexer Indexer
+}
+
+func NewSearchSink(indexer Indexer) *SearchSink {
+	return &SearchSink{indexer: indexer}
+}
+
+func (s *SearchSink) Name() string { return "search" }
+
+func (s *SearchSink) Handle(ctx context.Context, change Change) error {
+	if change.New != nil && change.New.IsPublished() {
+		if err := s.indexer.IndexPost(ctx, change.New); err != nil {
+			return fmt.Errorf("failed to index post %s: %w", change.PostID, err)
+		}
+		return nil
+	}
+	if change.Old == nil || !change.Old.IsPublished() {
+		return nil // never indexed
+	}
+	if err := s.indexer.RemovePost(ctx, change.PostID); err != nil {
+		return fmt.Errorf("failed to remove post %s from the index: %w", change.PostID, err)
+	}
+	return nil
+}
+
+// memoryStoreSize is how many keys the default in-memory store remembers.
+const memoryStoreSize = 10000
+
+// MemoryStore is an IdempotencyStore remembering the most recent keys of one
+// process.
+type MemoryStore struct {
+	mu    sync.Mutex
+	size  int
+	seen  map[string]struct{}
+	order []string
+}
+
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{size: size, seen: make(map[string]struct{}, size)}
+}
+
+func (s *MemoryStore) Seen(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.seen[key]
+	return ok, nil
+}
+
+func (s *MemoryStore) Mark(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.seen[key]; ok {
+		return nil
+	}
+	if len(s.order) == s.size {
+		delete(s.seen, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.seen[key] = struct{}{}
+	s.order = append(s.order, key)
+	return nil
+}
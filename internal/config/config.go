@@ -0,0 +1,567 @@
+// Package config loads the application configuration from built-in defaults,
+// an optional YAML or JSON file, environment variables and command-line flags,
+// each layer overriding the previous one, and validates the result.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"blog-api/internal/feed"
+	"blog-api/internal/logging"
+	"blog-api/internal/sanitize"
+	"blog-api/internal/search"
+	"blog-api/internal/tracing"
+)
+
+// Server modes. Lambda serves API Gateway events, stream consumes the posts
+// table's DynamoDB stream under Lambda, and standalone runs its own HTTP
+// server.
+const (
+	ModeLambda     = "lambda"
+	ModeStream     = "stream"
+	ModeStandalone = "standalone"
+)
+
+// Metrics backends. EMF is the default under Lambda, where nothing can scrape
+// the function, and Prometheus everywhere else.
+const (
+	MetricsEMF        = "emf"
+	MetricsPrometheus = "prometheus"
+)
+
+// redacted replaces secret values in Redacted.
+const redacted = "REDACTED"
+
+type Config struct {
+	Storage  StorageConfig  `yaml:"storage" json:"storage"`
+	Server   ServerConfig   `yaml:"server" json:"server"`
+	CORS     CORSConfig     `yaml:"cors" json:"cors"`
+	Auth     AuthConfig     `yaml:"auth" json:"auth"`
+	Limits   LimitsConfig   `yaml:"limits" json:"limits"`
+	GraphQL  GraphQLConfig  `yaml:"graphql" json:"graphql"`
+	GRPC     GRPCConfig     `yaml:"grpc" json:"grpc"`
+	Webhooks WebhooksConfig `yaml:"webhooks" json:"webhooks"`
+	Streams  StreamsConfig  `yaml:"streams" json:"streams"`
+	Search   SearchConfig   `yaml:"search" json:"search"`
+	Outbox   OutboxConfig   `yaml:"outbox" json:"outbox"`
+	Logging  LoggingConfig  `yaml:"logging" json:"logging"`
+	Site     SiteConfig     `yaml:"site" json:"site"`
+	Sanitize SanitizeConfig `yaml:"sanitize" json:"sanitize"`
+	Metrics  MetricsConfig  `yaml:"metrics" json:"metrics"`
+	Tracing  TracingConfig  `yaml:"tracing" json:"tracing"`
+}
+
+type StorageConfig struct {
+	// Endpoint overrides the DynamoDB endpoint, e.g. for DynamoDB Local. Empty
+	// uses the regular AWS endpoint of Region.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	Region   string `yaml:"region" json:"region"`
+	Table    string `yaml:"table" json:"table"`
+}
+
+type ServerConfig struct {
+	Mode            string        `yaml:"mode" json:"mode"`
+	Addr            string        `yaml:"addr" json:"addr"`
+	ReadTimeout     time.Duration `yaml:"readTimeout" json:"readTimeout"`
+	WriteTimeout    time.Duration `yaml:"writeTimeout" json:"writeTimeout"`
+	IdleTimeout     time.Duration `yaml:"idleTimeout" json:"idleTimeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout" json:"shutdownTimeout"`
+}
+
+type CORSConfig struct {
+	CORSPolicy `yaml:",inline"`
+	// Routes replaces the policy for single route templates such as
+	// "/v1/posts/{id}". It can only be set in the config file.
+	Routes map[string]CORSPolicy `yaml:"routes,omitempty" json:"routes,omitempty"`
+}
+
+// CORSPolicy lists what cross-origin callers may do. Origins are exact, such
+// as "https://blog.example.com", wildcard subdomains such as
+// "https://*.example.com", or "*".
+type CORSPolicy struct {
+	AllowedOrigins   []string      `yaml:"allowedOrigins" json:"allowedOrigins"`
+	AllowedMethods   []string      `yaml:"allowedMethods" json:"allowedMethods"`
+	AllowedHeaders   []string      `yaml:"allowedHeaders" json:"allowedHeaders"`
+	ExposedHeaders   []string      `yaml:"exposedHeaders" json:"exposedHeaders"`
+	AllowCredentials bool          `yaml:"allowCredentials" json:"allowCredentials"`
+	MaxAge           time.Duration `yaml:"maxAge" json:"maxAge"`
+}
+
+// AuthConfig protects the write endpoints. Authentication is off when neither
+// API keys nor a JWT secret are set.
+type AuthConfig struct {
+	APIKeys     []string `yaml:"apiKeys" json:"apiKeys"`
+	JWTSecret   string   `yaml:"jwtSecret" json:"jwtSecret"`
+	JWTIssuer   string   `yaml:"jwtIssuer" json:"jwtIssuer"`
+	JWTAudience string   `yaml:"jwtAudience" json:"jwtAudience"`
+}
+
+type LimitsConfig struct {
+	// MaxBodyBytes bounds JSON request bodies and MaxImportBytes the NDJSON
+	// body of an import.
+	MaxBodyBytes   int64 `yaml:"maxBodyBytes" json:"maxBodyBytes"`
+	MaxImportBytes int64 `yaml:"maxImportBytes" json:"maxImportBytes"`
+}
+
+type GraphQLConfig struct {
+	// MaxDepth and MaxComplexity reject operations that nest too deeply or
+	// select too much before they run.
+	MaxDepth      int `yaml:"maxDepth" json:"maxDepth"`
+	MaxComplexity int `yaml:"maxComplexity" json:"maxComplexity"`
+}
+
+type LoggingConfig struct {
+	Level string `yaml:"level" json:"level"`
+	// Format is "json" or "text". Empty picks JSON under Lambda and text
+	// everywhere else.
+	Format       string `yaml:"format" json:"format"`
+	Bodies       bool   `yaml:"bodies" json:"bodies"`
+	MaxBodyBytes int    `yaml:"maxBodyBytes" json:"maxBodyBytes"`
+	// RedactHeaders and RedactFields are added to the built-in lists.
+	RedactHeaders []string `yaml:"redactHeaders" json:"redactHeaders"`
+	RedactFields  []string `yaml:"redactFields" json:"redactFields"`
+}
+
+type SiteConfig struct {
+	Title       string `yaml:"title" json:"title"`
+	Description string `yaml:"description" json:"description"`
+	BaseURL     string `yaml:"baseURL" json:"baseURL"`
+	// Host is treated as internal when sanitizing links.
+	Host      string `yaml:"host" json:"host"`
+	FeedLimit int    `yaml:"feedLimit" json:"feedLimit"`
+}
+
+// SanitizeConfig is the allowlist post HTML is sanitized with. Scripts, styles
+// and other active content are always removed with their content.
+type SanitizeConfig struct {
+	AllowedTags []string `yaml:"allowedTags" json:"allowedTags"`
+	// AllowedAttributes are "tag.attribute" for one allowed tag, or a bare
+	// attribute name for every allowed tag.
+	AllowedAttributes []string `yaml:"allowedAttributes" json:"allowedAttributes"`
+	// URLSchemes are accepted in href, src and cite; relative URLs always are.
+	URLSchemes []string `yaml:"urlSchemes" json:"urlSchemes"`
+	// Nofollow adds rel="nofollow" to links that leave Site.Host.
+	Nofollow bool `yaml:"nofollow" json:"nofollow"`
+}
+
+type GRPCConfig struct {
+	// Addr is where the gRPC API listens in standalone mode; empty disables
+	// it.
+	Addr string `yaml:"addr" json:"addr"`
+}
+
+type WebhooksConfig struct {
+	// Table holds the webhooks and their deliveries; empty disables webhooks.
+	Table string `yaml:"table" json:"table"`
+	// MaxAttempts is how often a delivery is sent before it is dead, waiting
+	// RetryBaseDelay after the first failure and twice as long after each
+	// further one.
+	MaxAttempts    int           `yaml:"maxAttempts" json:"maxAttempts"`
+	RetryBaseDelay time.Duration `yaml:"retryBaseDelay" json:"retryBaseDelay"`
+	Timeout        time.Duration `yaml:"timeout" json:"timeout"`
+	// FromStream leaves recording deliveries to the stream consumer, so
+	// standalone servers only send them.
+	FromStream bool `yaml:"fromStream" json:"fromStream"`
+}
+
+type StreamsConfig struct {
+	// IdempotencyTable remembers which changes the stream consumer handled;
+	// empty remembers them in memory only.
+	IdempotencyTable string `yaml:"idempotencyTable" json:"idempotencyTable"`
+}
+
+// SearchConfig points the stream consumer at an Elasticsearch or OpenSearch
+// index of the published posts.
+type SearchConfig struct {
+	// Endpoint is the base URL of the cluster; empty disables the index.
+	Endpoint string `yaml:"endpoint" json:"endpoint"`
+	Index    string `yaml:"index" json:"index"`
+	APIKey   string `yaml:"apiKey" json:"apiKey"`
+}
+
+type OutboxConfig struct {
+	// Table receives a message of every post write, in the same transaction;
+	// empty disables the outbox.
+	Table string `yaml:"table" json:"table"`
+	// MaxAttempts is how often a message is published before it is kept as
+	// dead instead.
+	MaxAttempts int `yaml:"maxAttempts" json:"maxAttempts"`
+}
+
+type MetricsConfig struct {
+	Backend   string `yaml:"backend" json:"backend"`
+	Namespace string `yaml:"namespace" json:"namespace"`
+	// Addr starts a separate listener serving only /metrics.
+	Addr string `yaml:"addr" json:"addr"`
+	// Token exposes /metrics on the API itself behind a bearer token.
+	Token string `yaml:"token" json:"token"`
+}
+
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter" json:"exporter"`
+	Endpoint    string  `yaml:"endpoint" json:"endpoint"`
+	Insecure    bool    `yaml:"insecure" json:"insecure"`
+	ServiceName string  `yaml:"serviceName" json:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio" json:"sampleRatio"`
+}
+
+// Default returns the built-in configuration. The server mode and metrics
+// backend follow the Lambda environment variables found through lookupEnv.
+func Default(lookupEnv func(string) (string, bool)) Config {
+	mode, backend := ModeStandalone, MetricsPrometheus
+	if _, ok := lookupEnv("AWS_LAMBDA_RUNTIME_API"); ok {
+		mode = ModeLambda
+	}
+	if _, ok := lookupEnv("AWS_LAMBDA_FUNCTION_NAME"); ok {
+		backend = MetricsEMF
+	}
+
+	logCfg := logging.DefaultConfig()
+	return Config{
+		Storage: StorageConfig{Region: "us-east-1", Table: "TestTable"},
+		Server: ServerConfig{
+			Mode:            mode,
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		CORS: CORSConfig{CORSPolicy: CORSPolicy{
+			AllowedOrigins: []string{"*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-Api-Key", "X-Request-ID"},
+			ExposedHeaders: []string{"ETag", "Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "X-Request-ID"},
+			MaxAge:         10 * time.Minute,
+		}},
+		Limits:  LimitsConfig{MaxBodyBytes: 1 << 20, MaxImportBytes: 64 << 20},
+		GraphQL: GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000},
+		GRPC:    GRPCConfig{Addr: ":9090"},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:    8,
+			RetryBaseDelay: 30 * time.Second,
+			Timeout:        10 * time.Second,
+		},
+		Search: SearchConfig{Index: "posts"},
+		Outbox: OutboxConfig{MaxAttempts: 10},
+		Logging: LoggingConfig{
+			Level:        "info",
+			Format:       logCfg.Format,
+			MaxBodyBytes: logCfg.MaxBodyBytes,
+		},
+		Site:     SiteConfig{Title: "Blog", BaseURL: "http://localhost:8080", FeedLimit: 20},
+		Sanitize: defaultSanitize(),
+		Metrics: MetricsConfig{
+			Backend:   backend,
+			Namespace: "BlogAPI",
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			ServiceName: "blog-api",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// defaultSanitize spells out sanitize.DefaultPolicy, so that the effective
+// allowlist shows up in the printed configuration.
+func defaultSanitize() SanitizeConfig {
+	policy := sanitize.DefaultPolicy()
+	cfg := SanitizeConfig{
+		URLSchemes: policy.AllowedSchemes,
+		Nofollow:   policy.NofollowExternalLinks,
+	}
+	for tag := range policy.AllowedTags {
+		cfg.AllowedTags = append(cfg.AllowedTags, tag)
+	}
+	sort.Strings(cfg.AllowedTags)
+	for _, tag := range cfg.AllowedTags {
+		for _, attr := range policy.AllowedTags[tag] {
+			cfg.AllowedAttributes = append(cfg.AllowedAttributes, tag+"."+attr)
+		}
+	}
+	cfg.AllowedAttributes = append(cfg.AllowedAttributes, policy.GlobalAttributes...)
+	return cfg
+}
+
+// Validate checks every setting and reports all problems at once.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+	oneOf := func(key, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: must be one of %s, got %q", key, strings.Join(allowed, ", "), value))
+	}
+
+	if c.Storage.Endpoint != "" {
+		check(isAbsoluteURL(c.Storage.Endpoint), "storage.endpoint", "must be an absolute URL, got %q", c.Storage.Endpoint)
+	}
+	check(c.Storage.Region != "", "storage.region", "must not be empty")
+	check(c.Storage.Table != "", "storage.table", "must not be empty")
+
+	oneOf("server.mode", c.Server.Mode, ModeLambda, ModeStream, ModeStandalone)
+	if c.Server.Mode == ModeStandalone {
+		check(c.Server.Addr != "", "server.addr", "must not be empty in standalone mode")
+	}
+	check(c.Server.ReadTimeout >= 0, "server.readTimeout", "must not be negative")
+	check(c.Server.WriteTimeout >= 0, "server.writeTimeout", "must not be negative")
+	check(c.Server.IdleTimeout >= 0, "server.idleTimeout", "must not be negative")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdownTimeout", "must be positive")
+
+	errs = append(errs, c.CORS.CORSPolicy.validate("cors")...)
+	for template, policy := range c.CORS.Routes {
+		key := fmt.Sprintf("cors.routes[%s]", template)
+		check(strings.HasPrefix(template, "/"), key, "must be a route template such as /v1/posts/{id}")
+		errs = append(errs, policy.validate(key)...)
+	}
+
+	for i, key := range c.Auth.APIKeys {
+		check(len(key) >= 16, fmt.Sprintf("auth.apiKeys[%d]", i), "must be at least 16 characters")
+	}
+	if c.Auth.JWTSecret != "" {
+		check(len(c.Auth.JWTSecret) >= 32, "auth.jwtSecret", "must be at least 32 characters")
+	} else {
+		check(c.Auth.JWTIssuer == "" && c.Auth.JWTAudience == "", "auth.jwtSecret", "must be set when a JWT issuer or audience is")
+	}
+
+	check(c.Limits.MaxBodyBytes > 0, "limits.maxBodyBytes", "must be positive")
+	check(c.Limits.MaxImportBytes > 0, "limits.maxImportBytes", "must be positive")
+	check(c.GraphQL.MaxDepth > 0, "graphql.maxDepth", "must be positive")
+	check(c.GraphQL.MaxComplexity > 0, "graphql.maxComplexity", "must be positive")
+	if c.Server.Mode == ModeStandalone && c.GRPC.Addr != "" {
+		check(c.GRPC.Addr != c.Server.Addr, "grpc.addr", "must differ from server.addr")
+	}
+	if c.Webhooks.Table != "" {
+		check(c.Webhooks.Table != c.Storage.Table, "webhooks.table", "must differ from storage.table")
+		check(len(c.Auth.APIKeys) > 0 || c.Auth.JWTSecret != "", "webhooks.table", "requires auth.apiKeys or auth.jwtSecret, as webhooks are managed with credentials")
+		check(c.Webhooks.MaxAttempts > 0, "webhooks.maxAttempts", "must be positive")
+		check(c.Webhooks.RetryBaseDelay > 0, "webhooks.retryBaseDelay", "must be positive")
+		check(c.Webhooks.Timeout > 0, "webhooks.timeout", "must be positive")
+	}
+	if table := c.Streams.IdempotencyTable; table != "" {
+		check(table != c.Storage.Table && table != c.Webhooks.Table, "streams.idempotencyTable", "must differ from storage.table and webhooks.table")
+	}
+	if c.Search.Endpoint != "" {
+		check(isAbsoluteURL(c.Search.Endpoint), "search.endpoint", "must be an absolute URL, got %q", c.Search.Endpoint)
+		check(c.Search.Index != "", "search.index", "must not be empty when search.endpoint is set")
+	}
+	if table := c.Outbox.Table; table != "" {
+		check(table != c.Storage.Table && table != c.Webhooks.Table && table != c.Streams.IdempotencyTable,
+			"outbox.table", "must differ from storage.table, webhooks.table and streams.idempotencyTable")
+		check(c.Outbox.MaxAttempts > 0, "outbox.maxAttempts", "must be positive")
+	}
+
+	if _, err := logging.ParseLevel(c.Logging.Level); err != nil {
+		errs = append(errs, fmt.Errorf("logging.level: %w", err))
+	}
+	if c.Logging.Format != "" {
+		oneOf("logging.format", c.Logging.Format, logging.FormatJSON, logging.FormatText)
+	}
+	check(c.Logging.MaxBodyBytes >= 0, "logging.maxBodyBytes", "must not be negative")
+
+	check(isAbsoluteURL(c.Site.BaseURL), "site.baseURL", "must be an absolute URL, got %q", c.Site.BaseURL)
+	check(c.Site.FeedLimit >= 1, "site.feedLimit", "must be at least 1")
+
+	errs = append(errs, c.Sanitize.validate()...)
+
+	oneOf("metrics.backend", c.Metrics.Backend, MetricsEMF, MetricsPrometheus)
+	check(c.Metrics.Namespace != "", "metrics.namespace", "must not be empty")
+
+	oneOf("tracing.exporter", c.Tracing.Exporter, tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP)
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sampleRatio", "must be between 0 and 1")
+	check(c.Tracing.ServiceName != "", "tracing.serviceName", "must not be empty")
+
+	return errors.Join(errs...)
+}
+
+func (p CORSPolicy) validate(key string) []error {
+	var errs []error
+	if len(p.AllowedOrigins) == 0 {
+		errs = append(errs, fmt.Errorf("%s.allowedOrigins: must not be empty", key))
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				errs = append(errs, fmt.Errorf("%s.allowCredentials: cannot be combined with the \"*\" origin", key))
+			}
+			continue
+		}
+		if !isOrigin(origin) {
+			errs = append(errs, fmt.Errorf("%s.allowedOrigins: %q must be \"*\", an origin such as https://example.com or a pattern such as https://*.example.com", key, origin))
+		}
+	}
+	if len(p.AllowedMethods) == 0 {
+		errs = append(errs, fmt.Errorf("%s.allowedMethods: must not be empty", key))
+	}
+	if p.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("%s.maxAge: must not be negative", key))
+	}
+	return errs
+}
+
+var (
+	htmlName  = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	urlScheme = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+)
+
+func (s SanitizeConfig) validate() []error {
+	var errs []error
+	dropped := sanitize.DefaultPolicy().DropContentTags
+	tags := make(map[string]bool, len(s.AllowedTags))
+	for _, tag := range s.AllowedTags {
+		switch {
+		case !htmlName.MatchString(tag):
+			errs = append(errs, fmt.Errorf("sanitize.allowedTags: %q must be a lowercase tag name", tag))
+		case contains(dropped, tag):
+			errs = append(errs, fmt.Errorf("sanitize.allowedTags: %q is always removed with its content", tag))
+		}
+		tags[tag] = true
+	}
+	for _, attr := range s.AllowedAttributes {
+		tag, name, scoped := strings.Cut(attr, ".")
+		if !scoped {
+			name = tag
+		}
+		switch {
+		case !htmlName.MatchString(name):
+			errs = append(errs, fmt.Errorf("sanitize.allowedAttributes: %q must be a lowercase attribute name, optionally prefixed with its tag", attr))
+		case strings.HasPrefix(name, "on") || name == "style":
+			errs = append(errs, fmt.Errorf("sanitize.allowedAttributes: %q would allow scripts or styles", attr))
+		case scoped && !tags[tag]:
+			errs = append(errs, fmt.Errorf("sanitize.allowedAttributes: %q names a tag missing from sanitize.allowedTags", attr))
+		}
+	}
+	for _, scheme := range s.URLSchemes {
+		switch {
+		case !urlScheme.MatchString(scheme):
+			errs = append(errs, fmt.Errorf("sanitize.urlSchemes: %q must be a lowercase URL scheme", scheme))
+		case scheme == "javascript" || scheme == "vbscript" || scheme == "data":
+			errs = append(errs, fmt.Errorf("sanitize.urlSchemes: %q would allow scripts", scheme))
+		}
+	}
+	return errs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// isOrigin reports whether s is a scheme, host and optional port, with at most
+// a leading "*." wildcard label.
+func isOrigin(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil || !isAbsoluteURL(s) || strings.TrimSuffix(u.Path, "/") != "" || u.RawQuery != "" || u.User != nil {
+		return false
+	}
+	return !strings.Contains(strings.TrimPrefix(u.Hostname(), "*."), "*")
+}
+
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Redacted returns a copy of c with every secret replaced.
+func (c Config) Redacted() Config {
+	mask := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return redacted
+	}
+	if len(c.Auth.APIKeys) > 0 {
+		keys := make([]string, len(c.Auth.APIKeys))
+		for i := range keys {
+			keys[i] = redacted
+		}
+		c.Auth.APIKeys = keys
+	}
+	c.Auth.JWTSecret = mask(c.Auth.JWTSecret)
+	c.Metrics.Token = mask(c.Metrics.Token)
+	c.Search.APIKey = mask(c.Search.APIKey)
+	return c
+}
+
+// LoggingConfig returns the logging settings. Validate must have succeeded.
+func (c Config) LoggingConfig() logging.Config {
+	cfg := logging.DefaultConfig()
+	cfg.Level, _ = logging.ParseLevel(c.Logging.Level)
+	cfg.Format = c.Logging.Format
+	cfg.LogBodies = c.Logging.Bodies
+	cfg.MaxBodyBytes = c.Logging.MaxBodyBytes
+	cfg.RedactHeaders = append(cfg.RedactHeaders, c.Logging.RedactHeaders...)
+	return cfg.WithRedactFields(c.Logging.RedactFields...)
+}
+
+// SanitizePolicy returns the sanitization policy, treating Site.Host as
+// internal. Validate must have succeeded.
+func (c Config) SanitizePolicy() *sanitize.Policy {
+	policy := sanitize.DefaultPolicy()
+	policy.AllowedTags = make(map[string][]string, len(c.Sanitize.AllowedTags))
+	for _, tag := range c.Sanitize.AllowedTags {
+		policy.AllowedTags[tag] = nil
+	}
+	policy.GlobalAttributes = nil
+	for _, attr := range c.Sanitize.AllowedAttributes {
+		if tag, name, ok := strings.Cut(attr, "."); ok {
+			policy.AllowedTags[tag] = append(policy.AllowedTags[tag], name)
+		} else {
+			policy.GlobalAttributes = append(policy.GlobalAttributes, attr)
+		}
+	}
+	policy.AllowedSchemes = c.Sanitize.URLSchemes
+	policy.NofollowExternalLinks = c.Sanitize.Nofollow
+	if c.Site.Host != "" {
+		policy.InternalHosts = append(policy.InternalHosts, c.Site.Host)
+	}
+	return policy
+}
+
+// SearchConfig returns the search index settings.
+func (c Config) SearchConfig() search.Config {
+	return search.Config{
+		Endpoint: c.Search.Endpoint,
+		Index:    c.Search.Index,
+		APIKey:   c.Search.APIKey,
+	}
+}
+
+// FeedConfig returns the feed settings.
+func (c Config) FeedConfig() feed.Config {
+	return feed.Config{
+		Title:       c.Site.Title,
+		Description: c.Site.Description,
+		BaseURL:     c.Site.BaseURL,
+		Limit:       c.Site.FeedLimit,
+	}
+}
+
+// TracingConfig returns the tracing settings.
+func (c Config) TracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Tracing.Exporter,
+		Endpoint:    c.Tracing.Endpoint,
+		Insecure:    c.Tracing.Insecure,
+		ServiceName: c.Tracing.ServiceName,
+		SampleRatio: c.Tracing.SampleRatio,
+	}
+}
@@ -0,0 +1,270 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"blog-api/internal/sanitize"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, opts, err := Load(nil, envMap(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, Options{}, opts)
+		assert.Equal(t, Default(envMap(nil)), cfg)
+		assert.Equal(t, ModeStandalone, cfg.Server.Mode)
+		assert.Equal(t, MetricsPrometheus, cfg.Metrics.Backend)
+		assert.Empty(t, cfg.Storage.Endpoint)
+	})
+
+	t.Run("Lambda Defaults", func(t *testing.T) {
+		cfg, _, err := Load(nil, envMap(map[string]string{
+			"AWS_LAMBDA_RUNTIME_API":   "127.0.0.1:9001",
+			"AWS_LAMBDA_FUNCTION_NAME": "blog-api",
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, ModeLambda, cfg.Server.Mode)
+		assert.Equal(t, MetricsEMF, cfg.Metrics.Backend)
+	})
+
+	t.Run("Layers", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", `
+storage:
+  table: FromFile
+  region: eu-west-1
+server:
+  readTimeout: 3s
+cors:
+  allowedOrigins: [https://blog.example.com]
+  maxAge: 1h
+  routes:
+    /v1/posts/{id}:
+      allowedOrigins: ["*"]
+      allowedMethods: [GET]
+site:
+  feedLimit: 5
+`)
+		cfg, opts, err := Load(
+			[]string{"--config", file, "--storage.table", "FromFlag", "--logging.bodies"},
+			envMap(map[string]string{"DYNAMODB_TABLE": "FromEnv", "DYNAMODB_REGION": "us-west-2"}),
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, file, opts.File)
+		assert.Equal(t, "FromFlag", cfg.Storage.Table)
+		assert.Equal(t, "us-west-2", cfg.Storage.Region)
+		assert.Equal(t, 3*time.Second, cfg.Server.ReadTimeout)
+		assert.Equal(t, []string{"https://blog.example.com"}, cfg.CORS.AllowedOrigins)
+		assert.Equal(t, time.Hour, cfg.CORS.MaxAge)
+		assert.Equal(t, []string{"GET"}, cfg.CORS.Routes["/v1/posts/{id}"].AllowedMethods)
+		assert.Equal(t, 5, cfg.Site.FeedLimit)
+		assert.True(t, cfg.Logging.Bodies)
+	})
+
+	t.Run("JSON File From Env", func(t *testing.T) {
+		file := writeFile(t, "config.json", `{"storage": {"endpoint": "http://localhost:8000"}, "limits": {"maxBodyBytes": 1024}}`)
+		cfg, opts, err := Load(nil, envMap(map[string]string{FileEnv: file}))
+		assert.NoError(t, err)
+		assert.Equal(t, file, opts.File)
+		assert.Equal(t, "http://localhost:8000", cfg.Storage.Endpoint)
+		assert.Equal(t, int64(1024), cfg.Limits.MaxBodyBytes)
+	})
+
+	t.Run("Unknown File Key", func(t *testing.T) {
+		file := writeFile(t, "config.yaml", "storage:\n  tabel: Posts\n")
+		_, _, err := Load([]string{"--config", file}, envMap(nil))
+		assert.ErrorContains(t, err, "field tabel not found")
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, _, err := Load([]string{"--config", filepath.Join(t.TempDir(), "missing.yaml")}, envMap(nil))
+		assert.ErrorContains(t, err, "failed to open config file")
+	})
+
+	t.Run("Aggregated Errors", func(t *testing.T) {
+		_, _, err := Load(
+			[]string{"--tracing.sampleRatio", "2"},
+			envMap(map[string]string{"FEED_LIMIT": "many", "LOG_BODIES": "maybe"}),
+		)
+		assert.ErrorContains(t, err, `site.feedLimit: invalid FEED_LIMIT: "many"`)
+		assert.ErrorContains(t, err, `logging.bodies: invalid LOG_BODIES: "maybe"`)
+		assert.ErrorContains(t, err, "tracing.sampleRatio: must be between 0 and 1")
+
+		_, _, err = Load(
+			[]string{"--tracing.sampleRatio", "2", "--storage.table", ""},
+			envMap(map[string]string{"SERVER_MODE": "daemon"}),
+		)
+		assert.ErrorContains(t, err, "storage.table: must not be empty")
+		assert.ErrorContains(t, err, `server.mode: must be one of lambda, stream, standalone, got "daemon"`)
+		assert.ErrorContains(t, err, "tracing.sampleRatio: must be between 0 and 1")
+	})
+
+	t.Run("Print Config", func(t *testing.T) {
+		_, opts, err := Load([]string{"--print-config"}, envMap(nil))
+		assert.NoError(t, err)
+		assert.True(t, opts.PrintConfig)
+	})
+
+	t.Run("Help", func(t *testing.T) {
+		_, _, err := Load([]string{"-h"}, envMap(nil))
+		assert.True(t, errors.Is(err, flag.ErrHelp))
+	})
+
+	t.Run("Positional Arguments", func(t *testing.T) {
+		cfg, opts, err := Load([]string{"migrate", "--storage.table", "Posts", "status"}, envMap(nil))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"migrate", "status"}, opts.Args)
+		assert.Equal(t, "Posts", cfg.Storage.Table)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default(envMap(nil))
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"Relative Endpoint", func(c *Config) { c.Storage.Endpoint = "localhost:8000" }, "storage.endpoint: must be an absolute URL"},
+		{"Bad Origin", func(c *Config) { c.CORS.AllowedOrigins = []string{"example.com"} }, `cors.allowedOrigins: "example.com" must be`},
+		{"Wildcard In Path", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://example.com/*"} }, `cors.allowedOrigins: "https://example.com/*" must be`},
+		{"Inner Wildcard", func(c *Config) { c.CORS.AllowedOrigins = []string{"https://a.*.example.com"} }, `cors.allowedOrigins: "https://a.*.example.com" must be`},
+		{"Credentials With Any Origin", func(c *Config) { c.CORS.AllowCredentials = true }, `cors.allowCredentials: cannot be combined with the "*" origin`},
+		{"Bad Route Policy", func(c *Config) {
+			c.CORS.Routes = map[string]CORSPolicy{"posts": {AllowedOrigins: []string{"*"}}}
+		}, "cors.routes[posts]: must be a route template"},
+		{"Route Policy Without Methods", func(c *Config) {
+			c.CORS.Routes = map[string]CORSPolicy{"/v1/posts": {AllowedOrigins: []string{"*"}}}
+		}, "cors.routes[/v1/posts].allowedMethods: must not be empty"},
+		{"Short API Key", func(c *Config) { c.Auth.APIKeys = []string{"short"} }, "auth.apiKeys[0]: must be at least 16 characters"},
+		{"Short JWT Secret", func(c *Config) { c.Auth.JWTSecret = "secret" }, "auth.jwtSecret: must be at least 32 characters"},
+		{"Issuer Without Secret", func(c *Config) { c.Auth.JWTIssuer = "blog" }, "auth.jwtSecret: must be set"},
+		{"Zero Body Limit", func(c *Config) { c.Limits.MaxBodyBytes = 0 }, "limits.maxBodyBytes: must be positive"},
+		{"Shared gRPC Address", func(c *Config) { c.GRPC.Addr = c.Server.Addr }, "grpc.addr: must differ from server.addr"},
+		{"Shared Webhooks Table", func(c *Config) { c.Webhooks.Table = c.Storage.Table }, "webhooks.table: must differ from storage.table"},
+		{"Shared Idempotency Table", func(c *Config) { c.Streams.IdempotencyTable = c.Storage.Table }, "streams.idempotencyTable: must differ"},
+		{"Shared Outbox Table", func(c *Config) { c.Outbox.Table = c.Storage.Table }, "outbox.table: must differ"},
+		{"Zero Webhook Attempts", func(c *Config) {
+			c.Webhooks.Table = "Webhooks"
+			c.Webhooks.MaxAttempts = 0
+		}, "webhooks.maxAttempts: must be positive"},
+		{"Zero Outbox Attempts", func(c *Config) {
+			c.Outbox.Table = "Outbox"
+			c.Outbox.MaxAttempts = 0
+		}, "outbox.maxAttempts: must be positive"},
+		{"Webhooks Without Credentials", func(c *Config) { c.Webhooks.Table = "Webhooks" }, "webhooks.table: requires auth.apiKeys or auth.jwtSecret"},
+		{"Relative Search Endpoint", func(c *Config) { c.Search.Endpoint = "search:9200" }, "search.endpoint: must be an absolute URL"},
+		{"Zero GraphQL Depth", func(c *Config) { c.GraphQL.MaxDepth = 0 }, "graphql.maxDepth: must be positive"},
+		{"Bad Log Level", func(c *Config) { c.Logging.Level = "loud" }, `logging.level: invalid log level "loud"`},
+		{"Bad Log Format", func(c *Config) { c.Logging.Format = "xml" }, "logging.format: must be one of json, text"},
+		{"Bad Exporter", func(c *Config) { c.Tracing.Exporter = "jaeger" }, "tracing.exporter: must be one of none, stdout, otlp"},
+		{"Bad Backend", func(c *Config) { c.Metrics.Backend = "statsd" }, "metrics.backend: must be one of emf, prometheus"},
+		{"Script Tag", func(c *Config) { c.Sanitize.AllowedTags = append(c.Sanitize.AllowedTags, "script") }, `sanitize.allowedTags: "script" is always removed`},
+		{"Event Handler", func(c *Config) { c.Sanitize.AllowedAttributes = []string{"img.onerror"} }, `sanitize.allowedAttributes: "img.onerror" would allow scripts`},
+		{"Attribute Of Missing Tag", func(c *Config) { c.Sanitize.AllowedAttributes = []string{"video.src"} }, `sanitize.allowedAttributes: "video.src" names a tag missing`},
+		{"Script Scheme", func(c *Config) { c.Sanitize.URLSchemes = []string{"https", "javascript"} }, `sanitize.urlSchemes: "javascript" would allow scripts`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid
+			tt.modify(&cfg)
+			assert.ErrorContains(t, cfg.Validate(), tt.want)
+		})
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, valid.Validate())
+
+		cfg := valid
+		cfg.CORS.AllowedOrigins = []string{"https://blog.example.com", "https://*.example.com:8443"}
+		cfg.CORS.AllowCredentials = true
+		assert.NoError(t, cfg.Validate())
+
+		cfg = valid
+		cfg.Webhooks.Table = "Webhooks"
+		cfg.Auth.APIKeys = []string{"0123456789abcdef"}
+		assert.NoError(t, cfg.Validate())
+	})
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default(envMap(nil))
+	cfg.Auth.APIKeys = []string{"key-0123456789ab", "key-ba9876543210"}
+	cfg.Auth.JWTSecret = "0123456789abcdef0123456789abcdef"
+	cfg.Metrics.Token = "scrape-token"
+	cfg.Search.APIKey = "search-key"
+
+	var buf bytes.Buffer
+	assert.NoError(t, Print(&buf, cfg))
+	out := buf.String()
+	assert.NotContains(t, out, "key-0123456789ab")
+	assert.NotContains(t, out, "0123456789abcdef")
+	assert.NotContains(t, out, "scrape-token")
+	assert.NotContains(t, out, "search-key")
+	assert.Contains(t, out, "jwtSecret: REDACTED")
+	assert.Contains(t, out, "readTimeout: 15s")
+
+	// The original is left untouched.
+	assert.Equal(t, "key-0123456789ab", cfg.Auth.APIKeys[0])
+}
+
+func TestLoggingConfig(t *testing.T) {
+	cfg := Default(envMap(nil))
+	cfg.Logging.Level = "debug"
+	cfg.Logging.RedactFields = []string{"ssn"}
+
+	logCfg := cfg.LoggingConfig()
+	assert.Equal(t, slog.LevelDebug, logCfg.Level)
+	assert.Contains(t, logCfg.RedactFields, "password")
+	assert.Contains(t, logCfg.RedactFields, "ssn")
+}
+
+func TestSanitizePolicy(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg := Default(envMap(nil))
+
+		assert.Equal(t, sanitize.DefaultPolicy(), cfg.SanitizePolicy())
+	})
+
+	t.Run("Configured", func(t *testing.T) {
+		cfg, _, err := Load(nil, envMap(map[string]string{
+			"SANITIZE_ALLOWED_TAGS":       "p,a",
+			"SANITIZE_ALLOWED_ATTRIBUTES": "a.href,class",
+			"SANITIZE_URL_SCHEMES":        "https",
+			"SANITIZE_NOFOLLOW":           "false",
+			"SITE_HOST":                   "blog.example.com",
+		}))
+		assert.NoError(t, err)
+
+		policy := cfg.SanitizePolicy()
+		assert.Equal(t, map[string][]string{"p": nil, "a": {"href"}}, policy.AllowedTags)
+		assert.Equal(t, []string{"class"}, policy.GlobalAttributes)
+		assert.Equal(t, []string{"blog.example.com"}, policy.InternalHosts)
+
+		out, _ := policy.Sanitize(`<p class="x"><a href="http://example.com" title="t">a</a><b>b</b></p>`)
+		assert.Equal(t, `<p class="x"><a>a</a>b</p>`, out)
+	})
+}
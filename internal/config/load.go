@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable holding the config file path when
+// --config is not given.
+const FileEnv = "CONFIG_FILE"
+
+// Options are the command-line switches that are not settings themselves.
+type Options struct {
+	File        string
+	PrintConfig bool
+	// Args are the positional arguments, such as a subcommand. Flags may
+	// appear before, between or after them.
+	Args []string
+}
+
+// field binds a setting to its file key, environment variable and flag. The
+// flag is named after the key.
+type field struct {
+	key    string
+	env    string
+	usage  string
+	target func(*Config) interface{}
+}
+
+var fields = []field{
+	{"storage.endpoint", "DYNAMODB_ENDPOINT", "DynamoDB endpoint override, e.g. http://localhost:8000", func(c *Config) interface{} { return &c.Storage.Endpoint }},
+	{"storage.region", "DYNAMODB_REGION", "AWS region of the table", func(c *Config) interface{} { return &c.Storage.Region }},
+	{"storage.table", "DYNAMODB_TABLE", "DynamoDB table name", func(c *Config) interface{} { return &c.Storage.Table }},
+
+	{"server.mode", "SERVER_MODE", "lambda, stream or standalone", func(c *Config) interface{} { return &c.Server.Mode }},
+	{"server.addr", "SERVER_ADDR", "listen address in standalone mode", func(c *Config) interface{} { return &c.Server.Addr }},
+	{"server.readTimeout", "SERVER_READ_TIMEOUT", "maximum duration for reading a request", func(c *Config) interface{} { return &c.Server.ReadTimeout }},
+	{"server.writeTimeout", "SERVER_WRITE_TIMEOUT", "maximum duration for writing a response", func(c *Config) interface{} { return &c.Server.WriteTimeout }},
+	{"server.idleTimeout", "SERVER_IDLE_TIMEOUT", "keep-alive timeout", func(c *Config) interface{} { return &c.Server.IdleTimeout }},
+	{"server.shutdownTimeout", "SERVER_SHUTDOWN_TIMEOUT", "grace period for in-flight requests on shutdown", func(c *Config) interface{} { return &c.Server.ShutdownTimeout }},
+
+	{"cors.allowedOrigins", "CORS_ALLOWED_ORIGINS", "comma-separated allowed origins", func(c *Config) interface{} { return &c.CORS.AllowedOrigins }},
+	{"cors.allowedMethods", "CORS_ALLOWED_METHODS", "comma-separated allowed methods", func(c *Config) interface{} { return &c.CORS.AllowedMethods }},
+	{"cors.allowedHeaders", "CORS_ALLOWED_HEADERS", "comma-separated allowed request headers", func(c *Config) interface{} { return &c.CORS.AllowedHeaders }},
+	{"cors.exposedHeaders", "CORS_EXPOSED_HEADERS", "comma-separated response headers readable by scripts", func(c *Config) interface{} { return &c.CORS.ExposedHeaders }},
+	{"cors.allowCredentials", "CORS_ALLOW_CREDENTIALS", "allow cookies and authorization on cross-origin requests", func(c *Config) interface{} { return &c.CORS.AllowCredentials }},
+	{"cors.maxAge", "CORS_MAX_AGE", "how long browsers may cache preflight answers", func(c *Config) interface{} { return &c.CORS.MaxAge }},
+
+	{"auth.apiKeys", "AUTH_API_KEYS", "comma-separated API keys accepted for writes", func(c *Config) interface{} { return &c.Auth.APIKeys }},
+	{"auth.jwtSecret", "AUTH_JWT_SECRET", "HS256 secret of accepted JWTs", func(c *Config) interface{} { return &c.Auth.JWTSecret }},
+	{"auth.jwtIssuer", "AUTH_JWT_ISSUER", "required JWT issuer", func(c *Config) interface{} { return &c.Auth.JWTIssuer }},
+	{"auth.jwtAudience", "AUTH_JWT_AUDIENCE", "required JWT audience", func(c *Config) interface{} { return &c.Auth.JWTAudience }},
+
+	{"limits.maxBodyBytes", "LIMITS_MAX_BODY_BYTES", "maximum JSON request body size", func(c *Config) interface{} { return &c.Limits.MaxBodyBytes }},
+	{"limits.maxImportBytes", "LIMITS_MAX_IMPORT_BYTES", "maximum import body size", func(c *Config) interface{} { return &c.Limits.MaxImportBytes }},
+
+	{"graphql.maxDepth", "GRAPHQL_MAX_DEPTH", "maximum nesting of GraphQL selections", func(c *Config) interface{} { return &c.GraphQL.MaxDepth }},
+	{"graphql.maxComplexity", "GRAPHQL_MAX_COMPLEXITY", "maximum cost of a GraphQL operation", func(c *Config) interface{} { return &c.GraphQL.MaxComplexity }},
+
+	{"grpc.addr", "GRPC_ADDR", "gRPC listen address in standalone mode, empty to disable", func(c *Config) interface{} { return &c.GRPC.Addr }},
+
+	{"webhooks.table", "WEBHOOKS_TABLE", "DynamoDB table of webhooks and deliveries, empty to disable webhooks", func(c *Config) interface{} { return &c.Webhooks.Table }},
+	{"webhooks.maxAttempts", "WEBHOOKS_MAX_ATTEMPTS", "attempts before a delivery is dead", func(c *Config) interface{} { return &c.Webhooks.MaxAttempts }},
+	{"webhooks.retryBaseDelay", "WEBHOOKS_RETRY_BASE_DELAY", "wait after the first failed attempt, doubling with each further one", func(c *Config) interface{} { return &c.Webhooks.RetryBaseDelay }},
+	{"webhooks.timeout", "WEBHOOKS_TIMEOUT", "timeout of each delivery attempt", func(c *Config) interface{} { return &c.Webhooks.Timeout }},
+	{"webhooks.fromStream", "WEBHOOKS_FROM_STREAM", "record deliveries in the stream consumer instead of standalone servers", func(c *Config) interface{} { return &c.Webhooks.FromStream }},
+
+	{"streams.idempotencyTable", "STREAMS_IDEMPOTENCY_TABLE", "DynamoDB table remembering handled stream records, empty for memory", func(c *Config) interface{} { return &c.Streams.IdempotencyTable }},
+
+	{"search.endpoint", "SEARCH_ENDPOINT", "Elasticsearch or OpenSearch URL the stream consumer indexes published posts in, empty to disable", func(c *Config) interface{} { return &c.Search.Endpoint }},
+	{"search.index", "SEARCH_INDEX", "name of the search index", func(c *Config) interface{} { return &c.Search.Index }},
+	{"search.apiKey", "SEARCH_API_KEY", "API key of the search cluster", func(c *Config) interface{} { return &c.Search.APIKey }},
+
+	{"outbox.table", "OUTBOX_TABLE", "DynamoDB table of the post change outbox, empty to disable it", func(c *Config) interface{} { return &c.Outbox.Table }},
+	{"outbox.maxAttempts", "OUTBOX_MAX_ATTEMPTS", "attempts to publish an outbox message before it is dead", func(c *Config) interface{} { return &c.Outbox.MaxAttempts }},
+
+	{"logging.level", "LOG_LEVEL", "debug, info, warn or error", func(c *Config) interface{} { return &c.Logging.Level }},
+	{"logging.format", "LOG_FORMAT", "json or text", func(c *Config) interface{} { return &c.Logging.Format }},
+	{"logging.bodies", "LOG_BODIES", "log request and response bodies", func(c *Config) interface{} { return &c.Logging.Bodies }},
+	{"logging.maxBodyBytes", "LOG_MAX_BODY_BYTES", "maximum logged body size", func(c *Config) interface{} { return &c.Logging.MaxBodyBytes }},
+	{"logging.redactHeaders", "LOG_REDACT_HEADERS", "comma-separated extra headers to redact", func(c *Config) interface{} { return &c.Logging.RedactHeaders }},
+	{"logging.redactFields", "LOG_REDACT_FIELDS", "comma-separated extra body fields to redact", func(c *Config) interface{} { return &c.Logging.RedactFields }},
+
+	{"site.title", "SITE_TITLE", "feed title", func(c *Config) interface{} { return &c.Site.Title }},
+	{"site.description", "SITE_DESCRIPTION", "feed description", func(c *Config) interface{} { return &c.Site.Description }},
+	{"site.baseURL", "SITE_BASE_URL", "public URL of the site", func(c *Config) interface{} { return &c.Site.BaseURL }},
+	{"site.host", "SITE_HOST", "host treated as internal when sanitizing links", func(c *Config) interface{} { return &c.Site.Host }},
+	{"site.feedLimit", "FEED_LIMIT", "posts per feed", func(c *Config) interface{} { return &c.Site.FeedLimit }},
+
+	{"sanitize.allowedTags", "SANITIZE_ALLOWED_TAGS", "comma-separated tags kept in post HTML", func(c *Config) interface{} { return &c.Sanitize.AllowedTags }},
+	{"sanitize.allowedAttributes", "SANITIZE_ALLOWED_ATTRIBUTES", "comma-separated attributes kept in post HTML, as tag.attribute or attribute for every tag", func(c *Config) interface{} { return &c.Sanitize.AllowedAttributes }},
+	{"sanitize.urlSchemes", "SANITIZE_URL_SCHEMES", "comma-separated URL schemes allowed in links and images", func(c *Config) interface{} { return &c.Sanitize.URLSchemes }},
+	{"sanitize.nofollow", "SANITIZE_NOFOLLOW", "add rel=nofollow to external links", func(c *Config) interface{} { return &c.Sanitize.Nofollow }},
+
+	{"metrics.backend", "METRICS_BACKEND", "emf or prometheus", func(c *Config) interface{} { return &c.Metrics.Backend }},
+	{"metrics.namespace", "METRICS_NAMESPACE", "CloudWatch namespace of EMF metrics", func(c *Config) interface{} { return &c.Metrics.Namespace }},
+	{"metrics.addr", "METRICS_ADDR", "separate listener for /metrics", func(c *Config) interface{} { return &c.Metrics.Addr }},
+	{"metrics.token", "METRICS_TOKEN", "bearer token for /metrics on the API", func(c *Config) interface{} { return &c.Metrics.Token }},
+
+	{"tracing.exporter", "TRACE_EXPORTER", "none, stdout or otlp", func(c *Config) interface{} { return &c.Tracing.Exporter }},
+	{"tracing.endpoint", "TRACE_OTLP_ENDPOINT", "OTLP/HTTP collector endpoint", func(c *Config) interface{} { return &c.Tracing.Endpoint }},
+	{"tracing.insecure", "TRACE_OTLP_INSECURE", "use plain HTTP for OTLP", func(c *Config) interface{} { return &c.Tracing.Insecure }},
+	{"tracing.serviceName", "TRACE_SERVICE_NAME", "service.name resource attribute", func(c *Config) interface{} { return &c.Tracing.ServiceName }},
+	{"tracing.sampleRatio", "TRACE_SAMPLE_RATIO", "fraction of new traces sampled", func(c *Config) interface{} { return &c.Tracing.SampleRatio }},
+}
+
+// Load builds the configuration from defaults, the config file, the
+// environment and args, in that order, and validates it. All problems are
+// reported together. flag.ErrHelp is returned as is for -h.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, Options, error) {
+	var opts Options
+	fs := flag.NewFlagSet("blog-api", flag.ContinueOnError)
+	fs.StringVar(&opts.File, "config", "", "YAML or JSON config file (or $"+FileEnv+")")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective configuration with secrets redacted and exit")
+
+	type flagValue struct {
+		field field
+		value string
+	}
+	var set []flagValue
+	for _, f := range fields {
+		f := f
+		record := func(v string) error {
+			set = append(set, flagValue{f, v})
+			return nil
+		}
+		usage := fmt.Sprintf("%s (env %s)", f.usage, f.env)
+		if _, ok := f.target(&Config{}).(*bool); ok {
+			fs.BoolFunc(f.key, usage, record)
+		} else {
+			fs.Func(f.key, usage, record)
+		}
+	}
+	for {
+		if err := fs.Parse(args); err != nil {
+			return Config{}, opts, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		opts.Args = append(opts.Args, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	cfg := Default(lookupEnv)
+	if opts.File == "" {
+		opts.File, _ = lookupEnv(FileEnv)
+	}
+	if opts.File != "" {
+		if err := loadFile(opts.File, &cfg); err != nil {
+			return Config{}, opts, err
+		}
+	}
+
+	var errs []error
+	for _, f := range fields {
+		if v, ok := lookupEnv(f.env); ok {
+			if err := setValue(f.target(&cfg), v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: invalid %s: %w", f.key, f.env, err))
+			}
+		}
+	}
+	for _, fv := range set {
+		if err := setValue(fv.field.target(&cfg), fv.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid flag value: %w", fv.field.key, err))
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return Config{}, opts, errors.Join(errs...)
+	}
+	return cfg, opts, nil
+}
+
+// loadFile decodes a YAML or JSON file over cfg. Unknown keys are rejected.
+func loadFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setValue parses v into the setting pointed to by target.
+func setValue(target interface{}, v string) error {
+	var err error
+	switch p := target.(type) {
+	case *string:
+		*p = v
+	case *[]string:
+		*p = splitList(v)
+	case *bool:
+		*p, err = strconv.ParseBool(v)
+	case *int:
+		*p, err = strconv.Atoi(v)
+	case *int64:
+		*p, err = strconv.ParseInt(v, 10, 64)
+	case *float64:
+		*p, err = strconv.ParseFloat(v, 64)
+	case *time.Duration:
+		*p, err = time.ParseDuration(v)
+	default:
+		return fmt.Errorf("unsupported setting type %T", target)
+	}
+	if err != nil {
+		return fmt.Errorf("%q", v)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping blanks.
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Print writes cfg as YAML with its secrets redacted.
+func Print(w io.Writer, cfg Config) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg.Redacted()); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return enc.Close()
+}
@@ -1,5 +1,30 @@
 package custom_errors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var ErrNotFound = errors.New("resource not found")
+
+// ErrTooManyIDs is returned for bulk operations over more IDs than they allow.
+var ErrTooManyIDs = errors.New("too many IDs")
+
+// ErrBatchAborted is reported for the items a transactional batch left
+// untouched because it was aborted.
+var ErrBatchAborted = errors.New("transaction aborted, post was not deleted")
+
+// MissingIDsError reports the IDs that did not exist during a batch operation.
+// It matches ErrNotFound with errors.Is.
+type MissingIDsError struct {
+	IDs []string
+}
+
+func (e *MissingIDsError) Error() string {
+	return fmt.Sprintf("resources not found: %s", strings.Join(e.IDs, ", "))
+}
+
+func (e *MissingIDsError) Is(target error) bool {
+	return target == ErrNotFound
+}
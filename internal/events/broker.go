@@ -0,0 +1,290 @@
+// Package events turns the post service's change notifications into post
+// events and fans them out to subscribers, such as streaming API clients.
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+)
+
+// Event types.
+const (
+	TypeCreated = "created"
+	TypeUpdated = "updated"
+	TypeDeleted = "deleted"
+)
+
+// ErrSlowSubscriber ends a subscription that did not keep up with events.
+var ErrSlowSubscriber = errors.New("subscriber fell behind")
+
+// Event is a change to one post.
+type Event struct {
+	Type string
+	ID   string
+	// Post is the post after the change, nil when it was deleted.
+	Post *models.Post
+	// Fields lists the JSON names of the fields an update changed, when
+	// they are known.
+	Fields []string
+	Time   time.Time
+	// DedupeID identifies an event that may be published more than once,
+	// such as an outbox message; empty otherwise.
+	DedupeID string
+	// Seq numbers the events published by one broker from 1, in order; zero
+	// for events from elsewhere.
+	Seq uint64
+}
+
+// PostLoader reads the current state of a changed post. Posts that do not
+// exist are reported with an error that has a NotFound method returning true.
+type PostLoader interface {
+	GetPostByID(ctx context.Context, id string) (*models.Post, error)
+}
+
+// loadAttempts is how many times a changed post is read before its change is
+// dropped.
+const loadAttempts = 3
+
+// change is a queued change notification.
+type change struct {
+	id        string
+	eventType string
+}
+
+// Broker is a services.ChangeListener. Changes are queued and turned into
+// events by Run, so that writes never wait for subscribers.
+type Broker struct {
+	loader PostLoader
+	queue  chan change
+	now    func() time.Time
+	// retryDelay is the pause before a failed read is retried; it grows
+	// with each attempt.
+	retryDelay time.Duration
+
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+	seq  uint64
+	// replay holds the latest events, the oldest first, for SubscribeAfter;
+	// replaySize is zero when replay is disabled.
+	replay     []Event
+	replaySize int
+}
+
+// NewBroker creates a broker queueing up to queueSize changes.
+func NewBroker(loader PostLoader, queueSize int) *Broker {
+	return &Broker{
+		loader:     loader,
+		queue:      make(chan change, queueSize),
+		now:        time.Now,
+		retryDelay: 100 * time.Millisecond,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// EnableReplay keeps the latest size events for SubscribeAfter. Changes are
+// then published even when nobody is subscribed, so that clients that
+// reconnect find the events they missed. It must be called before Run.
+func (b *Broker) EnableReplay(size int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replaySize = size
+}
+
+// PostChanged queues the change when anyone is subscribed or replay is
+// enabled. Changes are dropped when the queue is full.
+func (b *Broker) PostChanged(ctx context.Context, id, eventType string) {
+	b.mu.Lock()
+	idle := len(b.subs) == 0 && b.replaySize == 0
+	b.mu.Unlock()
+	if idle {
+		return
+	}
+	select {
+	case b.queue <- change{id: id, eventType: eventType}:
+	default:
+		logging.FromContext(ctx).Warn("event queue is full, dropping change", "id", id, "type", eventType)
+	}
+}
+
+// Run publishes the queued changes until ctx is done, then ends every
+// subscription.
+func (b *Broker) Run(ctx context.Context) {
+	defer b.closeAll()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-b.queue:
+			event, err := b.loadWithRetry(ctx, c)
+			if err != nil {
+				if ctx.Err() == nil {
+					logging.FromContext(ctx).Error("failed to read changed post, dropping change",
+						"id", c.id, "type", c.eventType, "error", err)
+				}
+				continue
+			}
+			b.publish(event)
+		}
+	}
+}
+
+// loadWithRetry is load, retried with a growing delay while it fails.
+func (b *Broker) loadWithRetry(ctx context.Context, c change) (Event, error) {
+	var err error
+	for attempt := 1; attempt <= loadAttempts; attempt++ {
+		var event Event
+		if event, err = b.load(ctx, c); err == nil {
+			return event, nil
+		}
+		if attempt == loadAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return Event{}, ctx.Err()
+		case <-time.After(time.Duration(attempt) * b.retryDelay):
+		}
+	}
+	return Event{}, err
+}
+
+// load builds the event of a change with the post's current state. A post
+// that no longer exists by the time it is read was deleted since, and is
+// reported as such; any other failure to read it is returned.
+func (b *Broker) load(ctx context.Context, c change) (Event, error) {
+	event := Event{Type: c.eventType, ID: c.id, Time: b.now()}
+	if c.eventType == TypeDeleted {
+		return event, nil
+	}
+	post, err := b.loader.GetPostByID(ctx, c.id)
+	switch {
+	case isNotFound(err):
+		event.Type = TypeDeleted
+	case err != nil:
+		return Event{}, err
+	default:
+		event.Post = post
+	}
+	return event, nil
+}
+
+// isNotFound reports whether err is a not-found error, recognised by its
+// NotFound method so that this package need not import the service package.
+func isNotFound(err error) bool {
+	var nf interface{ NotFound() bool }
+	return errors.As(err, &nf) && nf.NotFound()
+}
+
+func (b *Broker) publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event.Seq = b.seq
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.replay = append(b.replay[:0], b.replay[1:]...)
+		}
+		b.replay = append(b.replay, event)
+	}
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			b.end(sub, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Subscribe starts receiving events. Up to buffer events are held for the
+// subscriber; when it falls further behind, its subscription ends with
+// ErrSlowSubscriber.
+func (b *Broker) Subscribe(buffer int) *Subscription {
+	sub := &Subscription{ch: make(chan Event, buffer), broker: b}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// SubscribeAfter is Subscribe, starting with the kept events published
+// after the one numbered seq; they are held on top of buffer. It reports
+// false, subscribing without replay, when some of those events are no
+// longer kept or seq is not an event of this broker.
+func (b *Broker) SubscribeAfter(buffer int, seq uint64) (*Subscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	for i, event := range b.replay {
+		if event.Seq > seq {
+			missed = b.replay[i:]
+			break
+		}
+	}
+	ok := seq == b.seq
+	if len(missed) > 0 {
+		ok = missed[0].Seq == seq+1
+	}
+	if !ok {
+		missed = nil
+	}
+
+	sub := &Subscription{ch: make(chan Event, buffer+len(missed)), broker: b}
+	for _, event := range missed {
+		sub.ch <- event
+	}
+	b.subs[sub] = struct{}{}
+	return sub, ok
+}
+
+// end closes sub with err; the caller holds b.mu.
+func (b *Broker) end(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+func (b *Broker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subs {
+		b.end(sub, nil)
+	}
+}
+
+// Subscription is a stream of events.
+type Subscription struct {
+	ch     chan Event
+	broker *Broker
+	// err is set before ch is closed.
+	err error
+}
+
+// Events returns the channel of events. It is closed when the subscription
+// ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err returns why the subscription ended, once Events is closed: nil when
+// it was closed or the broker stopped, ErrSlowSubscriber otherwise.
+func (s *Subscription) Err() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.end(s, nil)
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPostLoader struct {
+	mock.Mock
+}
+
+func (m *MockPostLoader) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	args := m.Called(ctx, id)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+// notFoundError is the error the post service reports for missing posts.
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "post not found" }
+func (notFoundError) NotFound() bool { return true }
+
+func receive(t *testing.T, sub *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events():
+		return event, ok
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}, false
+	}
+}
+
+func TestBroker(t *testing.T) {
+	t.Run("Publishes Changes", func(t *testing.T) {
+		loader := new(MockPostLoader)
+		created := &models.Post{ID: "1", Version: 1}
+		updated := &models.Post{ID: "2", Version: 1}
+		loader.On("GetPostByID", mock.Anything, "1").Return(created, nil)
+		loader.On("GetPostByID", mock.Anything, "2").Return(updated, nil)
+		loader.On("GetPostByID", mock.Anything, "3").Return(nil, notFoundError{})
+
+		broker := NewBroker(loader, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go broker.Run(ctx)
+		sub := broker.Subscribe(10)
+		defer sub.Close()
+
+		broker.PostChanged(context.Background(), "1", TypeCreated)
+		broker.PostChanged(context.Background(), "2", TypeUpdated)
+		broker.PostChanged(context.Background(), "3", TypeUpdated)
+		broker.PostChanged(context.Background(), "4", TypeDeleted)
+
+		event, _ := receive(t, sub)
+		assert.Equal(t, TypeCreated, event.Type)
+		assert.Equal(t, created, event.Post)
+		event, _ = receive(t, sub)
+		assert.Equal(t, TypeUpdated, event.Type, "the type comes from the change, not the version")
+		assert.Equal(t, updated, event.Post)
+		event, _ = receive(t, sub)
+		assert.Equal(t, TypeDeleted, event.Type, "a post deleted since is reported as deleted")
+		assert.Equal(t, "3", event.ID)
+		assert.Nil(t, event.Post)
+		event, _ = receive(t, sub)
+		assert.Equal(t, TypeDeleted, event.Type)
+		assert.Equal(t, "4", event.ID)
+		loader.AssertNotCalled(t, "GetPostByID", mock.Anything, "4")
+	})
+
+	t.Run("Retries And Drops Posts That Cannot Be Read", func(t *testing.T) {
+		loader := new(MockPostLoader)
+		loader.On("GetPostByID", mock.Anything, "1").Return(nil, errors.New("throttled"))
+		loader.On("GetPostByID", mock.Anything, "2").Return(nil, errors.New("throttled")).Once()
+		loader.On("GetPostByID", mock.Anything, "2").Return(&models.Post{ID: "2"}, nil)
+
+		broker := NewBroker(loader, 10)
+		broker.retryDelay = time.Millisecond
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go broker.Run(ctx)
+		sub := broker.Subscribe(10)
+		defer sub.Close()
+
+		broker.PostChanged(context.Background(), "1", TypeUpdated)
+		broker.PostChanged(context.Background(), "2", TypeUpdated)
+
+		event, _ := receive(t, sub)
+		assert.Equal(t, "2", event.ID, "post 1 is dropped rather than reported as deleted")
+		assert.Equal(t, TypeUpdated, event.Type)
+		loader.AssertNumberOfCalls(t, "GetPostByID", loadAttempts+2)
+	})
+
+	t.Run("Ignores Changes Without Subscribers", func(t *testing.T) {
+		loader := new(MockPostLoader)
+		broker := NewBroker(loader, 1)
+
+		broker.PostChanged(context.Background(), "1", TypeCreated)
+
+		assert.Empty(t, broker.queue)
+	})
+
+	t.Run("Ends Slow Subscribers", func(t *testing.T) {
+		broker := NewBroker(new(MockPostLoader), 10)
+		slow := broker.Subscribe(1)
+		fast := broker.Subscribe(10)
+		defer fast.Close()
+
+		broker.publish(Event{Type: TypeUpdated, ID: "1"})
+		broker.publish(Event{Type: TypeUpdated, ID: "1"})
+
+		_, ok := receive(t, slow)
+		require.True(t, ok)
+		_, ok = receive(t, slow)
+		assert.False(t, ok)
+		assert.ErrorIs(t, slow.Err(), ErrSlowSubscriber)
+		assert.Len(t, fast.Events(), 2)
+	})
+
+	t.Run("Stopping Ends Subscriptions", func(t *testing.T) {
+		broker := NewBroker(new(MockPostLoader), 1)
+		sub := broker.Subscribe(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		broker.Run(ctx)
+
+		_, ok := receive(t, sub)
+		assert.False(t, ok)
+		assert.NoError(t, sub.Err())
+		sub.Close() // closing again is harmless
+	})
+
+	t.Run("Replays Missed Events", func(t *testing.T) {
+		broker := NewBroker(new(MockPostLoader), 10)
+		broker.EnableReplay(2)
+		for i := 0; i < 3; i++ {
+			broker.publish(Event{Type: TypeUpdated, ID: "1"})
+		}
+
+		sub, ok := broker.SubscribeAfter(1, 1)
+		defer sub.Close()
+
+		require.True(t, ok)
+		event, _ := receive(t, sub)
+		assert.Equal(t, uint64(2), event.Seq)
+		event, _ = receive(t, sub)
+		assert.Equal(t, uint64(3), event.Seq)
+
+		t.Run("Reports Events No Longer Kept", func(t *testing.T) {
+			sub, ok := broker.SubscribeAfter(1, 0)
+			defer sub.Close()
+
+			assert.False(t, ok)
+			assert.Empty(t, sub.Events())
+		})
+
+		t.Run("Reports Unknown Events", func(t *testing.T) {
+			sub, ok := broker.SubscribeAfter(1, 7)
+			defer sub.Close()
+
+			assert.False(t, ok)
+		})
+
+		t.Run("Queues Changes Without Subscribers", func(t *testing.T) {
+			idle := NewBroker(new(MockPostLoader), 1)
+			idle.EnableReplay(1)
+
+			idle.PostChanged(context.Background(), "1", TypeUpdated)
+
+			assert.Len(t, idle.queue, 1)
+		})
+	})
+}
@@ -0,0 +1,114 @@
+// Package feed builds RSS 2.0, Atom and JSON Feed documents from posts.
+package feed
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"blog-api/internal/models"
+
+	"github.com/gorilla/feeds"
+)
+
+// Supported feed formats, matching the extension of the feed route.
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatJSON = "json"
+)
+
+// Config describes the site the feeds are published for.
+type Config struct {
+	Title       string
+	Description string
+	// BaseURL is the public URL of the site; post links are BaseURL + "/posts/{id}".
+	BaseURL string
+	// Limit is the number of newest posts included in a feed.
+	Limit int
+}
+
+// Entry is a post together with its rendered HTML body.
+type Entry struct {
+	Post *models.Post
+	HTML string
+}
+
+// ContentType returns the media type served for format.
+func ContentType(format string) string {
+	switch format {
+	case FormatRSS:
+		return "application/rss+xml; charset=utf-8"
+	case FormatAtom:
+		return "application/atom+xml; charset=utf-8"
+	case FormatJSON:
+		return "application/feed+json; charset=utf-8"
+	}
+	return ""
+}
+
+// Build renders entries as a feed in the given format. subtitle, if set, is
+// appended to the site title, e.g. for per-author or per-tag feeds.
+func Build(cfg Config, format, subtitle string, entries []Entry) ([]byte, error) {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	title := cfg.Title
+	if subtitle != "" {
+		title = fmt.Sprintf("%s - %s", cfg.Title, subtitle)
+	}
+
+	f := &feeds.Feed{
+		Title:       title,
+		Link:        &feeds.Link{Href: baseURL},
+		Description: cfg.Description,
+		Id:          baseURL,
+		Updated:     LastModified(entries),
+	}
+
+	for _, entry := range entries {
+		link := PostURL(baseURL, entry.Post.ID)
+		f.Add(&feeds.Item{
+			Title:   entry.Post.Title,
+			Link:    &feeds.Link{Href: link},
+			Author:  &feeds.Author{Name: entry.Post.Author},
+			Id:      link,
+			Created: entry.Post.CreatedAt,
+			Updated: entry.Post.UpdatedAt,
+			Content: entry.HTML,
+		})
+	}
+
+	var (
+		out string
+		err error
+	)
+	switch format {
+	case FormatRSS:
+		out, err = f.ToRss()
+	case FormatAtom:
+		out, err = f.ToAtom()
+	case FormatJSON:
+		out, err = f.ToJSON()
+	default:
+		return nil, fmt.Errorf("unsupported feed format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to build %s feed: %w", format, err)
+	}
+	return []byte(out), nil
+}
+
+// PostURL returns the public link of a post.
+func PostURL(baseURL, id string) string {
+	return strings.TrimRight(baseURL, "/") + "/posts/" + id
+}
+
+// LastModified returns the newest update time among entries.
+func LastModified(entries []Entry) time.Time {
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.Post.UpdatedAt.After(latest) {
+			latest = entry.Post.UpdatedAt
+		}
+	}
+	return latest
+}
@@ -0,0 +1,73 @@
+package feed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"blog-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	cfg := Config{Title: "My Blog", Description: "Posts", BaseURL: "https://blog.example.com/"}
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Post: &models.Post{ID: "2", Title: "Second", Author: "Jane", CreatedAt: newer, UpdatedAt: newer}, HTML: "<p>two</p>"},
+		{Post: &models.Post{ID: "1", Title: "First", Author: "John", CreatedAt: older, UpdatedAt: older}, HTML: "<p>one</p>"},
+	}
+
+	t.Run("RSS", func(t *testing.T) {
+		body, err := Build(cfg, FormatRSS, "", entries)
+		assert.NoError(t, err)
+
+		var doc struct {
+			Channel struct {
+				Title string `xml:"title"`
+				Items []struct {
+					Link string `xml:"link"`
+				} `xml:"item"`
+			} `xml:"channel"`
+		}
+		assert.NoError(t, xml.Unmarshal(body, &doc))
+		assert.Equal(t, "My Blog", doc.Channel.Title)
+		assert.Len(t, doc.Channel.Items, 2)
+		assert.Equal(t, "https://blog.example.com/posts/2", doc.Channel.Items[0].Link)
+	})
+
+	t.Run("Atom With Subtitle", func(t *testing.T) {
+		body, err := Build(cfg, FormatAtom, "Posts by Jane", entries[:1])
+		assert.NoError(t, err)
+
+		var doc struct {
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+		}
+		assert.NoError(t, xml.Unmarshal(body, &doc))
+		assert.Equal(t, "My Blog - Posts by Jane", doc.Title)
+		assert.Equal(t, newer.Format(time.RFC3339), doc.Updated)
+	})
+
+	t.Run("JSON Feed", func(t *testing.T) {
+		body, err := Build(cfg, FormatJSON, "", entries)
+		assert.NoError(t, err)
+
+		var doc struct {
+			Version string `json:"version"`
+			Items   []struct {
+				ContentHTML string `json:"content_html"`
+			} `json:"items"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &doc))
+		assert.Contains(t, doc.Version, "jsonfeed.org")
+		assert.Equal(t, "<p>two</p>", doc.Items[0].ContentHTML)
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		_, err := Build(cfg, "txt", "", entries)
+		assert.Error(t, err)
+	})
+}
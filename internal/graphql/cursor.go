@@ -0,0 +1,39 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// maxCursorOffset bounds how many posts a cursor may skip, and so how many
+// posts a page may read beyond its size.
+const maxCursorOffset = 10 * maxPageSize
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// edgeCursor locates a post in the listing of published posts: Offset posts
+// past the start of the page that the service's cursor Page begins. The
+// service only returns a cursor after the last post of a page, so the other
+// edges of a page point into it by offset.
+type edgeCursor struct {
+	Page   string `json:"p,omitempty"`
+	Offset int    `json:"o,omitempty"`
+}
+
+func (c edgeCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeEdgeCursor(s string) (edgeCursor, error) {
+	var c edgeCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 || c.Offset > maxCursorOffset {
+		return c, errInvalidCursor
+	}
+	return c, nil
+}
@@ -0,0 +1,79 @@
+package graphql
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+// Error codes reported in the "code" extension of GraphQL errors.
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeInternal        = "INTERNAL_SERVER_ERROR"
+	CodeQueryTooDeep    = "QUERY_TOO_DEEP"
+	CodeQueryTooComplex = "QUERY_TOO_COMPLEX"
+)
+
+// Error is a resolver or limit error with a machine-readable code.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError.
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+func badInput(err error) *Error {
+	return &Error{Code: CodeBadUserInput, Message: err.Error()}
+}
+
+func notFound(message string) *Error {
+	return &Error{Code: CodeNotFound, Message: message}
+}
+
+func internal(message string) *Error {
+	return &Error{Code: CodeInternal, Message: message}
+}
+
+// isNotFound reports whether err is a not-found error of the service.
+func isNotFound(err error) bool {
+	var nf interface{ NotFound() bool }
+	return errors.As(err, &nf) && nf.NotFound()
+}
+
+// withExtensions restores the code extension of errors returned from
+// deferred resolvers, which the executor wraps without keeping it.
+func withExtensions(errs []gqlerrors.FormattedError) []gqlerrors.FormattedError {
+	for i := range errs {
+		if errs[i].Extensions != nil {
+			continue
+		}
+		if ext := extendedError(errs[i].OriginalError()); ext != nil {
+			errs[i].Extensions = ext.Extensions()
+		}
+	}
+	return errs
+}
+
+func extendedError(err error) gqlerrors.ExtendedError {
+	for err != nil {
+		switch e := err.(type) {
+		case gqlerrors.ExtendedError:
+			return e
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+// Package graphql serves the posts API as GraphQL at POST /v1/graphql. Reads
+// and writes delegate to the same service as the REST handlers; lookups of
+// posts by ID are batched per request, and queries are rejected before they
+// run when they nest too deeply or would cost too much.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blog-api/internal/auth"
+	"blog-api/internal/handlers"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/requestid"
+	gql "github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Service is the post service the resolvers delegate to.
+type Service interface {
+	handlers.PostService
+	ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error)
+}
+
+// Config holds the limits applied to every operation. Zero values pick the
+// defaults.
+type Config struct {
+	// Auth guards mutations when it has credentials configured.
+	Auth *auth.Authenticator
+	// MaxDepth bounds how deeply selections nest; 10 by default.
+	MaxDepth int
+	// MaxComplexity bounds the cost of an operation, see measure; 1000 by
+	// default.
+	MaxComplexity int
+}
+
+const (
+	defaultMaxDepth      = 10
+	defaultMaxComplexity = 1000
+)
+
+var tracer = otel.Tracer("blog-api/internal/graphql")
+
+type Handler struct {
+	service Service
+	schema  gql.Schema
+	cfg     Config
+}
+
+// NewHandler creates a handler serving the posts schema backed by service.
+func NewHandler(service Service, cfg Config) (*Handler, error) {
+	schema, err := newSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build GraphQL schema: %w", err)
+	}
+	if cfg.MaxDepth <= 0 {
+		cfg.MaxDepth = defaultMaxDepth
+	}
+	if cfg.MaxComplexity <= 0 {
+		cfg.MaxComplexity = defaultMaxComplexity
+	}
+	return &Handler{service: service, schema: schema, cfg: cfg}, nil
+}
+
+// Request is the JSON body of a GraphQL request.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Response is the JSON body of a GraphQL response.
+type Response struct {
+	Data   interface{}                `json:"data,omitempty"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+// ServeHTTP executes one operation. Errors in the document, its limits or its
+// resolvers are reported in the errors of a 200 response; only a malformed
+// body and missing credentials for a mutation fail the request itself.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, r, errors.New("body must be a JSON GraphQL request"), http.StatusBadRequest)
+		return
+	}
+	if req.Query == "" {
+		writeError(w, r, errors.New("query must not be empty"), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(req.Query),
+		Name: "GraphQL request",
+	})})
+	if err != nil {
+		writeResponse(w, r, Response{Errors: gqlerrors.FormatErrors(err)})
+		return
+	}
+	if result := gql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		writeResponse(w, r, Response{Errors: result.Errors})
+		return
+	}
+
+	ctx := r.Context()
+	op := operation(doc, req.OperationName)
+	if op != nil && op.Operation == ast.OperationTypeMutation && h.cfg.Auth != nil && h.cfg.Auth.Enabled() {
+		principal, err := h.cfg.Auth.Authenticate(r)
+		if err != nil {
+			logging.FromContext(ctx).Debug("authentication failed", "error", err)
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, r, err, http.StatusUnauthorized)
+			return
+		}
+		ctx = auth.WithPrincipal(ctx, principal)
+	}
+	if op != nil {
+		if err := h.checkLimits(doc, op, req.Variables); err != nil {
+			writeResponse(w, r, Response{Errors: withExtensions(gqlerrors.FormatErrors(err))})
+			return
+		}
+	}
+
+	ctx, span := tracer.Start(ctx, "GraphQL.Execute", trace.WithAttributes(
+		attribute.String("graphql.operation.name", req.OperationName),
+	))
+	defer span.End()
+
+	result := gql.Execute(gql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withState(ctx, newState(ctx, h.service)),
+	})
+	writeResponse(w, r, Response{Data: result.Data, Errors: withExtensions(result.Errors)})
+}
+
+// operation returns the operation named name, or the only operation of doc
+// when name is empty. Execution reports the error when there is none.
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = op
+		} else if op.Name != nil && op.Name.Value == name {
+			return op
+		}
+	}
+	return found
+}
+
+func (h *Handler) checkLimits(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) error {
+	depth, cost := measure(doc, op, variables)
+	if depth > h.cfg.MaxDepth {
+		return &Error{Code: CodeQueryTooDeep, Message: fmt.Sprintf("query depth %d exceeds the limit of %d", depth, h.cfg.MaxDepth)}
+	}
+	if cost > h.cfg.MaxComplexity {
+		return &Error{Code: CodeQueryTooComplex, Message: fmt.Sprintf("query complexity %d exceeds the limit of %d", cost, h.cfg.MaxComplexity)}
+	}
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, resp Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logging.FromContext(r.Context()).Error("failed to encode GraphQL response", "error", err)
+	}
+}
+
+// writeError answers with the API's JSON error body.
+func writeError(w http.ResponseWriter, r *http.Request, err error, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := map[string]string{
+		"error":       http.StatusText(status),
+		"description": err.Error(),
+	}
+	if id := requestid.ID(r.Context()); id != "" {
+		response["requestId"] = id
+	}
+	if encodeErr := json.NewEncoder(w).Encode(response); encodeErr != nil {
+		logging.FromContext(r.Context()).Error("failed to encode error response", "error", encodeErr)
+	}
+}
@@ -0,0 +1,451 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog-api/internal/auth"
+	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/sanitize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetAllPosts(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error) {
+	args := m.Called(ctx, page, limit, fields)
+	return args.Get(0).([]*models.Post), args.Error(1)
+}
+
+func (m *MockService) GetPostFields(ctx context.Context, id string, fields []string) (*models.Post, error) {
+	args := m.Called(ctx, id, fields)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+func (m *MockService) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	args := m.Called(ctx, id)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+func (m *MockService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, post)
+	created, _ := args.Get(0).(*models.Post)
+	report, _ := args.Get(1).(*sanitize.Report)
+	return created, report, args.Error(2)
+}
+
+func (m *MockService) UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, id, post)
+	updated, _ := args.Get(0).(*models.Post)
+	report, _ := args.Get(1).(*sanitize.Report)
+	return updated, report, args.Error(2)
+}
+
+func (m *MockService) DeletePost(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockService) RenderPost(ctx context.Context, post *models.Post) (*render.Document, error) {
+	args := m.Called(ctx, post)
+	doc, _ := args.Get(0).(*render.Document)
+	return doc, args.Error(1)
+}
+
+func (m *MockService) ImportPosts(ctx context.Context, posts []*models.Post) []error {
+	return m.Called(ctx, posts).Get(0).([]error)
+}
+
+func (m *MockService) ExportPosts(ctx context.Context, fn func(*models.Post) error) error {
+	return m.Called(ctx, fn).Error(0)
+}
+
+func (m *MockService) BatchGetPosts(ctx context.Context, ids []string) ([]*models.Post, []string, error) {
+	args := m.Called(ctx, ids)
+	posts, _ := args.Get(0).([]*models.Post)
+	missing, _ := args.Get(1).([]string)
+	return posts, missing, args.Error(2)
+}
+
+func (m *MockService) BatchDeletePosts(ctx context.Context, ids []string, transactional bool) []error {
+	return m.Called(ctx, ids, transactional).Get(0).([]error)
+}
+
+func (m *MockService) ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error) {
+	args := m.Called(ctx, filter, limit, cursor)
+	posts, _ := args.Get(0).([]*models.Post)
+	return posts, args.String(1), args.Error(2)
+}
+
+var _ Service = (*MockService)(nil)
+
+const testAPIKey = "0123456789abcdef"
+
+type gqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func newTestHandler(t *testing.T, service *MockService, cfg Config) *Handler {
+	t.Helper()
+	h, err := NewHandler(service, cfg)
+	require.NoError(t, err)
+	return h
+}
+
+func do(t *testing.T, h http.Handler, query string, variables map[string]interface{}, header http.Header) (*httptest.ResponseRecorder, gqlResponse) {
+	t.Helper()
+	body, err := json.Marshal(Request{Query: query, Variables: variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp gqlResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	}
+	return rec, resp
+}
+
+func errorCodes(resp gqlResponse) []interface{} {
+	var codes []interface{}
+	for _, e := range resp.Errors {
+		codes = append(codes, e.Extensions["code"])
+	}
+	return codes
+}
+
+func TestPostQuery(t *testing.T) {
+	t.Run("Batches Lookups By ID", func(t *testing.T) {
+		service := new(MockService)
+		service.On("BatchGetPosts", mock.Anything, mock.MatchedBy(func(ids []string) bool {
+			return assert.ElementsMatch(t, []string{"1", "2", "3"}, ids)
+		})).Return([]*models.Post{
+			{ID: "1", Title: "First"},
+			{ID: "2", Title: "Second"},
+		}, []string{"3"}, nil).Once()
+		h := newTestHandler(t, service, Config{})
+
+		rec, resp := do(t, h, `{
+			a: post(id: "1") { id title }
+			b: post(id: "2") { title }
+			again: post(id: "1") { title }
+			c: post(id: "3") { title }
+		}`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"id": "1", "title": "First"}`, string(resp.Data["a"]))
+		assert.JSONEq(t, `{"title": "Second"}`, string(resp.Data["b"]))
+		assert.JSONEq(t, `{"title": "First"}`, string(resp.Data["again"]))
+		assert.JSONEq(t, `null`, string(resp.Data["c"]))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Renders Once For Rendered Fields", func(t *testing.T) {
+		service := new(MockService)
+		post := &models.Post{ID: "1", Title: "First", Content: "# Hello"}
+		service.On("BatchGetPosts", mock.Anything, []string{"1"}).Return([]*models.Post{post}, nil, nil)
+		service.On("RenderPost", mock.Anything, post).Return(&render.Document{
+			HTML: `<h1 id="hello">Hello</h1>`,
+			TOC:  []*render.TOCEntry{{Level: 1, ID: "hello", Title: "Hello"}},
+		}, nil).Once()
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, `{ post(id: "1") { contentHtml toc { id title children { id } } tags status contentFormat } }`, nil, nil)
+
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{
+			"contentHtml": "<h1 id=\"hello\">Hello</h1>",
+			"toc": [{"id": "hello", "title": "Hello", "children": []}],
+			"tags": [],
+			"status": "PUBLISHED",
+			"contentFormat": "PLAIN"
+		}`, string(resp.Data["post"]))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Lookup Failure", func(t *testing.T) {
+		service := new(MockService)
+		service.On("BatchGetPosts", mock.Anything, []string{"1"}).Return(nil, nil, errors.New("boom"))
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, `{ post(id: "1") { title } }`, nil, nil)
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "failed to fetch post", resp.Errors[0].Message)
+		assert.Equal(t, []interface{}{CodeInternal}, errorCodes(resp))
+	})
+}
+
+func TestPostsQuery(t *testing.T) {
+	posts := []*models.Post{{ID: "1", Title: "First"}, {ID: "2", Title: "Second"}, {ID: "3", Title: "Third"}}
+	query := `query($first: Int, $after: String) {
+		posts(first: $first, after: $after, filter: {author: "ann"}) {
+			edges { cursor node { id } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+	filter := models.PostFilter{Author: "ann"}
+
+	var page struct {
+		Edges []struct {
+			Cursor string `json:"cursor"`
+			Node   struct {
+				ID string `json:"id"`
+			} `json:"node"`
+		} `json:"edges"`
+		PageInfo struct {
+			HasNextPage bool    `json:"hasNextPage"`
+			EndCursor   *string `json:"endCursor"`
+		} `json:"pageInfo"`
+	}
+
+	t.Run("First Page", func(t *testing.T) {
+		service := new(MockService)
+		service.On("ListPublishedPosts", mock.Anything, filter, 2, "").Return(posts[:2], "page2", nil)
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, query, map[string]interface{}{"first": 2}, nil)
+
+		require.Empty(t, resp.Errors)
+		require.NoError(t, json.Unmarshal(resp.Data["posts"], &page))
+		require.Len(t, page.Edges, 2)
+		assert.Equal(t, "1", page.Edges[0].Node.ID)
+		assert.Equal(t, edgeCursor{Offset: 1}.encode(), page.Edges[0].Cursor)
+		assert.Equal(t, edgeCursor{Page: "page2"}.encode(), page.Edges[1].Cursor)
+		assert.True(t, page.PageInfo.HasNextPage)
+		assert.Equal(t, &page.Edges[1].Cursor, page.PageInfo.EndCursor)
+	})
+
+	t.Run("After End Cursor", func(t *testing.T) {
+		service := new(MockService)
+		service.On("ListPublishedPosts", mock.Anything, filter, 2, "page2").Return(posts[2:], "", nil)
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, query, map[string]interface{}{"first": 2, "after": edgeCursor{Page: "page2"}.encode()}, nil)
+
+		require.Empty(t, resp.Errors)
+		require.NoError(t, json.Unmarshal(resp.Data["posts"], &page))
+		require.Len(t, page.Edges, 1)
+		assert.Equal(t, "3", page.Edges[0].Node.ID)
+		assert.False(t, page.PageInfo.HasNextPage)
+	})
+
+	t.Run("After Edge Inside A Page", func(t *testing.T) {
+		service := new(MockService)
+		service.On("ListPublishedPosts", mock.Anything, filter, 3, "").Return(posts, "page3", nil)
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, query, map[string]interface{}{"first": 2, "after": edgeCursor{Offset: 1}.encode()}, nil)
+
+		require.Empty(t, resp.Errors)
+		require.NoError(t, json.Unmarshal(resp.Data["posts"], &page))
+		require.Len(t, page.Edges, 2)
+		assert.Equal(t, "2", page.Edges[0].Node.ID)
+		assert.Equal(t, edgeCursor{Offset: 2}.encode(), page.Edges[0].Cursor)
+		assert.Equal(t, edgeCursor{Page: "page3"}.encode(), page.Edges[1].Cursor)
+	})
+
+	t.Run("Invalid Arguments", func(t *testing.T) {
+		h := newTestHandler(t, new(MockService), Config{})
+
+		_, resp := do(t, h, query, map[string]interface{}{"first": 51}, nil)
+		assert.Equal(t, []interface{}{CodeBadUserInput}, errorCodes(resp))
+
+		_, resp = do(t, h, query, map[string]interface{}{"after": "not a cursor"}, nil)
+		assert.Equal(t, []interface{}{CodeBadUserInput}, errorCodes(resp))
+	})
+}
+
+func TestMutations(t *testing.T) {
+	authenticator := auth.New(auth.Config{APIKeys: []string{testAPIKey}})
+	withKey := http.Header{auth.APIKeyHeader: {testAPIKey}}
+
+	t.Run("Require Credentials", func(t *testing.T) {
+		service := new(MockService)
+		h := newTestHandler(t, service, Config{Auth: authenticator})
+
+		rec, _ := do(t, h, `mutation { deletePost(id: "1") }`, nil, nil)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+		service.AssertNotCalled(t, "DeletePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Queries Need No Credentials", func(t *testing.T) {
+		service := new(MockService)
+		service.On("BatchGetPosts", mock.Anything, []string{"1"}).Return(nil, []string{"1"}, nil)
+		h := newTestHandler(t, service, Config{Auth: authenticator})
+
+		rec, resp := do(t, h, `{ post(id: "1") { id } }`, nil, nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, resp.Errors)
+	})
+
+	t.Run("Create Post", func(t *testing.T) {
+		service := new(MockService)
+		service.On("CreatePost", mock.Anything, &models.Post{
+			Title: "Hello", Content: "<p>Hi</p><script></script>", ContentFormat: models.ContentFormatHTML, Author: "ann", Tags: []string{"go"},
+		}).Return(&models.Post{ID: "1", Title: "Hello"}, &sanitize.Report{Removed: []sanitize.Removal{{Kind: "element", Name: "script", Count: 1}}}, nil)
+		h := newTestHandler(t, service, Config{Auth: authenticator})
+
+		_, resp := do(t, h, `mutation($input: CreatePostInput!) {
+			createPost(input: $input) { post { id title } removed { name count } }
+		}`, map[string]interface{}{"input": map[string]interface{}{
+			"title": "Hello", "content": "<p>Hi</p><script></script>", "contentFormat": "HTML", "author": "ann", "tags": []string{"go"},
+		}}, withKey)
+
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"post": {"id": "1", "title": "Hello"}, "removed": [{"name": "script", "count": 1}]}`, string(resp.Data["createPost"]))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Create Invalid Post", func(t *testing.T) {
+		service := new(MockService)
+		service.On("CreatePost", mock.Anything, mock.Anything).Return(nil, nil, errors.New("post validation failed"))
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, `mutation { createPost(input: {title: "Hi", content: "x", author: "ann"}) { post { id } } }`, nil, nil)
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "post validation failed", resp.Errors[0].Message)
+		assert.Equal(t, []interface{}{CodeBadUserInput}, errorCodes(resp))
+	})
+
+	t.Run("Update Changes Only Given Fields", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(&models.Post{ID: "1", Title: "Old", Content: "Body", Author: "ann"}, nil)
+		service.On("UpdatePost", mock.Anything, "1", &models.Post{ID: "1", Title: "New", Content: "Body", Author: "ann", Status: models.StatusDraft}).
+			Return(&models.Post{ID: "1", Title: "New", Status: models.StatusDraft}, nil, nil)
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, `mutation { updatePost(id: "1", input: {title: "New", status: DRAFT}) { post { title status } removed { name } } }`, nil, nil)
+
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"post": {"title": "New", "status": "DRAFT"}, "removed": []}`, string(resp.Data["updatePost"]))
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Update Missing Post", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(nil, errors.New("not found"))
+		h := newTestHandler(t, service, Config{})
+
+		_, resp := do(t, h, `mutation { updatePost(id: "1", input: {title: "New"}) { post { id } } }`, nil, nil)
+
+		assert.Equal(t, []interface{}{CodeNotFound}, errorCodes(resp))
+	})
+
+	t.Run("Delete Post", func(t *testing.T) {
+		service := new(MockService)
+		service.On("DeletePost", mock.Anything, "1").Return(nil)
+		service.On("DeletePost", mock.Anything, "2").Return(errors.New("not found"))
+		h := newTestHandler(t, service, Config{Auth: authenticator})
+
+		_, resp := do(t, h, `mutation { deletePost(id: "1") }`, nil, withKey)
+		assert.Empty(t, resp.Errors)
+		assert.JSONEq(t, `"1"`, string(resp.Data["deletePost"]))
+
+		_, resp = do(t, h, `mutation { deletePost(id: "2") }`, nil, withKey)
+		assert.Equal(t, []interface{}{CodeNotFound}, errorCodes(resp))
+	})
+}
+
+func TestLimits(t *testing.T) {
+	t.Run("Depth", func(t *testing.T) {
+		service := new(MockService)
+		h := newTestHandler(t, service, Config{MaxDepth: 3})
+
+		_, resp := do(t, h, `{ post(id: "1") { toc { children { children { id } } } } }`, nil, nil)
+
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "query depth 5 exceeds the limit of 3", resp.Errors[0].Message)
+		assert.Equal(t, []interface{}{CodeQueryTooDeep}, errorCodes(resp))
+		service.AssertNotCalled(t, "BatchGetPosts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Depth Through Fragments", func(t *testing.T) {
+		h := newTestHandler(t, new(MockService), Config{MaxDepth: 3})
+
+		_, resp := do(t, h, `
+			{ post(id: "1") { ...Headings } }
+			fragment Headings on Post { toc { children { id } } }
+		`, nil, nil)
+
+		assert.Equal(t, []interface{}{CodeQueryTooDeep}, errorCodes(resp))
+	})
+
+	t.Run("Complexity Counts Every Post Of A Page", func(t *testing.T) {
+		service := new(MockService)
+		h := newTestHandler(t, service, Config{MaxComplexity: 100})
+		query := `query($first: Int) { posts(first: $first) { edges { node { id contentHtml } } } }`
+
+		// posts + first * (edges + node + id + contentHtml)
+		_, resp := do(t, h, query, map[string]interface{}{"first": 20}, nil)
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, "query complexity 161 exceeds the limit of 100", resp.Errors[0].Message)
+		assert.Equal(t, []interface{}{CodeQueryTooComplex}, errorCodes(resp))
+
+		service.On("ListPublishedPosts", mock.Anything, models.PostFilter{}, 5, "").Return([]*models.Post{}, "", nil)
+		_, resp = do(t, h, query, map[string]interface{}{"first": 5}, nil)
+		assert.Empty(t, resp.Errors)
+	})
+
+	t.Run("Introspection Is Free", func(t *testing.T) {
+		h := newTestHandler(t, new(MockService), Config{MaxDepth: 2, MaxComplexity: 1})
+
+		_, resp := do(t, h, `{ __schema { types { name fields { type { ofType { name } } } } } }`, nil, nil)
+
+		assert.Empty(t, resp.Errors)
+	})
+}
+
+func TestRequestErrors(t *testing.T) {
+	h := newTestHandler(t, new(MockService), Config{})
+
+	t.Run("Malformed Body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/graphql", strings.NewReader("{"))
+		rec := httptest.NewRecorder()
+
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "body must be a JSON GraphQL request")
+	})
+
+	t.Run("Syntax Error", func(t *testing.T) {
+		rec, resp := do(t, h, `{ post(id: "1") { `, nil, nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Len(t, resp.Errors, 1)
+		assert.Nil(t, resp.Data)
+	})
+
+	t.Run("Unknown Field", func(t *testing.T) {
+		_, resp := do(t, h, `{ post(id: "1") { secret } }`, nil, nil)
+
+		require.Len(t, resp.Errors, 1)
+		assert.Contains(t, resp.Errors[0].Message, `Cannot query field "secret"`)
+	})
+}
@@ -0,0 +1,106 @@
+package graphql
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Field costs. Every field costs 1 except the ones that render the post,
+// and the selection of a posts connection is counted once per post of the
+// requested page.
+const (
+	fieldCost  = 1
+	renderCost = 5
+)
+
+var fieldCosts = map[string]int{
+	"contentHtml": renderCost,
+	"toc":         renderCost,
+}
+
+// measure returns the nesting depth and the cost of op. Fragments are
+// expanded where they are spread; introspection fields are free.
+func measure(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) (depth, cost int) {
+	m := &meter{fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, def := range doc.Definitions {
+		if frag, ok := def.(*ast.FragmentDefinition); ok && frag.Name != nil {
+			m.fragments[frag.Name.Value] = frag
+		}
+	}
+	return m.selections(op.SelectionSet, make(map[string]bool))
+}
+
+type meter struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// selections returns the depth and cost of set. visiting holds the fragments
+// being expanded; validation rejects cycles, but they must not hang us.
+func (m *meter) selections(set *ast.SelectionSet, visiting map[string]bool) (depth, cost int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, sel := range set.Selections {
+		var d, c int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			d, c = m.field(sel, visiting)
+		case *ast.InlineFragment:
+			d, c = m.selections(sel.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			name := sel.Name.Value
+			frag, ok := m.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			d, c = m.selections(frag.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+		depth = max(depth, d)
+		cost += c
+	}
+	return depth, cost
+}
+
+func (m *meter) field(field *ast.Field, visiting map[string]bool) (depth, cost int) {
+	name := field.Name.Value
+	if strings.HasPrefix(name, "__") {
+		return 0, 0
+	}
+	depth, cost = m.selections(field.SelectionSet, visiting)
+	if name == "posts" {
+		cost *= pageSize(m.argument(field, "first"))
+	}
+	own, ok := fieldCosts[name]
+	if !ok {
+		own = fieldCost
+	}
+	return depth + 1, cost + own
+}
+
+// argument returns the value of the named integer argument of field, or
+// nil when it is absent or not an integer.
+func (m *meter) argument(field *ast.Field, name string) *int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != name {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				return &n
+			}
+		case *ast.Variable:
+			// Variables decoded from JSON are float64.
+			if f, ok := m.variables[value.Name.Value].(float64); ok {
+				n := int(f)
+				return &n
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/render"
+)
+
+// batchSize is the most IDs passed to one BatchGetPosts call.
+const batchSize = 100
+
+// state is the per-request data shared by the resolvers.
+type state struct {
+	service Service
+	posts   *postLoader
+
+	mu      sync.Mutex
+	renders map[*models.Post]*render.Document
+}
+
+func newState(ctx context.Context, service Service) *state {
+	return &state{
+		service: service,
+		posts:   newPostLoader(ctx, service),
+		renders: make(map[*models.Post]*render.Document),
+	}
+}
+
+type stateKey struct{}
+
+func withState(ctx context.Context, s *state) context.Context {
+	return context.WithValue(ctx, stateKey{}, s)
+}
+
+func stateFrom(ctx context.Context) *state {
+	return ctx.Value(stateKey{}).(*state)
+}
+
+// render renders post once per request, however many of its rendered fields
+// are selected.
+func (s *state) render(ctx context.Context, post *models.Post) (*render.Document, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if doc, ok := s.renders[post]; ok {
+		return doc, nil
+	}
+	doc, err := s.service.RenderPost(ctx, post)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to render post", "id", post.ID, "error", err)
+		return nil, internal("failed to render post")
+	}
+	s.renders[post] = doc
+	return doc, nil
+}
+
+// postLoader collects the IDs of the posts requested while a level of the
+// query is resolved and fetches them together once the first is needed,
+// so that a query selecting many posts by ID costs one BatchGetPosts call
+// instead of one GetPostByID call per post. Results are cached for the
+// rest of the request.
+type postLoader struct {
+	ctx     context.Context
+	service Service
+
+	mu      sync.Mutex
+	pending []string
+	queued  map[string]bool
+	// posts holds the fetched posts; IDs that do not exist map to nil.
+	posts map[string]*models.Post
+	errs  map[string]error
+}
+
+func newPostLoader(ctx context.Context, service Service) *postLoader {
+	return &postLoader{
+		ctx:     ctx,
+		service: service,
+		queued:  make(map[string]bool),
+		posts:   make(map[string]*models.Post),
+		errs:    make(map[string]error),
+	}
+}
+
+// load queues id and returns a thunk resolving to its post, or to nil when
+// it does not exist. The executor runs thunks after the rest of the level
+// has been resolved.
+func (l *postLoader) load(id string) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.posts[id]; !ok && !l.queued[id] {
+		l.queued[id] = true
+		l.pending = append(l.pending, id)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch()
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if err := l.errs[id]; err != nil {
+			return nil, err
+		}
+		if post := l.posts[id]; post != nil {
+			return post, nil
+		}
+		return nil, nil
+	}
+}
+
+// prime caches a post returned by a mutation.
+func (l *postLoader) prime(post *models.Post) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.posts[post.ID] = post
+}
+
+// forget drops a deleted post from the cache.
+func (l *postLoader) forget(id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.posts[id] = nil
+}
+
+// dispatch fetches the queued IDs.
+func (l *postLoader) dispatch() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for len(l.pending) > 0 {
+		ids := l.pending[:min(batchSize, len(l.pending))]
+		l.pending = l.pending[len(ids):]
+		for _, id := range ids {
+			delete(l.queued, id)
+		}
+
+		posts, missing, err := l.service.BatchGetPosts(l.ctx, ids)
+		if err != nil {
+			logging.FromContext(l.ctx).Error("failed to batch fetch posts", "error", err)
+			for _, id := range ids {
+				l.errs[id] = internal("failed to fetch post")
+			}
+			continue
+		}
+		for _, post := range posts {
+			l.posts[post.ID] = post
+		}
+		for _, id := range missing {
+			l.posts[id] = nil
+		}
+	}
+}
@@ -0,0 +1,405 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/sanitize"
+	gql "github.com/graphql-go/graphql"
+)
+
+// Page sizes of the posts connection.
+const (
+	defaultPageSize = 10
+	maxPageSize     = 50
+)
+
+// pageSize returns the page size requested by first, within bounds.
+func pageSize(first *int) int {
+	if first == nil {
+		return defaultPageSize
+	}
+	return min(max(*first, 1), maxPageSize)
+}
+
+var postStatusEnum = gql.NewEnum(gql.EnumConfig{
+	Name: "PostStatus",
+	Values: gql.EnumValueConfigMap{
+		"DRAFT":     &gql.EnumValueConfig{Value: models.StatusDraft},
+		"PUBLISHED": &gql.EnumValueConfig{Value: models.StatusPublished},
+	},
+})
+
+var contentFormatEnum = gql.NewEnum(gql.EnumConfig{
+	Name: "ContentFormat",
+	Values: gql.EnumValueConfigMap{
+		"PLAIN":    &gql.EnumValueConfig{Value: models.ContentFormatPlain},
+		"MARKDOWN": &gql.EnumValueConfig{Value: models.ContentFormatMarkdown},
+		"HTML":     &gql.EnumValueConfig{Value: models.ContentFormatHTML},
+	},
+})
+
+var tocEntryType = gql.NewObject(gql.ObjectConfig{
+	Name:        "TOCEntry",
+	Description: "A heading of a rendered post.",
+	Fields: gql.Fields{
+		"level": &gql.Field{Type: gql.NewNonNull(gql.Int)},
+		"id":    &gql.Field{Type: gql.NewNonNull(gql.String), Description: "Anchor of the heading in contentHtml."},
+		"title": &gql.Field{Type: gql.NewNonNull(gql.String)},
+	},
+})
+
+func init() {
+	// Added here because the field refers to its own type.
+	tocEntryType.AddFieldConfig("children", &gql.Field{
+		Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(tocEntryType))),
+		Resolve: func(p gql.ResolveParams) (interface{}, error) {
+			if children := p.Source.(*render.TOCEntry).Children; children != nil {
+				return children, nil
+			}
+			return []*render.TOCEntry{}, nil
+		},
+	})
+}
+
+var postType = gql.NewObject(gql.ObjectConfig{
+	Name: "Post",
+	Fields: gql.Fields{
+		"id":      &gql.Field{Type: gql.NewNonNull(gql.ID)},
+		"title":   &gql.Field{Type: gql.NewNonNull(gql.String)},
+		"content": &gql.Field{Type: gql.NewNonNull(gql.String), Description: "Stored content in contentFormat."},
+		"contentFormat": &gql.Field{
+			Type:    gql.NewNonNull(contentFormatEnum),
+			Resolve: func(p gql.ResolveParams) (interface{}, error) { return p.Source.(*models.Post).Format(), nil },
+		},
+		"author": &gql.Field{Type: gql.NewNonNull(gql.String)},
+		"tags": &gql.Field{
+			Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(gql.String))),
+			Resolve: func(p gql.ResolveParams) (interface{}, error) {
+				if tags := p.Source.(*models.Post).Tags; tags != nil {
+					return tags, nil
+				}
+				return []string{}, nil
+			},
+		},
+		"status": &gql.Field{
+			Type: gql.NewNonNull(postStatusEnum),
+			Resolve: func(p gql.ResolveParams) (interface{}, error) {
+				if status := p.Source.(*models.Post).Status; status != "" {
+					return status, nil
+				}
+				return models.StatusPublished, nil
+			},
+		},
+		"version":   &gql.Field{Type: gql.NewNonNull(gql.Int)},
+		"createdAt": &gql.Field{Type: gql.NewNonNull(gql.DateTime)},
+		"updatedAt": &gql.Field{Type: gql.NewNonNull(gql.DateTime)},
+		"contentHtml": &gql.Field{
+			Type:        gql.NewNonNull(gql.String),
+			Description: "Sanitized HTML rendering of the content.",
+			Resolve: func(p gql.ResolveParams) (interface{}, error) {
+				doc, err := stateFrom(p.Context).render(p.Context, p.Source.(*models.Post))
+				if err != nil {
+					return nil, err
+				}
+				return doc.HTML, nil
+			},
+		},
+		"toc": &gql.Field{
+			Type:        gql.NewNonNull(gql.NewList(gql.NewNonNull(tocEntryType))),
+			Description: "Table of contents of the rendered content.",
+			Resolve: func(p gql.ResolveParams) (interface{}, error) {
+				doc, err := stateFrom(p.Context).render(p.Context, p.Source.(*models.Post))
+				if err != nil {
+					return nil, err
+				}
+				return doc.TOC, nil
+			},
+		},
+	},
+})
+
+var pageInfoType = gql.NewObject(gql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: gql.Fields{
+		"hasNextPage": &gql.Field{Type: gql.NewNonNull(gql.Boolean)},
+		"endCursor":   &gql.Field{Type: gql.String},
+	},
+})
+
+var postEdgeType = gql.NewObject(gql.ObjectConfig{
+	Name: "PostEdge",
+	Fields: gql.Fields{
+		"cursor": &gql.Field{Type: gql.NewNonNull(gql.String)},
+		"node":   &gql.Field{Type: gql.NewNonNull(postType)},
+	},
+})
+
+var postConnectionType = gql.NewObject(gql.ObjectConfig{
+	Name: "PostConnection",
+	Fields: gql.Fields{
+		"edges":    &gql.Field{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(postEdgeType)))},
+		"pageInfo": &gql.Field{Type: gql.NewNonNull(pageInfoType)},
+	},
+})
+
+var removalType = gql.NewObject(gql.ObjectConfig{
+	Name:        "Removal",
+	Description: "Markup stripped from a post's content.",
+	Fields: gql.Fields{
+		"kind":  &gql.Field{Type: gql.NewNonNull(gql.String)},
+		"name":  &gql.Field{Type: gql.NewNonNull(gql.String)},
+		"count": &gql.Field{Type: gql.NewNonNull(gql.Int)},
+	},
+})
+
+var postPayloadType = gql.NewObject(gql.ObjectConfig{
+	Name: "PostPayload",
+	Fields: gql.Fields{
+		"post":    &gql.Field{Type: gql.NewNonNull(postType)},
+		"removed": &gql.Field{Type: gql.NewNonNull(gql.NewList(gql.NewNonNull(removalType)))},
+	},
+})
+
+var postFilterInput = gql.NewInputObject(gql.InputObjectConfig{
+	Name: "PostFilter",
+	Fields: gql.InputObjectConfigFieldMap{
+		"author": &gql.InputObjectFieldConfig{Type: gql.String},
+		"tag":    &gql.InputObjectFieldConfig{Type: gql.String},
+	},
+})
+
+var createPostInput = gql.NewInputObject(gql.InputObjectConfig{
+	Name: "CreatePostInput",
+	Fields: gql.InputObjectConfigFieldMap{
+		"title":         &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+		"content":       &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+		"contentFormat": &gql.InputObjectFieldConfig{Type: contentFormatEnum},
+		"author":        &gql.InputObjectFieldConfig{Type: gql.NewNonNull(gql.String)},
+		"tags":          &gql.InputObjectFieldConfig{Type: gql.NewList(gql.NewNonNull(gql.String))},
+		"status":        &gql.InputObjectFieldConfig{Type: postStatusEnum},
+	},
+})
+
+// updatePostInput changes only the fields it sets, like PATCH /v1/posts/{id}.
+var updatePostInput = gql.NewInputObject(gql.InputObjectConfig{
+	Name: "UpdatePostInput",
+	Fields: gql.InputObjectConfigFieldMap{
+		"title":         &gql.InputObjectFieldConfig{Type: gql.String},
+		"content":       &gql.InputObjectFieldConfig{Type: gql.String},
+		"contentFormat": &gql.InputObjectFieldConfig{Type: contentFormatEnum},
+		"author":        &gql.InputObjectFieldConfig{Type: gql.String},
+		"tags":          &gql.InputObjectFieldConfig{Type: gql.NewList(gql.NewNonNull(gql.String))},
+		"status":        &gql.InputObjectFieldConfig{Type: postStatusEnum},
+	},
+})
+
+func newSchema() (gql.Schema, error) {
+	query := gql.NewObject(gql.ObjectConfig{
+		Name: "Query",
+		Fields: gql.Fields{
+			"post": &gql.Field{
+				Type:        postType,
+				Description: "The post with the given ID, of any status, or null.",
+				Args: gql.FieldConfigArgument{
+					"id": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.ID)},
+				},
+				Resolve: resolvePost,
+			},
+			"posts": &gql.Field{
+				Type:        gql.NewNonNull(postConnectionType),
+				Description: fmt.Sprintf("Published posts, newest first. first defaults to %d and may be at most %d.", defaultPageSize, maxPageSize),
+				Args: gql.FieldConfigArgument{
+					"first":  &gql.ArgumentConfig{Type: gql.Int},
+					"after":  &gql.ArgumentConfig{Type: gql.String},
+					"filter": &gql.ArgumentConfig{Type: postFilterInput},
+				},
+				Resolve: resolvePosts,
+			},
+		},
+	})
+
+	mutation := gql.NewObject(gql.ObjectConfig{
+		Name: "Mutation",
+		Fields: gql.Fields{
+			"createPost": &gql.Field{
+				Type: gql.NewNonNull(postPayloadType),
+				Args: gql.FieldConfigArgument{
+					"input": &gql.ArgumentConfig{Type: gql.NewNonNull(createPostInput)},
+				},
+				Resolve: resolveCreatePost,
+			},
+			"updatePost": &gql.Field{
+				Type: gql.NewNonNull(postPayloadType),
+				Args: gql.FieldConfigArgument{
+					"id":    &gql.ArgumentConfig{Type: gql.NewNonNull(gql.ID)},
+					"input": &gql.ArgumentConfig{Type: gql.NewNonNull(updatePostInput)},
+				},
+				Resolve: resolveUpdatePost,
+			},
+			"deletePost": &gql.Field{
+				Type:        gql.NewNonNull(gql.ID),
+				Description: "Deletes a post and returns its ID.",
+				Args: gql.FieldConfigArgument{
+					"id": &gql.ArgumentConfig{Type: gql.NewNonNull(gql.ID)},
+				},
+				Resolve: resolveDeletePost,
+			},
+		},
+	})
+
+	return gql.NewSchema(gql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func resolvePost(p gql.ResolveParams) (interface{}, error) {
+	return stateFrom(p.Context).posts.load(p.Args["id"].(string)), nil
+}
+
+type postConnection struct {
+	Edges    []postEdge `json:"edges"`
+	PageInfo pageInfo   `json:"pageInfo"`
+}
+
+type postEdge struct {
+	Cursor string       `json:"cursor"`
+	Node   *models.Post `json:"node"`
+}
+
+type pageInfo struct {
+	HasNextPage bool    `json:"hasNextPage"`
+	EndCursor   *string `json:"endCursor"`
+}
+
+func resolvePosts(p gql.ResolveParams) (interface{}, error) {
+	first := defaultPageSize
+	if n, ok := p.Args["first"].(int); ok {
+		if n < 1 || n > maxPageSize {
+			return nil, badInput(fmt.Errorf("first must be between 1 and %d", maxPageSize))
+		}
+		first = n
+	}
+	var after edgeCursor
+	if s, ok := p.Args["after"].(string); ok {
+		var err error
+		if after, err = decodeEdgeCursor(s); err != nil {
+			return nil, badInput(err)
+		}
+	}
+	var filter models.PostFilter
+	if f, ok := p.Args["filter"].(map[string]interface{}); ok {
+		filter.Author, _ = f["author"].(string)
+		filter.Tag, _ = f["tag"].(string)
+	}
+
+	st := stateFrom(p.Context)
+	posts, next, err := st.service.ListPublishedPosts(p.Context, filter, after.Offset+first, after.Page)
+	if err != nil {
+		logging.FromContext(p.Context).Error("failed to list posts", "error", err)
+		return nil, internal("failed to fetch posts")
+	}
+	posts = posts[min(after.Offset, len(posts)):]
+
+	conn := postConnection{Edges: make([]postEdge, len(posts)), PageInfo: pageInfo{HasNextPage: next != ""}}
+	for i, post := range posts {
+		cursor := edgeCursor{Page: after.Page, Offset: after.Offset + i + 1}
+		if i == len(posts)-1 && next != "" {
+			// The listing resumes right after the last post.
+			cursor = edgeCursor{Page: next}
+		}
+		conn.Edges[i] = postEdge{Cursor: cursor.encode(), Node: post}
+		st.posts.prime(post)
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+	return conn, nil
+}
+
+// postInput copies the fields set in input onto post.
+func postInput(post *models.Post, input map[string]interface{}) {
+	if v, ok := input["title"].(string); ok {
+		post.Title = v
+	}
+	if v, ok := input["content"].(string); ok {
+		post.Content = v
+	}
+	if v, ok := input["contentFormat"].(string); ok {
+		post.ContentFormat = v
+	}
+	if v, ok := input["author"].(string); ok {
+		post.Author = v
+	}
+	if v, ok := input["status"].(string); ok {
+		post.Status = v
+	}
+	if v, ok := input["tags"].([]interface{}); ok {
+		post.Tags = make([]string, 0, len(v))
+		for _, tag := range v {
+			post.Tags = append(post.Tags, tag.(string))
+		}
+	}
+}
+
+type postPayload struct {
+	Post    *models.Post       `json:"post"`
+	Removed []sanitize.Removal `json:"removed"`
+}
+
+func newPostPayload(post *models.Post, report *sanitize.Report) postPayload {
+	payload := postPayload{Post: post, Removed: []sanitize.Removal{}}
+	if report.Changed() {
+		payload.Removed = report.Removed
+	}
+	return payload
+}
+
+func resolveCreatePost(p gql.ResolveParams) (interface{}, error) {
+	var post models.Post
+	postInput(&post, p.Args["input"].(map[string]interface{}))
+
+	st := stateFrom(p.Context)
+	created, report, err := st.service.CreatePost(p.Context, &post)
+	if err != nil {
+		return nil, badInput(err)
+	}
+	st.posts.prime(created)
+	return newPostPayload(created, report), nil
+}
+
+func resolveUpdatePost(p gql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	input := p.Args["input"].(map[string]interface{})
+	if title, ok := input["title"].(string); ok && title == "" {
+		return nil, badInput(errors.New("title cannot be empty"))
+	}
+
+	st := stateFrom(p.Context)
+	post, err := st.service.GetPostByID(p.Context, id)
+	if err != nil {
+		return nil, notFound("post not found")
+	}
+	postInput(post, input)
+
+	updated, report, err := st.service.UpdatePost(p.Context, id, post)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, notFound("post not found")
+		}
+		return nil, badInput(err)
+	}
+	st.posts.prime(updated)
+	return newPostPayload(updated, report), nil
+}
+
+func resolveDeletePost(p gql.ResolveParams) (interface{}, error) {
+	id := p.Args["id"].(string)
+	st := stateFrom(p.Context)
+	if err := st.service.DeletePost(p.Context, id); err != nil {
+		return nil, notFound("post not found")
+	}
+	st.posts.forget(id)
+	return id, nil
+}
@@ -0,0 +1,114 @@
+package grpcapi
+
+import (
+	"fmt"
+	"slices"
+
+	blogv1 "blog-api/api/blog/v1"
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var contentFormats = map[string]blogv1.ContentFormat{
+	models.ContentFormatPlain:    blogv1.ContentFormat_CONTENT_FORMAT_PLAIN,
+	models.ContentFormatMarkdown: blogv1.ContentFormat_CONTENT_FORMAT_MARKDOWN,
+	models.ContentFormatHTML:     blogv1.ContentFormat_CONTENT_FORMAT_HTML,
+}
+
+var statuses = map[string]blogv1.PostStatus{
+	models.StatusDraft:     blogv1.PostStatus_POST_STATUS_DRAFT,
+	models.StatusPublished: blogv1.PostStatus_POST_STATUS_PUBLISHED,
+}
+
+var eventTypes = map[string]blogv1.PostEvent_Type{
+	events.TypeCreated: blogv1.PostEvent_TYPE_CREATED,
+	events.TypeUpdated: blogv1.PostEvent_TYPE_UPDATED,
+	events.TypeDeleted: blogv1.PostEvent_TYPE_DELETED,
+}
+
+// patchablePaths are the update mask paths accepted by PatchPost.
+var patchablePaths = []string{"title", "content", "content_format", "author", "tags", "status"}
+
+func toProto(post *models.Post) *blogv1.Post {
+	status := statuses[post.Status]
+	if post.Status == "" {
+		status = blogv1.PostStatus_POST_STATUS_PUBLISHED
+	}
+	return &blogv1.Post{
+		Id:            post.ID,
+		Title:         post.Title,
+		Content:       post.Content,
+		ContentFormat: contentFormats[post.Format()],
+		Author:        post.Author,
+		Tags:          post.Tags,
+		Status:        status,
+		Version:       post.Version,
+		CreateTime:    timestamppb.New(post.CreatedAt),
+		UpdateTime:    timestamppb.New(post.UpdatedAt),
+	}
+}
+
+func toProtoEvent(event events.Event) *blogv1.PostEvent {
+	pb := &blogv1.PostEvent{
+		Type: eventTypes[event.Type],
+		Id:   event.ID,
+		Time: timestamppb.New(event.Time),
+	}
+	if event.Post != nil {
+		pb.Post = toProto(event.Post)
+	}
+	return pb
+}
+
+// fromProto returns the writable fields of pb. Unspecified enums become
+// empty, leaving the service to apply its defaults.
+func fromProto(pb *blogv1.Post) *models.Post {
+	post := &models.Post{
+		ID:      pb.GetId(),
+		Title:   pb.GetTitle(),
+		Content: pb.GetContent(),
+		Author:  pb.GetAuthor(),
+		Tags:    pb.GetTags(),
+	}
+	for name, value := range contentFormats {
+		if pb.GetContentFormat() == value {
+			post.ContentFormat = name
+		}
+	}
+	for name, value := range statuses {
+		if pb.GetStatus() == value {
+			post.Status = name
+		}
+	}
+	return post
+}
+
+// applyMask copies the fields named by mask from pb onto post.
+func applyMask(post *models.Post, pb *blogv1.Post, mask *fieldmaskpb.FieldMask) error {
+	paths := mask.GetPaths()
+	if len(paths) == 0 {
+		return fmt.Errorf("update_mask must name at least one of %v", patchablePaths)
+	}
+	patched := fromProto(pb)
+	for _, path := range paths {
+		switch path {
+		case "title":
+			post.Title = patched.Title
+		case "content":
+			post.Content = patched.Content
+		case "content_format":
+			post.ContentFormat = patched.ContentFormat
+		case "author":
+			post.Author = patched.Author
+		case "tags":
+			post.Tags = slices.Clone(patched.Tags)
+		case "status":
+			post.Status = patched.Status
+		default:
+			return fmt.Errorf("update_mask path %q is not one of %v", path, patchablePaths)
+		}
+	}
+	return nil
+}
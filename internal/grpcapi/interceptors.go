@@ -0,0 +1,140 @@
+package grpcapi
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	blogv1 "blog-api/api/blog/v1"
+	"blog-api/internal/auth"
+	"blog-api/internal/logging"
+	"blog-api/internal/requestid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// writeMethods are the methods that need credentials.
+var writeMethods = map[string]bool{
+	blogv1.PostService_CreatePost_FullMethodName: true,
+	blogv1.PostService_UpdatePost_FullMethodName: true,
+	blogv1.PostService_PatchPost_FullMethodName:  true,
+	blogv1.PostService_DeletePost_FullMethodName: true,
+}
+
+// requestIDKey is the metadata key of the request ID; gRPC lowercases keys.
+var requestIDKey = strings.ToLower(requestid.Header)
+
+// begin adopts the caller's request ID or creates one and returns ctx with
+// the ID and a logger carrying it, plus the header echoing the ID back.
+func begin(ctx context.Context, logger *slog.Logger, method string) (context.Context, *slog.Logger, metadata.MD) {
+	var ids requestid.IDs
+	if values := metadata.ValueFromIncomingContext(ctx, requestIDKey); len(values) > 0 && requestid.Valid(values[0]) {
+		ids.RequestID = values[0]
+	} else {
+		ids.RequestID = requestid.New()
+	}
+	reqLogger := logger.With(ids.LogAttrs()...).With("method", method)
+	ctx = logging.WithLogger(requestid.WithIDs(ctx, ids), reqLogger)
+	return ctx, reqLogger, metadata.Pairs(requestIDKey, ids.RequestID)
+}
+
+// logCall writes the access-log line of a call, at the level the REST API
+// uses for the matching HTTP status.
+func logCall(ctx context.Context, logger *slog.Logger, start time.Time, err error) {
+	code := status.Code(err)
+	logger.Log(ctx, callLevel(code), "rpc",
+		"code", code.String(),
+		"latency_ms", float64(time.Since(start).Microseconds())/1000,
+	)
+}
+
+func callLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Unknown, codes.Internal, codes.Unavailable, codes.DataLoss, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+func unaryLogging(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, reqLogger, header := begin(ctx, logger, info.FullMethod)
+		if err := grpc.SetHeader(ctx, header); err != nil {
+			reqLogger.Warn("failed to set response header", "error", err)
+		}
+		resp, err := handler(ctx, req)
+		logCall(ctx, reqLogger, start, err)
+		return resp, err
+	}
+}
+
+func streamLogging(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, reqLogger, header := begin(ss.Context(), logger, info.FullMethod)
+		if err := ss.SetHeader(header); err != nil {
+			reqLogger.Warn("failed to set response header", "error", err)
+		}
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logCall(ctx, reqLogger, start, err)
+		return err
+	}
+}
+
+// authenticate checks the credentials of calls to write methods, accepting
+// the same x-api-key and authorization values as the REST API.
+func authenticate(ctx context.Context, authenticator *auth.Authenticator, method string) (context.Context, error) {
+	if !writeMethods[method] || authenticator == nil || !authenticator.Enabled() {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	first := func(key string) string {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	principal, err := authenticator.AuthenticateCredentials(first(auth.APIKeyHeader), first("authorization"))
+	if err != nil {
+		logging.FromContext(ctx).Debug("authentication failed", "error", err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func unaryAuth(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, authenticator, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), authenticator, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream replaces the context of a stream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,247 @@
+// Package grpcapi serves the blog.v1.PostService gRPC API on top of the post
+// service, with the REST API's authentication, access logging and error
+// semantics.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	blogv1 "blog-api/api/blog/v1"
+	"blog-api/internal/auth"
+	"blog-api/internal/events"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/repository"
+	"blog-api/internal/sanitize"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type PostService interface {
+	GetPostByID(ctx context.Context, id string) (*models.Post, error)
+	ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error)
+	CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error)
+	UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error)
+	DeletePost(ctx context.Context, id string) error
+}
+
+// Watcher delivers post events to WatchPosts.
+type Watcher interface {
+	Subscribe(buffer int) *events.Subscription
+}
+
+// Config holds the settings shared by every call. The zero value logs
+// through slog.Default() and requires no credentials.
+type Config struct {
+	Logger *slog.Logger
+	// Auth guards the methods that change posts when it has credentials
+	// configured.
+	Auth *auth.Authenticator
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// watchBuffer is how many events a WatchPosts call may fall behind
+	// before it is ended.
+	watchBuffer = 64
+)
+
+var _ blogv1.PostServiceServer = (*server)(nil)
+
+type server struct {
+	blogv1.UnimplementedPostServiceServer
+	service PostService
+	watcher Watcher
+}
+
+// NewServer creates a gRPC server exposing service as blog.v1.PostService,
+// with server reflection for tools such as grpcurl.
+func NewServer(service PostService, watcher Watcher, cfg Config) *grpc.Server {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogging(logger), unaryAuth(cfg.Auth)),
+		grpc.ChainStreamInterceptor(streamLogging(logger), streamAuth(cfg.Auth)),
+	)
+	blogv1.RegisterPostServiceServer(s, &server{service: service, watcher: watcher})
+	reflection.Register(s)
+	return s
+}
+
+func (s *server) GetPost(ctx context.Context, req *blogv1.GetPostRequest) (*blogv1.Post, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	}
+	post, err := s.service.GetPostByID(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(ctx, err, "get post")
+	}
+	return toProto(post), nil
+}
+
+func (s *server) ListPosts(ctx context.Context, req *blogv1.ListPostsRequest) (*blogv1.ListPostsResponse, error) {
+	size := int(req.GetPageSize())
+	switch {
+	case size < 0 || size > maxPageSize:
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", maxPageSize)
+	case size == 0:
+		size = defaultPageSize
+	}
+
+	filter := models.PostFilter{Author: req.GetAuthor(), Tag: req.GetTag()}
+	posts, next, err := s.service.ListPublishedPosts(ctx, filter, size, req.GetPageToken())
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		return nil, toStatus(ctx, err, "list posts")
+	}
+
+	resp := &blogv1.ListPostsResponse{Posts: make([]*blogv1.Post, len(posts)), NextPageToken: next}
+	for i, post := range posts {
+		resp.Posts[i] = toProto(post)
+	}
+	return resp, nil
+}
+
+func (s *server) CreatePost(ctx context.Context, req *blogv1.CreatePostRequest) (*blogv1.Post, error) {
+	if req.GetPost() == nil {
+		return nil, status.Error(codes.InvalidArgument, "post must be set")
+	}
+	post := fromProto(req.GetPost())
+	if err := post.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	created, _, err := s.service.CreatePost(ctx, post)
+	if err != nil {
+		return nil, toStatus(ctx, err, "create post")
+	}
+	return toProto(created), nil
+}
+
+func (s *server) UpdatePost(ctx context.Context, req *blogv1.UpdatePostRequest) (*blogv1.Post, error) {
+	id := req.GetPost().GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "post.id must not be empty")
+	}
+	post := fromProto(req.GetPost())
+	if err := post.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.update(ctx, id, post)
+}
+
+func (s *server) PatchPost(ctx context.Context, req *blogv1.PatchPostRequest) (*blogv1.Post, error) {
+	id := req.GetPost().GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "post.id must not be empty")
+	}
+	post, err := s.service.GetPostByID(ctx, id)
+	if err != nil {
+		return nil, toStatus(ctx, err, "get post")
+	}
+	if err := applyMask(post, req.GetPost(), req.GetUpdateMask()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := post.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return s.update(ctx, id, post)
+}
+
+func (s *server) update(ctx context.Context, id string, post *models.Post) (*blogv1.Post, error) {
+	updated, _, err := s.service.UpdatePost(ctx, id, post)
+	if err != nil {
+		return nil, toStatus(ctx, err, "update post")
+	}
+	return toProto(updated), nil
+}
+
+func (s *server) DeletePost(ctx context.Context, req *blogv1.DeletePostRequest) (*emptypb.Empty, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "id must not be empty")
+	}
+	if err := s.service.DeletePost(ctx, req.GetId()); err != nil {
+		return nil, toStatus(ctx, err, "delete post")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchPosts streams events until the client goes away. A client that does
+// not keep up is ended with ResourceExhausted, and every stream is ended with
+// Unavailable when the server shuts down.
+func (s *server) WatchPosts(req *blogv1.WatchPostsRequest, stream blogv1.PostService_WatchPostsServer) error {
+	ctx := stream.Context()
+	sub := s.watcher.Subscribe(watchBuffer)
+	defer sub.Close()
+	// Sending the headers now tells the client that every later change will
+	// be delivered.
+	if err := stream.SendHeader(nil); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				if errors.Is(sub.Err(), events.ErrSlowSubscriber) {
+					return status.Error(codes.ResourceExhausted, "client fell behind the event stream")
+				}
+				return status.Error(codes.Unavailable, "server is shutting down")
+			}
+			if !matches(event, req) {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// matches applies the request's filters to the post of event. Deletions carry
+// no post and always match.
+func matches(event events.Event, req *blogv1.WatchPostsRequest) bool {
+	post := event.Post
+	if post == nil {
+		return true
+	}
+	if req.GetAuthor() != "" && post.Author != req.GetAuthor() {
+		return false
+	}
+	return req.GetTag() == "" || slices.Contains(post.Tags, req.GetTag())
+}
+
+// toStatus maps a service error to a gRPC status. Errors other than not
+// found and cancellation are logged and reported as Internal without
+// their details.
+func toStatus(ctx context.Context, err error, action string) error {
+	switch {
+	case isNotFound(err):
+		return status.Error(codes.NotFound, "post not found")
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	}
+	logging.FromContext(ctx).Error(fmt.Sprintf("failed to %s", action), "error", err)
+	return status.Errorf(codes.Internal, "failed to %s", action)
+}
+
+// isNotFound reports whether err is a not-found error, recognised by its
+// NotFound method so that this package need not import the service package.
+func isNotFound(err error) bool {
+	var nf interface{ NotFound() bool }
+	return errors.As(err, &nf) && nf.NotFound()
+}
@@ -0,0 +1,308 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	blogv1 "blog-api/api/blog/v1"
+	"blog-api/internal/auth"
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+	"blog-api/internal/repository"
+	"blog-api/internal/sanitize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	args := m.Called(ctx, id)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+func (m *MockService) ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error) {
+	args := m.Called(ctx, filter, limit, cursor)
+	posts, _ := args.Get(0).([]*models.Post)
+	return posts, args.String(1), args.Error(2)
+}
+
+func (m *MockService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, post)
+	created, _ := args.Get(0).(*models.Post)
+	return created, nil, args.Error(1)
+}
+
+func (m *MockService) UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error) {
+	args := m.Called(ctx, id, post)
+	updated, _ := args.Get(0).(*models.Post)
+	return updated, nil, args.Error(1)
+}
+
+func (m *MockService) DeletePost(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "not found" }
+func (notFoundError) NotFound() bool { return true }
+
+const testAPIKey = "secret"
+
+func setup(t *testing.T, service *MockService, watcher Watcher) blogv1.PostServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(service, watcher, Config{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Auth:   auth.New(auth.Config{APIKeys: []string{testAPIKey}}),
+	})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return blogv1.NewPostServiceClient(conn)
+}
+
+func withKey(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, auth.APIKeyHeader, testAPIKey)
+}
+
+func validPost() *models.Post {
+	return &models.Post{ID: "1", Title: "Title", Content: "Content", Author: "alice", Version: 1}
+}
+
+func TestGetPost(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(validPost(), nil)
+		client := setup(t, service, nil)
+
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-123")
+		post, err := client.GetPost(ctx, &blogv1.GetPostRequest{Id: "1"}, grpc.Header(&header))
+
+		require.NoError(t, err)
+		assert.Equal(t, "Title", post.GetTitle())
+		assert.Equal(t, blogv1.ContentFormat_CONTENT_FORMAT_PLAIN, post.GetContentFormat())
+		assert.Equal(t, blogv1.PostStatus_POST_STATUS_PUBLISHED, post.GetStatus())
+		assert.Equal(t, []string{"req-123"}, header.Get("x-request-id"))
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "missing").Return(nil, notFoundError{})
+		client := setup(t, service, nil)
+
+		_, err := client.GetPost(context.Background(), &blogv1.GetPostRequest{Id: "missing"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Empty ID", func(t *testing.T) {
+		client := setup(t, new(MockService), nil)
+
+		_, err := client.GetPost(context.Background(), &blogv1.GetPostRequest{})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Internal Error Hides Details", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(nil, errors.New("dynamodb exploded"))
+		client := setup(t, service, nil)
+
+		_, err := client.GetPost(context.Background(), &blogv1.GetPostRequest{Id: "1"})
+
+		assert.Equal(t, codes.Internal, status.Code(err))
+		assert.NotContains(t, status.Convert(err).Message(), "dynamodb")
+	})
+}
+
+func TestListPosts(t *testing.T) {
+	t.Run("Default Page Size", func(t *testing.T) {
+		service := new(MockService)
+		filter := models.PostFilter{Author: "alice"}
+		service.On("ListPublishedPosts", mock.Anything, filter, defaultPageSize, "tok").
+			Return([]*models.Post{validPost()}, "next", nil)
+		client := setup(t, service, nil)
+
+		resp, err := client.ListPosts(context.Background(), &blogv1.ListPostsRequest{Author: "alice", PageToken: "tok"})
+
+		require.NoError(t, err)
+		assert.Len(t, resp.GetPosts(), 1)
+		assert.Equal(t, "next", resp.GetNextPageToken())
+	})
+
+	t.Run("Page Size Too Large", func(t *testing.T) {
+		client := setup(t, new(MockService), nil)
+
+		_, err := client.ListPosts(context.Background(), &blogv1.ListPostsRequest{PageSize: maxPageSize + 1})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Invalid Page Token", func(t *testing.T) {
+		service := new(MockService)
+		service.On("ListPublishedPosts", mock.Anything, mock.Anything, mock.Anything, "bad").
+			Return(nil, "", fmt.Errorf("failed to list posts: %w", repository.ErrInvalidCursor))
+		client := setup(t, service, nil)
+
+		_, err := client.ListPosts(context.Background(), &blogv1.ListPostsRequest{PageToken: "bad"})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestCreatePost(t *testing.T) {
+	t.Run("Requires Credentials", func(t *testing.T) {
+		client := setup(t, new(MockService), nil)
+
+		_, err := client.CreatePost(context.Background(), &blogv1.CreatePostRequest{Post: &blogv1.Post{Title: "Title"}})
+
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		service := new(MockService)
+		service.On("CreatePost", mock.Anything, mock.MatchedBy(func(p *models.Post) bool {
+			return p.ContentFormat == models.ContentFormatMarkdown && p.Status == models.StatusDraft
+		})).Return(validPost(), nil)
+		client := setup(t, service, nil)
+
+		post, err := client.CreatePost(withKey(context.Background()), &blogv1.CreatePostRequest{Post: &blogv1.Post{
+			Title:         "Title",
+			Content:       "Content",
+			Author:        "alice",
+			ContentFormat: blogv1.ContentFormat_CONTENT_FORMAT_MARKDOWN,
+			Status:        blogv1.PostStatus_POST_STATUS_DRAFT,
+		}})
+
+		require.NoError(t, err)
+		assert.Equal(t, "1", post.GetId())
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Validation Error", func(t *testing.T) {
+		client := setup(t, new(MockService), nil)
+
+		_, err := client.CreatePost(withKey(context.Background()), &blogv1.CreatePostRequest{Post: &blogv1.Post{Title: "Title"}})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestPatchPost(t *testing.T) {
+	t.Run("Applies Mask", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(validPost(), nil)
+		service.On("UpdatePost", mock.Anything, "1", mock.MatchedBy(func(p *models.Post) bool {
+			return p.Title == "New" && p.Author == "alice"
+		})).Return(validPost(), nil)
+		client := setup(t, service, nil)
+
+		_, err := client.PatchPost(withKey(context.Background()), &blogv1.PatchPostRequest{
+			Post:       &blogv1.Post{Id: "1", Title: "New", Author: "ignored"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"title"}},
+		})
+
+		require.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Path", func(t *testing.T) {
+		service := new(MockService)
+		service.On("GetPostByID", mock.Anything, "1").Return(validPost(), nil)
+		client := setup(t, service, nil)
+
+		_, err := client.PatchPost(withKey(context.Background()), &blogv1.PatchPostRequest{
+			Post:       &blogv1.Post{Id: "1"},
+			UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"version"}},
+		})
+
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		service.AssertNotCalled(t, "UpdatePost", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestDeletePost(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		service := new(MockService)
+		service.On("DeletePost", mock.Anything, "1").Return(nil)
+		client := setup(t, service, nil)
+
+		_, err := client.DeletePost(withKey(context.Background()), &blogv1.DeletePostRequest{Id: "1"})
+
+		require.NoError(t, err)
+		service.AssertExpectations(t)
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		service := new(MockService)
+		service.On("DeletePost", mock.Anything, "1").Return(notFoundError{})
+		client := setup(t, service, nil)
+
+		_, err := client.DeletePost(withKey(context.Background()), &blogv1.DeletePostRequest{Id: "1"})
+
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+}
+
+func TestWatchPosts(t *testing.T) {
+	t.Run("Streams Matching Events", func(t *testing.T) {
+		service := new(MockService)
+		other := &models.Post{ID: "2", Author: "bob", Version: 1}
+		mine := &models.Post{ID: "3", Author: "alice", Version: 2}
+		service.On("GetPostByID", mock.Anything, "2").Return(other, nil)
+		service.On("GetPostByID", mock.Anything, "3").Return(mine, nil)
+		service.On("GetPostByID", mock.Anything, "4").Return(nil, notFoundError{})
+		broker := events.NewBroker(service, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go broker.Run(ctx)
+		client := setup(t, service, broker)
+
+		stream, err := client.WatchPosts(ctx, &blogv1.WatchPostsRequest{Author: "alice"})
+		require.NoError(t, err)
+		// The headers arrive once the server has subscribed.
+		_, err = stream.Header()
+		require.NoError(t, err)
+		for _, id := range []string{"2", "3", "4"} {
+			broker.PostChanged(ctx, id, events.TypeUpdated)
+		}
+
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, blogv1.PostEvent_TYPE_UPDATED, event.GetType())
+		assert.Equal(t, "3", event.GetPost().GetId())
+		event, err = stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, blogv1.PostEvent_TYPE_DELETED, event.GetType())
+		assert.Equal(t, "4", event.GetId())
+
+		cancel()
+		_, err = stream.Recv()
+		assert.Error(t, err)
+	})
+}
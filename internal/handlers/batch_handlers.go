@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+)
+
+// maxBatchIDs is the maximum number of IDs accepted by the batch endpoints.
+const maxBatchIDs = 100
+
+// Batch delete modes.
+const (
+	BatchModeTransactional = "transactional"
+	BatchModeBestEffort    = "bestEffort"
+)
+
+type BatchGetRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type BatchGetResponse struct {
+	Posts   []*models.Post `json:"posts"`
+	Missing []string       `json:"missing"`
+}
+
+type BatchDeleteRequest struct {
+	IDs  []string `json:"ids"`
+	Mode string   `json:"mode"`
+}
+
+type BatchDeleteResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BatchDeleteResponse struct {
+	Mode    string              `json:"mode"`
+	Deleted int                 `json:"deleted"`
+	Failed  int                 `json:"failed"`
+	Results []BatchDeleteResult `json:"results"`
+}
+
+const (
+	batchStatusDeleted  = "deleted"
+	batchStatusNotFound = "notFound"
+	batchStatusFailed   = "failed"
+)
+
+func validateBatchIDs(ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("ids must not be empty")
+	}
+	if len(ids) > maxBatchIDs {
+		return fmt.Errorf("at most %d ids are allowed", maxBatchIDs)
+	}
+	for _, id := range ids {
+		if id == "" {
+			return errors.New("ids must not contain empty values")
+		}
+	}
+	return nil
+}
+
+// BatchGetPosts returns up to 100 posts in the order their IDs were requested,
+// along with the IDs that do not exist.
+func (h *PostHandler) BatchGetPosts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PostHandler.BatchGetPosts")
+	defer span.End()
+	var req BatchGetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		return
+	}
+	if err := validateBatchIDs(req.IDs); err != nil {
+		handleError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	posts, missing, err := h.service.BatchGetPosts(ctx, req.IDs)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to batch fetch posts", "error", err)
+		handleError(w, r, errors.New("failed to fetch posts"), http.StatusInternalServerError)
+		return
+	}
+	if missing == nil {
+		missing = []string{}
+	}
+
+	writeJSONResponse(w, BatchGetResponse{Posts: posts, Missing: missing}, http.StatusOK)
+}
+
+// BatchDeletePosts deletes up to 100 posts, either all-or-nothing
+// ("transactional", the default) or independently ("bestEffort"). A
+// transaction aborted because posts do not exist is answered with 409
+// Conflict, one over too many IDs with 400 and any other failure with 500.
+func (h *PostHandler) BatchDeletePosts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PostHandler.BatchDeletePosts")
+	defer span.End()
+	var req BatchDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		return
+	}
+	if err := validateBatchIDs(req.IDs); err != nil {
+		handleError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = BatchModeTransactional
+	}
+	if req.Mode != BatchModeTransactional && req.Mode != BatchModeBestEffort {
+		handleError(w, r, fmt.Errorf("mode must be %q or %q", BatchModeTransactional, BatchModeBestEffort), http.StatusBadRequest)
+		return
+	}
+
+	errs := h.service.BatchDeletePosts(ctx, req.IDs, req.Mode == BatchModeTransactional)
+	if req.Mode == BatchModeTransactional {
+		if err := transactionError(errs); err != nil {
+			if errors.Is(err, custom_errors.ErrTooManyIDs) {
+				handleError(w, r, err, http.StatusBadRequest)
+				return
+			}
+			logging.FromContext(ctx).Error("failed to batch delete posts", "error", err)
+			handleError(w, r, errors.New("failed to delete posts"), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resp := BatchDeleteResponse{Mode: req.Mode, Results: make([]BatchDeleteResult, 0, len(req.IDs))}
+	for i, err := range errs {
+		result := BatchDeleteResult{ID: req.IDs[i], Status: batchStatusDeleted}
+		switch {
+		case err == nil:
+			resp.Deleted++
+		case isNotFound(err):
+			result.Status = batchStatusNotFound
+			result.Error = err.Error()
+			resp.Failed++
+		default:
+			result.Status = batchStatusFailed
+			result.Error = err.Error()
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, result)
+	}
+
+	status := http.StatusOK
+	if req.Mode == BatchModeTransactional && resp.Failed > 0 {
+		status = http.StatusConflict
+	}
+	writeJSONResponse(w, resp, status)
+}
+
+// transactionError returns the error that failed a transactional batch
+// delete, or nil if it succeeded or was aborted because posts do not exist.
+func transactionError(errs []error) error {
+	var failed error
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, custom_errors.ErrBatchAborted) || isNotFound(err):
+			return nil
+		default:
+			failed = err
+		}
+	}
+	return failed
+}
+
+// isNotFound reports whether err is a not-found error, recognised by its
+// NotFound method so that handlers need not import the service package.
+func isNotFound(err error) bool {
+	var nf interface{ NotFound() bool }
+	return errors.As(err, &nf) && nf.NotFound()
+}
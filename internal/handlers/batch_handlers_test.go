@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"blog-api/internal/custom_errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// notFoundError is recognised as not found, like the service's error.
+type notFoundError struct{}
+
+func (notFoundError) Error() string  { return "post not found" }
+func (notFoundError) NotFound() bool { return true }
+
+func TestBatchDeletePosts(t *testing.T) {
+	batchDelete := func(t *testing.T, service *MockPostService, body string) (*httptest.ResponseRecorder, BatchDeleteResponse) {
+		rec := httptest.NewRecorder()
+		NewPostHandler(service).BatchDeletePosts(rec, httptest.NewRequest("POST", "/posts:batchDelete", strings.NewReader(body)))
+		var resp BatchDeleteResponse
+		if rec.Code != http.StatusBadRequest {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp
+	}
+
+	t.Run("Transactional Success", func(t *testing.T) {
+		mockService := new(MockPostService)
+		mockService.On("BatchDeletePosts", []string{"a", "b"}, true).Return([]error{nil, nil})
+
+		rec, resp := batchDelete(t, mockService, `{"ids":["a","b"]}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, BatchModeTransactional, resp.Mode)
+		assert.Equal(t, 2, resp.Deleted)
+	})
+
+	t.Run("Transactional Conflict", func(t *testing.T) {
+		mockService := new(MockPostService)
+		mockService.On("BatchDeletePosts", []string{"a", "b"}, true).Return([]error{custom_errors.ErrBatchAborted, notFoundError{}})
+
+		rec, resp := batchDelete(t, mockService, `{"ids":["a","b"]}`)
+
+		assert.Equal(t, http.StatusConflict, rec.Code)
+		assert.Equal(t, batchStatusFailed, resp.Results[0].Status)
+		assert.Equal(t, batchStatusNotFound, resp.Results[1].Status)
+	})
+
+	t.Run("Transactional Failure", func(t *testing.T) {
+		mockService := new(MockPostService)
+		failed := errors.New("failed to delete posts: throttled")
+		mockService.On("BatchDeletePosts", []string{"a", "b"}, true).Return([]error{failed, failed})
+
+		rec, _ := batchDelete(t, mockService, `{"ids":["a","b"]}`)
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "throttled")
+	})
+
+	t.Run("Transactional Too Many IDs", func(t *testing.T) {
+		mockService := new(MockPostService)
+		tooMany := fmt.Errorf("failed to delete posts: %w: 2, at most 1 allowed", custom_errors.ErrTooManyIDs)
+		mockService.On("BatchDeletePosts", []string{"a", "b"}, true).Return([]error{tooMany, tooMany})
+
+		rec, _ := batchDelete(t, mockService, `{"ids":["a","b"]}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "at most 1 allowed")
+	})
+
+	t.Run("Best Effort Partial", func(t *testing.T) {
+		mockService := new(MockPostService)
+		mockService.On("BatchDeletePosts", []string{"a", "b"}, false).Return([]error{nil, errors.New("throttled")})
+
+		rec, resp := batchDelete(t, mockService, `{"ids":["a","b"],"mode":"bestEffort"}`)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 1, resp.Deleted)
+		assert.Equal(t, 1, resp.Failed)
+	})
+
+	t.Run("Rejects Invalid Requests", func(t *testing.T) {
+		for _, body := range []string{`{"ids":[]}`, `{"ids":["a"],"mode":"some"}`, `{"ids":[""]}`, `nope`} {
+			rec, _ := batchDelete(t, new(MockPostService), body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		}
+	})
+}
+
+func TestBatchGetPosts(t *testing.T) {
+	mockService := new(MockPostService)
+	mockService.On("BatchGetPosts", []string{"a", "b"}).Return(nil, []string{"b"}, nil)
+
+	rec := httptest.NewRecorder()
+	NewPostHandler(mockService).BatchGetPosts(rec, httptest.NewRequest("POST", "/posts:batchGet", strings.NewReader(`{"ids":["a","b"]}`)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"posts":null,"missing":["b"]}`, rec.Body.String())
+	mockService.AssertExpectations(t)
+}
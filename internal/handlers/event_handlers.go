@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/events"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+)
+
+// EventSource delivers post events to PostEventsHandler, such as
+// events.Broker with replay enabled.
+type EventSource interface {
+	Subscribe(buffer int) *events.Subscription
+	SubscribeAfter(buffer int, seq uint64) (*events.Subscription, bool)
+}
+
+// PostEventsConfig holds the stream settings. Zero values select the
+// defaults.
+type PostEventsConfig struct {
+	// Heartbeat is how often a comment is sent while no event is, so that
+	// proxies keep the connection open; 15s by default.
+	Heartbeat time.Duration
+	// Buffer is how many events a client may fall behind before its stream
+	// is ended; 64 by default.
+	Buffer int
+}
+
+const (
+	defaultHeartbeat   = 15 * time.Second
+	defaultEventBuffer = 64
+	// eventWriteTimeout bounds every write to a client, so that a stalled
+	// client cannot hold its stream open.
+	eventWriteTimeout = 10 * time.Second
+	// reconnectDelay is the retry hint sent to clients, in milliseconds.
+	reconnectDelay = 3000
+)
+
+// streamEventTypes names the server-sent events like the webhook events.
+var streamEventTypes = map[string]string{
+	events.TypeCreated: models.EventPostCreated,
+	events.TypeUpdated: models.EventPostUpdated,
+	events.TypeDeleted: models.EventPostDeleted,
+}
+
+// StreamEvent is the data of a server-sent post event.
+type StreamEvent struct {
+	ID string `json:"id"`
+	// Post is the post after the change, absent when it was deleted.
+	Post *models.Post `json:"post,omitempty"`
+	Time time.Time    `json:"time"`
+}
+
+// PostEventsHandler streams post events as server-sent events.
+type PostEventsHandler struct {
+	source EventSource
+	cfg    PostEventsConfig
+	// epoch tells the event IDs of this process apart from those of an
+	// earlier one, whose events cannot be replayed.
+	epoch string
+}
+
+func NewPostEventsHandler(source EventSource, cfg PostEventsConfig) *PostEventsHandler {
+	if cfg.Heartbeat <= 0 {
+		cfg.Heartbeat = defaultHeartbeat
+	}
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = defaultEventBuffer
+	}
+	return &PostEventsHandler{source: source, cfg: cfg, epoch: strconv.FormatInt(time.Now().UnixNano(), 36)}
+}
+
+// ServeHTTP streams events until the client goes away, optionally only
+// those of posts by ?author= or with ?tag=; deletions always match. A client
+// that sends Last-Event-ID first receives the events it missed, or a reset
+// event when they are no longer kept and it has to reload what it shows. A
+// client that falls behind has its stream ended and catches up the same way
+// when it reconnects.
+func (h *PostEventsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PostEventsHandler.StreamEvents")
+	defer span.End()
+	if _, ok := w.(http.Flusher); !ok {
+		handleError(w, r, errors.New("streaming is not supported"), http.StatusNotImplemented)
+		return
+	}
+	author, tag := r.URL.Query().Get("author"), r.URL.Query().Get("tag")
+
+	sub, reset := h.subscribe(r.Header.Get("Last-Event-ID"))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventWriter{w: w, rc: http.NewResponseController(w)}
+	stream.printf("retry: %d\n\n", reconnectDelay)
+	if reset {
+		stream.printf("event: reset\ndata: {}\n\n")
+	}
+	stream.flush()
+
+	heartbeat := time.NewTicker(h.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for stream.err == nil {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			stream.printf(": heartbeat\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				if errors.Is(sub.Err(), events.ErrSlowSubscriber) {
+					logging.FromContext(ctx).Info("ended event stream of a client that fell behind")
+				}
+				return
+			}
+			if !matchesFilter(event.Post, author, tag) {
+				continue
+			}
+			data, err := json.Marshal(StreamEvent{ID: event.ID, Post: event.Post, Time: event.Time.UTC()})
+			if err != nil {
+				logging.FromContext(ctx).Error("failed to encode post event", "error", err)
+				continue
+			}
+			stream.printf("id: %s-%d\nevent: %s\ndata: %s\n\n", h.epoch, event.Seq, streamEventTypes[event.Type], data)
+		}
+		stream.flush()
+	}
+	logging.FromContext(ctx).Debug("event stream write failed", "error", stream.err)
+}
+
+// subscribe resumes after lastEventID when one is given, reporting whether
+// the client has to reset because that is not possible.
+func (h *PostEventsHandler) subscribe(lastEventID string) (*events.Subscription, bool) {
+	if lastEventID == "" {
+		return h.source.Subscribe(h.cfg.Buffer), false
+	}
+	epoch, seq, found := strings.Cut(lastEventID, "-")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if !found || err != nil || epoch != h.epoch {
+		return h.source.Subscribe(h.cfg.Buffer), true
+	}
+	sub, ok := h.source.SubscribeAfter(h.cfg.Buffer, n)
+	return sub, !ok
+}
+
+// matchesFilter applies the author and tag filters to post; nil, a deleted
+// post, always matches.
+func matchesFilter(post *models.Post, author, tag string) bool {
+	if post == nil {
+		return true
+	}
+	if author != "" && post.Author != author {
+		return false
+	}
+	return tag == "" || slices.Contains(post.Tags, tag)
+}
+
+// eventWriter writes to a stream with a deadline per write, keeping the first
+// error.
+type eventWriter struct {
+	w   io.Writer
+	rc  *http.ResponseController
+	err error
+}
+
+func (s *eventWriter) printf(format string, args ...any) {
+	if s.err != nil {
+		return
+	}
+	// Servers without write deadlines, such as tests, report an error that
+	// is of no concern here.
+	_ = s.rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+	_, s.err = fmt.Fprintf(s.w, format, args...)
+}
+
+func (s *eventWriter) flush() {
+	if s.err == nil {
+		s.err = s.rc.Flush()
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blog-api/internal/feed"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"github.com/gorilla/mux"
+)
+
+type FeedService interface {
+	ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error)
+	RenderPost(ctx context.Context, post *models.Post) (*render.Document, error)
+}
+
+type FeedHandlerInterface interface {
+	GetFeed(w http.ResponseWriter, r *http.Request)
+}
+
+var _ FeedHandlerInterface = (*FeedHandler)(nil)
+
+type FeedHandler struct {
+	service FeedService
+	config  feed.Config
+}
+
+func NewFeedHandler(service FeedService, config feed.Config) *FeedHandler {
+	return &FeedHandler{service: service, config: config}
+}
+
+// GetFeed serves the site, author or tag feed in the format named by the
+// {format} route variable. It honours If-None-Match and If-Modified-Since.
+func (h *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	format := vars["format"]
+	filter := models.PostFilter{Author: vars["author"], Tag: vars["tag"]}
+
+	posts, _, err := h.service.ListPublishedPosts(ctx, filter, h.config.Limit, "")
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to fetch feed posts", "error", err)
+		handleError(w, r, errors.New("failed to fetch posts"), http.StatusInternalServerError)
+		return
+	}
+
+	entries := make([]feed.Entry, 0, len(posts))
+	for _, post := range posts {
+		doc, err := h.service.RenderPost(ctx, post)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to render post for feed", "id", post.ID, "error", err)
+			handleError(w, r, errors.New("failed to render feed"), http.StatusInternalServerError)
+			return
+		}
+		entries = append(entries, feed.Entry{Post: post, HTML: doc.HTML})
+	}
+
+	body, err := feed.Build(h.config, format, feedSubtitle(filter), entries)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to build feed", "format", format, "error", err)
+		handleError(w, r, errors.New("failed to build feed"), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", feed.ContentType(format))
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("ETag", contentETag(body))
+	http.ServeContent(w, r, "", feed.LastModified(entries), bytes.NewReader(body))
+}
+
+func feedSubtitle(filter models.PostFilter) string {
+	switch {
+	case filter.Author != "":
+		return fmt.Sprintf("Posts by %s", filter.Author)
+	case filter.Tag != "":
+		return fmt.Sprintf("Posts tagged %s", filter.Tag)
+	}
+	return ""
+}
+
+// contentETag returns a strong ETag derived from the response body.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"blog-api/internal/health"
+)
+
+type ReadinessService interface {
+	Ready(ctx context.Context) health.Report
+}
+
+type HealthHandlerInterface interface {
+	Liveness(w http.ResponseWriter, r *http.Request)
+	Readiness(w http.ResponseWriter, r *http.Request)
+}
+
+var _ HealthHandlerInterface = (*HealthHandler)(nil)
+
+// LivenessResponse is the body of a liveness probe.
+type LivenessResponse struct {
+	Status health.Status `json:"status"`
+}
+
+type HealthHandler struct {
+	service ReadinessService
+}
+
+func NewHealthHandler(service ReadinessService) *HealthHandler {
+	return &HealthHandler{service: service}
+}
+
+// Liveness reports that the process is up and serving HTTP. It never touches
+// dependencies, so a failing database does not get the process restarted.
+func (h *HealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, LivenessResponse{Status: health.StatusUp}, http.StatusOK)
+}
+
+// Readiness reports each dependency's status, answering 503 if any is down.
+func (h *HealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := h.service.Ready(r.Context())
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, report, status)
+}
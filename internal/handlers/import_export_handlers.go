@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+)
+
+const (
+	// importBatchSize is the number of parsed lines handed to the service at once.
+	importBatchSize = 100
+	// maxImportLineSize bounds the size of a single NDJSON line.
+	maxImportLineSize = 4 << 20
+	ndjsonContentType = "application/x-ndjson"
+)
+
+// ImportLineResult is the outcome of importing one NDJSON line.
+type ImportLineResult struct {
+	Line   int    `json:"line"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport is the response body of POST /posts:import.
+type ImportReport struct {
+	Imported int `json:"imported"`
+	Failed   int `json:"failed"`
+	// Incomplete is set when the body could not be read to the end; the
+	// last result is then the line that could not be read, and nothing
+	// after it was imported.
+	Incomplete bool               `json:"incomplete,omitempty"`
+	Results    []ImportLineResult `json:"results"`
+}
+
+const (
+	importStatusImported = "imported"
+	importStatusFailed   = "failed"
+)
+
+// ImportPosts reads newline-delimited JSON posts from the request body and
+// stores them in batches, reporting the outcome of every non-blank line. Of
+// the lines sharing an ID only the first is imported. When the body cannot
+// be read to the end, the lines read so far are still imported and the
+// report is returned with an error status.
+func (h *PostHandler) ImportPosts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PostHandler.ImportPosts")
+	defer span.End()
+	report := ImportReport{Results: []ImportLineResult{}}
+
+	var (
+		batch      []*models.Post
+		batchLines []int
+		// firstLines maps the IDs seen so far to the line they were first on.
+		firstLines = make(map[string]int)
+	)
+	flush := func() {
+		for i, err := range h.service.ImportPosts(ctx, batch) {
+			report.add(batchLines[i], batch[i].ID, err)
+		}
+		batch, batchLines = batch[:0], batchLines[:0]
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var post models.Post
+		if err := json.Unmarshal(raw, &post); err != nil {
+			report.add(line, "", fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+		if post.ID != "" {
+			if first, ok := firstLines[post.ID]; ok {
+				report.add(line, post.ID, fmt.Errorf("duplicate post ID=%s in import, first on line %d", post.ID, first))
+				continue
+			}
+			firstLines[post.ID] = line
+		}
+
+		batch = append(batch, &post)
+		batchLines = append(batchLines, line)
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	status := http.StatusOK
+	if err := scanner.Err(); err != nil {
+		report.add(line+1, "", fmt.Errorf("failed to read NDJSON body: %w", err))
+		report.Incomplete = true
+		status = http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+	}
+	writeJSONResponse(w, report, status)
+}
+
+func (report *ImportReport) add(line int, id string, err error) {
+	result := ImportLineResult{Line: line, ID: id, Status: importStatusImported}
+	if err != nil {
+		result.Status = importStatusFailed
+		result.Error = err.Error()
+		report.Failed++
+	} else {
+		report.Imported++
+	}
+	report.Results = append(report.Results, result)
+}
+
+// ExportPosts streams every stored post as newline-delimited JSON.
+func (h *PostHandler) ExportPosts(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "PostHandler.ExportPosts")
+	defer span.End()
+	w.Header().Set("Content-Type", ndjsonContentType)
+
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	started := false
+
+	err := h.service.ExportPosts(ctx, func(post *models.Post) error {
+		started = true
+		if err := encoder.Encode(post); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	logging.FromContext(ctx).Error("failed to export posts", "error", err)
+	if !started {
+		// Nothing has been written yet, so a proper error response is still possible.
+		handleError(w, r, errors.New("failed to export posts"), http.StatusInternalServerError)
+	}
+}
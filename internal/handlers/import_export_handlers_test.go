@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPosts(t *testing.T) {
+	mockService := new(MockPostService)
+	mockService.On("ImportPosts", mock.Anything).Return([]error{nil, errors.New("post validation failed")})
+	body := strings.Join([]string{
+		`{"id":"a","title":"First","content":"Content","author":"Author"}`,
+		``,
+		`{not json`,
+		`{"id":"b","title":"","content":"Content","author":"Author"}`,
+	}, "\n")
+
+	rec := httptest.NewRecorder()
+	NewPostHandler(mockService).ImportPosts(rec, httptest.NewRequest("POST", "/posts:import", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report ImportReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, []ImportLineResult{
+		{Line: 3, Status: importStatusFailed, Error: report.Results[0].Error},
+		{Line: 1, ID: "a", Status: importStatusImported},
+		{Line: 4, ID: "b", Status: importStatusFailed, Error: "post validation failed"},
+	}, report.Results)
+	assert.Contains(t, report.Results[0].Error, "invalid JSON")
+}
+
+func TestImportPostsStream(t *testing.T) {
+	post := func(id string) string {
+		return `{"id":"` + id + `","title":"Title","content":"Content","author":"Author"}`
+	}
+
+	t.Run("Rejects Duplicates Across Batches", func(t *testing.T) {
+		lines := []string{post("dup")}
+		for i := 0; i < importBatchSize; i++ {
+			lines = append(lines, post(fmt.Sprintf("p%d", i)))
+		}
+		lines = append(lines, post("dup"))
+		mockService := new(MockPostService)
+		mockService.On("ImportPosts", mock.Anything).Return(make([]error, importBatchSize)).Once()
+		mockService.On("ImportPosts", mock.Anything).Return(make([]error, 1)).Once()
+
+		rec := httptest.NewRecorder()
+		NewPostHandler(mockService).ImportPosts(rec, httptest.NewRequest("POST", "/posts:import", strings.NewReader(strings.Join(lines, "\n"))))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var report ImportReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, importBatchSize+1, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, ImportLineResult{
+			Line: importBatchSize + 2, ID: "dup", Status: importStatusFailed,
+			Error: "duplicate post ID=dup in import, first on line 1",
+		}, report.Results[importBatchSize])
+	})
+
+	t.Run("Reports Partial Import On Read Error", func(t *testing.T) {
+		mockService := new(MockPostService)
+		mockService.On("ImportPosts", []*models.Post{{ID: "a", Title: "Title", Content: "Content", Author: "Author"}}).Return([]error{nil})
+		body := io.MultiReader(strings.NewReader(post("a")+"\n"), iotest.ErrReader(errors.New("connection reset")))
+
+		rec := httptest.NewRecorder()
+		NewPostHandler(mockService).ImportPosts(rec, httptest.NewRequest("POST", "/posts:import", body))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var report ImportReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.True(t, report.Incomplete)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, 1, report.Failed)
+		assert.Equal(t, 2, report.Results[1].Line)
+		assert.Equal(t, "failed to read NDJSON body: connection reset", report.Results[1].Error)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Reports Bodies Over The Limit", func(t *testing.T) {
+		mockService := new(MockPostService)
+		mockService.On("ImportPosts", mock.Anything).Return([]error{nil})
+		body := strings.NewReader(post("a") + "\n" + post("b") + "\n")
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/posts:import", body)
+		req.Body = http.MaxBytesReader(rec, req.Body, int64(len(post("a"))+10))
+		NewPostHandler(mockService).ImportPosts(rec, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		var report ImportReport
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.True(t, report.Incomplete)
+		assert.Equal(t, 1, report.Imported)
+	})
+}
+
+func TestExportPosts(t *testing.T) {
+	mockService := new(MockPostService)
+	mockService.On("ExportPosts").Return([]*models.Post{{ID: "a"}, {ID: "b"}}, nil)
+
+	rec := httptest.NewRecorder()
+	NewPostHandler(mockService).ExportPosts(rec, httptest.NewRequest("GET", "/posts:export", nil))
+
+	assert.Equal(t, ndjsonContentType, rec.Header().Get("Content-Type"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"id":"b"`)
+}
@@ -4,20 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 
+	"blog-api/internal/logging"
 	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/requestid"
+	"blog-api/internal/sanitize"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
 )
 
 type PostService interface {
-	GetAllPosts(ctx context.Context, page, limit int) ([]*models.Post, error)
+	GetAllPosts(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error)
 	GetPostByID(ctx context.Context, id string) (*models.Post, error)
-	CreatePost(ctx context.Context, post *models.Post) (*models.Post, error)
-	UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, error)
+	GetPostFields(ctx context.Context, id string, fields []string) (*models.Post, error)
+	CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error)
+	UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error)
 	DeletePost(ctx context.Context, id string) error
+	RenderPost(ctx context.Context, post *models.Post) (*render.Document, error)
+	ImportPosts(ctx context.Context, posts []*models.Post) []error
+	ExportPosts(ctx context.Context, fn func(*models.Post) error) error
+	BatchGetPosts(ctx context.Context, ids []string) ([]*models.Post, []string, error)
+	BatchDeletePosts(ctx context.Context, ids []string, transactional bool) []error
 }
 
 type PostHandlerInterface interface {
@@ -27,10 +39,16 @@ type PostHandlerInterface interface {
 	UpdatePost(w http.ResponseWriter, r *http.Request)
 	PatchPost(w http.ResponseWriter, r *http.Request)
 	DeletePost(w http.ResponseWriter, r *http.Request)
+	ImportPosts(w http.ResponseWriter, r *http.Request)
+	ExportPosts(w http.ResponseWriter, r *http.Request)
+	BatchGetPosts(w http.ResponseWriter, r *http.Request)
+	BatchDeletePosts(w http.ResponseWriter, r *http.Request)
 }
 
 var _ PostHandlerInterface = (*PostHandler)(nil)
 
+var tracer = otel.Tracer("blog-api/internal/handlers")
+
 type PostHandler struct {
 	service PostService
 }
@@ -39,6 +57,27 @@ func NewPostHandler(service PostService) *PostHandler {
 	return &PostHandler{service: service}
 }
 
+// PostResponse is a post plus, when requested, its rendered form and metadata
+// about how the server changed it.
+type PostResponse struct {
+	*models.Post
+	*render.Document
+	Meta *ResponseMeta `json:"meta,omitempty"`
+}
+
+type ResponseMeta struct {
+	// Sanitization reports markup stripped from stored or rendered content.
+	Sanitization *sanitize.Report `json:"sanitization,omitempty"`
+}
+
+func newPostResponse(post *models.Post, doc *render.Document, report *sanitize.Report) PostResponse {
+	resp := PostResponse{Post: post, Document: doc}
+	if report.Changed() {
+		resp.Meta = &ResponseMeta{Sanitization: report}
+	}
+	return resp
+}
+
 func parseID(r *http.Request) (string, error) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -53,25 +92,29 @@ func writeJSONResponse(w http.ResponseWriter, data interface{}, status int) {
 	w.WriteHeader(status)
 	if data != nil {
 		if err := json.NewEncoder(w).Encode(data); err != nil {
-			log.Printf("Error encoding JSON response: %v", err)
+			slog.Error("failed to encode JSON response", "error", err)
 		}
 	}
 }
 
-func handleError(w http.ResponseWriter, err error, status int) {
+func handleError(w http.ResponseWriter, r *http.Request, err error, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	response := map[string]string{
 		"error":       http.StatusText(status),
 		"description": err.Error(),
 	}
+	if id := requestid.ID(r.Context()); id != "" {
+		response["requestId"] = id
+	}
 	if encodeErr := json.NewEncoder(w).Encode(response); encodeErr != nil {
-		log.Printf("Failed to encode error response: %v", encodeErr)
+		logging.FromContext(r.Context()).Error("failed to encode error response", "error", encodeErr)
 	}
 }
 
 func (h *PostHandler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.GetAllPosts")
+	defer span.End()
 	query := r.URL.Query()
 
 	pageStr := query.Get("page")
@@ -91,99 +134,178 @@ func (h *PostHandler) GetAllPosts(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
-	posts, err := h.service.GetAllPosts(ctx, page, limit)
+	fields, err := models.ParseFields(query.Get("fields"))
+	if err != nil {
+		handleError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.service.GetAllPosts(ctx, page, limit, fields)
 	if err != nil {
-		log.Printf("Error fetching posts: %v", err)
-		handleError(w, errors.New("failed to fetch posts"), http.StatusInternalServerError)
+		logging.FromContext(ctx).Error("failed to fetch posts", "error", err)
+		handleError(w, r, errors.New("failed to fetch posts"), http.StatusInternalServerError)
 		return
 	}
 
 	if posts == nil {
 		posts = []*models.Post{}
 	}
+	if fields == nil {
+		writeJSONResponse(w, posts, http.StatusOK)
+		return
+	}
+
+	sparse := make([]map[string]json.RawMessage, len(posts))
+	for i, post := range posts {
+		if sparse[i], err = selectFields(post, fields); err != nil {
+			logging.FromContext(ctx).Error("failed to select post fields", "error", err)
+			handleError(w, r, errors.New("failed to fetch posts"), http.StatusInternalServerError)
+			return
+		}
+	}
+	writeJSONResponse(w, sparse, http.StatusOK)
+}
 
-	writeJSONResponse(w, posts, http.StatusOK)
+// selectFields returns the JSON object of post with only the given fields.
+// Fields that are empty and omitted from a full post are omitted here too.
+func selectFields(post *models.Post, fields []string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode post: %w", err)
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to decode post: %w", err)
+	}
+	selected := make(map[string]json.RawMessage, len(fields))
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return selected, nil
 }
 
 func (h *PostHandler) GetPostByID(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.GetPostByID")
+	defer span.End()
 	id, err := parseID(r)
 	if err != nil {
-		handleError(w, errors.New("invalid ID"), http.StatusBadRequest)
+		handleError(w, r, errors.New("invalid ID"), http.StatusBadRequest)
 		return
 	}
 
-	post, err := h.service.GetPostByID(ctx, id)
+	renderMode := r.URL.Query().Get("render")
+	if renderMode != "" && renderMode != "html" {
+		handleError(w, r, errors.New("render must be 'html'"), http.StatusBadRequest)
+		return
+	}
+	fields, err := models.ParseFields(r.URL.Query().Get("fields"))
+	if err != nil {
+		handleError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	if fields != nil && renderMode != "" {
+		handleError(w, r, errors.New("fields cannot be combined with render"), http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.service.GetPostFields(ctx, id, fields)
+	if err != nil {
+		handleError(w, r, errors.New("post not found"), http.StatusNotFound)
+		return
+	}
+
+	if fields != nil {
+		sparse, err := selectFields(post, fields)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to select post fields", "id", id, "error", err)
+			handleError(w, r, errors.New("failed to fetch post"), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, sparse, http.StatusOK)
+		return
+	}
+	if renderMode == "" {
+		writeJSONResponse(w, post, http.StatusOK)
+		return
+	}
+
+	doc, err := h.service.RenderPost(ctx, post)
 	if err != nil {
-		handleError(w, errors.New("post not found"), http.StatusNotFound)
+		logging.FromContext(ctx).Error("failed to render post", "id", id, "error", err)
+		handleError(w, r, errors.New("failed to render post"), http.StatusInternalServerError)
 		return
 	}
-	writeJSONResponse(w, post, http.StatusOK)
+	writeJSONResponse(w, newPostResponse(post, doc, doc.Sanitization), http.StatusOK)
 }
 
 func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.CreatePost")
+	defer span.End()
 	var post models.Post
 	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-		handleError(w, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
 		return
 	}
 
-	createdPost, err := h.service.CreatePost(ctx, &post)
+	createdPost, report, err := h.service.CreatePost(ctx, &post)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	writeJSONResponse(w, createdPost, http.StatusCreated)
+	writeJSONResponse(w, newPostResponse(createdPost, nil, report), http.StatusCreated)
 }
 
 func (h *PostHandler) UpdatePost(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.UpdatePost")
+	defer span.End()
 	id, err := parseID(r)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	var post models.Post
 	if err := json.NewDecoder(r.Body).Decode(&post); err != nil {
-		handleError(w, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
 		return
 	}
 
-	updatedPost, err := h.service.UpdatePost(ctx, id, &post)
+	updatedPost, report, err := h.service.UpdatePost(ctx, id, &post)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	writeJSONResponse(w, updatedPost, http.StatusOK)
+	writeJSONResponse(w, newPostResponse(updatedPost, nil, report), http.StatusOK)
 }
 
 func (h *PostHandler) PatchPost(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.PatchPost")
+	defer span.End()
 	id, err := parseID(r)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		handleError(w, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
 		return
 	}
 
 	post, err := h.service.GetPostByID(ctx, id)
 	if err != nil {
-		handleError(w, errors.New("post not found"), http.StatusNotFound)
+		handleError(w, r, errors.New("post not found"), http.StatusNotFound)
 		return
 	}
 
 	if title, ok := updates["title"].(string); ok {
 		if title == "" {
-			handleError(w, errors.New("title cannot be empty"), http.StatusBadRequest)
+			handleError(w, r, errors.New("title cannot be empty"), http.StatusBadRequest)
 			return
 		}
 		post.Title = title
@@ -194,26 +316,45 @@ func (h *PostHandler) PatchPost(w http.ResponseWriter, r *http.Request) {
 	if author, ok := updates["author"].(string); ok {
 		post.Author = author
 	}
+	if contentFormat, ok := updates["contentFormat"].(string); ok {
+		post.ContentFormat = contentFormat
+	}
+	if status, ok := updates["status"].(string); ok {
+		post.Status = status
+	}
+	if rawTags, ok := updates["tags"].([]interface{}); ok {
+		tags := make([]string, 0, len(rawTags))
+		for _, rawTag := range rawTags {
+			tag, ok := rawTag.(string)
+			if !ok {
+				handleError(w, r, errors.New("tags must be strings"), http.StatusBadRequest)
+				return
+			}
+			tags = append(tags, tag)
+		}
+		post.Tags = tags
+	}
 
-	updatedPost, err := h.service.UpdatePost(ctx, id, post)
+	updatedPost, report, err := h.service.UpdatePost(ctx, id, post)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	writeJSONResponse(w, updatedPost, http.StatusOK)
+	writeJSONResponse(w, newPostResponse(updatedPost, nil, report), http.StatusOK)
 }
 
 func (h *PostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx, span := tracer.Start(r.Context(), "PostHandler.DeletePost")
+	defer span.End()
 	id, err := parseID(r)
 	if err != nil {
-		handleError(w, err, http.StatusBadRequest)
+		handleError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
 	if err := h.service.DeletePost(ctx, id); err != nil {
-		handleError(w, errors.New("post not found"), http.StatusNotFound)
+		handleError(w, r, errors.New("post not found"), http.StatusNotFound)
 		return
 	}
 
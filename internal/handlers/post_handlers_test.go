@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/gorilla/mux"
@@ -10,6 +11,8 @@ import (
 	"testing"
 
 	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/sanitize"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -18,8 +21,8 @@ type MockPostService struct {
 	mock.Mock
 }
 
-func (m *MockPostService) GetAllPosts(page, limit int) ([]*models.Post, error) {
-	args := m.Called(page, limit)
+func (m *MockPostService) GetAllPosts(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error) {
+	args := m.Called(ctx, page, limit, fields)
 	var posts []*models.Post
 	if args.Get(0) != nil {
 		posts = args.Get(0).([]*models.Post)
@@ -27,8 +30,12 @@ func (m *MockPostService) GetAllPosts(page, limit int) ([]*models.Post, error) {
 	return posts, args.Error(1)
 }
 
-func (m *MockPostService) GetPostByID(id string) (*models.Post, error) {
-	args := m.Called(id)
+func (m *MockPostService) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	return m.GetPostFields(ctx, id, nil)
+}
+
+func (m *MockPostService) GetPostFields(ctx context.Context, id string, fields []string) (*models.Post, error) {
+	args := m.Called(id, fields)
 	var post *models.Post
 	if args.Get(0) != nil {
 		post = args.Get(0).(*models.Post)
@@ -36,39 +43,75 @@ func (m *MockPostService) GetPostByID(id string) (*models.Post, error) {
 	return post, args.Error(1)
 }
 
-func (m *MockPostService) CreatePost(post *models.Post) (*models.Post, error) {
+func (m *MockPostService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, *sanitize.Report, error) {
 	args := m.Called(post)
 	var createdPost *models.Post
 	if args.Get(0) != nil {
 		createdPost = args.Get(0).(*models.Post)
 	}
-	return createdPost, args.Error(1)
+	return createdPost, nil, args.Error(1)
 }
 
-func (m *MockPostService) UpdatePost(id int, post *models.Post) (*models.Post, error) {
+func (m *MockPostService) UpdatePost(ctx context.Context, id string, post *models.Post) (*models.Post, *sanitize.Report, error) {
 	args := m.Called(id, post)
 	var updatedPost *models.Post
 	if args.Get(0) != nil {
 		updatedPost = args.Get(0).(*models.Post)
 	}
-	return updatedPost, args.Error(1)
+	return updatedPost, nil, args.Error(1)
 }
 
-func (m *MockPostService) DeletePost(id int) error {
+func (m *MockPostService) DeletePost(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *MockPostService) RenderPost(ctx context.Context, post *models.Post) (*render.Document, error) {
+	args := m.Called(post)
+	doc, _ := args.Get(0).(*render.Document)
+	return doc, args.Error(1)
+}
+
+func (m *MockPostService) ImportPosts(ctx context.Context, posts []*models.Post) []error {
+	args := m.Called(posts)
+	errs, _ := args.Get(0).([]error)
+	return errs
+}
+
+func (m *MockPostService) ExportPosts(ctx context.Context, fn func(*models.Post) error) error {
+	args := m.Called()
+	posts, _ := args.Get(0).([]*models.Post)
+	for _, post := range posts {
+		if err := fn(post); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockPostService) BatchGetPosts(ctx context.Context, ids []string) ([]*models.Post, []string, error) {
+	args := m.Called(ids)
+	posts, _ := args.Get(0).([]*models.Post)
+	missing, _ := args.Get(1).([]string)
+	return posts, missing, args.Error(2)
+}
+
+func (m *MockPostService) BatchDeletePosts(ctx context.Context, ids []string, transactional bool) []error {
+	args := m.Called(ids, transactional)
+	errs, _ := args.Get(0).([]error)
+	return errs
+}
+
 func TestPostHandlers(t *testing.T) {
 	t.Run("GetAllPosts - Success", func(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
 		posts := []*models.Post{
-			{ID: 1, Title: "Post 1", Content: "Content 1", Author: "Author 1"},
-			{ID: 2, Title: "Post 2", Content: "Content 2", Author: "Author 2"},
+			{ID: "1", Title: "Post 1", Content: "Content 1", Author: "Author 1"},
+			{ID: "2", Title: "Post 2", Content: "Content 2", Author: "Author 2"},
 		}
-		mockService.On("GetAllPosts", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(posts, nil)
+		mockService.On("GetAllPosts", mock.Anything, 1, 10, []string(nil)).Return(posts, nil)
 
 		req := httptest.NewRequest("GET", "/posts", nil)
 		rec := httptest.NewRecorder()
@@ -89,7 +132,7 @@ func TestPostHandlers(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		mockService.On("GetAllPosts", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("internal server error"))
+		mockService.On("GetAllPosts", mock.Anything, 1, 10, []string(nil)).Return(nil, errors.New("internal server error"))
 
 		req := httptest.NewRequest("GET", "/posts", nil)
 		rec := httptest.NewRecorder()
@@ -111,8 +154,8 @@ func TestPostHandlers(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		post := &models.Post{ID: 1, Title: "Post 1", Content: "Content 1", Author: "Author 1"}
-		mockService.On("GetPostByID", 1).Return(post, nil)
+		post := &models.Post{ID: "1", Title: "Post 1", Content: "Content 1", Author: "Author 1"}
+		mockService.On("GetPostFields", "1", []string(nil)).Return(post, nil)
 
 		req := httptest.NewRequest("GET", "/posts/1", nil)
 		req = muxSetVars(req, map[string]string{"id": "1"})
@@ -134,7 +177,7 @@ func TestPostHandlers(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		mockService.On("GetPostByID", 1).Return(nil, errors.New("post not found"))
+		mockService.On("GetPostFields", "1", []string(nil)).Return(nil, errors.New("post not found"))
 
 		req := httptest.NewRequest("GET", "/posts/1", nil)
 		req = muxSetVars(req, map[string]string{"id": "1"})
@@ -158,7 +201,7 @@ func TestPostHandlers(t *testing.T) {
 		handler := NewPostHandler(mockService)
 
 		post := &models.Post{Title: "New Post", Content: "New Content", Author: "Author"}
-		createdPost := &models.Post{ID: 1, Title: "New Post", Content: "New Content", Author: "Author"}
+		createdPost := &models.Post{ID: "1", Title: "New Post", Content: "New Content", Author: "Author"}
 		mockService.On("CreatePost", post).Return(createdPost, nil)
 
 		body, _ := json.Marshal(post)
@@ -202,8 +245,8 @@ func TestPostHandlers(t *testing.T) {
 		handler := NewPostHandler(mockService)
 
 		post := &models.Post{Title: "Updated Post", Content: "Updated Content", Author: "Author"}
-		updatedPost := &models.Post{ID: 1, Title: "Updated Post", Content: "Updated Content", Author: "Author"}
-		mockService.On("UpdatePost", 1, post).Return(updatedPost, nil)
+		updatedPost := &models.Post{ID: "1", Title: "Updated Post", Content: "Updated Content", Author: "Author"}
+		mockService.On("UpdatePost", "1", post).Return(updatedPost, nil)
 
 		body, _ := json.Marshal(post)
 		req := httptest.NewRequest("PUT", "/posts/1", bytes.NewReader(body))
@@ -227,8 +270,8 @@ func TestPostHandlers(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		req := httptest.NewRequest("PUT", "/posts/abc", nil)
-		req = muxSetVars(req, map[string]string{"id": "abc"})
+		req := httptest.NewRequest("PUT", "/posts/", nil)
+		req = muxSetVars(req, map[string]string{"id": ""})
 		rec := httptest.NewRecorder()
 
 		handler.UpdatePost(rec, req)
@@ -239,14 +282,14 @@ func TestPostHandlers(t *testing.T) {
 		err := json.Unmarshal(rec.Body.Bytes(), &errResponse)
 		assert.NoError(t, err)
 		assert.Equal(t, "Bad Request", errResponse["error"])
-		assert.Equal(t, "invalid ID", errResponse["description"])
+		assert.Equal(t, "id is empty", errResponse["description"])
 	})
 
 	t.Run("DeletePost - Success", func(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		mockService.On("DeletePost", 1).Return(nil)
+		mockService.On("DeletePost", "1").Return(nil)
 
 		req := httptest.NewRequest("DELETE", "/posts/1", nil)
 		req = muxSetVars(req, map[string]string{"id": "1"})
@@ -263,7 +306,7 @@ func TestPostHandlers(t *testing.T) {
 		mockService := new(MockPostService)
 		handler := NewPostHandler(mockService)
 
-		mockService.On("DeletePost", 1).Return(errors.New("post not found"))
+		mockService.On("DeletePost", "1").Return(errors.New("post not found"))
 
 		req := httptest.NewRequest("DELETE", "/posts/1", nil)
 		req = muxSetVars(req, map[string]string{"id": "1"})
@@ -286,3 +329,63 @@ func TestPostHandlers(t *testing.T) {
 func muxSetVars(r *http.Request, vars map[string]string) *http.Request {
 	return mux.SetURLVars(r, vars)
 }
+
+func TestGetPostByIDOptions(t *testing.T) {
+	post := &models.Post{ID: "1", Title: "Post 1", Content: "# Post", ContentFormat: models.ContentFormatMarkdown, Author: "Author 1"}
+
+	t.Run("Renders HTML", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := NewPostHandler(mockService)
+		mockService.On("GetPostFields", "1", []string(nil)).Return(post, nil)
+		mockService.On("RenderPost", post).Return(&render.Document{
+			HTML:         `<h1 id="post">Post</h1>`,
+			Sanitization: &sanitize.Report{Removed: []sanitize.Removal{{Kind: sanitize.KindElement, Name: "script", Count: 1}}},
+		}, nil)
+
+		req := muxSetVars(httptest.NewRequest("GET", "/posts/1?render=html", nil), map[string]string{"id": "1"})
+		rec := httptest.NewRecorder()
+		handler.GetPostByID(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var got map[string]any
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+		assert.Equal(t, `<h1 id="post">Post</h1>`, got["contentHtml"])
+		assert.Equal(t, "# Post", got["content"])
+		assert.NotNil(t, got["meta"])
+	})
+
+	t.Run("Rejects Unknown Render Mode", func(t *testing.T) {
+		handler := NewPostHandler(new(MockPostService))
+
+		req := muxSetVars(httptest.NewRequest("GET", "/posts/1?render=pdf", nil), map[string]string{"id": "1"})
+		rec := httptest.NewRecorder()
+		handler.GetPostByID(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Selects Fields", func(t *testing.T) {
+		mockService := new(MockPostService)
+		handler := NewPostHandler(mockService)
+		mockService.On("GetPostFields", "1", []string{"title", "tags"}).Return(&models.Post{ID: "1", Title: "Post 1"}, nil)
+
+		req := muxSetVars(httptest.NewRequest("GET", "/posts/1?fields=title,tags", nil), map[string]string{"id": "1"})
+		rec := httptest.NewRecorder()
+		handler.GetPostByID(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"title":"Post 1"}`, rec.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Unknown Fields", func(t *testing.T) {
+		handler := NewPostHandler(new(MockPostService))
+
+		req := httptest.NewRequest("GET", "/posts?fields=id,body", nil)
+		rec := httptest.NewRecorder()
+		handler.GetAllPosts(rec, req)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), `unknown field: \"body\"`)
+	})
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/sitemap"
+	"github.com/gorilla/mux"
+)
+
+type SitemapService interface {
+	Sitemap(ctx context.Context) (*sitemap.Sitemap, error)
+}
+
+type SitemapHandlerInterface interface {
+	GetSitemap(w http.ResponseWriter, r *http.Request)
+	GetSitemapChunk(w http.ResponseWriter, r *http.Request)
+}
+
+var _ SitemapHandlerInterface = (*SitemapHandler)(nil)
+
+type SitemapHandler struct {
+	service SitemapService
+}
+
+func NewSitemapHandler(service SitemapService) *SitemapHandler {
+	return &SitemapHandler{service: service}
+}
+
+// GetSitemap serves the whole sitemap when it fits in a single file, and a
+// sitemap index pointing at the chunks otherwise.
+func (h *SitemapHandler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	sm, ok := h.loadSitemap(w, r)
+	if !ok {
+		return
+	}
+
+	body := sm.Index
+	if len(sm.Chunks) == 1 {
+		body = sm.Chunks[0]
+	}
+	serveXML(w, r, sm, body)
+}
+
+// GetSitemapChunk serves the chunk numbered by the {n} route variable, starting at 1.
+func (h *SitemapHandler) GetSitemapChunk(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 1 {
+		handleError(w, r, errors.New("sitemap not found"), http.StatusNotFound)
+		return
+	}
+
+	sm, ok := h.loadSitemap(w, r)
+	if !ok {
+		return
+	}
+	if n > len(sm.Chunks) {
+		handleError(w, r, errors.New("sitemap not found"), http.StatusNotFound)
+		return
+	}
+	serveXML(w, r, sm, sm.Chunks[n-1])
+}
+
+func (h *SitemapHandler) loadSitemap(w http.ResponseWriter, r *http.Request) (*sitemap.Sitemap, bool) {
+	sm, err := h.service.Sitemap(r.Context())
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to generate sitemap", "error", err)
+		handleError(w, r, errors.New("failed to generate sitemap"), http.StatusInternalServerError)
+		return nil, false
+	}
+	return sm, true
+}
+
+func serveXML(w http.ResponseWriter, r *http.Request, sm *sitemap.Sitemap, body []byte) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("ETag", contentETag(body))
+	http.ServeContent(w, r, "", sm.LastModified, bytes.NewReader(body))
+}
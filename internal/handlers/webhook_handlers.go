@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// Page sizes of the delivery log.
+const (
+	defaultDeliveryLimit = 20
+	maxDeliveryLimit     = 100
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListDeliveries(ctx context.Context, webhookID string, limit int, cursor string) ([]*models.WebhookDelivery, string, error)
+	RedeliverDelivery(ctx context.Context, webhookID, deliveryID string) (*models.WebhookDelivery, error)
+}
+
+type WebhookHandlerInterface interface {
+	CreateWebhook(w http.ResponseWriter, r *http.Request)
+	ListWebhooks(w http.ResponseWriter, r *http.Request)
+	GetWebhook(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	ListDeliveries(w http.ResponseWriter, r *http.Request)
+	RedeliverDelivery(w http.ResponseWriter, r *http.Request)
+}
+
+var _ WebhookHandlerInterface = (*WebhookHandler)(nil)
+
+type WebhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(service WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// DeliveryPage is one page of a webhook's delivery log.
+type DeliveryPage struct {
+	Deliveries []*models.WebhookDelivery `json:"deliveries"`
+	// NextCursor fetches the next page; it is absent on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.CreateWebhook")
+	defer span.End()
+	var webhook models.Webhook
+	if err := json.NewDecoder(r.Body).Decode(&webhook); err != nil {
+		handleError(w, r, errors.New("Content-Type must be application/json"), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.service.CreateWebhook(ctx, &webhook)
+	if err != nil {
+		h.fail(w, r, err, "create webhook")
+		return
+	}
+	writeJSONResponse(w, created, http.StatusCreated)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.ListWebhooks")
+	defer span.End()
+	webhooks, err := h.service.ListWebhooks(ctx)
+	if err != nil {
+		h.fail(w, r, err, "list webhooks")
+		return
+	}
+	if webhooks == nil {
+		webhooks = []*models.Webhook{}
+	}
+	writeJSONResponse(w, webhooks, http.StatusOK)
+}
+
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.GetWebhook")
+	defer span.End()
+	webhook, err := h.service.GetWebhook(ctx, mux.Vars(r)["id"])
+	if err != nil {
+		h.fail(w, r, err, "get webhook")
+		return
+	}
+	writeJSONResponse(w, webhook, http.StatusOK)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.DeleteWebhook")
+	defer span.End()
+	if err := h.service.DeleteWebhook(ctx, mux.Vars(r)["id"]); err != nil {
+		h.fail(w, r, err, "delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries returns a page of the webhook's deliveries, newest first.
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.ListDeliveries")
+	defer span.End()
+	query := r.URL.Query()
+	limit := defaultDeliveryLimit
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxDeliveryLimit {
+			handleError(w, r, fmt.Errorf("limit must be between 1 and %d", maxDeliveryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	deliveries, next, err := h.service.ListDeliveries(ctx, mux.Vars(r)["id"], limit, query.Get("cursor"))
+	if err != nil {
+		h.fail(w, r, err, "list deliveries")
+		return
+	}
+	if deliveries == nil {
+		deliveries = []*models.WebhookDelivery{}
+	}
+	writeJSONResponse(w, DeliveryPage{Deliveries: deliveries, NextCursor: next}, http.StatusOK)
+}
+
+// RedeliverDelivery queues a delivery to be sent again.
+func (h *WebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	ctx, span := tracer.Start(r.Context(), "WebhookHandler.RedeliverDelivery")
+	defer span.End()
+	vars := mux.Vars(r)
+	delivery, err := h.service.RedeliverDelivery(ctx, vars["id"], vars["deliveryId"])
+	if err != nil {
+		h.fail(w, r, err, "redeliver")
+		return
+	}
+	writeJSONResponse(w, delivery, http.StatusAccepted)
+}
+
+// fail answers with the status matching a service error, hiding the details
+// of unexpected ones.
+func (h *WebhookHandler) fail(w http.ResponseWriter, r *http.Request, err error, action string) {
+	switch {
+	case isNotFound(err):
+		handleError(w, r, err, http.StatusNotFound)
+	case errors.Is(err, models.ErrInvalidWebhook):
+		handleError(w, r, err, http.StatusBadRequest)
+	case errors.Is(err, repository.ErrInvalidCursor):
+		handleError(w, r, errors.New("invalid cursor"), http.StatusBadRequest)
+	default:
+		logging.FromContext(r.Context()).Error("failed to "+action, "error", err)
+		handleError(w, r, fmt.Errorf("failed to %s", action), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,113 @@
+// Package health runs the dependency checks behind the readiness probe.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the state of a single check or of the whole service.
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// CheckResult is the outcome of one dependency check.
+type CheckResult struct {
+	Status    Status  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+// Report aggregates the checks. Status is up only if every check is up.
+type Report struct {
+	Status    Status                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checkedAt"`
+}
+
+type namedCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// Checker runs the registered checks concurrently, each bounded by timeout,
+// and caches the report for ttl so that frequent probes do not turn into a
+// steady load on the dependencies.
+type Checker struct {
+	timeout time.Duration
+	ttl     time.Duration
+	checks  []namedCheck
+	now     func() time.Time
+
+	mu     sync.Mutex
+	cached *Report
+}
+
+// NewChecker creates a checker with no checks; it reports up until checks are added.
+func NewChecker(timeout, ttl time.Duration) *Checker {
+	return &Checker{timeout: timeout, ttl: ttl, now: time.Now}
+}
+
+// Add registers check under name. It must be called before the first Ready.
+func (c *Checker) Add(name string, check func(context.Context) error) {
+	c.checks = append(c.checks, namedCheck{name: name, check: check})
+}
+
+// Ready returns the cached report, or runs the checks if it has expired.
+// Concurrent callers wait for a single run. The checks are not canceled with
+// ctx, as their report is cached for other callers: a probe that gives up
+// early must not leave a down report behind.
+func (c *Checker) Ready(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.cached != nil && c.now().Sub(c.cached.CheckedAt) < c.ttl {
+		return *c.cached
+	}
+
+	report := Report{
+		Status:    StatusUp,
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+		CheckedAt: c.now(),
+	}
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			results[i] = c.run(context.WithoutCancel(ctx), nc)
+		}(i, nc)
+	}
+	wg.Wait()
+
+	for i, nc := range c.checks {
+		report.Checks[nc.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	c.cached = &report
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, nc namedCheck) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := nc.check(ctx)
+	result := CheckResult{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	return result
+}
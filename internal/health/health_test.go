@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	t.Run("All Up", func(t *testing.T) {
+		c := NewChecker(time.Second, time.Minute)
+		c.Add("dynamodb", func(context.Context) error { return nil })
+
+		report := c.Ready(context.Background())
+
+		assert.Equal(t, StatusUp, report.Status)
+		assert.Equal(t, StatusUp, report.Checks["dynamodb"].Status)
+		assert.Empty(t, report.Checks["dynamodb"].Error)
+	})
+
+	t.Run("One Down", func(t *testing.T) {
+		c := NewChecker(time.Second, time.Minute)
+		c.Add("dynamodb", func(context.Context) error { return errors.New("table status is CREATING") })
+		c.Add("other", func(context.Context) error { return nil })
+
+		report := c.Ready(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, StatusDown, report.Checks["dynamodb"].Status)
+		assert.Equal(t, "table status is CREATING", report.Checks["dynamodb"].Error)
+		assert.Equal(t, StatusUp, report.Checks["other"].Status)
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		c := NewChecker(10*time.Millisecond, time.Minute)
+		c.Add("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		report := c.Ready(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Contains(t, report.Checks["slow"].Error, "deadline exceeded")
+	})
+
+	t.Run("Ignores Canceled Caller", func(t *testing.T) {
+		c := NewChecker(time.Second, time.Minute)
+		c.Add("dynamodb", func(ctx context.Context) error { return ctx.Err() })
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		c.Ready(ctx)
+		report := c.Ready(context.Background())
+
+		assert.Equal(t, StatusUp, report.Status)
+	})
+
+	t.Run("Caches Result", func(t *testing.T) {
+		now := time.Unix(1700000000, 0)
+		calls := 0
+		c := NewChecker(time.Second, 5*time.Second)
+		c.now = func() time.Time { return now }
+		c.Add("dynamodb", func(context.Context) error {
+			calls++
+			return nil
+		})
+
+		c.Ready(context.Background())
+		now = now.Add(4 * time.Second)
+		c.Ready(context.Background())
+		assert.Equal(t, 1, calls)
+
+		now = now.Add(2 * time.Second)
+		report := c.Ready(context.Background())
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, now, report.CheckedAt)
+	})
+}
@@ -0,0 +1,149 @@
+// Package logging configures the service's structured logger and carries it
+// through request contexts.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Output formats.
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+const redacted = "[REDACTED]"
+
+// Config controls the logger and what the access log records.
+type Config struct {
+	Level slog.Level
+	// Format is FormatJSON or FormatText. When empty, JSON is used under AWS
+	// Lambda and text everywhere else.
+	Format string
+	// LogBodies enables logging of request and response bodies, up to MaxBodyBytes each.
+	LogBodies    bool
+	MaxBodyBytes int
+	// RedactHeaders and RedactFields name headers and JSON body fields whose
+	// values are replaced before logging. Matching is case-insensitive. Add
+	// fields with WithRedactFields, which compiles the pattern RedactBody
+	// matches them with.
+	RedactHeaders []string
+	RedactFields  []string
+
+	// fieldPattern matches every field in RedactFields; nil when there are
+	// none or RedactFields was set directly.
+	fieldPattern *regexp.Regexp
+}
+
+// DefaultConfig returns a config that redacts common credentials and keeps bodies out of the logs.
+func DefaultConfig() Config {
+	return Config{
+		Level:         slog.LevelInfo,
+		MaxBodyBytes:  2048,
+		RedactHeaders: []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"},
+	}.WithRedactFields("password", "token", "secret", "apiKey")
+}
+
+// WithRedactFields returns a copy of c that also redacts fields.
+func (c Config) WithRedactFields(fields ...string) Config {
+	c.RedactFields = append(append([]string(nil), c.RedactFields...), fields...)
+	c.fieldPattern = compileFieldPattern(c.RedactFields)
+	return c
+}
+
+// New creates a logger writing to w.
+func New(w io.Writer, cfg Config) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	if resolveFormat(cfg.Format) == FormatJSON {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+func resolveFormat(format string) string {
+	if format != "" {
+		return format
+	}
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN".
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return level, nil
+}
+
+type contextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or slog.Default().
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RedactHeaderValues flattens h into a map, replacing the values of the configured headers.
+func (c Config) RedactHeaderValues(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		for _, secret := range c.RedactHeaders {
+			if strings.EqualFold(name, secret) {
+				value = redacted
+				break
+			}
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// RedactBody masks the configured JSON fields in body and truncates it to
+// MaxBodyBytes. Masking works on the raw text, so it also covers NDJSON and
+// bodies that were cut short.
+func (c Config) RedactBody(body []byte) string {
+	text := string(body)
+	pattern := c.fieldPattern
+	if pattern == nil {
+		pattern = compileFieldPattern(c.RedactFields)
+	}
+	if pattern != nil {
+		text = pattern.ReplaceAllString(text, `${1}"`+redacted+`"`)
+	}
+	if c.MaxBodyBytes > 0 && len(text) > c.MaxBodyBytes {
+		text = text[:c.MaxBodyBytes] + "...(truncated)"
+	}
+	return text
+}
+
+// compileFieldPattern returns a pattern matching any of the fields and its
+// value, or nil when there are no fields.
+func compileFieldPattern(fields []string) *regexp.Regexp {
+	if len(fields) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(fields))
+	for i, field := range fields {
+		quoted[i] = regexp.QuoteMeta(field)
+	}
+	return regexp.MustCompile(`(?i)("(?:` + strings.Join(quoted, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`)
+}
@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("JSON Format", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, Config{Format: FormatJSON, Level: slog.LevelWarn}).Warn("hello", "key", "value")
+
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+		assert.Equal(t, "hello", line["msg"])
+		assert.Equal(t, "value", line["key"])
+	})
+
+	t.Run("Level Filtering", func(t *testing.T) {
+		var buf bytes.Buffer
+		New(&buf, Config{Format: FormatText, Level: slog.LevelWarn}).Info("dropped")
+
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestContextLogger(t *testing.T) {
+	assert.Same(t, slog.Default(), FromContext(context.Background()))
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	assert.Same(t, logger, FromContext(WithLogger(context.Background(), logger)))
+}
+
+func TestRedaction(t *testing.T) {
+	cfg := DefaultConfig()
+
+	t.Run("Headers", func(t *testing.T) {
+		headers := http.Header{"Authorization": {"Bearer abc"}, "Accept": {"a", "b"}}
+
+		got := cfg.RedactHeaderValues(headers)
+
+		assert.Equal(t, map[string]string{"Authorization": "[REDACTED]", "Accept": "a, b"}, got)
+	})
+
+	t.Run("Body Fields", func(t *testing.T) {
+		body := []byte(`{"user":"jane","Password": "p\"w","token":123,"nested":{"secret":"s"}}`)
+
+		got := cfg.RedactBody(body)
+
+		assert.Equal(t, `{"user":"jane","Password": "[REDACTED]","token":"[REDACTED]","nested":{"secret":"[REDACTED]"}}`, got)
+	})
+
+	t.Run("Added Fields", func(t *testing.T) {
+		cfg := cfg.WithRedactFields("ssn")
+
+		got := cfg.RedactBody([]byte(`{"ssn":"123","password":"pw","name":"jane"}`))
+
+		assert.Equal(t, `{"ssn":"[REDACTED]","password":"[REDACTED]","name":"jane"}`, got)
+		assert.NotContains(t, DefaultConfig().RedactFields, "ssn")
+	})
+
+	t.Run("Fields Set Directly", func(t *testing.T) {
+		cfg := Config{RedactFields: []string{"pin"}}
+
+		assert.Equal(t, `{"pin":"[REDACTED]"}`, cfg.RedactBody([]byte(`{"pin":1234}`)))
+	})
+
+	t.Run("Truncation", func(t *testing.T) {
+		cfg := Config{MaxBodyBytes: 5}
+
+		assert.Equal(t, "abcde...(truncated)", cfg.RedactBody([]byte("abcdefgh")))
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("debug")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, level)
+
+	_, err = ParseLevel("loud")
+	assert.Error(t, err)
+}
@@ -0,0 +1,249 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+)
+
+// maxEMFValues is the most values CloudWatch accepts for one metric in one
+// EMF document; longer series are split across documents.
+const maxEMFValues = 100
+
+// throttleCodes are the DynamoDB error codes counted as throttles.
+var throttleCodes = map[string]bool{
+	"ProvisionedThroughputExceededException": true,
+	"ThrottlingException":                    true,
+	"RequestLimitExceeded":                   true,
+}
+
+type requestKey struct {
+	route, method, status string
+}
+
+type requestSeries struct {
+	latencies []float64
+}
+
+type dynamoSeries struct {
+	latencies     []float64
+	errors        int
+	throttles     int
+	capacityUnits float64
+}
+
+// EMF buffers measurements for one Lambda invocation and writes them as
+// CloudWatch Embedded Metric Format log lines on Flush. It keeps nothing
+// between invocations except whether the cold start has been reported.
+type EMF struct {
+	namespace string
+	service   string
+	w         io.Writer
+	now       func() time.Time
+
+	mu        sync.Mutex
+	coldStart bool
+	requests  map[requestKey]*requestSeries
+	dynamo    map[string]*dynamoSeries
+}
+
+var _ Recorder = (*EMF)(nil)
+
+// NewEMF creates a recorder writing EMF documents to w, usually stdout.
+func NewEMF(w io.Writer, namespace, service string) *EMF {
+	return &EMF{
+		namespace: namespace,
+		service:   service,
+		w:         w,
+		now:       time.Now,
+		coldStart: true,
+		requests:  make(map[requestKey]*requestSeries),
+		dynamo:    make(map[string]*dynamoSeries),
+	}
+}
+
+// RequestStarted is a no-op: in-flight gauges mean nothing when each Lambda
+// instance serves one request at a time.
+func (e *EMF) RequestStarted(string, string) {}
+
+func (e *EMF) RequestFinished(route, method string, status int, duration time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	key := requestKey{route: route, method: method, status: StatusClass(status)}
+	s, ok := e.requests[key]
+	if !ok {
+		s = &requestSeries{}
+		e.requests[key] = s
+	}
+	s.latencies = append(s.latencies, milliseconds(duration))
+}
+
+func (e *EMF) DynamoDBCall(call DynamoDBCall) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.dynamo[call.Operation]
+	if !ok {
+		s = &dynamoSeries{}
+		e.dynamo[call.Operation] = s
+	}
+	s.latencies = append(s.latencies, milliseconds(call.Duration))
+	s.capacityUnits += call.CapacityUnits
+	if call.ErrorCode != "" {
+		s.errors++
+		if throttleCodes[call.ErrorCode] {
+			s.throttles++
+		}
+	}
+}
+
+// Flush writes everything recorded since the last flush, one EMF document
+// per line, and resets the buffer. It is meant to be deferred in the Lambda
+// handler so metrics leave the process before the invocation is frozen.
+func (e *EMF) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	docs := e.documents(ctx)
+	e.coldStart = false
+	e.requests = make(map[requestKey]*requestSeries)
+	e.dynamo = make(map[string]*dynamoSeries)
+	e.mu.Unlock()
+
+	for _, doc := range docs {
+		line, err := json.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("failed to marshal EMF document: %w", err)
+		}
+		if _, err := e.w.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write EMF document: %w", err)
+		}
+	}
+	return nil
+}
+
+type emfMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// documents builds the EMF documents for the buffered measurements. The
+// caller must hold e.mu.
+func (e *EMF) documents(ctx context.Context) []map[string]interface{} {
+	timestamp := e.now().UnixMilli()
+	base := map[string]interface{}{"Service": e.service, "ColdStart": e.coldStart}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		base["lambdaRequestId"] = lc.AwsRequestID
+	}
+
+	newDoc := func(dimensions []string, metrics []emfMetric, values map[string]interface{}) map[string]interface{} {
+		doc := map[string]interface{}{
+			"_aws": map[string]interface{}{
+				"Timestamp": timestamp,
+				"CloudWatchMetrics": []map[string]interface{}{{
+					"Namespace":  e.namespace,
+					"Dimensions": [][]string{dimensions},
+					"Metrics":    metrics,
+				}},
+			},
+		}
+		for k, v := range base {
+			doc[k] = v
+		}
+		for k, v := range values {
+			doc[k] = v
+		}
+		return doc
+	}
+
+	coldStart := 0
+	if e.coldStart {
+		coldStart = 1
+	}
+	docs := []map[string]interface{}{newDoc(
+		[]string{"Service"},
+		[]emfMetric{{"Invocations", "Count"}, {"ColdStarts", "Count"}},
+		map[string]interface{}{"Invocations": 1, "ColdStarts": coldStart},
+	)}
+
+	keys := make([]requestKey, 0, len(e.requests))
+	for key := range e.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	for _, key := range keys {
+		for _, chunk := range chunkValues(e.requests[key].latencies) {
+			docs = append(docs, newDoc(
+				[]string{"Service", "Route", "Method", "StatusClass"},
+				[]emfMetric{{"Latency", "Milliseconds"}, {"Requests", "Count"}},
+				map[string]interface{}{
+					"Route": key.route, "Method": key.method, "StatusClass": key.status,
+					"Latency": chunk, "Requests": len(chunk),
+				},
+			))
+		}
+	}
+
+	operations := make([]string, 0, len(e.dynamo))
+	for op := range e.dynamo {
+		operations = append(operations, op)
+	}
+	sort.Strings(operations)
+	for _, op := range operations {
+		s := e.dynamo[op]
+		for i, chunk := range chunkValues(s.latencies) {
+			values := map[string]interface{}{
+				"Operation":       op,
+				"DynamoDBLatency": chunk,
+				"DynamoDBCalls":   len(chunk),
+			}
+			// Counters are reported once, with the first chunk.
+			if i == 0 {
+				values["DynamoDBErrors"] = s.errors
+				values["DynamoDBThrottles"] = s.throttles
+				values["DynamoDBCapacityUnits"] = s.capacityUnits
+			} else {
+				values["DynamoDBErrors"] = 0
+				values["DynamoDBThrottles"] = 0
+				values["DynamoDBCapacityUnits"] = 0
+			}
+			docs = append(docs, newDoc(
+				[]string{"Service", "Operation"},
+				[]emfMetric{
+					{"DynamoDBLatency", "Milliseconds"},
+					{"DynamoDBCalls", "Count"},
+					{"DynamoDBErrors", "Count"},
+					{"DynamoDBThrottles", "Count"},
+					{"DynamoDBCapacityUnits", "Count"},
+				},
+				values,
+			))
+		}
+	}
+	return docs
+}
+
+func chunkValues(values []float64) [][]float64 {
+	var chunks [][]float64
+	for len(values) > maxEMFValues {
+		chunks = append(chunks, values[:maxEMFValues])
+		values = values[maxEMFValues:]
+	}
+	return append(chunks, values)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
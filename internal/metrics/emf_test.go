@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeEMF(t *testing.T, out string) []map[string]interface{} {
+	t.Helper()
+	var docs []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var doc map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &doc))
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func TestEMF(t *testing.T) {
+	var out bytes.Buffer
+	e := NewEMF(&out, "BlogAPI", "blog-api")
+	e.now = func() time.Time { return time.UnixMilli(1700000000000) }
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+
+	t.Run("Cold Start Invocation", func(t *testing.T) {
+		e.RequestStarted("/v1/posts/{id}", http.MethodGet)
+		e.RequestFinished("/v1/posts/{id}", http.MethodGet, http.StatusOK, 12*time.Millisecond)
+		e.DynamoDBCall(DynamoDBCall{Operation: "GetItem", Duration: 3 * time.Millisecond, CapacityUnits: 0.5})
+		e.DynamoDBCall(DynamoDBCall{Operation: "GetItem", Duration: 5 * time.Millisecond, ErrorCode: "ProvisionedThroughputExceededException"})
+
+		assert.NoError(t, e.Flush(ctx))
+		docs := decodeEMF(t, out.String())
+		assert.Len(t, docs, 3)
+
+		invocation := docs[0]
+		assert.Equal(t, float64(1), invocation["Invocations"])
+		assert.Equal(t, float64(1), invocation["ColdStarts"])
+		assert.Equal(t, "lambda-1", invocation["lambdaRequestId"])
+		aws := invocation["_aws"].(map[string]interface{})
+		assert.Equal(t, float64(1700000000000), aws["Timestamp"])
+		directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "BlogAPI", directive["Namespace"])
+		assert.Equal(t, []interface{}{[]interface{}{"Service"}}, directive["Dimensions"])
+
+		request := docs[1]
+		assert.Equal(t, "/v1/posts/{id}", request["Route"])
+		assert.Equal(t, "GET", request["Method"])
+		assert.Equal(t, "2xx", request["StatusClass"])
+		assert.Equal(t, []interface{}{12.0}, request["Latency"])
+		assert.Equal(t, float64(1), request["Requests"])
+		assert.Equal(t, true, request["ColdStart"])
+
+		dynamo := docs[2]
+		assert.Equal(t, "GetItem", dynamo["Operation"])
+		assert.Equal(t, []interface{}{3.0, 5.0}, dynamo["DynamoDBLatency"])
+		assert.Equal(t, float64(2), dynamo["DynamoDBCalls"])
+		assert.Equal(t, float64(1), dynamo["DynamoDBErrors"])
+		assert.Equal(t, float64(1), dynamo["DynamoDBThrottles"])
+		assert.Equal(t, 0.5, dynamo["DynamoDBCapacityUnits"])
+	})
+
+	t.Run("Warm Invocation Starts Empty", func(t *testing.T) {
+		out.Reset()
+		assert.NoError(t, e.Flush(ctx))
+
+		docs := decodeEMF(t, out.String())
+		assert.Len(t, docs, 1)
+		assert.Equal(t, float64(0), docs[0]["ColdStarts"])
+		assert.Equal(t, false, docs[0]["ColdStart"])
+	})
+
+	t.Run("Splits Long Series", func(t *testing.T) {
+		out.Reset()
+		for i := 0; i < maxEMFValues+5; i++ {
+			e.RequestFinished("/v1/posts", http.MethodGet, http.StatusOK, time.Millisecond)
+		}
+		assert.NoError(t, e.Flush(ctx))
+
+		docs := decodeEMF(t, out.String())
+		assert.Len(t, docs, 3)
+		assert.Equal(t, float64(maxEMFValues), docs[1]["Requests"])
+		assert.Equal(t, float64(5), docs[2]["Requests"])
+	})
+}
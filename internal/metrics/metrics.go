@@ -0,0 +1,64 @@
+// Package metrics defines what the service measures and the backends that
+// export it.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Recorder receives the service's measurements. Implementations must be safe
+// for concurrent use.
+type Recorder interface {
+	// RequestStarted and RequestFinished bracket every HTTP request. Route is
+	// the mux path template, never the raw path.
+	RequestStarted(route, method string)
+	RequestFinished(route, method string, status int, duration time.Duration)
+	// DynamoDBCall is called once per DynamoDB operation, after retries.
+	DynamoDBCall(call DynamoDBCall)
+}
+
+// DynamoDBCall describes one DynamoDB operation.
+type DynamoDBCall struct {
+	Operation string
+	// Route is the route template of the HTTP request that issued the call,
+	// or "" outside of a request.
+	Route    string
+	Duration time.Duration
+	// ErrorCode is the AWS error code of a failed call, or "" on success.
+	ErrorCode string
+	// CapacityUnits is the total capacity consumed across tables and indexes.
+	CapacityUnits float64
+}
+
+// Nop discards every measurement.
+type Nop struct{}
+
+var _ Recorder = Nop{}
+
+func (Nop) RequestStarted(string, string)                      {}
+func (Nop) RequestFinished(string, string, int, time.Duration) {}
+func (Nop) DynamoDBCall(DynamoDBCall)                          {}
+
+// StatusClass buckets an HTTP status code into "2xx", "4xx" and so on.
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+type routeKey struct{}
+
+// WithRoute returns a copy of ctx carrying the route template, so that
+// DynamoDB calls can be attributed to the endpoint that made them.
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey{}, route)
+}
+
+// Route returns the route template carried by ctx, or "".
+func Route(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey{}).(string)
+	return route
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "blog_api"
+
+// Prometheus records measurements as Prometheus metrics.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+	dynamoCalls     *prometheus.CounterVec
+	dynamoErrors    *prometheus.CounterVec
+	dynamoDuration  *prometheus.HistogramVec
+	dynamoCapacity  *prometheus.CounterVec
+}
+
+var _ Recorder = (*Prometheus)(nil)
+
+// NewPrometheus creates a recorder with its own registry, which also carries
+// the Go runtime and process collectors.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route template, method and status class.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route template, method and status class.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}, []string{"route", "method"}),
+		dynamoCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dynamodb_requests_total",
+			Help:      "DynamoDB operations by operation and issuing route.",
+		}, []string{"operation", "route"}),
+		dynamoErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dynamodb_errors_total",
+			Help:      "Failed DynamoDB operations by operation and AWS error code.",
+		}, []string{"operation", "code"}),
+		dynamoDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "dynamodb_request_duration_seconds",
+			Help:      "DynamoDB operation latency, including retries.",
+			Buckets:   []float64{.002, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		dynamoCapacity: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dynamodb_consumed_capacity_units_total",
+			Help:      "Capacity units consumed by operation and issuing route.",
+		}, []string{"operation", "route"}),
+	}
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.requests, p.requestDuration, p.inFlight,
+		p.dynamoCalls, p.dynamoErrors, p.dynamoDuration, p.dynamoCapacity,
+	)
+	return p
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+func (p *Prometheus) RequestStarted(route, method string) {
+	p.inFlight.WithLabelValues(route, method).Inc()
+}
+
+func (p *Prometheus) RequestFinished(route, method string, status int, duration time.Duration) {
+	class := StatusClass(status)
+	p.inFlight.WithLabelValues(route, method).Dec()
+	p.requests.WithLabelValues(route, method, class).Inc()
+	p.requestDuration.WithLabelValues(route, method, class).Observe(duration.Seconds())
+}
+
+func (p *Prometheus) DynamoDBCall(call DynamoDBCall) {
+	p.dynamoCalls.WithLabelValues(call.Operation, call.Route).Inc()
+	p.dynamoDuration.WithLabelValues(call.Operation).Observe(call.Duration.Seconds())
+	if call.ErrorCode != "" {
+		p.dynamoErrors.WithLabelValues(call.Operation, call.ErrorCode).Inc()
+	}
+	if call.CapacityUnits > 0 {
+		p.dynamoCapacity.WithLabelValues(call.Operation, call.Route).Add(call.CapacityUnits)
+	}
+}
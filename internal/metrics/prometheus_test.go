@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", StatusClass(http.StatusNoContent))
+	assert.Equal(t, "4xx", StatusClass(http.StatusNotFound))
+	assert.Equal(t, "5xx", StatusClass(http.StatusBadGateway))
+	assert.Equal(t, "unknown", StatusClass(0))
+}
+
+func TestRoute(t *testing.T) {
+	assert.Empty(t, Route(context.Background()))
+	assert.Equal(t, "/v1/posts/{id}", Route(WithRoute(context.Background(), "/v1/posts/{id}")))
+}
+
+func TestPrometheus(t *testing.T) {
+	p := NewPrometheus()
+
+	p.RequestStarted("/v1/posts/{id}", http.MethodGet)
+	p.RequestStarted("/v1/posts/{id}", http.MethodGet)
+	p.RequestFinished("/v1/posts/{id}", http.MethodGet, http.StatusNotFound, 30*time.Millisecond)
+	p.DynamoDBCall(DynamoDBCall{Operation: "GetItem", Route: "/v1/posts/{id}", Duration: time.Millisecond, CapacityUnits: 0.5})
+	p.DynamoDBCall(DynamoDBCall{Operation: "GetItem", Route: "/v1/posts/{id}", Duration: time.Millisecond, ErrorCode: "ProvisionedThroughputExceededException"})
+
+	rec := httptest.NewRecorder()
+	p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, out, `blog_api_http_requests_total{method="GET",route="/v1/posts/{id}",status="4xx"} 1`)
+	assert.Contains(t, out, `blog_api_http_request_duration_seconds_count{method="GET",route="/v1/posts/{id}",status="4xx"} 1`)
+	assert.Contains(t, out, `blog_api_http_requests_in_flight{method="GET",route="/v1/posts/{id}"} 1`)
+	assert.Contains(t, out, `blog_api_dynamodb_requests_total{operation="GetItem",route="/v1/posts/{id}"} 2`)
+	assert.Contains(t, out, `blog_api_dynamodb_errors_total{code="ProvisionedThroughputExceededException",operation="GetItem"} 1`)
+	assert.Contains(t, out, `blog_api_dynamodb_consumed_capacity_units_total{operation="GetItem",route="/v1/posts/{id}"} 0.5`)
+	assert.Contains(t, out, `go_goroutines`)
+}
@@ -0,0 +1,164 @@
+// Package migrate applies numbered data migrations to the posts table and
+// records them in the table's schema item, so that each runs once.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"blog-api/internal/repository"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDB is the subset of the DynamoDB client used by migrations.
+type DynamoDB interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+var _ DynamoDB = (*dynamodb.Client)(nil)
+
+// Migration is one numbered change. Up must be idempotent: a migration that
+// fails part-way is run again in full.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db DynamoDB, table string) error
+}
+
+// Applied is the record of a migration in the schema item.
+type Applied struct {
+	Version     int       `dynamodbav:"Version" json:"version"`
+	Description string    `dynamodbav:"Description" json:"description"`
+	AppliedAt   time.Time `dynamodbav:"AppliedAt" json:"appliedAt"`
+}
+
+// schemaItem is the item with repository.SchemaItemID.
+type schemaItem struct {
+	SchemaVersion int       `dynamodbav:"SchemaVersion"`
+	Migrations    []Applied `dynamodbav:"Migrations"`
+}
+
+type Migrator struct {
+	db         DynamoDB
+	table      string
+	migrations []Migration
+	now        func() time.Time
+}
+
+// NewMigrator creates a migrator for migrations, which are applied in order
+// of their versions.
+func NewMigrator(db DynamoDB, table string, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &Migrator{db: db, table: table, migrations: sorted, now: time.Now}
+}
+
+// Status returns the applied migrations and those still pending.
+func (m *Migrator) Status(ctx context.Context) ([]Applied, []Migration, error) {
+	schema, err := m.schema(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	done := make(map[int]bool, len(schema.Migrations))
+	for _, applied := range schema.Migrations {
+		done[applied.Version] = true
+	}
+
+	var pending []Migration
+	for i, migration := range m.migrations {
+		if i > 0 && migration.Version == m.migrations[i-1].Version {
+			return nil, nil, fmt.Errorf("migration %d is defined twice", migration.Version)
+		}
+		if done[migration.Version] {
+			continue
+		}
+		if migration.Version <= schema.SchemaVersion {
+			return nil, nil, fmt.Errorf("migration %d is older than schema version %d and cannot be applied", migration.Version, schema.SchemaVersion)
+		}
+		pending = append(pending, migration)
+	}
+	return schema.Migrations, pending, nil
+}
+
+// Up applies the pending migrations in order and returns those applied. It
+// stops at the first failure.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	_, pending, err := m.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, migration := range pending {
+		if err := migration.Up(ctx, m.db, m.table); err != nil {
+			return applied, fmt.Errorf("failed to apply migration %d (%s): %w", migration.Version, migration.Description, err)
+		}
+		if err := m.record(ctx, migration); err != nil {
+			return applied, err
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+func (m *Migrator) schema(ctx context.Context) (schemaItem, error) {
+	out, err := m.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(m.table),
+		Key:            schemaKey(),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return schemaItem{}, fmt.Errorf("failed to read schema item: %w", err)
+	}
+	var schema schemaItem
+	if err := attributevalue.UnmarshalMap(out.Item, &schema); err != nil {
+		return schemaItem{}, fmt.Errorf("failed to unmarshal schema item: %w", err)
+	}
+	return schema, nil
+}
+
+// record appends migration to the schema item. The condition keeps two
+// concurrent runs from both recording it.
+func (m *Migrator) record(ctx context.Context, migration Migration) error {
+	entry, err := attributevalue.Marshal([]Applied{{
+		Version:     migration.Version,
+		Description: migration.Description,
+		AppliedAt:   m.now().UTC(),
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal migration record: %w", err)
+	}
+
+	_, err = m.db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(m.table),
+		Key:                 schemaKey(),
+		UpdateExpression:    aws.String("SET SchemaVersion = :version, Migrations = list_append(if_not_exists(Migrations, :empty), :entry)"),
+		ConditionExpression: aws.String("attribute_not_exists(SchemaVersion) OR SchemaVersion < :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(migration.Version)},
+			":empty":   &types.AttributeValueMemberL{Value: []types.AttributeValue{}},
+			":entry":   entry,
+		},
+	})
+	var conflict *types.ConditionalCheckFailedException
+	if errors.As(err, &conflict) {
+		return fmt.Errorf("migration %d was recorded by another run", migration.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+	return nil
+}
+
+func schemaKey() map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: repository.SchemaItemID}}
+}
@@ -0,0 +1,213 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDynamoDB struct {
+	mock.Mock
+}
+
+func (m *MockDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	args := m.Called(ctx, params)
+	out, _ := args.Get(0).(*dynamodb.GetItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	args := m.Called(ctx, params)
+	out, _ := args.Get(0).(*dynamodb.UpdateItemOutput)
+	return out, args.Error(1)
+}
+
+func (m *MockDynamoDB) Scan(ctx context.Context, params *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	args := m.Called(ctx, params)
+	out, _ := args.Get(0).(*dynamodb.ScanOutput)
+	return out, args.Error(1)
+}
+
+func schemaOutput(version int, applied ...int) *dynamodb.GetItemOutput {
+	if version == 0 {
+		return &dynamodb.GetItemOutput{}
+	}
+	var list []types.AttributeValue
+	for _, v := range applied {
+		list = append(list, &types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+			"Version":     &types.AttributeValueMemberN{Value: string(rune('0' + v))},
+			"Description": &types.AttributeValueMemberS{Value: "earlier"},
+			"AppliedAt":   &types.AttributeValueMemberS{Value: "2024-01-01T00:00:00Z"},
+		}})
+	}
+	return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+		"ID":            &types.AttributeValueMemberS{Value: "__schema__"},
+		"SchemaVersion": &types.AttributeValueMemberN{Value: string(rune('0' + version))},
+		"Migrations":    &types.AttributeValueMemberL{Value: list},
+	}}
+}
+
+func recordOf(version string) interface{} {
+	return mock.MatchedBy(func(in *dynamodb.UpdateItemInput) bool {
+		v, ok := in.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN)
+		return ok && v.Value == version && aws.ToString(in.ConditionExpression) != ""
+	})
+}
+
+func TestMigratorUp(t *testing.T) {
+	var ran []int
+	migration := func(version int, err error) Migration {
+		return Migration{Version: version, Description: "test", Up: func(context.Context, DynamoDB, string) error {
+			ran = append(ran, version)
+			return err
+		}}
+	}
+
+	t.Run("Applies Pending In Order", func(t *testing.T) {
+		ran = nil
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(1, 1), nil)
+		db.On("UpdateItem", mock.Anything, recordOf("2")).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+		db.On("UpdateItem", mock.Anything, recordOf("3")).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+		m := NewMigrator(db, "Posts", []Migration{migration(3, nil), migration(1, nil), migration(2, nil)})
+		m.now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+		applied, err := m.Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 3}, ran)
+		assert.Len(t, applied, 2)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Nothing Pending", func(t *testing.T) {
+		ran = nil
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(2, 1, 2), nil)
+
+		applied, err := NewMigrator(db, "Posts", []Migration{migration(1, nil), migration(2, nil)}).Up(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+		assert.Empty(t, ran)
+		db.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Stops At Failure", func(t *testing.T) {
+		ran = nil
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(0), nil)
+		db.On("UpdateItem", mock.Anything, recordOf("1")).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+		applied, err := NewMigrator(db, "Posts", []Migration{migration(1, nil), migration(2, errors.New("boom")), migration(3, nil)}).Up(context.Background())
+
+		assert.EqualError(t, err, "failed to apply migration 2 (test): boom")
+		assert.Len(t, applied, 1)
+		assert.Equal(t, []int{1, 2}, ran)
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Concurrent Run", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(0), nil)
+		db.On("UpdateItem", mock.Anything, recordOf("1")).Return(nil, &types.ConditionalCheckFailedException{}).Once()
+
+		_, err := NewMigrator(db, "Posts", []Migration{migration(1, nil)}).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 1 was recorded by another run")
+	})
+
+	t.Run("Out Of Order Migration", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(3, 1, 3), nil)
+
+		_, err := NewMigrator(db, "Posts", []Migration{migration(1, nil), migration(2, nil), migration(3, nil)}).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 2 is older than schema version 3 and cannot be applied")
+	})
+
+	t.Run("Duplicate Version", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("GetItem", mock.Anything, mock.Anything).Return(schemaOutput(0), nil)
+
+		_, err := NewMigrator(db, "Posts", []Migration{migration(1, nil), migration(1, nil)}).Up(context.Background())
+
+		assert.EqualError(t, err, "migration 1 is defined twice")
+	})
+}
+
+func TestMigratorStatus(t *testing.T) {
+	db := new(MockDynamoDB)
+	db.On("GetItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.GetItemInput) bool {
+		id, ok := in.Key["ID"].(*types.AttributeValueMemberS)
+		return ok && id.Value == "__schema__" && aws.ToBool(in.ConsistentRead)
+	})).Return(schemaOutput(1, 1), nil)
+
+	applied, pending, err := NewMigrator(db, "Posts", Migrations()).Status(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Applied{{Version: 1, Description: "earlier", AppliedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}, applied)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, 2, pending[0].Version)
+}
+
+func TestBackfills(t *testing.T) {
+	item := func(id string, attrs map[string]types.AttributeValue) map[string]types.AttributeValue {
+		out := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}}
+		for k, v := range attrs {
+			out[k] = v
+		}
+		return out
+	}
+
+	t.Run("Timestamps", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("Scan", mock.Anything, mock.MatchedBy(func(in *dynamodb.ScanInput) bool {
+			return in.ExclusiveStartKey == nil &&
+				aws.ToString(in.FilterExpression) == "attribute_not_exists(CreatedAt) AND ID <> :schemaItem"
+		})).Return(&dynamodb.ScanOutput{
+			Items:            []map[string]types.AttributeValue{item("1", map[string]types.AttributeValue{"UpdatedAt": &types.AttributeValueMemberN{Value: "1700000000"}})},
+			LastEvaluatedKey: item("1", nil),
+		}, nil).Once()
+		db.On("Scan", mock.Anything, mock.MatchedBy(func(in *dynamodb.ScanInput) bool {
+			return in.ExclusiveStartKey != nil
+		})).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item("2", nil)}}, nil).Once()
+		db.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.UpdateItemInput) bool {
+			createdAt := in.ExpressionAttributeValues[":createdAt"].(*types.AttributeValueMemberN)
+			return in.Key["ID"].(*types.AttributeValueMemberS).Value == "1" && createdAt.Value == "1700000000"
+		})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+		db.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.UpdateItemInput) bool {
+			return in.Key["ID"].(*types.AttributeValueMemberS).Value == "2"
+		})).Return(nil, &types.ConditionalCheckFailedException{}).Once()
+
+		assert.NoError(t, backfillTimestamps(context.Background(), db, "Posts"))
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("Scan", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item("1", nil)}}, nil).Once()
+		db.On("UpdateItem", mock.Anything, mock.MatchedBy(func(in *dynamodb.UpdateItemInput) bool {
+			status := in.ExpressionAttributeValues[":published"].(*types.AttributeValueMemberS)
+			return aws.ToString(in.TableName) == "Posts" && status.Value == "published"
+		})).Return(&dynamodb.UpdateItemOutput{}, nil).Once()
+
+		assert.NoError(t, backfillStatus(context.Background(), db, "Posts"))
+		db.AssertExpectations(t)
+	})
+
+	t.Run("Update Failure", func(t *testing.T) {
+		db := new(MockDynamoDB)
+		db.On("Scan", mock.Anything, mock.Anything).Return(&dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{item("1", nil)}}, nil).Once()
+		db.On("UpdateItem", mock.Anything, mock.Anything).Return(nil, errors.New("throttled")).Once()
+
+		assert.EqualError(t, backfillStatus(context.Background(), db, "Posts"), "failed to update post: throttled")
+	})
+}
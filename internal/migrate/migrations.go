@@ -0,0 +1,120 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"blog-api/internal/models"
+	"blog-api/internal/repository"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Migrations returns the migrations of the posts table. New ones are
+// appended with the next version number; released ones never change.
+func Migrations() []Migration {
+	return []Migration{
+		{
+			Version:     1,
+			Description: "backfill CreatedAt and UpdatedAt on posts stored without them",
+			Up:          backfillTimestamps,
+		},
+		{
+			Version:     2,
+			Description: "set Status to published on posts stored without one",
+			Up:          backfillStatus,
+		},
+	}
+}
+
+// backfillTimestamps gives posts without CreatedAt one, so that they show up
+// in the indexes sorted by it. UpdatedAt is used when present, else the
+// current time.
+func backfillTimestamps(ctx context.Context, db DynamoDB, table string) error {
+	now := &types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Unix(), 10)}
+	return backfill(ctx, db, table, "attribute_not_exists(CreatedAt)", "ID, UpdatedAt", nil, func(item map[string]types.AttributeValue) *dynamodb.UpdateItemInput {
+		createdAt := types.AttributeValue(now)
+		if updatedAt, ok := item["UpdatedAt"]; ok {
+			createdAt = updatedAt
+		}
+		return &dynamodb.UpdateItemInput{
+			UpdateExpression:    aws.String("SET CreatedAt = :createdAt, UpdatedAt = if_not_exists(UpdatedAt, :createdAt)"),
+			ConditionExpression: aws.String("attribute_exists(ID) AND attribute_not_exists(CreatedAt)"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":createdAt": createdAt,
+			},
+		}
+	})
+}
+
+// backfillStatus marks posts stored before statuses existed as published.
+// They already count as published but are missing from the status index.
+func backfillStatus(ctx context.Context, db DynamoDB, table string) error {
+	names := map[string]string{"#status": "Status"}
+	return backfill(ctx, db, table, "attribute_not_exists(#status)", "ID", names, func(map[string]types.AttributeValue) *dynamodb.UpdateItemInput {
+		return &dynamodb.UpdateItemInput{
+			UpdateExpression:         aws.String("SET #status = :published"),
+			ConditionExpression:      aws.String("attribute_exists(ID) AND attribute_not_exists(#status)"),
+			ExpressionAttributeNames: names,
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":published": &types.AttributeValueMemberS{Value: models.StatusPublished},
+			},
+		}
+	})
+}
+
+// backfill scans the posts matching filter, reading the projected attributes,
+// and applies the update built for each. Items that were changed or deleted
+// in the meantime are skipped.
+func backfill(
+	ctx context.Context,
+	db DynamoDB,
+	table, filter, projection string,
+	names map[string]string,
+	update func(item map[string]types.AttributeValue) *dynamodb.UpdateItemInput,
+) error {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(table),
+		FilterExpression:         aws.String(filter + " AND ID <> :schemaItem"),
+		ProjectionExpression:     aws.String(projection),
+		ExpressionAttributeNames: names,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":schemaItem": &types.AttributeValueMemberS{Value: repository.SchemaItemID},
+		},
+	}
+
+	updated := 0
+	for {
+		out, err := db.Scan(ctx, input)
+		if err != nil {
+			return fmt.Errorf("failed to scan posts: %w", err)
+		}
+		for _, item := range out.Items {
+			params := update(item)
+			params.TableName = aws.String(table)
+			params.Key = map[string]types.AttributeValue{"ID": item["ID"]}
+
+			_, err := db.UpdateItem(ctx, params)
+			var conflict *types.ConditionalCheckFailedException
+			switch {
+			case errors.As(err, &conflict):
+				continue
+			case err != nil:
+				return fmt.Errorf("failed to update post: %w", err)
+			}
+			updated++
+		}
+		if out.LastEvaluatedKey == nil {
+			break
+		}
+		input.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+
+	slog.InfoContext(ctx, "backfilled posts", "table", table, "updated", updated)
+	return nil
+}
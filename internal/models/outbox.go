@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// OutboxMessage is a post change recorded in the outbox, in the same
+// transaction as the change itself, until it has been published.
+type OutboxMessage struct {
+	// ID stays the same however often the message is published, so
+	// consumers can ignore duplicates.
+	ID string `json:"id" dynamodbav:"ID"`
+	// Type is created, updated or deleted.
+	Type   string `json:"type" dynamodbav:"Type"`
+	PostID string `json:"postId" dynamodbav:"PostID"`
+	// Post is the post after the change, absent when it was deleted.
+	Post      *Post     `json:"post,omitempty" dynamodbav:"Post,omitempty"`
+	CreatedAt time.Time `json:"createdAt" dynamodbav:"CreatedAt,unixtime"`
+	// Sequence orders the messages, in Unix nanoseconds.
+	Sequence int64 `json:"-" dynamodbav:"Sequence"`
+	// LeaseUntil is when a relay that claimed the message gives it up.
+	LeaseUntil *time.Time `json:"-" dynamodbav:"LeaseUntil,unixtime,omitempty"`
+	// Attempts counts the claims of the message, each an attempt to publish it.
+	Attempts int `json:"-" dynamodbav:"Attempts,omitempty"`
+}
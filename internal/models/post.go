@@ -4,19 +4,16 @@ import (
 	"errors"
 	"fmt"
 	"github.com/go-playground/validator/v10"
+	"slices"
+	"strings"
+	"time"
 )
 
 var validate = validator.New()
 
-type Post struct {
-	ID      string `json:"id" dynamodbav:"ID"` // DynamoDB primary key
-	Title   string `json:"title" dynamodbav:"Title" validate:"required,min=3"`
-	Content string `json:"content" dynamodbav:"Content" validate:"required"`
-	Author  string `json:"author" dynamodbav:"Author" validate:"required"`
-}
-
-func (p *Post) Validate() error {
-	err := validate.Struct(p)
+// validateStruct checks the validate tags of v, naming every failed field.
+func validateStruct(v interface{}) error {
+	err := validate.Struct(v)
 	if err != nil {
 		validationErrors := err.(validator.ValidationErrors)
 		errorMessages := make([]string, 0, len(validationErrors))
@@ -25,6 +22,78 @@ func (p *Post) Validate() error {
 		}
 		return errors.New("validation failed: " + fmt.Sprintf("%v", errorMessages))
 	}
+	return nil
+}
+
+// Supported values for Post.ContentFormat.
+const (
+	ContentFormatPlain    = "plain"
+	ContentFormatMarkdown = "markdown"
+	ContentFormatHTML     = "html"
+)
+
+// ReservedPostID is the ID of the item recording the applied migrations,
+// which shares the posts table. No post may use it.
+const ReservedPostID = "__schema__"
+
+// Supported values for Post.Status.
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+)
+
+// Post is a blog post. Its Content is sanitized on write only in the html
+// format; Markdown and plain content may hold any markup and must be rendered
+// before it is displayed.
+type Post struct {
+	ID            string    `json:"id" dynamodbav:"ID"` // DynamoDB primary key
+	Title         string    `json:"title" dynamodbav:"Title" validate:"required,min=3"`
+	Content       string    `json:"content" dynamodbav:"Content" validate:"required"`
+	ContentFormat string    `json:"contentFormat,omitempty" dynamodbav:"ContentFormat,omitempty" validate:"omitempty,oneof=plain markdown html"`
+	Author        string    `json:"author" dynamodbav:"Author" validate:"required"`
+	Tags          []string  `json:"tags,omitempty" dynamodbav:"Tags,omitempty" validate:"omitempty,max=20,dive,required"`
+	Status        string    `json:"status,omitempty" dynamodbav:"Status,omitempty" validate:"omitempty,oneof=draft published"`
+	Version       int64     `json:"version" dynamodbav:"Version"` // Incremented by the repository on every write
+	CreatedAt     time.Time `json:"createdAt" dynamodbav:"CreatedAt,unixtime"`
+	UpdatedAt     time.Time `json:"updatedAt" dynamodbav:"UpdatedAt,unixtime"`
+}
+
+// PostFieldNames lists the JSON names of the post fields a client can select,
+// as in ?fields=id,title.
+var PostFieldNames = []string{"id", "title", "content", "contentFormat", "author", "tags", "status", "version", "createdAt", "updatedAt"}
+
+// ParseFields parses a comma-separated list of post fields, dropping
+// duplicates. An empty list selects every field and is returned as nil.
+func ParseFields(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return nil, nil
+	}
+	var fields []string
+	for _, field := range strings.Split(list, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(PostFieldNames, field) {
+			return nil, fmt.Errorf("unknown field: %q", field)
+		}
+		if !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// PostFilter narrows a listing of posts. Empty fields match everything.
+type PostFilter struct {
+	Author string
+	Tag    string
+}
+
+func (p *Post) Validate() error {
+	if err := validateStruct(p); err != nil {
+		return err
+	}
+	if p.ID == ReservedPostID {
+		return fmt.Errorf("validation failed: ID %q is reserved", p.ID)
+	}
 
 	//if strings.TrimSpace(p.Title) == "" {
 	//	return custom_errors.New("validation failed: Field 'Title' must not be empty or whitespace only")
@@ -39,6 +108,20 @@ func (p *Post) Validate() error {
 	return nil
 }
 
+// Format returns the post's content format, treating an unset value as plain text.
+func (p *Post) Format() string {
+	if p.ContentFormat == "" {
+		return ContentFormatPlain
+	}
+	return p.ContentFormat
+}
+
+// IsPublished reports whether the post is publicly visible. Posts stored
+// before statuses were introduced have no status and count as published.
+func (p *Post) IsPublished() bool {
+	return p.Status == "" || p.Status == StatusPublished
+}
+
 func NewPost(title, content, author string) *Post {
 	return &Post{
 		Title:   title,
@@ -118,3 +118,35 @@ func TestNewPost(t *testing.T) {
 		assert.Equal(t, "", post.Author, "Author should be empty")
 	})
 }
+
+func TestParseFields(t *testing.T) {
+	t.Run("Keeps Order And Drops Duplicates", func(t *testing.T) {
+		fields, err := ParseFields("title, id,title")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"title", "id"}, fields)
+	})
+
+	t.Run("Empty Selects Every Field", func(t *testing.T) {
+		fields, err := ParseFields("")
+
+		assert.NoError(t, err)
+		assert.Nil(t, fields)
+	})
+
+	t.Run("Rejects Unknown Fields", func(t *testing.T) {
+		for _, list := range []string{"id,body", "id,,title", "Title"} {
+			_, err := ParseFields(list)
+
+			assert.Error(t, err, list)
+		}
+	})
+}
+
+func TestPostReservedID(t *testing.T) {
+	post := &Post{ID: ReservedPostID, Title: "Title", Content: "Content", Author: "Author"}
+
+	err := post.Validate()
+
+	assert.ErrorContains(t, err, `ID "__schema__" is reserved`)
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Webhook event types.
+const (
+	EventPostCreated = "post.created"
+	EventPostUpdated = "post.updated"
+	EventPostDeleted = "post.deleted"
+)
+
+// Supported values for WebhookDelivery.Status. Pending deliveries are retried
+// until they succeed or run out of attempts and become dead.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+// ErrInvalidWebhook is wrapped by the validation errors of a webhook.
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhook subscribes a URL to post events. Secret signs every delivery; it is
+// only returned when the webhook is created.
+type Webhook struct {
+	ID        string    `json:"id" dynamodbav:"ID"`
+	URL       string    `json:"url" dynamodbav:"URL" validate:"required,http_url,max=2048"`
+	Events    []string  `json:"events" dynamodbav:"Events" validate:"required,min=1,unique,dive,oneof=post.created post.updated post.deleted"`
+	Secret    string    `json:"secret,omitempty" dynamodbav:"Secret" validate:"omitempty,min=16,max=256"`
+	CreatedAt time.Time `json:"createdAt" dynamodbav:"CreatedAt,unixtime"`
+}
+
+func (w *Webhook) Validate() error {
+	if err := validateStruct(w); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+	return nil
+}
+
+// Subscribed reports whether the webhook receives events of the given type.
+func (w *Webhook) Subscribed(eventType string) bool {
+	return slices.Contains(w.Events, eventType)
+}
+
+// WebhookEvent is the body of a webhook delivery. ID is shared by the
+// deliveries of the event to every webhook and by redeliveries, so receivers
+// can ignore duplicates.
+type WebhookEvent struct {
+	ID        string           `json:"id"`
+	Type      string           `json:"type"`
+	CreatedAt time.Time        `json:"createdAt"`
+	Data      WebhookEventData `json:"data"`
+}
+
+type WebhookEventData struct {
+	ID string `json:"id"`
+	// Post is the post after the change, absent when it was deleted.
+	Post *Post `json:"post,omitempty"`
+	// Fields lists the fields an update changed, when they are known.
+	Fields []string `json:"fields,omitempty"`
+}
+
+// WebhookDelivery is one event sent to one webhook, with the outcome of its
+// latest attempt.
+type WebhookDelivery struct {
+	ID        string `json:"id" dynamodbav:"ID"`
+	WebhookID string `json:"webhookId" dynamodbav:"WebhookID"`
+	EventID   string `json:"eventId" dynamodbav:"EventID"`
+	EventType string `json:"eventType" dynamodbav:"EventType"`
+	// Payload is the exact body sent, a WebhookEvent.
+	Payload  json.RawMessage `json:"payload" dynamodbav:"Payload"`
+	Status   string          `json:"status" dynamodbav:"Status"`
+	Attempts int             `json:"attempts" dynamodbav:"Attempts"`
+	// NextAttemptAt is when a pending delivery is sent next.
+	NextAttemptAt time.Time  `json:"nextAttemptAt" dynamodbav:"NextAttemptAt,unixtime"`
+	LastAttemptAt *time.Time `json:"lastAttemptAt,omitempty" dynamodbav:"LastAttemptAt,unixtime,omitempty"`
+	// ResponseStatus is the HTTP status of the latest attempt, 0 when no
+	// response was received.
+	ResponseStatus int       `json:"responseStatus,omitempty" dynamodbav:"ResponseStatus,omitempty"`
+	Error          string    `json:"error,omitempty" dynamodbav:"Error,omitempty"`
+	CreatedAt      time.Time `json:"createdAt" dynamodbav:"CreatedAt,unixtime"`
+	// ExpiresAt is when DynamoDB removes the delivery, in Unix seconds.
+	ExpiresAt int64 `json:"-" dynamodbav:"ExpiresAt,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookValidation(t *testing.T) {
+	valid := func() *Webhook {
+		return &Webhook{
+			URL:    "https://example.com/hooks",
+			Events: []string{EventPostCreated, EventPostDeleted},
+			Secret: "whsec_0123456789abcdef",
+		}
+	}
+	tests := []struct {
+		name   string
+		modify func(w *Webhook)
+		errMsg string
+	}{
+		{name: "Valid Webhook", modify: func(w *Webhook) {}},
+		{name: "Missing URL", modify: func(w *Webhook) { w.URL = "" }, errMsg: "URL"},
+		{name: "Relative URL", modify: func(w *Webhook) { w.URL = "/hooks" }, errMsg: "URL"},
+		{name: "No Events", modify: func(w *Webhook) { w.Events = nil }, errMsg: "Events"},
+		{name: "Unknown Event", modify: func(w *Webhook) { w.Events = []string{"post.read"} }, errMsg: "Events"},
+		{name: "Duplicate Events", modify: func(w *Webhook) { w.Events = []string{EventPostCreated, EventPostCreated} }, errMsg: "Events"},
+		{name: "Short Secret", modify: func(w *Webhook) { w.Secret = "short" }, errMsg: "Secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := valid()
+			tt.modify(webhook)
+
+			err := webhook.Validate()
+
+			if tt.errMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, ErrInvalidWebhook)
+			assert.ErrorContains(t, err, tt.errMsg)
+		})
+	}
+}
+
+func TestWebhookSubscribed(t *testing.T) {
+	webhook := &Webhook{Events: []string{EventPostCreated}}
+
+	assert.True(t, webhook.Subscribed(EventPostCreated))
+	assert.False(t, webhook.Subscribed(EventPostDeleted))
+}
@@ -0,0 +1,159 @@
+// Package openapi builds OpenAPI 3.1 documents, deriving JSON schemas from Go
+// types through their json and validate struct tags.
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []*Parameter        `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Content returns a single-entry content map.
+func Content(mediaType string, schema *Schema) map[string]MediaType {
+	return map[string]MediaType{mediaType: {Schema: schema}}
+}
+
+// Builder accumulates operations and the component schemas they reference.
+type Builder struct {
+	doc     *Document
+	schemas *schemaRegistry
+}
+
+func NewBuilder(info Info) *Builder {
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	return &Builder{doc: doc, schemas: newSchemaRegistry(doc.Components.Schemas)}
+}
+
+// Schema registers the type of v as a component and returns a reference to it.
+func (b *Builder) Schema(v interface{}) *Schema {
+	return b.schemas.schemaFor(v)
+}
+
+// Add registers op under a gorilla/mux path template such as "/posts/{id}" or
+// "/feed.{format:rss|atom|json}". Path parameters are derived from the template.
+func (b *Builder) Add(method, template string, op *Operation) {
+	path, params := PathFromTemplate(template)
+	op.Parameters = append(params, op.Parameters...)
+
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+}
+
+// Document returns the built document.
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+// Has reports whether the document describes method on the mux path template.
+func (d *Document) Has(method, template string) bool {
+	path, _ := PathFromTemplate(template)
+	item, ok := d.Paths[path]
+	if !ok {
+		return false
+	}
+	_, ok = (*item)[strings.ToLower(method)]
+	return ok
+}
+
+var (
+	templateVar = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+	enumPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\|[A-Za-z0-9_-]+)+$`)
+)
+
+// PathFromTemplate converts a gorilla/mux path template into an OpenAPI path
+// and the path parameters it declares. Variables restricted to alternatives
+// ("a|b") become enums; other patterns are kept as a regex pattern.
+func PathFromTemplate(template string) (string, []*Parameter) {
+	var params []*Parameter
+	path := templateVar.ReplaceAllStringFunc(template, func(match string) string {
+		parts := templateVar.FindStringSubmatch(match)
+		name, pattern := parts[1], parts[2]
+
+		schema := &Schema{Type: "string"}
+		switch {
+		case pattern == "":
+		case enumPattern.MatchString(pattern):
+			schema.Enum = strings.Split(pattern, "|")
+		default:
+			schema.Pattern = fmt.Sprintf("^%s$", pattern)
+		}
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+// Operations lists "METHOD path" for every operation in the document, sorted.
+func (d *Document) Operations() []string {
+	var ops []string
+	for path, item := range d.Paths {
+		for method := range *item {
+			ops = append(ops, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testNode struct {
+	Name     string      `json:"name" validate:"required,min=3,max=10"`
+	Kind     string      `json:"kind,omitempty" validate:"omitempty,oneof=a b"`
+	Labels   []string    `json:"labels,omitempty" validate:"omitempty,max=5,dive,required"`
+	At       time.Time   `json:"at"`
+	Hidden   string      `json:"-"`
+	Children []*testNode `json:"children,omitempty"`
+	*testEmbedded
+}
+
+type testEmbedded struct {
+	Extra int `json:"extra"`
+}
+
+func TestSchemaFromStructTags(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+
+	ref := b.Schema(testNode{})
+	assert.Equal(t, "#/components/schemas/testNode", ref.Ref)
+
+	schema := b.Document().Components.Schemas["testNode"]
+	assert.Equal(t, []string{"name"}, schema.Required)
+	assert.Equal(t, 3, *schema.Properties["name"].MinLength)
+	assert.Equal(t, 10, *schema.Properties["name"].MaxLength)
+	assert.Equal(t, []string{"a", "b"}, schema.Properties["kind"].Enum)
+	assert.Equal(t, 5, *schema.Properties["labels"].MaxItems)
+	assert.Equal(t, 1, *schema.Properties["labels"].Items.MinLength)
+	assert.Equal(t, "date-time", schema.Properties["at"].Format)
+	assert.Equal(t, "#/components/schemas/testNode", schema.Properties["children"].Items.Ref)
+	assert.Equal(t, "integer", schema.Properties["extra"].Type)
+	assert.NotContains(t, schema.Properties, "Hidden")
+}
+
+func TestPathFromTemplate(t *testing.T) {
+	path, params := PathFromTemplate("/v1/tags/{tag}/feed.{format:rss|atom|json}")
+	assert.Equal(t, "/v1/tags/{tag}/feed.{format}", path)
+	assert.Len(t, params, 2)
+	assert.Equal(t, "tag", params[0].Name)
+	assert.Equal(t, []string{"rss", "atom", "json"}, params[1].Schema.Enum)
+
+	path, params = PathFromTemplate("/sitemap-{n:[0-9]+}.xml")
+	assert.Equal(t, "/sitemap-{n}.xml", path)
+	assert.Equal(t, "^[0-9]+$", params[0].Schema.Pattern)
+}
+
+func TestBuilderAdd(t *testing.T) {
+	b := NewBuilder(Info{Title: "test", Version: "1"})
+	b.Add("GET", "/posts/{id}", &Operation{OperationID: "getPost"})
+
+	doc := b.Document()
+	assert.True(t, doc.Has("GET", "/posts/{id}"))
+	assert.False(t, doc.Has("DELETE", "/posts/{id}"))
+	assert.Equal(t, []string{"GET /posts/{id}"}, doc.Operations())
+	assert.Equal(t, "id", (*doc.Paths["/posts/{id}"])["get"].Parameters[0].Name)
+}
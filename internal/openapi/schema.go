@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema (draft 2020-12) object as used by OpenAPI 3.1.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+}
+
+// ArrayOf returns an array schema with the given items.
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// schemaRegistry derives schemas from Go types. Named struct types become
+// components so that recursive types can be expressed with $ref.
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry(components map[string]*Schema) *schemaRegistry {
+	return &schemaRegistry{components: components, names: make(map[reflect.Type]string)}
+}
+
+func (r *schemaRegistry) schemaFor(v interface{}) *Schema {
+	return r.schemaForType(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schemaForType(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{} // any JSON value
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		return &Schema{Ref: "#/components/schemas/" + r.component(t)}
+	case t.Kind() == reflect.Struct:
+		return r.structSchema(t)
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return ArrayOf(r.schemaForType(t.Elem()))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaForType(t.Elem())}
+	}
+	return &Schema{}
+}
+
+// component registers t under a unique name and returns that name.
+func (r *schemaRegistry) component(t reflect.Type) string {
+	if name, ok := r.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := r.components[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	r.names[t] = name
+	r.components[name] = &Schema{} // placeholder, so recursive references resolve
+	*r.components[name] = *r.structSchema(t)
+	return name
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the JSON-visible fields of t to schema, inlining embedded
+// structs the way encoding/json does.
+func (r *schemaRegistry) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			r.addFields(schema, fieldType)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := r.schemaForType(field.Type)
+		if applyValidation(prop, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = prop
+	}
+}
+
+// jsonName returns the explicit JSON name of field, if any, and whether the
+// field is hidden from JSON.
+func jsonName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() && !field.Anonymous {
+		return "", true
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	return strings.Split(tag, ",")[0], false
+}
+
+// applyValidation maps go-playground/validator rules onto schema keywords and
+// reports whether the field is required. Rules after "dive" apply to items.
+func applyValidation(schema *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	required := false
+	target := schema
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if target == schema {
+				required = true
+			} else if target.Type == "string" {
+				target.MinLength = intPtr(1)
+			}
+		case "dive":
+			if target.Items == nil {
+				return required
+			}
+			// Items may be a shared component reference, so constrain a copy.
+			items := *target.Items
+			target.Items = &items
+			target = &items
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			setBound(target, name == "min", n)
+		case "oneof":
+			target.Enum = strings.Fields(param)
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, isMin bool, n int) {
+	switch schema.Type {
+	case "string":
+		if isMin {
+			schema.MinLength = intPtr(n)
+		} else {
+			schema.MaxLength = intPtr(n)
+		}
+	case "array":
+		if isMin {
+			schema.MinItems = intPtr(n)
+		} else {
+			schema.MaxItems = intPtr(n)
+		}
+	case "integer", "number":
+		f := float64(n)
+		if isMin {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
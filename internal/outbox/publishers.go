@@ -0,0 +1,62 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+)
+
+// LogPublisher logs every message.
+type LogPublisher struct {
+	logger *slog.Logger
+}
+
+func NewLogPublisher(logger *slog.Logger) *LogPublisher {
+	return &LogPublisher{logger: logger}
+}
+
+func (p *LogPublisher) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	p.logger.InfoContext(ctx, "post changed", "type", message.Type, "post_id", message.PostID, "message_id", message.ID)
+	return nil
+}
+
+// EventPublisher publishes post events, such as webhooks.Dispatcher.
+type EventPublisher interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// Events publishes messages as post events, with the message ID as their
+// DedupeID.
+type Events struct {
+	publisher EventPublisher
+}
+
+func NewEvents(publisher EventPublisher) *Events {
+	return &Events{publisher: publisher}
+}
+
+func (p *Events) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	return p.publisher.Publish(ctx, events.Event{
+		Type:     message.Type,
+		ID:       message.PostID,
+		Post:     message.Post,
+		Time:     message.CreatedAt,
+		DedupeID: message.ID,
+	})
+}
+
+// Publishers publishes every message to each of its publishers in turn,
+// stopping at the first error. The message is then published again to all
+// of them, so each must tolerate duplicates.
+type Publishers []Publisher
+
+func (ps Publishers) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	for _, p := range ps {
+		if err := p.Publish(ctx, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Package outbox publishes the messages DynamoPostRepository records in its
+// outbox, in the same transaction as every post write, so that no change is
+// lost when a process dies right after writing. Delivery is at least once:
+// a message is deleted only after it was published, and consumers tell
+// duplicates apart by the message ID.
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"blog-api/internal/models"
+)
+
+// Store holds the outbox messages.
+type Store interface {
+	PendingMessages(ctx context.Context, limit int, cursor string) ([]*models.OutboxMessage, string, error)
+	ClaimMessage(ctx context.Context, message *models.OutboxMessage, now, until time.Time) (bool, error)
+	DeleteMessage(ctx context.Context, id string) error
+	MarkDead(ctx context.Context, id string) error
+}
+
+// Publisher delivers messages somewhere else. It may be handed the same
+// message again after an error or a crash.
+type Publisher interface {
+	Publish(ctx context.Context, message *models.OutboxMessage) error
+}
+
+// Config holds the relay settings. Zero values select the defaults.
+type Config struct {
+	// Lease is how long a claimed message is left to one relay before
+	// another may publish it; 30s by default.
+	Lease time.Duration
+	// MaxAttempts is how often a message is tried before it is marked dead;
+	// 10 by default.
+	MaxAttempts int
+	Logger      *slog.Logger
+}
+
+const (
+	defaultLease       = 30 * time.Second
+	defaultMaxAttempts = 10
+	// pollInterval is how often the outbox is drained when no change wakes
+	// the relay in between.
+	pollInterval = 5 * time.Second
+	// batchSize is how many messages are read at once.
+	batchSize = 25
+)
+
+type Relay struct {
+	store     Store
+	publisher Publisher
+	cfg       Config
+	logger    *slog.Logger
+	now       func() time.Time
+	// wake asks Run to drain the outbox before the next poll.
+	wake chan struct{}
+}
+
+func NewRelay(store Store, publisher Publisher, cfg Config) *Relay {
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultLease
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// PostChanged makes Run drain the outbox right away. It lets the relay be a
+// services.ChangeListener.
+func (r *Relay) PostChanged(ctx context.Context, id, eventType string) {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run drains the outbox until ctx is done. Any number of relays may share a
+// store: each message is claimed by one of them before it is published.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		r.Drain(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// Drain publishes the pending messages in the order they were written and
+// returns how many it published, paging past the messages leased by this or
+// another relay. It stops at the first message that fails to publish; that
+// message is retried once its lease expires, by which time later messages may
+// have been published, so consumers must not rely on the order. A message
+// that failed MaxAttempts times is marked dead instead, and the drain goes
+// on. Processes without background work, such as Lambda functions, call it
+// directly.
+func (r *Relay) Drain(ctx context.Context) int {
+	published := 0
+	cursor := ""
+	for ctx.Err() == nil {
+		messages, next, err := r.store.PendingMessages(ctx, batchSize, cursor)
+		if err != nil {
+			if ctx.Err() == nil {
+				r.logger.Error("failed to read outbox messages", "error", err)
+			}
+			return published
+		}
+
+		for _, message := range messages {
+			now := r.now()
+			ok, err := r.store.ClaimMessage(ctx, message, now, now.Add(r.cfg.Lease))
+			if err != nil {
+				r.logger.Error("failed to claim outbox message", "id", message.ID, "error", err)
+				return published
+			}
+			if !ok {
+				continue // leased, by another relay or after failing
+			}
+			if err := r.publisher.Publish(ctx, message); err != nil {
+				if message.Attempts < r.cfg.MaxAttempts {
+					r.logger.Error("failed to publish outbox message", "id", message.ID, "type", message.Type,
+						"post_id", message.PostID, "attempts", message.Attempts, "error", err)
+					return published
+				}
+				r.logger.Error("giving up on outbox message", "id", message.ID, "type", message.Type,
+					"post_id", message.PostID, "attempts", message.Attempts, "error", err)
+				if err := r.store.MarkDead(context.WithoutCancel(ctx), message.ID); err != nil {
+					r.logger.Error("failed to mark outbox message dead", "id", message.ID, "error", err)
+				}
+				continue
+			}
+			published++
+			// A message that cannot be deleted is published again once its
+			// lease expires.
+			if err := r.store.DeleteMessage(context.WithoutCancel(ctx), message.ID); err != nil {
+				r.logger.Error("failed to delete published outbox message", "id", message.ID, "error", err)
+			}
+		}
+		if next == "" {
+			return published
+		}
+		cursor = next
+	}
+	return published
+}
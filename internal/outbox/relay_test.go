@@ -0,0 +1,217 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore is an in-memory Store. Its cursors are the Sequence of the
+// last message of a page.
+type memoryStore struct {
+	mu       sync.Mutex
+	messages map[string]*models.OutboxMessage
+	dead     []string
+}
+
+func newMemoryStore(messages ...*models.OutboxMessage) *memoryStore {
+	s := &memoryStore{messages: make(map[string]*models.OutboxMessage)}
+	for _, message := range messages {
+		s.messages[message.ID] = message
+	}
+	return s
+}
+
+func (s *memoryStore) PendingMessages(ctx context.Context, limit int, cursor string) ([]*models.OutboxMessage, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	after := int64(-1)
+	if cursor != "" {
+		after, _ = strconv.ParseInt(cursor, 10, 64)
+	}
+	var pending []*models.OutboxMessage
+	for _, message := range s.messages {
+		if message.Sequence > after {
+			copied := *message
+			pending = append(pending, &copied)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Sequence < pending[j].Sequence })
+	if len(pending) <= limit {
+		return pending, "", nil
+	}
+	pending = pending[:limit]
+	return pending, strconv.FormatInt(pending[limit-1].Sequence, 10), nil
+}
+
+func (s *memoryStore) ClaimMessage(ctx context.Context, message *models.OutboxMessage, now, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.messages[message.ID]
+	if !ok || (stored.LeaseUntil != nil && stored.LeaseUntil.After(now)) {
+		return false, nil
+	}
+	stored.LeaseUntil = &until
+	stored.Attempts++
+	message.LeaseUntil, message.Attempts = stored.LeaseUntil, stored.Attempts
+	return true, nil
+}
+
+func (s *memoryStore) MarkDead(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, id)
+	s.dead = append(s.dead, id)
+	return nil
+}
+
+func (s *memoryStore) DeleteMessage(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.messages, id)
+	return nil
+}
+
+// recordingPublisher records the IDs of the messages it publishes and fails
+// on those in fail.
+type recordingPublisher struct {
+	fail      map[string]bool
+	published []string
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, message *models.OutboxMessage) error {
+	if p.fail[message.ID] {
+		return errors.New("unavailable")
+	}
+	p.published = append(p.published, message.ID)
+	return nil
+}
+
+func messages(n int) []*models.OutboxMessage {
+	out := make([]*models.OutboxMessage, n)
+	for i := range out {
+		out[i] = &models.OutboxMessage{ID: fmt.Sprintf("m%02d", i), Type: events.TypeCreated, PostID: "p1", Sequence: int64(i)}
+	}
+	return out
+}
+
+func newTestRelay(store Store, publisher Publisher, now time.Time) *Relay {
+	r := NewRelay(store, publisher, Config{})
+	r.now = func() time.Time { return now }
+	return r
+}
+
+func TestDrain(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Publishes In Order And Deletes", func(t *testing.T) {
+		store := newMemoryStore(messages(30)...)
+		publisher := &recordingPublisher{}
+
+		published := newTestRelay(store, publisher, now).Drain(context.Background())
+
+		assert.Equal(t, 30, published)
+		assert.Equal(t, "m00", publisher.published[0])
+		assert.Equal(t, "m29", publisher.published[29])
+		assert.Empty(t, store.messages)
+	})
+
+	t.Run("Stops At First Failure", func(t *testing.T) {
+		store := newMemoryStore(messages(3)...)
+		publisher := &recordingPublisher{fail: map[string]bool{"m01": true}}
+		relay := newTestRelay(store, publisher, now)
+
+		assert.Equal(t, 1, relay.Drain(context.Background()))
+		assert.Equal(t, []string{"m00"}, publisher.published)
+		assert.Len(t, store.messages, 2)
+
+		t.Run("Retries After The Lease", func(t *testing.T) {
+			publisher.fail = nil
+
+			assert.Equal(t, 1, relay.Drain(context.Background()), "m01 is still leased")
+
+			relay.now = func() time.Time { return now.Add(defaultLease) }
+			assert.Equal(t, 1, relay.Drain(context.Background()))
+			assert.Equal(t, []string{"m00", "m02", "m01"}, publisher.published)
+			assert.Empty(t, store.messages)
+		})
+	})
+
+	t.Run("Skips Messages Claimed Elsewhere", func(t *testing.T) {
+		lease := now.Add(time.Minute)
+		claimed := messages(2)
+		claimed[0].LeaseUntil = &lease
+		store := newMemoryStore(claimed...)
+		publisher := &recordingPublisher{}
+
+		newTestRelay(store, publisher, now).Drain(context.Background())
+
+		assert.Equal(t, []string{"m01"}, publisher.published)
+		assert.Len(t, store.messages, 1)
+	})
+
+	t.Run("Pages Past Leased Messages", func(t *testing.T) {
+		lease := now.Add(time.Minute)
+		all := messages(batchSize + 5)
+		for _, message := range all[:batchSize] {
+			message.LeaseUntil = &lease
+		}
+		store := newMemoryStore(all...)
+		publisher := &recordingPublisher{}
+
+		published := newTestRelay(store, publisher, now).Drain(context.Background())
+
+		assert.Equal(t, 5, published)
+		assert.Equal(t, "m25", publisher.published[0])
+		assert.Len(t, store.messages, batchSize)
+	})
+
+	t.Run("Marks Messages Dead After Max Attempts", func(t *testing.T) {
+		store := newMemoryStore(messages(2)...)
+		publisher := &recordingPublisher{fail: map[string]bool{"m00": true}}
+		relay := NewRelay(store, publisher, Config{MaxAttempts: 2})
+		at := now
+		relay.now = func() time.Time { return at }
+
+		assert.Equal(t, 0, relay.Drain(context.Background()))
+		assert.Empty(t, store.dead)
+		assert.Equal(t, 1, store.messages["m00"].Attempts)
+
+		at = at.Add(defaultLease)
+		assert.Equal(t, 1, relay.Drain(context.Background()), "the drain goes on past the dead message")
+		assert.Equal(t, []string{"m00"}, store.dead)
+		assert.Equal(t, []string{"m01"}, publisher.published)
+		assert.Empty(t, store.messages)
+	})
+}
+
+type eventRecorder struct {
+	events []events.Event
+}
+
+func (r *eventRecorder) Publish(ctx context.Context, event events.Event) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func TestEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	err := NewEvents(recorder).Publish(context.Background(), &models.OutboxMessage{
+		ID: "m1", Type: events.TypeDeleted, PostID: "p1", CreatedAt: created,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []events.Event{{Type: events.TypeDeleted, ID: "p1", Time: created, DedupeID: "m1"}}, recorder.events)
+}
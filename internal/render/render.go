@@ -0,0 +1,197 @@
+// Package render turns stored post content into HTML for clients that do not
+// want to re-implement Markdown rendering themselves.
+package render
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"strings"
+
+	"blog-api/internal/models"
+	"blog-api/internal/sanitize"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+)
+
+// TOCEntry is a single heading in a generated table of contents.
+type TOCEntry struct {
+	Level    int         `json:"level"`
+	ID       string      `json:"id"`
+	Title    string      `json:"title"`
+	Children []*TOCEntry `json:"children,omitempty"`
+}
+
+// Document is the rendered form of a post's content.
+type Document struct {
+	HTML string      `json:"contentHtml"`
+	TOC  []*TOCEntry `json:"toc,omitempty"`
+
+	// Sanitization lists what the sanitizer stripped from the rendered HTML, if anything.
+	Sanitization *sanitize.Report `json:"-"`
+}
+
+// Renderer converts post content to HTML according to its ContentFormat.
+//
+// Markdown is parsed as CommonMark with the GFM extensions (tables, task lists,
+// strikethrough, autolinks). Fenced code blocks carry a "language-<lang>" class
+// so clients can plug in any syntax highlighter, and every heading gets an ID
+// and an anchor link. Raw HTML is allowed through the Markdown renderer and
+// every rendered document, whatever its format, is passed through the
+// sanitization policy. Rendered documents are cached per post ID and content,
+// so a post overwritten without a new version, as an import does, is rendered
+// again.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *sanitize.Policy
+	cache  *lruCache
+}
+
+// NewRenderer creates a Renderer that sanitizes its output with policy and
+// keeps up to cacheSize rendered documents.
+func NewRenderer(cacheSize int, policy *sanitize.Policy) *Renderer {
+	return &Renderer{
+		md: goldmark.New(
+			goldmark.WithExtensions(extension.GFM),
+			goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+			goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+		),
+		policy: policy,
+		cache:  newLRUCache(cacheSize),
+	}
+}
+
+// Render returns the HTML form of the post's content.
+func (r *Renderer) Render(post *models.Post) (*Document, error) {
+	if post == nil {
+		return nil, fmt.Errorf("post cannot be nil")
+	}
+
+	key := fmt.Sprintf("%s:%s:%x", post.ID, post.Format(), sha256.Sum256([]byte(post.Content)))
+	if doc, ok := r.cache.get(key); ok {
+		return doc, nil
+	}
+
+	var (
+		doc *Document
+		err error
+	)
+	switch post.Format() {
+	case models.ContentFormatMarkdown:
+		doc, err = r.renderMarkdown([]byte(post.Content))
+	case models.ContentFormatHTML:
+		doc = &Document{HTML: post.Content}
+	case models.ContentFormatPlain:
+		doc = renderPlain(post.Content)
+	default:
+		err = fmt.Errorf("unsupported content format %q", post.ContentFormat)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.HTML, doc.Sanitization = r.policy.Sanitize(doc.HTML)
+
+	r.cache.add(key, doc)
+	return doc, nil
+}
+
+func (r *Renderer) renderMarkdown(source []byte) (*Document, error) {
+	root := r.md.Parser().Parse(text.NewReader(source))
+	toc := addHeadingAnchors(root, source)
+
+	var buf bytes.Buffer
+	if err := r.md.Renderer().Render(&buf, source, root); err != nil {
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
+	}
+	return &Document{HTML: buf.String(), TOC: toc}, nil
+}
+
+// addHeadingAnchors appends a self-link to every heading and returns the
+// headings as a nested table of contents.
+func addHeadingAnchors(root ast.Node, source []byte) []*TOCEntry {
+	var (
+		toc   []*TOCEntry
+		stack []*TOCEntry
+	)
+
+	_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		rawID, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		id := string(rawID.([]byte))
+
+		entry := &TOCEntry{Level: heading.Level, ID: id, Title: nodeText(heading, source)}
+		for len(stack) > 0 && stack[len(stack)-1].Level >= entry.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			toc = append(toc, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, entry)
+		}
+		stack = append(stack, entry)
+
+		anchor := ast.NewLink()
+		anchor.Destination = []byte("#" + id)
+		anchor.SetAttributeString("class", []byte("anchor"))
+		anchor.AppendChild(anchor, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, anchor)
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return toc
+}
+
+func nodeText(n ast.Node, source []byte) string {
+	var sb strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			sb.Write(t.Segment.Value(source))
+		case *ast.String:
+			sb.Write(t.Value)
+		case *ast.CodeSpan:
+			for child := t.FirstChild(); child != nil; child = child.NextSibling() {
+				if seg, ok := child.(*ast.Text); ok {
+					sb.Write(seg.Segment.Value(source))
+				}
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return sb.String()
+}
+
+// renderPlain escapes the content and splits it into paragraphs on blank lines.
+func renderPlain(content string) *Document {
+	var sb strings.Builder
+	normalized := strings.ReplaceAll(content, "\r\n", "\n")
+	for _, para := range strings.Split(normalized, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		sb.WriteString("<p>")
+		sb.WriteString(strings.ReplaceAll(html.EscapeString(para), "\n", "<br>\n"))
+		sb.WriteString("</p>\n")
+	}
+	return &Document{HTML: sb.String()}
+}
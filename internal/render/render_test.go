@@ -0,0 +1,107 @@
+package render
+
+import (
+	"testing"
+
+	"blog-api/internal/models"
+	"blog-api/internal/sanitize"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	renderer := NewRenderer(10, sanitize.DefaultPolicy())
+
+	t.Run("Markdown With GFM Extensions", func(t *testing.T) {
+		post := &models.Post{
+			ID:            "1",
+			Version:       1,
+			ContentFormat: models.ContentFormatMarkdown,
+			Content:       "# Intro\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] done\n- [ ] todo\n\n```go\nfmt.Println()\n```\n",
+		}
+
+		doc, err := renderer.Render(post)
+
+		assert.NoError(t, err)
+		assert.Contains(t, doc.HTML, `<h1 id="intro">Intro<a href="#intro" class="anchor">#</a></h1>`)
+		assert.Contains(t, doc.HTML, "<table>")
+		assert.Contains(t, doc.HTML, `<input checked="" disabled="" type="checkbox"`)
+		assert.Contains(t, doc.HTML, `<code class="language-go">`)
+	})
+
+	t.Run("Table Of Contents", func(t *testing.T) {
+		post := &models.Post{
+			ID:            "2",
+			ContentFormat: models.ContentFormatMarkdown,
+			Content:       "# One\n## Two `code`\n### Three\n## Four\n# Five\n",
+		}
+
+		doc, err := renderer.Render(post)
+
+		assert.NoError(t, err)
+		assert.Len(t, doc.TOC, 2)
+		assert.Equal(t, "One", doc.TOC[0].Title)
+		assert.Len(t, doc.TOC[0].Children, 2)
+		assert.Equal(t, "Two code", doc.TOC[0].Children[0].Title)
+		assert.Equal(t, "three", doc.TOC[0].Children[0].Children[0].ID)
+		assert.Equal(t, "Five", doc.TOC[1].Title)
+	})
+
+	t.Run("Plain Text Is Escaped", func(t *testing.T) {
+		post := &models.Post{ID: "3", Content: "<b>hi</b>\n\nsecond"}
+
+		doc, err := renderer.Render(post)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>&lt;b&gt;hi&lt;/b&gt;</p>\n<p>second</p>\n", doc.HTML)
+	})
+
+	t.Run("Cached Per Content", func(t *testing.T) {
+		post := &models.Post{ID: "4", Version: 1, ContentFormat: models.ContentFormatMarkdown, Content: "old"}
+		first, err := renderer.Render(post)
+		assert.NoError(t, err)
+
+		post.Version = 2
+		cached, err := renderer.Render(post)
+		assert.NoError(t, err)
+		assert.Same(t, first, cached)
+
+		// An import overwrites the content without bumping the version.
+		post.Content = "new"
+		fresh, err := renderer.Render(post)
+		assert.NoError(t, err)
+		assert.Contains(t, fresh.HTML, "new")
+	})
+
+	t.Run("Raw HTML Is Sanitized", func(t *testing.T) {
+		post := &models.Post{
+			ID:            "6",
+			ContentFormat: models.ContentFormatMarkdown,
+			Content:       "Hello <span onclick=\"x()\">there</span>\n\n<script>alert(1)</script>\n",
+		}
+
+		doc, err := renderer.Render(post)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "<p>Hello <span>there</span></p>\n\n", doc.HTML)
+		assert.True(t, doc.Sanitization.Changed())
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		_, err := renderer.Render(&models.Post{ID: "5", ContentFormat: "rtf"})
+		assert.Error(t, err)
+	})
+}
+
+func TestLRUCacheEviction(t *testing.T) {
+	cache := newLRUCache(2)
+	cache.add("a", &Document{HTML: "a"})
+	cache.add("b", &Document{HTML: "b"})
+	cache.get("a")
+	cache.add("c", &Document{HTML: "c"})
+
+	_, ok := cache.get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/requestid"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// RequestError annotates a failed DynamoDB call with the API request that
+// issued it and the request ID DynamoDB assigned to the call.
+type RequestError struct {
+	Operation    string
+	RequestID    string
+	AWSRequestID string
+	Err          error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("request_id=%s aws_request_id=%s: %v", e.RequestID, e.AWSRequestID, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// WithRequestCorrelation is a dynamodb.Options function that wraps every
+// failed call in a *RequestError and logs it with the caller's request IDs.
+// Errors keep their original chain, so errors.As still finds service errors.
+func WithRequestCorrelation(o *dynamodb.Options) {
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RequestCorrelation", correlate), middleware.After)
+	})
+}
+
+func correlate(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	if err == nil {
+		return out, metadata, nil
+	}
+
+	reqErr := &RequestError{
+		Operation: awsmiddleware.GetOperationName(ctx),
+		RequestID: requestid.ID(ctx),
+		Err:       err,
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		reqErr.AWSRequestID = respErr.ServiceRequestID()
+	} else if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+		reqErr.AWSRequestID = id
+	}
+
+	logging.FromContext(ctx).Warn("dynamodb request failed",
+		"operation", reqErr.Operation,
+		"aws_request_id", reqErr.AWSRequestID,
+		"error", err,
+	)
+	return out, metadata, reqErr
+}
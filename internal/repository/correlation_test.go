@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"blog-api/internal/logging"
+	"blog-api/internal/requestid"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubDoer func(*http.Request) (*http.Response, error)
+
+func (f stubDoer) Do(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithRequestCorrelation(t *testing.T) {
+	client, _ := stubDynamoDB(t, http.StatusBadRequest,
+		`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"missing table"}`,
+		WithRequestCorrelation)
+
+	var logs bytes.Buffer
+	ctx := requestid.WithIDs(context.Background(), requestid.IDs{RequestID: "client-42"})
+	ctx = logging.WithLogger(ctx, slog.New(slog.NewJSONHandler(&logs, nil)))
+
+	_, err := client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("Posts"),
+		Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}},
+	})
+
+	var reqErr *RequestError
+	assert.True(t, errors.As(err, &reqErr))
+	assert.Equal(t, "client-42", reqErr.RequestID)
+	assert.Equal(t, "AWS-REQ-1", reqErr.AWSRequestID)
+	assert.Equal(t, "GetItem", reqErr.Operation)
+	assert.Contains(t, err.Error(), "request_id=client-42")
+
+	var notFound *types.ResourceNotFoundException
+	assert.True(t, errors.As(err, &notFound), "service error must stay reachable")
+
+	assert.Contains(t, logs.String(), `"aws_request_id":"AWS-REQ-1"`)
+	assert.Contains(t, logs.String(), `"operation":"GetItem"`)
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrInvalidCursor is returned for cursors this package did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageKey holds every attribute that can appear in a LastEvaluatedKey of the
+// posts, webhooks and outbox tables or their indexes.
+type pageKey struct {
+	ID        string `json:"id" dynamodbav:"ID"`
+	Status    string `json:"status,omitempty" dynamodbav:"Status,omitempty"`
+	Author    string `json:"author,omitempty" dynamodbav:"Author,omitempty"`
+	CreatedAt *int64 `json:"createdAt,omitempty" dynamodbav:"CreatedAt,omitempty"`
+	Kind      string `json:"kind,omitempty" dynamodbav:"Kind,omitempty"`
+	WebhookID string `json:"webhookId,omitempty" dynamodbav:"WebhookID,omitempty"`
+	Sequence  *int64 `json:"sequence,omitempty" dynamodbav:"Sequence,omitempty"`
+}
+
+// encodeCursor turns a LastEvaluatedKey into an opaque pagination cursor.
+func encodeCursor(key map[string]types.AttributeValue) (string, error) {
+	if key == nil {
+		return "", nil
+	}
+
+	var pk pageKey
+	if err := attributevalue.UnmarshalMap(key, &pk); err != nil {
+		return "", fmt.Errorf("failed to decode page key: %w", err)
+	}
+	raw, err := json.Marshal(pk)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor is the inverse of encodeCursor. An empty cursor yields a nil key.
+func decodeCursor(cursor string) (map[string]types.AttributeValue, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	var pk pageKey
+	if err := json.Unmarshal(raw, &pk); err != nil || pk.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return attributevalue.MarshalMap(pk)
+}
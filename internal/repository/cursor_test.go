@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	key := map[string]types.AttributeValue{
+		"ID":        &types.AttributeValueMemberS{Value: "abc"},
+		"Status":    &types.AttributeValueMemberS{Value: "published"},
+		"CreatedAt": &types.AttributeValueMemberN{Value: "1700000000"},
+	}
+
+	cursor, err := encodeCursor(key)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, cursor)
+
+	decoded, err := decodeCursor(cursor)
+	assert.NoError(t, err)
+	assert.Equal(t, key, decoded)
+}
+
+func TestCursorEdgeCases(t *testing.T) {
+	cursor, err := encodeCursor(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, cursor)
+
+	key, err := decodeCursor("")
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	_, err = decodeCursor("not base64!")
+	assert.Error(t, err)
+
+	_, err = decodeCursor("e30") // {}
+	assert.Error(t, err)
+}
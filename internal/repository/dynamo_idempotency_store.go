@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// idempotencyRetention is how long a key is remembered. Stream records are
+// retried for at most 24 hours, so keys outlive every retry.
+const idempotencyRetention = 48 * time.Hour
+
+// idempotencyTable is the layout of the idempotency table: one item per key,
+// expiring through TTL.
+var idempotencyTable = tableSpec{key: keySchema{Hash: "ID"}}
+
+// DynamoIdempotencyStore remembers processed keys in a DynamoDB table.
+type DynamoIdempotencyStore struct {
+	Client    *dynamodb.Client
+	TableName string
+	now       func() time.Time
+}
+
+func NewDynamoIdempotencyStore(client *dynamodb.Client, tableName string) *DynamoIdempotencyStore {
+	return &DynamoIdempotencyStore{Client: client, TableName: tableName, now: time.Now}
+}
+
+// EnsureTable creates the table and its TTL setting when they are missing.
+func (s *DynamoIdempotencyStore) EnsureTable(ctx context.Context) ([]string, error) {
+	return ensureTable(ctx, s.Client, s.TableName, idempotencyTable)
+}
+
+func (s *DynamoIdempotencyStore) CheckTable(ctx context.Context) error {
+	return checkTable(ctx, s.Client, s.TableName, idempotencyTable)
+}
+
+// Seen reports whether key was marked within the retention period.
+func (s *DynamoIdempotencyStore) Seen(ctx context.Context, key string) (bool, error) {
+	result, err := s.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(s.TableName),
+		Key:                  map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: key}},
+		ConsistentRead:       aws.Bool(true),
+		ProjectionExpression: aws.String(TTLAttribute),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get idempotency key %s: %w", key, err)
+	}
+	if result.Item == nil {
+		return false, nil
+	}
+	// TTL deletes expired items late, so they are checked here.
+	attr, ok := result.Item[TTLAttribute].(*types.AttributeValueMemberN)
+	if !ok {
+		return false, nil
+	}
+	expires, err := strconv.ParseInt(attr.Value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse expiry of idempotency key %s: %w", key, err)
+	}
+	return expires > s.now().Unix(), nil
+}
+
+// Mark remembers key for the retention period.
+func (s *DynamoIdempotencyStore) Mark(ctx context.Context, key string) error {
+	if _, err := s.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.TableName),
+		Item: map[string]types.AttributeValue{
+			"ID":         &types.AttributeValueMemberS{Value: key},
+			TTLAttribute: &types.AttributeValueMemberN{Value: fmt.Sprint(s.now().Add(idempotencyRetention).Unix())},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to put idempotency key %s: %w", key, err)
+	}
+	return nil
+}
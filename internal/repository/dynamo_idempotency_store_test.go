@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1760788800, 0)
+	store := func(t *testing.T, script map[string][]stubResponse) (*DynamoIdempotencyStore, map[string][]string) {
+		client, _, bodies := scriptedDynamoDB(t, script)
+		s := NewDynamoIdempotencyStore(client, "Idempotency")
+		s.now = func() time.Time { return now }
+		return s, bodies
+	}
+
+	t.Run("Unseen Key", func(t *testing.T) {
+		s, _ := store(t, map[string][]stubResponse{"GetItem": {{http.StatusOK, `{}`}}})
+
+		seen, err := s.Seen(ctx, "e1#log")
+
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("Seen Key", func(t *testing.T) {
+		s, _ := store(t, map[string][]stubResponse{"GetItem": {{http.StatusOK, `{"Item":{"ExpiresAt":{"N":"1760800000"}}}`}}})
+
+		seen, err := s.Seen(ctx, "e1#log")
+
+		require.NoError(t, err)
+		assert.True(t, seen)
+	})
+
+	t.Run("Expired Key", func(t *testing.T) {
+		s, _ := store(t, map[string][]stubResponse{"GetItem": {{http.StatusOK, `{"Item":{"ExpiresAt":{"N":"1760700000"}}}`}}})
+
+		seen, err := s.Seen(ctx, "e1#log")
+
+		require.NoError(t, err)
+		assert.False(t, seen)
+	})
+
+	t.Run("Mark", func(t *testing.T) {
+		s, bodies := store(t, map[string][]stubResponse{"PutItem": {{http.StatusOK, `{}`}}})
+
+		require.NoError(t, s.Mark(ctx, "e1#log"))
+
+		assert.Contains(t, bodies["PutItem"][0], `"ID":{"S":"e1#log"}`)
+		assert.Contains(t, bodies["PutItem"][0], `"ExpiresAt":{"N":"1760961600"}`)
+	})
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SequenceIndexName is the index of the outbox table keyed by Kind and sorted
+// by Sequence, which lists the messages in the order they were written.
+const SequenceIndexName = "SequenceIndex"
+
+// Kinds of outbox items. Messages that could not be published are kept as
+// kindDead, out of the way of the pending ones.
+const (
+	kindMessage = "message"
+	kindDead    = "dead"
+)
+
+// outboxTable is the layout of the outbox table.
+var outboxTable = tableSpec{
+	key: keySchema{Hash: "ID"},
+	indexes: []indexSpec{
+		{name: SequenceIndexName, key: keySchema{Hash: "Kind", Range: "Sequence"}},
+	},
+}
+
+// DynamoOutboxStore holds the outbox messages written by DynamoPostRepository
+// until they are published.
+type DynamoOutboxStore struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewDynamoOutboxStore(client *dynamodb.Client, tableName string) *DynamoOutboxStore {
+	return &DynamoOutboxStore{Client: client, TableName: tableName}
+}
+
+// EnsureTable creates the table and its index when they are missing.
+func (s *DynamoOutboxStore) EnsureTable(ctx context.Context) ([]string, error) {
+	return ensureTable(ctx, s.Client, s.TableName, outboxTable)
+}
+
+func (s *DynamoOutboxStore) CheckTable(ctx context.Context) error {
+	return checkTable(ctx, s.Client, s.TableName, outboxTable)
+}
+
+// PendingMessages returns up to limit messages, the oldest first, including
+// those claimed by a relay, starting after cursor. The returned cursor is
+// empty on the last page.
+func (s *DynamoOutboxStore) PendingMessages(ctx context.Context, limit int, cursor string) ([]*models.OutboxMessage, string, error) {
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	result, err := s.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(s.TableName),
+		IndexName:              aws.String(SequenceIndexName),
+		KeyConditionExpression: aws.String("Kind = :kind"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind": &types.AttributeValueMemberS{Value: kindMessage},
+		},
+		ExclusiveStartKey: startKey,
+		Limit:             aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query outbox messages: %w", err)
+	}
+
+	var messages []*models.OutboxMessage
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &messages); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal outbox messages: %w", err)
+	}
+	next, err := encodeCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return messages, next, nil
+}
+
+// ClaimMessage leases the message until the given time and counts the
+// attempt, unless another relay holds a lease that has not expired by now or
+// the message is gone. It reports whether the claim succeeded, and then sets
+// the message's lease and attempts.
+func (s *DynamoOutboxStore) ClaimMessage(ctx context.Context, message *models.OutboxMessage, now, until time.Time) (bool, error) {
+	result, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.TableName),
+		Key:                 map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: message.ID}},
+		UpdateExpression:    aws.String("SET LeaseUntil = :until ADD Attempts :one"),
+		ConditionExpression: aws.String("Kind = :kind AND (attribute_not_exists(LeaseUntil) OR LeaseUntil <= :now)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind":  &types.AttributeValueMemberS{Value: kindMessage},
+			":now":   &types.AttributeValueMemberN{Value: fmt.Sprint(now.Unix())},
+			":until": &types.AttributeValueMemberN{Value: fmt.Sprint(until.Unix())},
+			":one":   &types.AttributeValueMemberN{Value: "1"},
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	var failed *types.ConditionalCheckFailedException
+	switch {
+	case errors.As(err, &failed):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to claim outbox message with ID=%s: %w", message.ID, err)
+	}
+	if err := attributevalue.UnmarshalMap(result.Attributes, message); err != nil {
+		return false, fmt.Errorf("failed to unmarshal claimed outbox message with ID=%s: %w", message.ID, err)
+	}
+	return true, nil
+}
+
+// MarkDead keeps a message that will not be published any more, so that it
+// can be inspected, and takes it out of the pending messages.
+func (s *DynamoOutboxStore) MarkDead(ctx context.Context, id string) error {
+	if _, err := s.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.TableName),
+		Key:                 map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+		UpdateExpression:    aws.String("SET Kind = :dead REMOVE LeaseUntil"),
+		ConditionExpression: aws.String("attribute_exists(ID)"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":dead": &types.AttributeValueMemberS{Value: kindDead},
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to mark outbox message with ID=%s dead: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteMessage removes a published message.
+func (s *DynamoOutboxStore) DeleteMessage(ctx context.Context, id string) error {
+	if _, err := s.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(s.TableName),
+		Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+	}); err != nil {
+		return fmt.Errorf("failed to delete outbox message with ID=%s: %w", id, err)
+	}
+	return nil
+}
+
+// outboxPut is the transaction item recording message in the outbox table.
+func outboxPut(table string, message *models.OutboxMessage) (types.TransactWriteItem, error) {
+	item, err := marshalItem(kindMessage, message)
+	if err != nil {
+		return types.TransactWriteItem{}, fmt.Errorf("failed to marshal outbox message: %w", err)
+	}
+	return types.TransactWriteItem{Put: &types.Put{TableName: aws.String(table), Item: item}}, nil
+}
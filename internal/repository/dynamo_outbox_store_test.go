@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const versionConflict = `{"__type":"com.amazonaws.dynamodb.v20120810#TransactionCanceledException","message":"Transaction cancelled",` +
+	`"CancellationReasons":[{"Code":"ConditionalCheckFailed"},{"Code":"None"}]}`
+
+func TestPostRepositoryOutbox(t *testing.T) {
+	ctx := context.Background()
+	repository := func(t *testing.T, script map[string][]stubResponse) (*DynamoPostRepository, *[]string, map[string][]string) {
+		client, calls, bodies := scriptedDynamoDB(t, script)
+		repo := NewDynamoPostRepository(client, "Posts")
+		repo.OutboxTable = "Outbox"
+		return repo, calls, bodies
+	}
+	stored := `{"Item":{"ID":{"S":"p1"},"Title":{"S":"Hello"},"Version":{"N":"3"},"CreatedAt":{"N":"1760788800"}}}`
+
+	t.Run("Create Writes Message In Transaction", func(t *testing.T) {
+		repo, calls, bodies := repository(t, map[string][]stubResponse{"TransactWriteItems": {{http.StatusOK, `{}`}}})
+
+		post, err := repo.Create(ctx, &models.Post{ID: "p1", Title: "Hello", Content: "Body", Author: "ann"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), post.Version)
+		assert.Equal(t, []string{"TransactWriteItems"}, *calls)
+		body := bodies["TransactWriteItems"][0]
+		assert.Contains(t, body, `"TableName":"Posts"`)
+		assert.Contains(t, body, `"TableName":"Outbox"`)
+		assert.Contains(t, body, `"Kind":{"S":"message"}`)
+		assert.Contains(t, body, `"Type":{"S":"created"}`)
+		assert.Contains(t, body, `"PostID":{"S":"p1"}`)
+	})
+
+	t.Run("Update Retries Version Conflict", func(t *testing.T) {
+		repo, calls, bodies := repository(t, map[string][]stubResponse{
+			"GetItem":            {{http.StatusOK, stored}},
+			"TransactWriteItems": {{http.StatusBadRequest, versionConflict}, {http.StatusOK, `{}`}},
+		})
+
+		post, err := repo.Update(ctx, "p1", &models.Post{Title: "Hello again", Content: "Body", Author: "ann"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GetItem", "TransactWriteItems", "GetItem", "TransactWriteItems"}, *calls)
+		assert.Equal(t, "p1", post.ID)
+		assert.Equal(t, int64(4), post.Version)
+		assert.Equal(t, int64(1760788800), post.CreatedAt.Unix())
+		body := bodies["TransactWriteItems"][1]
+		assert.Contains(t, body, `"ConditionExpression":"Version = :seen"`)
+		assert.Contains(t, body, `":seen":{"N":"3"}`)
+		assert.Contains(t, body, `"Type":{"S":"updated"}`)
+	})
+
+	t.Run("Update Gives Up After Repeated Conflicts", func(t *testing.T) {
+		repo, _, _ := repository(t, map[string][]stubResponse{
+			"GetItem":            {{http.StatusOK, stored}},
+			"TransactWriteItems": {{http.StatusBadRequest, versionConflict}},
+		})
+
+		_, err := repo.Update(ctx, "p1", &models.Post{Title: "Hello again", Content: "Body", Author: "ann"})
+
+		assert.ErrorContains(t, err, "failed to update post with ID=p1")
+	})
+
+	t.Run("Update Missing Post", func(t *testing.T) {
+		repo, calls, _ := repository(t, map[string][]stubResponse{"GetItem": {{http.StatusOK, `{}`}}})
+
+		_, err := repo.Update(ctx, "p1", &models.Post{Title: "Hello again", Content: "Body", Author: "ann"})
+
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+		assert.Equal(t, []string{"GetItem"}, *calls)
+	})
+
+	t.Run("Delete Missing Post Records Nothing", func(t *testing.T) {
+		repo, _, bodies := repository(t, map[string][]stubResponse{
+			"TransactWriteItems": {{http.StatusBadRequest, versionConflict}},
+		})
+
+		require.NoError(t, repo.Delete(ctx, "p1"))
+		assert.Contains(t, bodies["TransactWriteItems"][0], `"ConditionExpression":"attribute_exists(ID)"`)
+	})
+
+	t.Run("BatchCreate Writes Messages In Transactions", func(t *testing.T) {
+		repo, calls, bodies := repository(t, map[string][]stubResponse{"TransactWriteItems": {{http.StatusOK, `{}`}}})
+		posts := make([]*models.Post, outboxBatchSize+1)
+		for i := range posts {
+			posts[i] = &models.Post{Title: "Hello", Content: "Body", Author: "ann"}
+		}
+
+		errs := repo.BatchCreate(ctx, posts)
+
+		assert.Equal(t, make([]error, len(posts)), errs)
+		assert.Equal(t, []string{"TransactWriteItems", "TransactWriteItems"}, *calls)
+		assert.Equal(t, outboxBatchSize, strings.Count(bodies["TransactWriteItems"][0], `"Type":{"S":"created"}`))
+		assert.Equal(t, 1, strings.Count(bodies["TransactWriteItems"][1], `"Type":{"S":"created"}`))
+		assert.Contains(t, bodies["TransactWriteItems"][1], `"PostID":{"S":"`+posts[outboxBatchSize].ID+`"}`)
+	})
+
+	t.Run("BatchCreate Fails Every Post Of A Failed Transaction", func(t *testing.T) {
+		repo, _, _ := repository(t, map[string][]stubResponse{"TransactWriteItems": {{http.StatusBadRequest, versionConflict}}})
+
+		errs := repo.BatchCreate(ctx, []*models.Post{{ID: "p1"}, {ID: "p2"}})
+
+		assert.ErrorContains(t, errs[0], "failed to write posts in transaction")
+		assert.ErrorContains(t, errs[1], "failed to write posts in transaction")
+	})
+
+	t.Run("TransactDelete Writes Messages", func(t *testing.T) {
+		repo, _, bodies := repository(t, map[string][]stubResponse{"TransactWriteItems": {{http.StatusOK, `{}`}}})
+
+		require.NoError(t, repo.TransactDelete(ctx, []string{"p1", "p2"}))
+		body := bodies["TransactWriteItems"][0]
+		assert.Equal(t, 2, strings.Count(body, `"Type":{"S":"deleted"}`))
+		assert.Contains(t, body, `"PostID":{"S":"p2"}`)
+	})
+
+	t.Run("TransactDelete Reports Missing Posts", func(t *testing.T) {
+		repo, _, _ := repository(t, map[string][]stubResponse{"TransactWriteItems": {{http.StatusBadRequest, versionConflict}}})
+
+		err := repo.TransactDelete(ctx, []string{"p1", "p2"})
+
+		var missing *custom_errors.MissingIDsError
+		require.ErrorAs(t, err, &missing)
+		assert.Equal(t, []string{"p1"}, missing.IDs)
+	})
+
+	t.Run("TransactDelete Fits Messages In The Transaction", func(t *testing.T) {
+		repo, calls, _ := repository(t, nil)
+
+		err := repo.TransactDelete(ctx, make([]string, outboxBatchSize+1))
+
+		assert.ErrorIs(t, err, custom_errors.ErrTooManyIDs)
+		assert.Empty(t, *calls)
+	})
+
+	t.Run("BatchDelete Retries Without Missing Posts", func(t *testing.T) {
+		repo, calls, bodies := repository(t, map[string][]stubResponse{
+			"TransactWriteItems": {{http.StatusBadRequest, versionConflict}, {http.StatusOK, `{}`}},
+		})
+
+		errs := repo.BatchDelete(ctx, []string{"p1", "p2"})
+
+		assert.ErrorIs(t, errs[0], custom_errors.ErrNotFound)
+		assert.NoError(t, errs[1])
+		assert.Equal(t, []string{"TransactWriteItems", "TransactWriteItems"}, *calls)
+		retried := bodies["TransactWriteItems"][1]
+		assert.NotContains(t, retried, `"p1"`)
+		assert.Equal(t, 1, strings.Count(retried, `"Type":{"S":"deleted"}`))
+	})
+}
+
+func TestOutboxStore(t *testing.T) {
+	ctx := context.Background()
+	now, until := time.Unix(100, 0), time.Unix(130, 0)
+
+	t.Run("Pending Messages", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"Query": {
+				{http.StatusOK, `{"Items":[{"ID":{"S":"m1"},"Kind":{"S":"message"},"Type":{"S":"deleted"},"PostID":{"S":"p1"},"CreatedAt":{"N":"100"},"Sequence":{"N":"100000000000"}}],` +
+					`"LastEvaluatedKey":{"ID":{"S":"m1"},"Kind":{"S":"message"},"Sequence":{"N":"100000000000"}}}`},
+				{http.StatusOK, `{"Items":[]}`},
+			},
+		})
+		store := NewDynamoOutboxStore(client, "Outbox")
+
+		messages, cursor, err := store.PendingMessages(ctx, 25, "")
+
+		require.NoError(t, err)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "m1", messages[0].ID)
+		assert.Equal(t, "p1", messages[0].PostID)
+		assert.Nil(t, messages[0].Post)
+		assert.Contains(t, bodies["Query"][0], `"IndexName":"SequenceIndex"`)
+		require.NotEmpty(t, cursor)
+
+		messages, cursor, err = store.PendingMessages(ctx, 25, cursor)
+
+		require.NoError(t, err)
+		assert.Empty(t, messages)
+		assert.Empty(t, cursor)
+		assert.Contains(t, bodies["Query"][1], `"ExclusiveStartKey":{`)
+		assert.Contains(t, bodies["Query"][1], `"Sequence":{"N":"100000000000"}`)
+	})
+
+	t.Run("Claim Message", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"UpdateItem": {{http.StatusOK, `{"Attributes":{"LeaseUntil":{"N":"130"},"Attempts":{"N":"3"}}}`}},
+		})
+		message := &models.OutboxMessage{ID: "m1", PostID: "p1"}
+
+		claimed, err := NewDynamoOutboxStore(client, "Outbox").ClaimMessage(ctx, message, now, until)
+
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Contains(t, bodies["UpdateItem"][0], `":until":{"N":"130"}`)
+		assert.Contains(t, bodies["UpdateItem"][0], `ADD Attempts :one`)
+		assert.Equal(t, 3, message.Attempts)
+		assert.Equal(t, until.Unix(), message.LeaseUntil.Unix())
+		assert.Equal(t, "p1", message.PostID)
+	})
+
+	t.Run("Claim Message Leased By Another Relay", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{"UpdateItem": {{http.StatusBadRequest, conditionFailed}}})
+
+		claimed, err := NewDynamoOutboxStore(client, "Outbox").ClaimMessage(ctx, &models.OutboxMessage{ID: "m1"}, now, until)
+
+		require.NoError(t, err)
+		assert.False(t, claimed)
+	})
+
+	t.Run("Mark Dead", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{"UpdateItem": {{http.StatusOK, `{}`}}})
+
+		require.NoError(t, NewDynamoOutboxStore(client, "Outbox").MarkDead(ctx, "m1"))
+		assert.Contains(t, bodies["UpdateItem"][0], `":dead":{"S":"dead"}`)
+	})
+}
@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"blog-api/internal/custom_errors"
+	postevents "blog-api/internal/events"
 	"blog-api/internal/models"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -13,9 +17,93 @@ import (
 	"github.com/google/uuid"
 )
 
+// Global secondary indexes on the posts table.
+const (
+	// StatusIndexName is keyed by Status and sorted by CreatedAt.
+	StatusIndexName = "StatusIndex"
+	// AuthorIndexName is keyed by Author and sorted by CreatedAt.
+	AuthorIndexName = "AuthorIndex"
+)
+
+// postAttributes maps the JSON name of every post field to its attribute.
+var postAttributes = map[string]string{
+	"id":            "ID",
+	"title":         "Title",
+	"content":       "Content",
+	"contentFormat": "ContentFormat",
+	"author":        "Author",
+	"tags":          "Tags",
+	"status":        "Status",
+	"version":       "Version",
+	"createdAt":     "CreatedAt",
+	"updatedAt":     "UpdatedAt",
+}
+
+// postProjection and projectionNames read every attribute of a post.
+var postProjection, projectionNames = mustProjection(models.PostFieldNames)
+
+// projection builds the ProjectionExpression reading the given post fields,
+// or all of them when there are none. ID is always read, so that an existing
+// post is never returned empty. Attributes are referenced through names like
+// #Status, as some of them are reserved words.
+func projection(fields []string) (string, map[string]string, error) {
+	if len(fields) == 0 {
+		fields = models.PostFieldNames
+	}
+	names := map[string]string{"#ID": "ID"}
+	refs := []string{"#ID"}
+	for _, field := range fields {
+		attr, ok := postAttributes[field]
+		if !ok {
+			return "", nil, fmt.Errorf("unknown post field: %q", field)
+		}
+		if _, ok := names["#"+attr]; !ok {
+			names["#"+attr] = attr
+			refs = append(refs, "#"+attr)
+		}
+	}
+	return strings.Join(refs, ", "), names, nil
+}
+
+func mustProjection(fields []string) (string, map[string]string) {
+	expr, names, err := projection(fields)
+	if err != nil {
+		panic(err)
+	}
+	return expr, names
+}
+
+// skipSchemaItem filters the migration metadata item out of scans.
+const skipSchemaItem = "ID <> :schemaItem"
+
+var skipSchemaValues = map[string]types.AttributeValue{":schemaItem": &types.AttributeValueMemberS{Value: SchemaItemID}}
+
+const (
+	// batchWriteSize is the maximum number of items in a BatchWriteItem request.
+	batchWriteSize = 25
+	// MaxBatchGetSize is the maximum number of keys in a BatchGetItem request.
+	MaxBatchGetSize = 100
+	// MaxTransactionSize is the maximum number of items in a TransactWriteItems request.
+	MaxTransactionSize = 100
+	// outboxBatchSize is the number of posts written per transaction by the
+	// bulk operations when every write is paired with an outbox message.
+	outboxBatchSize = MaxTransactionSize / 2
+	// maxBatchAttempts bounds how often unprocessed batch items are retried.
+	maxBatchAttempts = 5
+	// batchRetryBaseDelay is the first backoff delay between batch retries.
+	batchRetryBaseDelay = 50 * time.Millisecond
+	// maxUpdateAttempts bounds how often an update through the outbox is
+	// retried when the post changed between reading and writing it.
+	maxUpdateAttempts = 3
+)
+
 type DynamoPostRepository struct {
 	Client    *dynamodb.Client
 	TableName string
+	// OutboxTable, when set, receives a message of every change in the same
+	// transaction as the change. The bulk operations then write posts in
+	// transactions of at most outboxBatchSize posts and their messages.
+	OutboxTable string
 }
 
 func NewDynamoPostRepository(client *dynamodb.Client, tableName string) *DynamoPostRepository {
@@ -25,14 +113,19 @@ func NewDynamoPostRepository(client *dynamodb.Client, tableName string) *DynamoP
 	}
 }
 
-// GetAll returns a paginated list of posts using a scan.
+// GetAll returns a paginated list of posts using a scan, reading only the
+// given fields when there are any.
 //
 // Note: This approach is not ideal for large datasets due to performance implications.
 // For large data sets, consider using key-based queries and LastEvaluatedKey for pagination.
-func (r *DynamoPostRepository) GetAll(ctx context.Context, page, limit int) ([]*models.Post, error) {
+func (r *DynamoPostRepository) GetAll(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error) {
 	if page <= 0 || limit <= 0 {
 		return nil, fmt.Errorf("invalid pagination parameters: page=%d, limit=%d", page, limit)
 	}
+	expr, names, err := projection(fields)
+	if err != nil {
+		return nil, err
+	}
 
 	itemsToSkip := (page - 1) * limit
 	var (
@@ -42,10 +135,13 @@ func (r *DynamoPostRepository) GetAll(ctx context.Context, page, limit int) ([]*
 
 	for {
 		input := &dynamodb.ScanInput{
-			TableName:            aws.String(r.TableName),
-			ExclusiveStartKey:    lastEvaluatedKey,
-			Limit:                aws.Int32(int32(limit)),
-			ProjectionExpression: aws.String("ID, Title, Content, Author, CreatedAt"),
+			TableName:                 aws.String(r.TableName),
+			ExclusiveStartKey:         lastEvaluatedKey,
+			Limit:                     aws.Int32(int32(limit)),
+			ProjectionExpression:      aws.String(expr),
+			FilterExpression:          aws.String(skipSchemaItem),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: skipSchemaValues,
 		}
 
 		result, err := r.Client.Scan(ctx, input)
@@ -81,24 +177,35 @@ func (r *DynamoPostRepository) GetAll(ctx context.Context, page, limit int) ([]*
 }
 
 func (r *DynamoPostRepository) GetByID(ctx context.Context, id string) (*models.Post, error) {
+	return r.GetFields(ctx, id, nil)
+}
+
+// GetFields returns a post with only the given fields read, or every field
+// when there are none.
+func (r *DynamoPostRepository) GetFields(ctx context.Context, id string, fields []string) (*models.Post, error) {
 	if id == "" {
 		return nil, errors.New("id cannot be empty")
 	}
+	expr, names, err := projection(fields)
+	if err != nil {
+		return nil, err
+	}
 
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.TableName),
 		Key: map[string]types.AttributeValue{
 			"ID": &types.AttributeValueMemberS{Value: id},
 		},
-		ProjectionExpression: aws.String("ID, Title, Content, Author, CreatedAt"),
+		ProjectionExpression:     aws.String(expr),
+		ExpressionAttributeNames: names,
 	}
 
 	result, err := r.Client.GetItem(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get post by ID=%s: %w", id, err)
 	}
-	if result.Item == nil {
-		return nil, fmt.Errorf("post with ID=%s not found", id)
+	if result.Item == nil || id == SchemaItemID {
+		return nil, fmt.Errorf("post with ID=%s: %w", id, custom_errors.ErrNotFound)
 	}
 
 	var post models.Post
@@ -117,12 +224,24 @@ func (r *DynamoPostRepository) Create(ctx context.Context, post *models.Post) (*
 	if post.ID == "" {
 		post.ID = generateUniqueID()
 	}
+	now := time.Now().UTC()
+	post.Version = 1
+	post.CreatedAt = now
+	post.UpdatedAt = now
 
 	item, err := attributevalue.MarshalMap(post)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal post: %w", err)
 	}
 
+	if r.OutboxTable != "" {
+		put := types.TransactWriteItem{Put: &types.Put{TableName: aws.String(r.TableName), Item: item}}
+		if err := r.writeWithOutbox(ctx, put, postevents.TypeCreated, post.ID, post); err != nil {
+			return nil, fmt.Errorf("failed to create post with ID=%s: %w", post.ID, err)
+		}
+		return post, nil
+	}
+
 	input := &dynamodb.PutItemInput{
 		TableName: aws.String(r.TableName),
 		Item:      item,
@@ -143,19 +262,27 @@ func (r *DynamoPostRepository) Update(ctx context.Context, id string, updatedPos
 		return nil, errors.New("updated post cannot be nil")
 	}
 
+	if r.OutboxTable != "" {
+		return r.updateWithOutbox(ctx, id, updatedPost)
+	}
+
+	values, err := updateValues(updatedPost, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tags for post with ID=%s: %w", id, err)
+	}
+	values[":zero"] = &types.AttributeValueMemberN{Value: "0"}
+	values[":one"] = &types.AttributeValueMemberN{Value: "1"}
+
 	// Use UpdateItem to only change the necessary fields.
 	input := &dynamodb.UpdateItemInput{
 		TableName: aws.String(r.TableName),
 		Key: map[string]types.AttributeValue{
 			"ID": &types.AttributeValueMemberS{Value: id},
 		},
-		UpdateExpression: aws.String("SET Title = :title, Content = :content, Author = :author"),
-		ExpressionAttributeValues: map[string]types.AttributeValue{
-			":title":   &types.AttributeValueMemberS{Value: updatedPost.Title},
-			":content": &types.AttributeValueMemberS{Value: updatedPost.Content},
-			":author":  &types.AttributeValueMemberS{Value: updatedPost.Author},
-		},
-		ReturnValues: types.ReturnValueAllNew,
+		UpdateExpression:          aws.String(updateFields + ", Version = if_not_exists(Version, :zero) + :one"),
+		ExpressionAttributeNames:  map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
 	}
 
 	result, err := r.Client.UpdateItem(ctx, input)
@@ -171,11 +298,107 @@ func (r *DynamoPostRepository) Update(ctx context.Context, id string, updatedPos
 	return &post, nil
 }
 
+// updateFields is the part of the update expression setting the fields of
+// updateValues.
+const updateFields = "SET Title = :title, Content = :content, ContentFormat = :contentFormat, Author = :author, " +
+	"Tags = :tags, #status = :status, UpdatedAt = :now"
+
+func updateValues(post *models.Post, now time.Time) (map[string]types.AttributeValue, error) {
+	tags, err := attributevalue.Marshal(post.Tags)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]types.AttributeValue{
+		":title":         &types.AttributeValueMemberS{Value: post.Title},
+		":content":       &types.AttributeValueMemberS{Value: post.Content},
+		":contentFormat": &types.AttributeValueMemberS{Value: post.Format()},
+		":author":        &types.AttributeValueMemberS{Value: post.Author},
+		":tags":          tags,
+		":status":        &types.AttributeValueMemberS{Value: post.Status},
+		":now":           &types.AttributeValueMemberN{Value: fmt.Sprint(now.Unix())},
+	}, nil
+}
+
+// updateWithOutbox updates the post and records the outcome in the outbox.
+// A transaction returns no attributes, so the post is read first and written
+// on condition that its version is unchanged, retrying when it was not.
+func (r *DynamoPostRepository) updateWithOutbox(ctx context.Context, id string, updatedPost *models.Post) (*models.Post, error) {
+	for attempt := 1; ; attempt++ {
+		result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:                aws.String(r.TableName),
+			Key:                      map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+			ProjectionExpression:     aws.String(postProjection),
+			ExpressionAttributeNames: projectionNames,
+			ConsistentRead:           aws.Bool(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get post by ID=%s: %w", id, err)
+		}
+		if result.Item == nil || id == SchemaItemID {
+			return nil, fmt.Errorf("post with ID=%s: %w", id, custom_errors.ErrNotFound)
+		}
+		var current models.Post
+		if err := attributevalue.UnmarshalMap(result.Item, &current); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal post with ID=%s: %w", id, err)
+		}
+
+		now := time.Now()
+		values, err := updateValues(updatedPost, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal tags for post with ID=%s: %w", id, err)
+		}
+		values[":version"] = &types.AttributeValueMemberN{Value: fmt.Sprint(current.Version + 1)}
+		condition := "attribute_not_exists(Version)"
+		if current.Version != 0 {
+			condition = "Version = :seen"
+			values[":seen"] = &types.AttributeValueMemberN{Value: fmt.Sprint(current.Version)}
+		}
+		update := types.TransactWriteItem{Update: &types.Update{
+			TableName:                 aws.String(r.TableName),
+			Key:                       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+			UpdateExpression:          aws.String(updateFields + ", Version = :version"),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  map[string]string{"#status": "Status"},
+			ExpressionAttributeValues: values,
+		}}
+
+		post := *updatedPost
+		post.ID = id
+		post.ContentFormat = updatedPost.Format()
+		post.Version = current.Version + 1
+		post.CreatedAt = current.CreatedAt
+		post.UpdatedAt = time.Unix(now.Unix(), 0).UTC()
+
+		err = r.writeWithOutbox(ctx, update, postevents.TypeUpdated, id, &post)
+		switch {
+		case err == nil:
+			return &post, nil
+		case canceledByCondition(err, 0) && attempt < maxUpdateAttempts:
+			continue
+		default:
+			return nil, fmt.Errorf("failed to update post with ID=%s: %w", id, err)
+		}
+	}
+}
+
 func (r *DynamoPostRepository) Delete(ctx context.Context, id string) error {
 	if id == "" {
 		return errors.New("id cannot be empty")
 	}
 
+	if r.OutboxTable != "" {
+		// Deleting a missing post records nothing.
+		del := types.TransactWriteItem{Delete: &types.Delete{
+			TableName:           aws.String(r.TableName),
+			Key:                 map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+			ConditionExpression: aws.String("attribute_exists(ID)"),
+		}}
+		if err := r.writeWithOutbox(ctx, del, postevents.TypeDeleted, id, nil); err != nil && !canceledByCondition(err, 0) {
+			return fmt.Errorf("failed to delete post with ID=%s: %w", id, err)
+		}
+		return nil
+	}
+
 	input := &dynamodb.DeleteItemInput{
 		TableName: aws.String(r.TableName),
 		Key: map[string]types.AttributeValue{
@@ -190,6 +413,532 @@ func (r *DynamoPostRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// ListPublished returns up to limit published posts matching filter, newest
+// first, starting after cursor. The returned cursor is empty on the last page.
+//
+// Posts are read from StatusIndex, or from AuthorIndex when filtering by author,
+// so only published posts are ever touched. Tag filtering is applied as a
+// FilterExpression on top of the index query.
+func (r *DynamoPostRepository) ListPublished(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		IndexName:                aws.String(StatusIndexName),
+		KeyConditionExpression:   aws.String("#Status = :published"),
+		ExpressionAttributeNames: projectionNames,
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":published": &types.AttributeValueMemberS{Value: models.StatusPublished},
+		},
+		ProjectionExpression: aws.String(postProjection),
+		ScanIndexForward:     aws.Bool(false),
+		ExclusiveStartKey:    startKey,
+	}
+
+	var filters []string
+	if filter.Author != "" {
+		input.IndexName = aws.String(AuthorIndexName)
+		input.KeyConditionExpression = aws.String("Author = :author")
+		input.ExpressionAttributeValues[":author"] = &types.AttributeValueMemberS{Value: filter.Author}
+		filters = append(filters, "#Status = :published")
+	}
+	if filter.Tag != "" {
+		input.ExpressionAttributeValues[":tag"] = &types.AttributeValueMemberS{Value: filter.Tag}
+		filters = append(filters, "contains(Tags, :tag)")
+	}
+	if len(filters) > 0 {
+		input.FilterExpression = aws.String(strings.Join(filters, " AND "))
+	}
+
+	var posts []*models.Post
+	for {
+		// Never read past the page, so that LastEvaluatedKey is a valid cursor.
+		input.Limit = aws.Int32(int32(limit - len(posts)))
+
+		result, err := r.Client.Query(ctx, input)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to query published posts: %w", err)
+		}
+
+		var batch []*models.Post
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &batch); err != nil {
+			return nil, "", fmt.Errorf("failed to unmarshal published posts batch: %w", err)
+		}
+		posts = append(posts, batch...)
+
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+		if result.LastEvaluatedKey == nil || len(posts) >= limit {
+			break
+		}
+	}
+
+	next, err := encodeCursor(input.ExclusiveStartKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, next, nil
+}
+
+// ListPage returns up to limit posts of any status in table order, starting
+// after cursor. The returned cursor is empty on the last page. A page may be
+// one short when it passes the schema item.
+func (r *DynamoPostRepository) ListPage(ctx context.Context, limit int, cursor string) ([]*models.Post, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit: %d", limit)
+	}
+
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := r.Client.Scan(ctx, &dynamodb.ScanInput{
+		TableName:                 aws.String(r.TableName),
+		ExclusiveStartKey:         startKey,
+		Limit:                     aws.Int32(int32(limit)),
+		ProjectionExpression:      aws.String(postProjection),
+		FilterExpression:          aws.String(skipSchemaItem),
+		ExpressionAttributeNames:  projectionNames,
+		ExpressionAttributeValues: skipSchemaValues,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to scan posts: %w", err)
+	}
+
+	var posts []*models.Post
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &posts); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal posts page: %w", err)
+	}
+
+	next, err := encodeCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return posts, next, nil
+}
+
+// BatchCreate stores posts with BatchWriteItem in chunks of 25, retrying
+// unprocessed items with exponential backoff, or in transactions with their
+// outbox messages when there is an outbox. Existing posts with the same ID
+// are overwritten. The returned slice holds, for each post, nil or the error
+// that kept it from being written.
+func (r *DynamoPostRepository) BatchCreate(ctx context.Context, posts []*models.Post) []error {
+	if r.OutboxTable != "" {
+		return r.batchCreateWithOutbox(ctx, posts)
+	}
+	errs := make([]error, len(posts))
+	now := time.Now().UTC()
+
+	for start := 0; start < len(posts); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(posts) {
+			end = len(posts)
+		}
+
+		pending := make(map[string]int, end-start) // post ID -> index in posts
+		requests := make([]types.WriteRequest, 0, end-start)
+		for i := start; i < end; i++ {
+			item, err := prepareImport(posts[i], now)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			pending[posts[i].ID] = i
+			requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: item}})
+		}
+
+		if len(requests) == 0 {
+			continue
+		}
+		unprocessed, err := r.batchWrite(ctx, requests)
+		if err != nil {
+			for _, i := range pending {
+				errs[i] = err
+			}
+			continue
+		}
+		for _, req := range unprocessed {
+			var key pageKey
+			if err := attributevalue.UnmarshalMap(req.PutRequest.Item, &key); err == nil {
+				errs[pending[key.ID]] = fmt.Errorf("post with ID=%s was not processed after %d attempts", key.ID, maxBatchAttempts)
+			}
+		}
+	}
+
+	return errs
+}
+
+// batchCreateWithOutbox stores posts in transactions of outboxBatchSize
+// posts, each paired with a created message. A failed transaction fails every
+// post in it.
+func (r *DynamoPostRepository) batchCreateWithOutbox(ctx context.Context, posts []*models.Post) []error {
+	errs := make([]error, len(posts))
+	now := time.Now().UTC()
+
+	for start := 0; start < len(posts); start += outboxBatchSize {
+		end := start + outboxBatchSize
+		if end > len(posts) {
+			end = len(posts)
+		}
+
+		var indexes []int
+		var writes, messages []types.TransactWriteItem
+		for i := start; i < end; i++ {
+			item, err := prepareImport(posts[i], now)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			message, err := r.outboxMessage(postevents.TypeCreated, posts[i].ID, posts[i], now, len(messages))
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+			indexes = append(indexes, i)
+			writes = append(writes, types.TransactWriteItem{Put: &types.Put{TableName: aws.String(r.TableName), Item: item}})
+			messages = append(messages, message)
+		}
+		if len(writes) == 0 {
+			continue
+		}
+
+		_, err := r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: append(writes, messages...)})
+		if err != nil {
+			for _, i := range indexes {
+				errs[i] = fmt.Errorf("failed to write posts in transaction: %w", err)
+			}
+		}
+	}
+
+	return errs
+}
+
+// prepareImport fills in the ID, version and timestamps a post lacks and
+// marshals it.
+func prepareImport(post *models.Post, now time.Time) (map[string]types.AttributeValue, error) {
+	if post.ID == "" {
+		post.ID = generateUniqueID()
+	}
+	if post.Version == 0 {
+		post.Version = 1
+	}
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = now
+	}
+	if post.UpdatedAt.IsZero() {
+		post.UpdatedAt = post.CreatedAt
+	}
+
+	item, err := attributevalue.MarshalMap(post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal post: %w", err)
+	}
+	return item, nil
+}
+
+// batchWrite sends requests and retries the unprocessed ones. It returns the
+// requests that were still unprocessed after the last attempt.
+func (r *DynamoPostRepository) batchWrite(ctx context.Context, requests []types.WriteRequest) ([]types.WriteRequest, error) {
+	delay := batchRetryBaseDelay
+	for attempt := 1; len(requests) > 0; attempt++ {
+		result, err := r.Client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{r.TableName: requests},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch write posts: %w", err)
+		}
+
+		requests = result.UnprocessedItems[r.TableName]
+		if len(requests) == 0 || attempt == maxBatchAttempts {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return requests, nil
+}
+
+// BatchGet fetches up to MaxBatchGetSize posts by ID with BatchGetItem,
+// retrying unprocessed keys. IDs that do not exist are absent from the result.
+func (r *DynamoPostRepository) BatchGet(ctx context.Context, ids []string) (map[string]*models.Post, error) {
+	if len(ids) > MaxBatchGetSize {
+		return nil, fmt.Errorf("too many IDs: %d, at most %d allowed", len(ids), MaxBatchGetSize)
+	}
+
+	keys := make([]map[string]types.AttributeValue, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}})
+	}
+
+	posts := make(map[string]*models.Post, len(ids))
+	delay := batchRetryBaseDelay
+	for attempt := 1; len(keys) > 0; attempt++ {
+		result, err := r.Client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{
+				r.TableName: {
+					Keys:                     keys,
+					ProjectionExpression:     aws.String(postProjection),
+					ExpressionAttributeNames: projectionNames,
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to batch get posts: %w", err)
+		}
+
+		var batch []*models.Post
+		if err := attributevalue.UnmarshalListOfMaps(result.Responses[r.TableName], &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal posts batch: %w", err)
+		}
+		for _, post := range batch {
+			if post.ID != SchemaItemID {
+				posts[post.ID] = post
+			}
+		}
+
+		keys = result.UnprocessedKeys[r.TableName].Keys
+		if len(keys) == 0 {
+			break
+		}
+		if attempt == maxBatchAttempts {
+			return nil, fmt.Errorf("%d keys were not processed after %d attempts", len(keys), maxBatchAttempts)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+
+	return posts, nil
+}
+
+// TransactDelete deletes up to MaxTransactionSize posts atomically, or up to
+// outboxBatchSize with their outbox messages when there is an outbox. Larger
+// batches fail with custom_errors.ErrTooManyIDs. If any of the posts does not
+// exist nothing is deleted and a *custom_errors.MissingIDsError listing the
+// missing IDs is returned.
+func (r *DynamoPostRepository) TransactDelete(ctx context.Context, ids []string) error {
+	limit := MaxTransactionSize
+	if r.OutboxTable != "" {
+		limit = outboxBatchSize
+	}
+	if len(ids) > limit {
+		return fmt.Errorf("%w: %d, at most %d allowed", custom_errors.ErrTooManyIDs, len(ids), limit)
+	}
+
+	items, err := r.deleteItems(ids)
+	if err != nil {
+		return err
+	}
+	_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err == nil {
+		return nil
+	}
+	if missing := missingIDs(err, ids); len(missing) > 0 {
+		return &custom_errors.MissingIDsError{IDs: missing}
+	}
+	return fmt.Errorf("failed to delete posts in transaction: %w", err)
+}
+
+// deleteItems deletes the posts on condition that they exist, followed by
+// their deleted messages when there is an outbox.
+func (r *DynamoPostRepository) deleteItems(ids []string) ([]types.TransactWriteItem, error) {
+	items := make([]types.TransactWriteItem, 0, 2*len(ids))
+	for _, id := range ids {
+		items = append(items, types.TransactWriteItem{
+			Delete: &types.Delete{
+				TableName:           aws.String(r.TableName),
+				Key:                 map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+				ConditionExpression: aws.String("attribute_exists(ID)"),
+			},
+		})
+	}
+	if r.OutboxTable == "" {
+		return items, nil
+	}
+	now := time.Now().UTC()
+	for i, id := range ids {
+		message, err := r.outboxMessage(postevents.TypeDeleted, id, nil, now, i)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, message)
+	}
+	return items, nil
+}
+
+// missingIDs returns the IDs whose deletion by deleteItems was canceled
+// because the post does not exist.
+func missingIDs(err error, ids []string) []string {
+	var missing []string
+	for i, id := range ids {
+		if canceledByCondition(err, i) {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// BatchDelete deletes posts with BatchWriteItem in chunks of 25, retrying
+// unprocessed items. BatchWriteItem cannot delete on condition, so each chunk
+// is looked up first and posts that do not exist fail with
+// custom_errors.ErrNotFound; a post deleted in between is still reported as
+// deleted. With an outbox, posts are deleted in transactions with their
+// messages instead, and posts that do not exist fail with
+// custom_errors.ErrNotFound. The returned slice holds, for each ID, nil or
+// the error that kept it from being deleted.
+func (r *DynamoPostRepository) BatchDelete(ctx context.Context, ids []string) []error {
+	if r.OutboxTable != "" {
+		return r.batchDeleteWithOutbox(ctx, ids)
+	}
+	errs := make([]error, len(ids))
+
+	for start := 0; start < len(ids); start += batchWriteSize {
+		end := start + batchWriteSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		found, err := r.BatchGet(ctx, ids[start:end])
+		if err != nil {
+			for i := start; i < end; i++ {
+				errs[i] = err
+			}
+			continue
+		}
+
+		pending := make(map[string]int, end-start)
+		requests := make([]types.WriteRequest, 0, end-start)
+		for i := start; i < end; i++ {
+			if _, ok := found[ids[i]]; !ok {
+				errs[i] = fmt.Errorf("post with ID=%s: %w", ids[i], custom_errors.ErrNotFound)
+				continue
+			}
+			pending[ids[i]] = i
+			requests = append(requests, types.WriteRequest{DeleteRequest: &types.DeleteRequest{
+				Key: map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: ids[i]}},
+			}})
+		}
+
+		unprocessed, err := r.batchWrite(ctx, requests)
+		if err != nil {
+			for _, i := range pending {
+				errs[i] = err
+			}
+			continue
+		}
+		for _, req := range unprocessed {
+			var key pageKey
+			if err := attributevalue.UnmarshalMap(req.DeleteRequest.Key, &key); err == nil {
+				errs[pending[key.ID]] = fmt.Errorf("post with ID=%s was not processed after %d attempts", key.ID, maxBatchAttempts)
+			}
+		}
+	}
+
+	return errs
+}
+
+// batchDeleteWithOutbox deletes posts in transactions of outboxBatchSize
+// posts and their deleted messages. Posts that do not exist are taken out of
+// their transaction, which is then tried again without them.
+func (r *DynamoPostRepository) batchDeleteWithOutbox(ctx context.Context, ids []string) []error {
+	errs := make([]error, len(ids))
+
+	for start := 0; start < len(ids); start += outboxBatchSize {
+		end := start + outboxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		pending := make([]int, 0, end-start) // indexes in ids
+		for i := start; i < end; i++ {
+			pending = append(pending, i)
+		}
+		for len(pending) > 0 {
+			chunk := make([]string, len(pending))
+			for j, i := range pending {
+				chunk[j] = ids[i]
+			}
+			items, err := r.deleteItems(chunk)
+			if err == nil {
+				_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+			}
+			if err == nil {
+				break
+			}
+			if len(missingIDs(err, chunk)) == 0 {
+				for _, i := range pending {
+					errs[i] = fmt.Errorf("failed to delete posts in transaction: %w", err)
+				}
+				break
+			}
+
+			remaining := pending[:0]
+			for j, i := range pending {
+				if canceledByCondition(err, j) {
+					errs[i] = fmt.Errorf("post with ID=%s: %w", ids[i], custom_errors.ErrNotFound)
+				} else {
+					remaining = append(remaining, i)
+				}
+			}
+			pending = remaining
+		}
+	}
+
+	return errs
+}
+
+// writeWithOutbox applies write together with an outbox message of the
+// change, so that the message is recorded if and only if the change is.
+func (r *DynamoPostRepository) writeWithOutbox(ctx context.Context, write types.TransactWriteItem, eventType, id string, post *models.Post) error {
+	put, err := r.outboxMessage(eventType, id, post, time.Now().UTC(), 0)
+	if err != nil {
+		return err
+	}
+	_, err = r.Client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{write, put},
+	})
+	return err
+}
+
+// outboxMessage puts a message of a change at now into the outbox. Messages
+// written together are ordered by seq.
+func (r *DynamoPostRepository) outboxMessage(eventType, id string, post *models.Post, now time.Time, seq int) (types.TransactWriteItem, error) {
+	return outboxPut(r.OutboxTable, &models.OutboxMessage{
+		ID:        generateUniqueID(),
+		Type:      eventType,
+		PostID:    id,
+		Post:      post,
+		CreatedAt: now,
+		Sequence:  now.UnixNano() + int64(seq),
+	})
+}
+
+// canceledByCondition reports whether err is a transaction canceled by the
+// condition of its i-th item.
+func canceledByCondition(err error, i int) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || i >= len(canceled.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(canceled.CancellationReasons[i].Code) == "ConditionalCheckFailed"
+}
+
 func generateUniqueID() string {
 	return uuid.New().String()
 }
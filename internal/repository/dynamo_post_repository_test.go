@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjection(t *testing.T) {
+	t.Run("Reads The Given Fields And ID", func(t *testing.T) {
+		expr, names, err := projection([]string{"title", "status", "title"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "#ID, #Title, #Status", expr)
+		assert.Equal(t, map[string]string{"#ID": "ID", "#Title": "Title", "#Status": "Status"}, names)
+	})
+
+	t.Run("Reads Every Field By Default", func(t *testing.T) {
+		expr, _, err := projection(nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, postProjection, expr)
+		for _, field := range models.PostFieldNames {
+			assert.Contains(t, postAttributes, field)
+		}
+		assert.Len(t, projectionNames, len(models.PostFieldNames))
+	})
+
+	t.Run("Rejects Unknown Fields", func(t *testing.T) {
+		_, _, err := projection([]string{"id", "body"})
+
+		assert.ErrorContains(t, err, `unknown post field: "body"`)
+	})
+}
+
+// projectionRequest holds the parts of a read request that select attributes.
+type projectionRequest struct {
+	ProjectionExpression     string
+	ExpressionAttributeNames map[string]string
+	FilterExpression         string
+}
+
+func decodeProjection(t *testing.T, body string) projectionRequest {
+	t.Helper()
+	var req projectionRequest
+	require.NoError(t, json.Unmarshal([]byte(body), &req))
+	return req
+}
+
+func TestPostRepositoryFields(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("GetFields Projects The Fields", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"GetItem": {{http.StatusOK, `{"Item":{"ID":{"S":"p1"},"Title":{"S":"Hello"}}}`}},
+		})
+
+		post, err := NewDynamoPostRepository(client, "Posts").GetFields(ctx, "p1", []string{"title"})
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.Post{ID: "p1", Title: "Hello"}, post)
+		req := decodeProjection(t, bodies["GetItem"][0])
+		assert.Equal(t, "#ID, #Title", req.ProjectionExpression)
+		assert.Equal(t, map[string]string{"#ID": "ID", "#Title": "Title"}, req.ExpressionAttributeNames)
+	})
+
+	t.Run("GetAll Projects The Fields", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"Scan": {{http.StatusOK, `{"Items":[{"ID":{"S":"p1"},"Author":{"S":"ann"}}]}`}},
+		})
+
+		posts, err := NewDynamoPostRepository(client, "Posts").GetAll(ctx, 1, 10, []string{"author"})
+
+		require.NoError(t, err)
+		assert.Equal(t, []*models.Post{{ID: "p1", Author: "ann"}}, posts)
+		req := decodeProjection(t, bodies["Scan"][0])
+		assert.Equal(t, "#ID, #Author", req.ProjectionExpression)
+		assert.Equal(t, map[string]string{"#ID": "ID", "#Author": "Author"}, req.ExpressionAttributeNames)
+	})
+
+	t.Run("ListPublished Reads Every Field", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"Query": {{http.StatusOK, `{"Items":[]}`}},
+		})
+
+		_, _, err := NewDynamoPostRepository(client, "Posts").ListPublished(ctx, models.PostFilter{Author: "ann"}, 10, "")
+
+		require.NoError(t, err)
+		req := decodeProjection(t, bodies["Query"][0])
+		assert.Equal(t, postProjection, req.ProjectionExpression)
+		assert.Equal(t, "#Status = :published", req.FilterExpression)
+		for name, attr := range projectionNames {
+			assert.Equal(t, attr, req.ExpressionAttributeNames[name])
+		}
+	})
+}
+
+func TestPostRepositoryBatchDelete(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reports Missing Posts", func(t *testing.T) {
+		client, calls, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"BatchGetItem":   {{http.StatusOK, `{"Responses":{"Posts":[{"ID":{"S":"p2"}}]}}`}},
+			"BatchWriteItem": {{http.StatusOK, `{}`}},
+		})
+
+		errs := NewDynamoPostRepository(client, "Posts").BatchDelete(ctx, []string{"p1", "p2"})
+
+		assert.ErrorIs(t, errs[0], custom_errors.ErrNotFound)
+		assert.NoError(t, errs[1])
+		assert.Equal(t, []string{"BatchGetItem", "BatchWriteItem"}, *calls)
+		assert.NotContains(t, bodies["BatchWriteItem"][0], `"p1"`)
+	})
+
+	t.Run("Skips The Write When No Post Exists", func(t *testing.T) {
+		client, calls, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"BatchGetItem": {{http.StatusOK, `{"Responses":{"Posts":[]}}`}},
+		})
+
+		errs := NewDynamoPostRepository(client, "Posts").BatchDelete(ctx, []string{"p1"})
+
+		assert.ErrorIs(t, errs[0], custom_errors.ErrNotFound)
+		assert.Equal(t, []string{"BatchGetItem"}, *calls)
+	})
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Global secondary indexes on the webhooks table.
+const (
+	// KindIndexName is keyed by Kind and sorted by CreatedAt.
+	KindIndexName = "KindIndex"
+	// WebhookIndexName is keyed by WebhookID and sorted by CreatedAt. Only
+	// deliveries have a WebhookID.
+	WebhookIndexName = "WebhookIndex"
+	// DueIndexName is keyed by Status and sorted by NextAttemptAt. Only
+	// deliveries have them.
+	DueIndexName = "DueIndex"
+)
+
+// Values of the Kind attribute, which tells the items of the webhooks table
+// apart.
+const (
+	kindWebhook  = "webhook"
+	kindDelivery = "delivery"
+)
+
+// webhooksTable is the layout of the webhooks table.
+var webhooksTable = tableSpec{
+	key: keySchema{Hash: "ID"},
+	indexes: []indexSpec{
+		{name: KindIndexName, key: keySchema{Hash: "Kind", Range: "CreatedAt"}},
+		{name: WebhookIndexName, key: keySchema{Hash: "WebhookID", Range: "CreatedAt"}},
+		{name: DueIndexName, key: keySchema{Hash: "Status", Range: "NextAttemptAt"}},
+	},
+}
+
+// DynamoWebhookRepository stores webhooks and their deliveries in a table of
+// their own.
+type DynamoWebhookRepository struct {
+	Client    *dynamodb.Client
+	TableName string
+}
+
+func NewDynamoWebhookRepository(client *dynamodb.Client, tableName string) *DynamoWebhookRepository {
+	return &DynamoWebhookRepository{
+		Client:    client,
+		TableName: tableName,
+	}
+}
+
+// EnsureTable creates the table, its indexes and the TTL setting when they
+// are missing, like DynamoPostRepository.EnsureTable.
+func (r *DynamoWebhookRepository) EnsureTable(ctx context.Context) ([]string, error) {
+	return ensureTable(ctx, r.Client, r.TableName, webhooksTable)
+}
+
+// CheckTable verifies the table like DynamoPostRepository.CheckTable.
+func (r *DynamoWebhookRepository) CheckTable(ctx context.Context) error {
+	return checkTable(ctx, r.Client, r.TableName, webhooksTable)
+}
+
+func (r *DynamoWebhookRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	if webhook.ID == "" {
+		webhook.ID = generateUniqueID()
+	}
+	webhook.CreatedAt = time.Now().UTC()
+
+	item, err := marshalItem(kindWebhook, webhook)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(r.TableName),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(ID)"),
+	}); err != nil {
+		return fmt.Errorf("failed to create webhook with ID=%s: %w", webhook.ID, err)
+	}
+	return nil
+}
+
+// GetWebhook returns the webhook or an error matching
+// custom_errors.ErrNotFound.
+func (r *DynamoWebhookRepository) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.get(ctx, kindWebhook, id, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks returns every webhook, oldest first.
+func (r *DynamoWebhookRepository) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName),
+		IndexName:              aws.String(KindIndexName),
+		KeyConditionExpression: aws.String("Kind = :kind"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind": &types.AttributeValueMemberS{Value: kindWebhook},
+		},
+	}
+
+	var webhooks []*models.Webhook
+	for {
+		result, err := r.Client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query webhooks: %w", err)
+		}
+		var batch []*models.Webhook
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &batch); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal webhooks batch: %w", err)
+		}
+		webhooks = append(webhooks, batch...)
+
+		if result.LastEvaluatedKey == nil {
+			return webhooks, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// DeleteWebhook deletes the webhook or returns an error matching
+// custom_errors.ErrNotFound. Its deliveries stay until they expire.
+func (r *DynamoWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	_, err := r.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:           aws.String(r.TableName),
+		Key:                 map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+		ConditionExpression: aws.String("Kind = :kind"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":kind": &types.AttributeValueMemberS{Value: kindWebhook},
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	switch {
+	case errors.As(err, &failed):
+		return fmt.Errorf("webhook with ID=%s: %w", id, custom_errors.ErrNotFound)
+	case err != nil:
+		return fmt.Errorf("failed to delete webhook with ID=%s: %w", id, err)
+	}
+	return nil
+}
+
+// SaveDelivery creates or replaces a delivery.
+func (r *DynamoWebhookRepository) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.ID == "" {
+		delivery.ID = generateUniqueID()
+	}
+	item, err := marshalItem(kindDelivery, delivery)
+	if err != nil {
+		return fmt.Errorf("failed to marshal delivery: %w", err)
+	}
+	if _, err := r.Client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.TableName),
+		Item:      item,
+	}); err != nil {
+		return fmt.Errorf("failed to save delivery with ID=%s: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// GetDelivery returns the delivery or an error matching
+// custom_errors.ErrNotFound.
+func (r *DynamoWebhookRepository) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.get(ctx, kindDelivery, id, &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries returns up to limit deliveries of a webhook, newest first,
+// starting after cursor. The returned cursor is empty on the last page.
+func (r *DynamoWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int, cursor string) ([]*models.WebhookDelivery, string, error) {
+	if limit <= 0 {
+		return nil, "", fmt.Errorf("invalid limit: %d", limit)
+	}
+	startKey, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.TableName),
+		IndexName:              aws.String(WebhookIndexName),
+		KeyConditionExpression: aws.String("WebhookID = :webhook"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":webhook": &types.AttributeValueMemberS{Value: webhookID},
+		},
+		ScanIndexForward:  aws.Bool(false),
+		Limit:             aws.Int32(int32(limit)),
+		ExclusiveStartKey: startKey,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query deliveries of webhook with ID=%s: %w", webhookID, err)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &deliveries); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal deliveries page: %w", err)
+	}
+	next, err := encodeCursor(result.LastEvaluatedKey)
+	if err != nil {
+		return nil, "", err
+	}
+	return deliveries, next, nil
+}
+
+// DueDeliveries returns up to limit pending deliveries whose next attempt is
+// not after now, the longest waiting first.
+func (r *DynamoWebhookRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	result, err := r.Client.Query(ctx, &dynamodb.QueryInput{
+		TableName:                aws.String(r.TableName),
+		IndexName:                aws.String(DueIndexName),
+		KeyConditionExpression:   aws.String("#status = :pending AND NextAttemptAt <= :now"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: models.DeliveryPending},
+			":now":     &types.AttributeValueMemberN{Value: fmt.Sprint(now.Unix())},
+		},
+		Limit: aws.Int32(int32(limit)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query due deliveries: %w", err)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal due deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery postpones the next attempt of a pending delivery to until,
+// provided nobody else has changed it since it was read, so that only one
+// instance sends it. It reports whether the claim succeeded.
+func (r *DynamoWebhookRepository) ClaimDelivery(ctx context.Context, delivery *models.WebhookDelivery, until time.Time) (bool, error) {
+	_, err := r.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                aws.String(r.TableName),
+		Key:                      map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: delivery.ID}},
+		UpdateExpression:         aws.String("SET NextAttemptAt = :until"),
+		ConditionExpression:      aws.String("#status = :pending AND NextAttemptAt = :seen"),
+		ExpressionAttributeNames: map[string]string{"#status": "Status"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pending": &types.AttributeValueMemberS{Value: models.DeliveryPending},
+			":seen":    &types.AttributeValueMemberN{Value: fmt.Sprint(delivery.NextAttemptAt.Unix())},
+			":until":   &types.AttributeValueMemberN{Value: fmt.Sprint(until.Unix())},
+		},
+	})
+	var failed *types.ConditionalCheckFailedException
+	switch {
+	case errors.As(err, &failed):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to claim delivery with ID=%s: %w", delivery.ID, err)
+	}
+	delivery.NextAttemptAt = time.Unix(until.Unix(), 0).UTC()
+	return true, nil
+}
+
+// get reads the item with id into v, reporting items of another kind as not
+// found.
+func (r *DynamoWebhookRepository) get(ctx context.Context, kind, id string, v interface{}) error {
+	result, err := r.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(r.TableName),
+		Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: id}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get %s with ID=%s: %w", kind, id, err)
+	}
+	if stored, ok := result.Item["Kind"].(*types.AttributeValueMemberS); !ok || stored.Value != kind {
+		return fmt.Errorf("%s with ID=%s: %w", kind, id, custom_errors.ErrNotFound)
+	}
+	if err := attributevalue.UnmarshalMap(result.Item, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s with ID=%s: %w", kind, id, err)
+	}
+	return nil
+}
+
+// marshalItem marshals v and tags it with kind.
+func marshalItem(kind string, v interface{}) (map[string]types.AttributeValue, error) {
+	item, err := attributevalue.MarshalMap(v)
+	if err != nil {
+		return nil, err
+	}
+	item["Kind"] = &types.AttributeValueMemberS{Value: kind}
+	return item, nil
+}
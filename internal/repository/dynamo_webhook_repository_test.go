@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const conditionFailed = `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`
+
+func TestWebhookRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Get Webhook Ignores Deliveries", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"GetItem": {{http.StatusOK, `{"Item":{"ID":{"S":"d1"},"Kind":{"S":"delivery"}}}`}},
+		})
+		repo := NewDynamoWebhookRepository(client, "Webhooks")
+
+		_, err := repo.GetWebhook(ctx, "d1")
+
+		assert.ErrorIs(t, err, custom_errors.ErrNotFound)
+	})
+
+	t.Run("Get Webhook", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"GetItem": {{http.StatusOK, `{"Item":{"ID":{"S":"w1"},"Kind":{"S":"webhook"},"URL":{"S":"https://example.com"},"Events":{"L":[{"S":"post.created"}]},"CreatedAt":{"N":"1760788800"}}}`}},
+		})
+		repo := NewDynamoWebhookRepository(client, "Webhooks")
+
+		webhook, err := repo.GetWebhook(ctx, "w1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com", webhook.URL)
+		assert.Equal(t, []string{models.EventPostCreated}, webhook.Events)
+	})
+
+	t.Run("Claim Delivery", func(t *testing.T) {
+		client, _, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"UpdateItem": {{http.StatusOK, `{}`}},
+		})
+		repo := NewDynamoWebhookRepository(client, "Webhooks")
+		delivery := &models.WebhookDelivery{ID: "d1", NextAttemptAt: time.Unix(100, 0)}
+
+		claimed, err := repo.ClaimDelivery(ctx, delivery, time.Unix(120, 0))
+
+		require.NoError(t, err)
+		assert.True(t, claimed)
+		assert.Equal(t, int64(120), delivery.NextAttemptAt.Unix())
+		assert.Contains(t, bodies["UpdateItem"][0], `":seen":{"N":"100"}`)
+	})
+
+	t.Run("Claim Delivery Taken By Another Instance", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"UpdateItem": {{http.StatusBadRequest, conditionFailed}},
+		})
+		repo := NewDynamoWebhookRepository(client, "Webhooks")
+		delivery := &models.WebhookDelivery{ID: "d1", NextAttemptAt: time.Unix(100, 0)}
+
+		claimed, err := repo.ClaimDelivery(ctx, delivery, time.Unix(120, 0))
+
+		require.NoError(t, err)
+		assert.False(t, claimed)
+		assert.Equal(t, int64(100), delivery.NextAttemptAt.Unix())
+	})
+}
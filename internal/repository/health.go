@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// keySchema is a hash key and an optional range key.
+type keySchema struct {
+	Hash  string
+	Range string
+}
+
+// tableSpec is the primary key of a table and the global secondary indexes
+// its repository queries, in the order they are created, plus the view type
+// of its stream when it needs one.
+type tableSpec struct {
+	key     keySchema
+	indexes []indexSpec
+	stream  types.StreamViewType
+}
+
+type indexSpec struct {
+	name string
+	key  keySchema
+}
+
+// indexList names the indexes of s for messages, such as "A, B and C".
+func (s tableSpec) indexList() string {
+	names := make([]string, len(s.indexes))
+	for i, index := range s.indexes {
+		names[i] = index.name
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// postsTable is the layout of the posts table.
+var postsTable = tableSpec{
+	key: keySchema{Hash: "ID"},
+	indexes: []indexSpec{
+		{name: StatusIndexName, key: keySchema{Hash: "Status", Range: "CreatedAt"}},
+		{name: AuthorIndexName, key: keySchema{Hash: "Author", Range: "CreatedAt"}},
+	},
+	// The change data capture consumer decodes both images of every change.
+	stream: types.StreamViewTypeNewAndOldImages,
+}
+
+// CheckTable verifies that the table is reachable, ACTIVE, keyed by ID and
+// has every index the repository relies on, active and with the expected keys.
+func (r *DynamoPostRepository) CheckTable(ctx context.Context) error {
+	return checkTable(ctx, r.Client, r.TableName, postsTable)
+}
+
+func checkTable(ctx context.Context, client *dynamodb.Client, name string, spec tableSpec) error {
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	if err != nil {
+		return fmt.Errorf("failed to describe table %s: %w", name, err)
+	}
+	table := out.Table
+	if table.TableStatus != types.TableStatusActive {
+		return fmt.Errorf("table %s status is %s", name, table.TableStatus)
+	}
+	if got := schemaOf(table.KeySchema); got != spec.key {
+		return fmt.Errorf("table %s key schema is %+v, expected %+v", name, got, spec.key)
+	}
+
+	found := make(map[string]types.GlobalSecondaryIndexDescription, len(table.GlobalSecondaryIndexes))
+	for _, index := range table.GlobalSecondaryIndexes {
+		found[aws.ToString(index.IndexName)] = index
+	}
+	for _, want := range spec.indexes {
+		index, ok := found[want.name]
+		if !ok {
+			return fmt.Errorf("table %s has no index %s", name, want.name)
+		}
+		if index.IndexStatus != types.IndexStatusActive {
+			return fmt.Errorf("index %s status is %s", want.name, index.IndexStatus)
+		}
+		if got := schemaOf(index.KeySchema); got != want.key {
+			return fmt.Errorf("index %s key schema is %+v, expected %+v", want.name, got, want.key)
+		}
+	}
+	return nil
+}
+
+func schemaOf(elements []types.KeySchemaElement) keySchema {
+	var schema keySchema
+	for _, e := range elements {
+		switch e.KeyType {
+		case types.KeyTypeHash:
+			schema.Hash = aws.ToString(e.AttributeName)
+		case types.KeyTypeRange:
+			schema.Range = aws.ToString(e.AttributeName)
+		}
+	}
+	return schema
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const describeTableTemplate = `{"Table":{
+	"TableName":"Posts",
+	"TableStatus":"ACTIVE",
+	"KeySchema":[{"AttributeName":"ID","KeyType":"HASH"}],
+	"StreamSpecification":{"StreamEnabled":true,"StreamViewType":"NEW_AND_OLD_IMAGES"},
+	"GlobalSecondaryIndexes":[
+		{"IndexName":"StatusIndex","IndexStatus":"STATUS_INDEX_STATE","KeySchema":[{"AttributeName":"Status","KeyType":"HASH"},{"AttributeName":"CreatedAt","KeyType":"RANGE"}]},
+		{"IndexName":"AuthorIndex","IndexStatus":"ACTIVE","KeySchema":[{"AttributeName":"Author","KeyType":"HASH"},{"AttributeName":"CreatedAt","KeyType":"RANGE"}]}
+	]}}`
+
+func TestCheckTable(t *testing.T) {
+	check := func(t *testing.T, status int, body string) error {
+		client, _ := stubDynamoDB(t, status, body)
+		return NewDynamoPostRepository(client, "Posts").CheckTable(context.Background())
+	}
+	healthy := strings.Replace(describeTableTemplate, "STATUS_INDEX_STATE", "ACTIVE", 1)
+
+	t.Run("Healthy", func(t *testing.T) {
+		assert.NoError(t, check(t, http.StatusOK, healthy))
+	})
+
+	t.Run("Table Not Active", func(t *testing.T) {
+		err := check(t, http.StatusOK, strings.Replace(healthy, `"TableStatus":"ACTIVE"`, `"TableStatus":"CREATING"`, 1))
+		assert.EqualError(t, err, "table Posts status is CREATING")
+	})
+
+	t.Run("Wrong Key Schema", func(t *testing.T) {
+		err := check(t, http.StatusOK, strings.Replace(healthy, `"AttributeName":"ID"`, `"AttributeName":"PostID"`, 1))
+		assert.ErrorContains(t, err, "table Posts key schema")
+	})
+
+	t.Run("Index Missing", func(t *testing.T) {
+		err := check(t, http.StatusOK, strings.Replace(healthy, `"IndexName":"AuthorIndex"`, `"IndexName":"OtherIndex"`, 1))
+		assert.EqualError(t, err, "table Posts has no index AuthorIndex")
+	})
+
+	t.Run("Index Backfilling", func(t *testing.T) {
+		err := check(t, http.StatusOK, strings.Replace(describeTableTemplate, "STATUS_INDEX_STATE", "CREATING", 1))
+		assert.EqualError(t, err, "index StatusIndex status is CREATING")
+	})
+
+	t.Run("Table Unreachable", func(t *testing.T) {
+		err := check(t, http.StatusBadRequest,
+			`{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`)
+		assert.ErrorContains(t, err, "failed to describe table Posts")
+	})
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"blog-api/internal/metrics"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithMetrics returns a dynamodb.Options function that reports every
+// operation to recorder. It asks DynamoDB for the total consumed capacity on
+// each call that supports it.
+func WithMetrics(recorder metrics.Recorder) func(*dynamodb.Options) {
+	return func(o *dynamodb.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Metrics",
+				func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+					requestCapacity(in.Parameters)
+					start := time.Now()
+					out, metadata, err := next.HandleInitialize(ctx, in)
+
+					call := metrics.DynamoDBCall{
+						Operation: awsmiddleware.GetOperationName(ctx),
+						Route:     metrics.Route(ctx),
+						Duration:  time.Since(start),
+					}
+					if err != nil {
+						call.ErrorCode = errorCode(err)
+					} else {
+						call.CapacityUnits = consumedCapacity(out.Result)
+					}
+					recorder.DynamoDBCall(call)
+					return out, metadata, err
+				}), middleware.After)
+		})
+	}
+}
+
+func requestCapacity(input interface{}) {
+	total := types.ReturnConsumedCapacityTotal
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.PutItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.UpdateItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.DeleteItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.QueryInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.ScanInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.BatchGetItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.BatchWriteItemInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.TransactWriteItemsInput:
+		in.ReturnConsumedCapacity = total
+	case *dynamodb.TransactGetItemsInput:
+		in.ReturnConsumedCapacity = total
+	}
+}
+
+func consumedCapacity(output interface{}) float64 {
+	switch out := output.(type) {
+	case *dynamodb.GetItemOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.PutItemOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.UpdateItemOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.DeleteItemOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.QueryOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.ScanOutput:
+		return capacityUnits(out.ConsumedCapacity)
+	case *dynamodb.BatchGetItemOutput:
+		return totalCapacityUnits(out.ConsumedCapacity)
+	case *dynamodb.BatchWriteItemOutput:
+		return totalCapacityUnits(out.ConsumedCapacity)
+	case *dynamodb.TransactWriteItemsOutput:
+		return totalCapacityUnits(out.ConsumedCapacity)
+	case *dynamodb.TransactGetItemsOutput:
+		return totalCapacityUnits(out.ConsumedCapacity)
+	}
+	return 0
+}
+
+func capacityUnits(c *types.ConsumedCapacity) float64 {
+	if c == nil || c.CapacityUnits == nil {
+		return 0
+	}
+	return *c.CapacityUnits
+}
+
+func totalCapacityUnits(capacities []types.ConsumedCapacity) float64 {
+	var total float64
+	for i := range capacities {
+		total += capacityUnits(&capacities[i])
+	}
+	return total
+}
+
+// errorCode returns the AWS error code of err, "Canceled" or "Timeout" for
+// context errors, and "Unknown" otherwise.
+func errorCode(err error) string {
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.ErrorCode()
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"blog-api/internal/metrics"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type callRecorder struct {
+	metrics.Nop
+	calls []metrics.DynamoDBCall
+}
+
+func (r *callRecorder) DynamoDBCall(call metrics.DynamoDBCall) {
+	r.calls = append(r.calls, call)
+}
+
+func stubDynamoDB(t *testing.T, status int, body string, optFns ...func(*dynamodb.Options)) (*dynamodb.Client, *[]byte) {
+	t.Helper()
+	var sent []byte
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://dynamodb.test"),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		Retryer:      aws.NopRetryer{},
+		HTTPClient: stubDoer(func(r *http.Request) (*http.Response, error) {
+			sent, _ = io.ReadAll(r.Body)
+			return &http.Response{
+				StatusCode: status,
+				Header: http.Header{
+					"Content-Type":     {"application/x-amz-json-1.0"},
+					"X-Amzn-Requestid": {"AWS-REQ-1"},
+				},
+				Body:    io.NopCloser(bytes.NewBufferString(body)),
+				Request: r,
+			}, nil
+		}),
+	}, optFns...)
+	return client, &sent
+}
+
+func TestWithMetrics(t *testing.T) {
+	key := map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}}
+	ctx := metrics.WithRoute(context.Background(), "/v1/posts/{id}")
+
+	t.Run("Records Consumed Capacity", func(t *testing.T) {
+		recorder := &callRecorder{}
+		client, sent := stubDynamoDB(t, http.StatusOK,
+			`{"Item":{"ID":{"S":"1"}},"ConsumedCapacity":{"TableName":"Posts","CapacityUnits":0.5}}`,
+			WithMetrics(recorder))
+
+		_, err := client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("Posts"), Key: key})
+
+		assert.NoError(t, err)
+		assert.Contains(t, string(*sent), `"ReturnConsumedCapacity":"TOTAL"`)
+		assert.Len(t, recorder.calls, 1)
+		assert.Equal(t, "GetItem", recorder.calls[0].Operation)
+		assert.Equal(t, "/v1/posts/{id}", recorder.calls[0].Route)
+		assert.Equal(t, 0.5, recorder.calls[0].CapacityUnits)
+		assert.Empty(t, recorder.calls[0].ErrorCode)
+	})
+
+	t.Run("Sums Batch Capacity", func(t *testing.T) {
+		recorder := &callRecorder{}
+		client, _ := stubDynamoDB(t, http.StatusOK,
+			`{"Responses":{},"ConsumedCapacity":[{"TableName":"Posts","CapacityUnits":1},{"TableName":"Other","CapacityUnits":2.5}]}`,
+			WithMetrics(recorder))
+
+		_, err := client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{
+			RequestItems: map[string]types.KeysAndAttributes{"Posts": {Keys: []map[string]types.AttributeValue{key}}},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3.5, recorder.calls[0].CapacityUnits)
+	})
+
+	t.Run("Records Error Code", func(t *testing.T) {
+		recorder := &callRecorder{}
+		client, _ := stubDynamoDB(t, http.StatusBadRequest,
+			`{"__type":"com.amazonaws.dynamodb.v20120810#ProvisionedThroughputExceededException","message":"slow down"}`,
+			WithMetrics(recorder))
+
+		_, err := client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("Posts"), Key: key})
+
+		assert.Error(t, err)
+		assert.Equal(t, "ProvisionedThroughputExceededException", recorder.calls[0].ErrorCode)
+	})
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"blog-api/internal/models"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// TTLAttribute holds the expiry of items that DynamoDB deletes on its own.
+const TTLAttribute = "ExpiresAt"
+
+// SchemaItemID is the key of the item recording applied migrations. Scans
+// skip it, and posts are not allowed to use it.
+const SchemaItemID = models.ReservedPostID
+
+// provisionPollInterval is how often EnsureTable checks whether a new table
+// or index has become active.
+var provisionPollInterval = 2 * time.Second
+
+// attributeTypes lists the type of every key attribute of the tables and
+// their indexes.
+var attributeTypes = map[string]types.ScalarAttributeType{
+	"ID":            types.ScalarAttributeTypeS,
+	"Status":        types.ScalarAttributeTypeS,
+	"Author":        types.ScalarAttributeTypeS,
+	"CreatedAt":     types.ScalarAttributeTypeN,
+	"Kind":          types.ScalarAttributeTypeS,
+	"WebhookID":     types.ScalarAttributeTypeS,
+	"NextAttemptAt": types.ScalarAttributeTypeN,
+	"Sequence":      types.ScalarAttributeTypeN,
+}
+
+// EnsureTable creates the table, the indexes the repository queries and the
+// TTL setting when they are missing, waiting for each to become active. It
+// returns a description of every change made.
+func (r *DynamoPostRepository) EnsureTable(ctx context.Context) ([]string, error) {
+	return ensureTable(ctx, r.Client, r.TableName, postsTable)
+}
+
+func ensureTable(ctx context.Context, client *dynamodb.Client, name string, spec tableSpec) ([]string, error) {
+	var changes []string
+
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+	var notFound *types.ResourceNotFoundException
+	switch {
+	case errors.As(err, &notFound):
+		if err := createTable(ctx, client, name, spec); err != nil {
+			return changes, err
+		}
+		if len(spec.indexes) == 0 {
+			changes = append(changes, fmt.Sprintf("created table %s", name))
+		} else {
+			changes = append(changes, fmt.Sprintf("created table %s with indexes %s", name, spec.indexList()))
+		}
+	case err != nil:
+		return changes, fmt.Errorf("failed to describe table %s: %w", name, err)
+	default:
+		if got := schemaOf(out.Table.KeySchema); got != spec.key {
+			return changes, fmt.Errorf("table %s key schema is %+v, expected %+v", name, got, spec.key)
+		}
+		indexChanges, err := ensureIndexes(ctx, client, name, out.Table, spec)
+		changes = append(changes, indexChanges...)
+		if err != nil {
+			return changes, err
+		}
+		enabled, err := ensureStream(ctx, client, name, out.Table, spec)
+		if err != nil {
+			return changes, err
+		}
+		if enabled {
+			changes = append(changes, fmt.Sprintf("enabled stream with %s", spec.stream))
+		}
+	}
+
+	enabled, err := ensureTTL(ctx, client, name)
+	if err != nil {
+		return changes, err
+	}
+	if enabled {
+		changes = append(changes, fmt.Sprintf("enabled TTL on %s", TTLAttribute))
+	}
+	return changes, nil
+}
+
+func createTable(ctx context.Context, client *dynamodb.Client, name string, spec tableSpec) error {
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(name),
+		BillingMode: types.BillingModePayPerRequest,
+		KeySchema:   keySchemaElements(spec.key),
+	}
+	if spec.stream != "" {
+		input.StreamSpecification = &types.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: spec.stream}
+	}
+	schemas := []keySchema{spec.key}
+	for _, index := range spec.indexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, types.GlobalSecondaryIndex{
+			IndexName:  aws.String(index.name),
+			KeySchema:  keySchemaElements(index.key),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		})
+		schemas = append(schemas, index.key)
+	}
+	input.AttributeDefinitions = attributeDefinitions(schemas...)
+
+	if _, err := client.CreateTable(ctx, input); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", name, err)
+	}
+	return waitActive(ctx, client, name)
+}
+
+// ensureIndexes adds the missing indexes one at a time, as DynamoDB allows
+// only one index creation per UpdateTable call.
+func ensureIndexes(ctx context.Context, client *dynamodb.Client, name string, table *types.TableDescription, spec tableSpec) ([]string, error) {
+	existing := make(map[string]keySchema, len(table.GlobalSecondaryIndexes))
+	for _, index := range table.GlobalSecondaryIndexes {
+		existing[aws.ToString(index.IndexName)] = schemaOf(index.KeySchema)
+	}
+
+	var changes []string
+	for _, want := range spec.indexes {
+		if got, ok := existing[want.name]; ok {
+			if got != want.key {
+				return changes, fmt.Errorf("index %s key schema is %+v, expected %+v", want.name, got, want.key)
+			}
+			continue
+		}
+
+		index := &types.CreateGlobalSecondaryIndexAction{
+			IndexName:  aws.String(want.name),
+			KeySchema:  keySchemaElements(want.key),
+			Projection: &types.Projection{ProjectionType: types.ProjectionTypeAll},
+		}
+		if !onDemand(table) && table.ProvisionedThroughput != nil {
+			index.ProvisionedThroughput = &types.ProvisionedThroughput{
+				ReadCapacityUnits:  table.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: table.ProvisionedThroughput.WriteCapacityUnits,
+			}
+		}
+		if _, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+			TableName:                   aws.String(name),
+			AttributeDefinitions:        attributeDefinitions(want.key),
+			GlobalSecondaryIndexUpdates: []types.GlobalSecondaryIndexUpdate{{Create: index}},
+		}); err != nil {
+			return changes, fmt.Errorf("failed to create index %s: %w", want.name, err)
+		}
+		if err := waitActive(ctx, client, name); err != nil {
+			return changes, err
+		}
+		changes = append(changes, fmt.Sprintf("created index %s", want.name))
+	}
+	return changes, nil
+}
+
+// ensureStream enables the stream spec asks for and reports whether it had
+// to. A stream with another view type is an error, as changing it replaces
+// the stream under its consumers.
+func ensureStream(ctx context.Context, client *dynamodb.Client, name string, table *types.TableDescription, spec tableSpec) (bool, error) {
+	if spec.stream == "" {
+		return false, nil
+	}
+	if stream := table.StreamSpecification; stream != nil && aws.ToBool(stream.StreamEnabled) {
+		if stream.StreamViewType != spec.stream {
+			return false, fmt.Errorf("table %s streams %s, expected %s", name, stream.StreamViewType, spec.stream)
+		}
+		return false, nil
+	}
+
+	if _, err := client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName:           aws.String(name),
+		StreamSpecification: &types.StreamSpecification{StreamEnabled: aws.Bool(true), StreamViewType: spec.stream},
+	}); err != nil {
+		return false, fmt.Errorf("failed to enable stream on table %s: %w", name, err)
+	}
+	return true, waitActive(ctx, client, name)
+}
+
+// ensureTTL enables TTL on TTLAttribute and reports whether it had to.
+func ensureTTL(ctx context.Context, client *dynamodb.Client, name string) (bool, error) {
+	out, err := client.DescribeTimeToLive(ctx, &dynamodb.DescribeTimeToLiveInput{TableName: aws.String(name)})
+	if err != nil {
+		return false, fmt.Errorf("failed to describe TTL of table %s: %w", name, err)
+	}
+	if ttl := out.TimeToLiveDescription; ttl != nil {
+		switch ttl.TimeToLiveStatus {
+		case types.TimeToLiveStatusEnabled, types.TimeToLiveStatusEnabling:
+			if attr := aws.ToString(ttl.AttributeName); attr != TTLAttribute {
+				return false, fmt.Errorf("table %s has TTL on %s, expected %s", name, attr, TTLAttribute)
+			}
+			return false, nil
+		}
+	}
+
+	if _, err := client.UpdateTimeToLive(ctx, &dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(name),
+		TimeToLiveSpecification: &types.TimeToLiveSpecification{
+			AttributeName: aws.String(TTLAttribute),
+			Enabled:       aws.Bool(true),
+		},
+	}); err != nil {
+		return false, fmt.Errorf("failed to enable TTL on table %s: %w", name, err)
+	}
+	return true, nil
+}
+
+// waitActive polls until the table and all of its indexes are ACTIVE.
+func waitActive(ctx context.Context, client *dynamodb.Client, name string) error {
+	for {
+		out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(name)})
+		if err != nil {
+			return fmt.Errorf("failed to describe table %s: %w", name, err)
+		}
+		active := out.Table.TableStatus == types.TableStatusActive
+		for _, index := range out.Table.GlobalSecondaryIndexes {
+			active = active && index.IndexStatus == types.IndexStatusActive
+		}
+		if active {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for table %s: %w", name, ctx.Err())
+		case <-time.After(provisionPollInterval):
+		}
+	}
+}
+
+func onDemand(table *types.TableDescription) bool {
+	return table.BillingModeSummary != nil && table.BillingModeSummary.BillingMode == types.BillingModePayPerRequest
+}
+
+func keySchemaElements(schema keySchema) []types.KeySchemaElement {
+	elements := []types.KeySchemaElement{{AttributeName: aws.String(schema.Hash), KeyType: types.KeyTypeHash}}
+	if schema.Range != "" {
+		elements = append(elements, types.KeySchemaElement{AttributeName: aws.String(schema.Range), KeyType: types.KeyTypeRange})
+	}
+	return elements
+}
+
+// attributeDefinitions defines every key attribute of schemas once.
+func attributeDefinitions(schemas ...keySchema) []types.AttributeDefinition {
+	seen := make(map[string]bool)
+	var defs []types.AttributeDefinition
+	for _, schema := range schemas {
+		for _, name := range []string{schema.Hash, schema.Range} {
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			defs = append(defs, types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: attributeTypes[name]})
+		}
+	}
+	return defs
+}
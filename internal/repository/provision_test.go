@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResponse struct {
+	status int
+	body   string
+}
+
+// scriptedDynamoDB answers each operation with the next of its responses,
+// repeating the last one, and records the operations and request bodies.
+func scriptedDynamoDB(t *testing.T, script map[string][]stubResponse) (*dynamodb.Client, *[]string, map[string][]string) {
+	t.Helper()
+	var calls []string
+	bodies := make(map[string][]string)
+	client := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://dynamodb.test"),
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		Retryer:      aws.NopRetryer{},
+		HTTPClient: stubDoer(func(r *http.Request) (*http.Response, error) {
+			op := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "DynamoDB_20120810.")
+			body, _ := io.ReadAll(r.Body)
+			calls = append(calls, op)
+			bodies[op] = append(bodies[op], string(body))
+
+			responses := script[op]
+			if len(responses) == 0 {
+				t.Fatalf("unexpected %s call", op)
+			}
+			resp := responses[0]
+			if len(responses) > 1 {
+				script[op] = responses[1:]
+			}
+			return &http.Response{
+				StatusCode: resp.status,
+				Header:     http.Header{"Content-Type": {"application/x-amz-json-1.0"}},
+				Body:       io.NopCloser(bytes.NewBufferString(resp.body)),
+				Request:    r,
+			}, nil
+		}),
+	})
+	return client, &calls, bodies
+}
+
+const (
+	tableNotFound = `{"__type":"com.amazonaws.dynamodb.v20120810#ResourceNotFoundException","message":"Requested resource not found"}`
+	ttlDisabled   = `{"TimeToLiveDescription":{"TimeToLiveStatus":"DISABLED"}}`
+	ttlEnabled    = `{"TimeToLiveDescription":{"TimeToLiveStatus":"ENABLED","AttributeName":"ExpiresAt"}}`
+)
+
+func TestEnsureTable(t *testing.T) {
+	provisionPollInterval = time.Millisecond
+	active := strings.Replace(describeTableTemplate, "STATUS_INDEX_STATE", "ACTIVE", 1)
+
+	t.Run("Creates Missing Table", func(t *testing.T) {
+		client, calls, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable": {
+				{http.StatusBadRequest, tableNotFound},
+				{http.StatusOK, strings.Replace(active, `"TableStatus":"ACTIVE"`, `"TableStatus":"CREATING"`, 1)},
+				{http.StatusOK, active},
+			},
+			"CreateTable":        {{http.StatusOK, `{}`}},
+			"DescribeTimeToLive": {{http.StatusOK, ttlDisabled}},
+			"UpdateTimeToLive":   {{http.StatusOK, `{}`}},
+		})
+
+		changes, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"created table Posts with indexes StatusIndex and AuthorIndex",
+			"enabled TTL on ExpiresAt",
+		}, changes)
+		assert.Equal(t, []string{"DescribeTable", "CreateTable", "DescribeTable", "DescribeTable", "DescribeTimeToLive", "UpdateTimeToLive"}, *calls)
+
+		create := bodies["CreateTable"][0]
+		assert.Contains(t, create, `"BillingMode":"PAY_PER_REQUEST"`)
+		assert.Contains(t, create, `{"AttributeName":"CreatedAt","AttributeType":"N"}`)
+		assert.Contains(t, create, `"IndexName":"StatusIndex"`)
+		assert.Contains(t, create, `"IndexName":"AuthorIndex"`)
+		assert.Equal(t, 1, strings.Count(create, `"AttributeName":"CreatedAt","AttributeType"`))
+		assert.Contains(t, create, `"StreamSpecification":{"StreamEnabled":true,"StreamViewType":"NEW_AND_OLD_IMAGES"}`)
+		assert.Contains(t, bodies["UpdateTimeToLive"][0], `"AttributeName":"ExpiresAt","Enabled":true`)
+	})
+
+	t.Run("Enables Missing Stream", func(t *testing.T) {
+		withoutStream := strings.Replace(active, `"StreamEnabled":true`, `"StreamEnabled":false`, 1)
+		client, calls, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable":      {{http.StatusOK, withoutStream}, {http.StatusOK, active}},
+			"UpdateTable":        {{http.StatusOK, `{}`}},
+			"DescribeTimeToLive": {{http.StatusOK, ttlEnabled}},
+		})
+
+		changes, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"enabled stream with NEW_AND_OLD_IMAGES"}, changes)
+		assert.Equal(t, []string{"DescribeTable", "UpdateTable", "DescribeTable", "DescribeTimeToLive"}, *calls)
+		assert.Contains(t, bodies["UpdateTable"][0], `"StreamViewType":"NEW_AND_OLD_IMAGES"`)
+	})
+
+	t.Run("Stream With Another View", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable": {{http.StatusOK, strings.Replace(active, `"NEW_AND_OLD_IMAGES"`, `"KEYS_ONLY"`, 1)}},
+		})
+
+		_, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.EqualError(t, err, "table Posts streams KEYS_ONLY, expected NEW_AND_OLD_IMAGES")
+	})
+
+	t.Run("Adds Missing Index", func(t *testing.T) {
+		withoutAuthor := strings.Replace(active, `"IndexName":"AuthorIndex"`, `"IndexName":"LegacyIndex"`, 1)
+		client, calls, bodies := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable":      {{http.StatusOK, withoutAuthor}, {http.StatusOK, active}},
+			"UpdateTable":        {{http.StatusOK, `{}`}},
+			"DescribeTimeToLive": {{http.StatusOK, ttlEnabled}},
+		})
+
+		changes, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"created index AuthorIndex"}, changes)
+		assert.Equal(t, []string{"DescribeTable", "UpdateTable", "DescribeTable", "DescribeTimeToLive"}, *calls)
+		assert.Contains(t, bodies["UpdateTable"][0], `"Create":{"IndexName":"AuthorIndex"`)
+	})
+
+	t.Run("Nothing To Do", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable":      {{http.StatusOK, active}},
+			"DescribeTimeToLive": {{http.StatusOK, ttlEnabled}},
+		})
+
+		changes, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("Wrong Key Schema", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable": {{http.StatusOK, strings.Replace(active, `"AttributeName":"ID"`, `"AttributeName":"PostID"`, 1)}},
+		})
+
+		_, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.ErrorContains(t, err, "table Posts key schema")
+	})
+
+	t.Run("TTL On Another Attribute", func(t *testing.T) {
+		client, _, _ := scriptedDynamoDB(t, map[string][]stubResponse{
+			"DescribeTable":      {{http.StatusOK, active}},
+			"DescribeTimeToLive": {{http.StatusOK, `{"TimeToLiveDescription":{"TimeToLiveStatus":"ENABLED","AttributeName":"Expiry"}}`}},
+		})
+
+		_, err := NewDynamoPostRepository(client, "Posts").EnsureTable(context.Background())
+
+		assert.EqualError(t, err, "table Posts has TTL on Expiry, expected ExpiresAt")
+	})
+}
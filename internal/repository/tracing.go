@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "blog-api/internal/repository"
+
+// WithTracing is a dynamodb.Options function that wraps every DynamoDB
+// operation in a client span carrying the operation, tables, index and
+// consumed capacity. The span covers retries.
+func WithTracing(o *dynamodb.Options) {
+	tracer := otel.Tracer(tracerName)
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("Tracing",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				operation := awsmiddleware.GetOperationName(ctx)
+				attrs := []attribute.KeyValue{
+					attribute.String("db.system", "dynamodb"),
+					attribute.String("rpc.system", "aws-api"),
+					attribute.String("rpc.service", "DynamoDB"),
+					attribute.String("rpc.method", operation),
+				}
+				if tables := tableNames(in.Parameters); len(tables) > 0 {
+					attrs = append(attrs, attribute.StringSlice("aws.dynamodb.table_names", tables))
+				}
+				if index := indexName(in.Parameters); index != "" {
+					attrs = append(attrs, attribute.String("aws.dynamodb.index_name", index))
+				}
+
+				ctx, span := tracer.Start(ctx, "DynamoDB."+operation,
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(attrs...),
+				)
+				defer span.End()
+
+				requestCapacity(in.Parameters)
+				out, metadata, err := next.HandleInitialize(ctx, in)
+
+				if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+					span.SetAttributes(attribute.String("aws.request_id", id))
+				}
+				if err != nil {
+					var respErr *awshttp.ResponseError
+					if errors.As(err, &respErr) {
+						span.SetAttributes(attribute.String("aws.request_id", respErr.ServiceRequestID()))
+					}
+					span.SetAttributes(attribute.String("aws.error_code", errorCode(err)))
+					span.RecordError(err)
+					span.SetStatus(codes.Error, err.Error())
+					return out, metadata, err
+				}
+				span.SetAttributes(attribute.Float64("aws.dynamodb.consumed_capacity", consumedCapacity(out.Result)))
+				return out, metadata, nil
+			}), middleware.After)
+	})
+}
+
+func tableNames(input interface{}) []string {
+	var names []string
+	switch in := input.(type) {
+	case *dynamodb.GetItemInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.PutItemInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.UpdateItemInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.DeleteItemInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.QueryInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.ScanInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.DescribeTableInput:
+		names = append(names, aws.ToString(in.TableName))
+	case *dynamodb.BatchGetItemInput:
+		for name := range in.RequestItems {
+			names = append(names, name)
+		}
+	case *dynamodb.BatchWriteItemInput:
+		for name := range in.RequestItems {
+			names = append(names, name)
+		}
+	case *dynamodb.TransactWriteItemsInput:
+		seen := make(map[string]bool)
+		for _, item := range in.TransactItems {
+			var name *string
+			switch {
+			case item.Put != nil:
+				name = item.Put.TableName
+			case item.Update != nil:
+				name = item.Update.TableName
+			case item.Delete != nil:
+				name = item.Delete.TableName
+			case item.ConditionCheck != nil:
+				name = item.ConditionCheck.TableName
+			}
+			if n := aws.ToString(name); n != "" && !seen[n] {
+				seen[n] = true
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func indexName(input interface{}) string {
+	switch in := input.(type) {
+	case *dynamodb.QueryInput:
+		return aws.ToString(in.IndexName)
+	case *dynamodb.ScanInput:
+		return aws.ToString(in.IndexName)
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestWithTracing(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	t.Run("Query Span", func(t *testing.T) {
+		client, sent := stubDynamoDB(t, http.StatusOK,
+			`{"Items":[],"Count":0,"ConsumedCapacity":{"TableName":"Posts","CapacityUnits":2}}`,
+			WithTracing)
+
+		_, err := client.Query(context.Background(), &dynamodb.QueryInput{
+			TableName:              aws.String("Posts"),
+			IndexName:              aws.String(StatusIndexName),
+			KeyConditionExpression: aws.String("#s = :s"),
+		})
+
+		assert.NoError(t, err)
+		assert.Contains(t, string(*sent), `"ReturnConsumedCapacity":"TOTAL"`)
+		span := spans.Ended()[len(spans.Ended())-1]
+		assert.Equal(t, "DynamoDB.Query", span.Name())
+		assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+		attrs := span.Attributes()
+		assert.Contains(t, attrs, attribute.String("rpc.method", "Query"))
+		assert.Contains(t, attrs, attribute.StringSlice("aws.dynamodb.table_names", []string{"Posts"}))
+		assert.Contains(t, attrs, attribute.String("aws.dynamodb.index_name", StatusIndexName))
+		assert.Contains(t, attrs, attribute.Float64("aws.dynamodb.consumed_capacity", 2))
+		assert.Contains(t, attrs, attribute.String("aws.request_id", "AWS-REQ-1"))
+	})
+
+	t.Run("Failed Call", func(t *testing.T) {
+		client, _ := stubDynamoDB(t, http.StatusBadRequest,
+			`{"__type":"com.amazonaws.dynamodb.v20120810#ProvisionedThroughputExceededException","message":"slow down"}`,
+			WithTracing)
+
+		_, err := client.GetItem(context.Background(), &dynamodb.GetItemInput{
+			TableName: aws.String("Posts"),
+			Key:       map[string]types.AttributeValue{"ID": &types.AttributeValueMemberS{Value: "1"}},
+		})
+
+		assert.Error(t, err)
+		span := spans.Ended()[len(spans.Ended())-1]
+		assert.Equal(t, "DynamoDB.GetItem", span.Name())
+		assert.Equal(t, codes.Error, span.Status().Code)
+		assert.Contains(t, span.Attributes(), attribute.String("aws.error_code", "ProvisionedThroughputExceededException"))
+	})
+}
@@ -0,0 +1,68 @@
+// Package requestid carries the IDs that correlate a request across the
+// client, API Gateway, Lambda and the service's own logs.
+package requestid
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+// Header is the request and response header carrying the request ID.
+const Header = "X-Request-ID"
+
+// validID bounds what a client may send as its own request ID, so that it is
+// safe to echo in headers and logs.
+var validID = regexp.MustCompile(`^[A-Za-z0-9._:\-]{1,128}$`)
+
+// IDs identifies a single request. RequestID is always set by the
+// middleware; the AWS IDs are only known under Lambda.
+type IDs struct {
+	RequestID           string
+	APIGatewayRequestID string
+	LambdaRequestID     string
+}
+
+// LogAttrs returns the non-empty IDs as slog key-value pairs.
+func (ids IDs) LogAttrs() []any {
+	var attrs []any
+	if ids.RequestID != "" {
+		attrs = append(attrs, "request_id", ids.RequestID)
+	}
+	if ids.APIGatewayRequestID != "" {
+		attrs = append(attrs, "apigw_request_id", ids.APIGatewayRequestID)
+	}
+	if ids.LambdaRequestID != "" {
+		attrs = append(attrs, "lambda_request_id", ids.LambdaRequestID)
+	}
+	return attrs
+}
+
+// Valid reports whether a client-supplied ID can be used as is.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// New generates a fresh request ID.
+func New() string {
+	return uuid.New().String()
+}
+
+type contextKey struct{}
+
+// WithIDs returns a copy of ctx carrying ids.
+func WithIDs(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the IDs carried by ctx, or the zero value.
+func FromContext(ctx context.Context) IDs {
+	ids, _ := ctx.Value(contextKey{}).(IDs)
+	return ids
+}
+
+// ID returns the request ID carried by ctx, or "".
+func ID(ctx context.Context) string {
+	return FromContext(ctx).RequestID
+}
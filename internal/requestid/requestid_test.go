@@ -0,0 +1,42 @@
+package requestid
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	t.Run("Round Trip", func(t *testing.T) {
+		ids := IDs{RequestID: "abc", LambdaRequestID: "lambda-1"}
+		ctx := WithIDs(context.Background(), ids)
+
+		assert.Equal(t, ids, FromContext(ctx))
+		assert.Equal(t, "abc", ID(ctx))
+	})
+
+	t.Run("Empty Context", func(t *testing.T) {
+		assert.Equal(t, IDs{}, FromContext(context.Background()))
+		assert.Empty(t, ID(context.Background()))
+	})
+}
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid("f3b1c2d4-0000-4a4a-9b9b-123456789abc"))
+	assert.True(t, Valid("client.trace:42_a"))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("has space"))
+	assert.False(t, Valid("line\nbreak"))
+	assert.False(t, Valid(strings.Repeat("a", 129)))
+	assert.True(t, Valid(New()))
+}
+
+func TestLogAttrs(t *testing.T) {
+	assert.Empty(t, IDs{}.LogAttrs())
+	assert.Equal(t, []any{"request_id", "abc"}, IDs{RequestID: "abc"}.LogAttrs())
+	assert.Equal(t,
+		[]any{"request_id", "abc", "apigw_request_id", "gw", "lambda_request_id", "fn"},
+		IDs{RequestID: "abc", APIGatewayRequestID: "gw", LambdaRequestID: "fn"}.LogAttrs())
+}
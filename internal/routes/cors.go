@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/auth"
+	"blog-api/internal/logging"
+	"blog-api/internal/requestid"
+	"github.com/gorilla/mux"
+)
+
+// CORSConfig is a cross-origin policy. Empty lists fall back to any origin and
+// the methods and headers of the API.
+type CORSConfig struct {
+	// AllowedOrigins holds exact origins such as "https://blog.example.com",
+	// wildcard-subdomain patterns such as "https://*.example.com", or "*".
+	AllowedOrigins []string
+	AllowedMethods []string
+	// AllowedHeaders may contain "*" to accept any request header.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge lets browsers cache preflight answers; zero leaves it to them.
+	MaxAge time.Duration
+	// Routes replaces the policy for single route templates such as
+	// "/v1/posts/{id}". Each entry is a complete policy.
+	Routes map[string]CORSConfig
+}
+
+var (
+	defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization", auth.APIKeyHeader, requestid.Header}
+	defaultCORSExposed = []string{"ETag", "Link", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", requestid.Header}
+)
+
+// corsPolicy is a CORSConfig prepared for matching.
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     []originPattern
+	methods     map[string]bool
+	allowMethod string
+	anyHeader   bool
+	headers     map[string]bool
+	allowHeader string
+	expose      string
+	credentials bool
+	maxAge      string
+}
+
+func newCORSPolicy(cfg CORSConfig) *corsPolicy {
+	p := &corsPolicy{
+		methods:     make(map[string]bool),
+		headers:     make(map[string]bool),
+		credentials: cfg.AllowCredentials,
+	}
+
+	origins := cfg.AllowedOrigins
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	for _, origin := range origins {
+		if origin == "*" {
+			p.anyOrigin = true
+			continue
+		}
+		p.origins = append(p.origins, newOriginPattern(origin))
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	for _, method := range methods {
+		p.methods[strings.ToUpper(method)] = true
+	}
+	p.allowMethod = strings.Join(methods, ", ")
+
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCORSHeaders
+	}
+	for _, header := range headers {
+		if header == "*" {
+			p.anyHeader = true
+		}
+		p.headers[strings.ToLower(header)] = true
+	}
+	p.allowHeader = strings.Join(headers, ", ")
+
+	exposed := cfg.ExposedHeaders
+	if len(exposed) == 0 {
+		exposed = defaultCORSExposed
+	}
+	p.expose = strings.Join(exposed, ", ")
+
+	if cfg.MaxAge > 0 {
+		p.maxAge = strconv.Itoa(int(cfg.MaxAge / time.Second))
+	}
+	return p
+}
+
+// originPattern matches an exact origin or, when wildcard, any subdomain of
+// the pattern's host with the same scheme and port.
+type originPattern struct {
+	exact    string
+	prefix   string
+	suffix   string
+	wildcard bool
+}
+
+func newOriginPattern(pattern string) originPattern {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+	scheme, host, ok := strings.Cut(pattern, "://")
+	if ok && strings.HasPrefix(host, "*.") {
+		return originPattern{prefix: scheme + "://", suffix: host[1:], wildcard: true}
+	}
+	return originPattern{exact: pattern}
+}
+
+func (o originPattern) match(origin string) bool {
+	if !o.wildcard {
+		return origin == o.exact
+	}
+	if !strings.HasPrefix(origin, o.prefix) || !strings.HasSuffix(origin, o.suffix) {
+		return false
+	}
+	sub := origin[len(o.prefix) : len(origin)-len(o.suffix)]
+	return sub != "" && !strings.ContainsAny(sub, "/:@")
+}
+
+func (p *corsPolicy) allowOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range p.origins {
+		if o.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// setOrigin answers with "*" when any origin is allowed without credentials,
+// so that the response stays cacheable, and echoes the origin otherwise.
+func (p *corsPolicy) setOrigin(h http.Header, origin string) {
+	if p.anyOrigin && !p.credentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// checkPreflight returns why a preflight must be refused, or "" to accept it.
+func (p *corsPolicy) checkPreflight(origin, method, headers string) string {
+	if !p.allowOrigin(origin) {
+		return "origin not allowed"
+	}
+	if !p.methods[strings.ToUpper(method)] {
+		return fmt.Sprintf("method %s not allowed", method)
+	}
+	if p.anyHeader {
+		return ""
+	}
+	for _, header := range strings.Split(headers, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !p.headers[header] {
+			return fmt.Sprintf("header %s not allowed", header)
+		}
+	}
+	return ""
+}
+
+func (p *corsPolicy) serve(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+	method := r.Header.Get("Access-Control-Request-Method")
+
+	if r.Method != http.MethodOptions || method == "" || origin == "" {
+		h.Add("Vary", "Origin")
+		if origin != "" && p.allowOrigin(origin) {
+			p.setOrigin(h, origin)
+			h.Set("Access-Control-Expose-Headers", p.expose)
+		}
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	h.Add("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+	requested := r.Header.Get("Access-Control-Request-Headers")
+	if reason := p.checkPreflight(origin, method, requested); reason != "" {
+		logging.FromContext(r.Context()).Info("CORS preflight refused", "origin", origin, "reason", reason)
+		h.Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		if err := json.NewEncoder(w).Encode(JSONErrorResponse{
+			Error:       "Forbidden",
+			Description: "CORS preflight refused: " + reason,
+			RequestID:   requestid.ID(r.Context()),
+		}); err != nil {
+			logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
+		}
+		return
+	}
+
+	p.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", p.allowMethod)
+	if p.anyHeader && requested != "" {
+		h.Set("Access-Control-Allow-Headers", requested)
+	} else {
+		h.Set("Access-Control-Allow-Headers", p.allowHeader)
+	}
+	if p.maxAge != "" {
+		h.Set("Access-Control-Max-Age", p.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// corsMiddleware applies the policy of the matched route template, falling
+// back to cfg. Disallowed origins get no CORS headers, so browsers withhold
+// the response; only refused preflights are answered with 403.
+func corsMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	fallback := newCORSPolicy(cfg)
+	byRoute := make(map[string]*corsPolicy, len(cfg.Routes))
+	for template, routeCfg := range cfg.Routes {
+		byRoute[template] = newCORSPolicy(routeCfg)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			policy, ok := byRoute[routeTemplate(r)]
+			if !ok {
+				policy = fallback
+			}
+			policy.serve(w, r, next)
+		})
+	}
+}
+
+// mountPreflight adds an OPTIONS route for every path template of router, so
+// that preflights reach the middleware instead of failing with 405. Plain
+// OPTIONS requests are answered with the Allow header. It returns the
+// methods served per path template.
+func mountPreflight(router *mux.Router) map[string][]string {
+	allowed := make(map[string][]string)
+	var templates []string
+	_ = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // subrouter prefixes carry no methods
+		}
+		if _, ok := allowed[template]; !ok {
+			templates = append(templates, template)
+		}
+		allowed[template] = append(allowed[template], methods...)
+		return nil
+	})
+
+	for _, template := range templates {
+		methods := append(allowed[template], http.MethodOptions)
+		sort.Strings(methods)
+		allow := strings.Join(methods, ", ")
+		router.HandleFunc(template, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Allow", allow)
+			w.WriteHeader(http.StatusNoContent)
+		}).Methods(http.MethodOptions)
+	}
+	return allowed
+}
@@ -0,0 +1,209 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCORSMiddleware(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"http://example.com", "https://*.example.org"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"ETag", "Link"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(cfg CORSConfig, req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		corsMiddleware(cfg)(next).ServeHTTP(rec, req)
+		return rec
+	}
+	preflight := func(origin, method, headers string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/posts", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", method)
+		if headers != "" {
+			req.Header.Set("Access-Control-Request-Headers", headers)
+		}
+		return req
+	}
+
+	t.Run("Valid CORS Preflight Request", func(t *testing.T) {
+		rec := serve(cfg, preflight("http://example.com", "PUT", "content-type, authorization"))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "http://example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, strings.Join(cfg.AllowedMethods, ", "), rec.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, strings.Join(cfg.AllowedHeaders, ", "), rec.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, "Origin, Access-Control-Request-Method, Access-Control-Request-Headers", rec.Header().Get("Vary"))
+		assert.Empty(t, rec.Body.String())
+	})
+
+	t.Run("Wildcard Subdomain", func(t *testing.T) {
+		for origin, allowed := range map[string]bool{
+			"https://blog.example.org":      true,
+			"https://a.b.example.org":       true,
+			"https://example.org":           false,
+			"http://blog.example.org":       false,
+			"https://blog.example.org:8443": false,
+			"https://evilexample.org":       false,
+			"https://blog.example.org.evil": false,
+		} {
+			rec := serve(cfg, preflight(origin, "GET", ""))
+			if allowed {
+				assert.Equal(t, http.StatusNoContent, rec.Code, origin)
+				assert.Equal(t, origin, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+			} else {
+				assert.Equal(t, http.StatusForbidden, rec.Code, origin)
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+			}
+		}
+	})
+
+	t.Run("Invalid Origin", func(t *testing.T) {
+		rec := serve(cfg, preflight("http://unauthorized.com", "GET", ""))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		var errorResponse JSONErrorResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&errorResponse))
+		assert.Equal(t, "Forbidden", errorResponse.Error)
+		assert.Contains(t, errorResponse.Description, "origin not allowed")
+	})
+
+	t.Run("Disallowed Method And Header", func(t *testing.T) {
+		rec := serve(cfg, preflight("http://example.com", "PATCH", ""))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "method PATCH not allowed")
+
+		rec = serve(cfg, preflight("http://example.com", "GET", "X-Custom"))
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "header x-custom not allowed")
+	})
+
+	t.Run("Simple Request From Allowed Origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+		req.Header.Set("Origin", "http://example.com")
+
+		rec := serve(cfg, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "http://example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Equal(t, "ETag, Link", rec.Header().Get("Access-Control-Expose-Headers"))
+		assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+	})
+
+	t.Run("Simple Request From Disallowed Origin", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+		req.Header.Set("Origin", "http://unauthorized.com")
+
+		rec := serve(cfg, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+	})
+
+	t.Run("No Origin in Request", func(t *testing.T) {
+		rec := serve(cfg, httptest.NewRequest(http.MethodGet, "/v1/posts", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "Origin", rec.Header().Get("Vary"))
+	})
+
+	t.Run("Any Origin Without Credentials", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+		req.Header.Set("Origin", "http://anywhere.test")
+
+		rec := serve(CORSConfig{}, req)
+
+		assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+		assert.Contains(t, rec.Header().Get("Access-Control-Expose-Headers"), "RateLimit-Remaining")
+	})
+
+	t.Run("Any Header", func(t *testing.T) {
+		rec := serve(CORSConfig{AllowedHeaders: []string{"*"}}, preflight("http://anywhere.test", "POST", "X-Custom, Content-Type"))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "X-Custom, Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	})
+}
+
+func TestCORSRoutes(t *testing.T) {
+	mockHandler := new(MockPostHandler)
+	router := SetupRouter(mockHandler, new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"https://admin.example.com"},
+			Routes: map[string]CORSConfig{
+				APIPrefix + PostWithID: {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+			},
+		},
+	})
+
+	t.Run("Preflight Reaches The Policy", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/posts", nil)
+		req.Header.Set("Origin", "https://admin.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "https://admin.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+		mockHandler.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Per-Route Override", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/posts/1", nil)
+		req.Header.Set("Origin", "https://reader.example.net")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET", rec.Header().Get("Access-Control-Allow-Methods"))
+
+		req.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+
+	t.Run("Plain OPTIONS Lists Methods", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodOptions, "/v1/posts/1", nil))
+
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+		assert.Equal(t, "DELETE, GET, OPTIONS, PATCH, PUT", rec.Header().Get("Allow"))
+	})
+
+	t.Run("Unknown Path", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/nope", nil)
+		req.Header.Set("Origin", "https://admin.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -2,165 +2,347 @@ package routes
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/json"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"blog-api/internal/auth"
+	"blog-api/internal/logging"
+	"blog-api/internal/metrics"
+	"blog-api/internal/requestid"
+	"blog-api/internal/tracing"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const tracerName = "blog-api/internal/routes"
+
 type JSONErrorResponse struct {
 	Error       string `json:"error"`
 	Description string `json:"description,omitempty"`
+	RequestID   string `json:"requestId,omitempty"`
 }
 
 func validateContentType(r *http.Request, validTypes []string) bool {
 	contentType := r.Header.Get("Content-Type")
-	log.Printf("Validating Content-Type. Found: %s, Valid Types: %v", contentType, validTypes)
 	for _, validType := range validTypes {
 		if strings.Contains(contentType, validType) {
-			log.Printf("Content-Type validation succeeded for: %s", validType)
 			return true
 		}
 	}
-	log.Printf("Content-Type validation failed for: %s", contentType)
 	return false
 }
 
 func validationMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Entering validationMiddleware. Method: %s, URL: %s, Headers: %v", r.Method, r.URL.Path, r.Header)
 		if (r.Method == http.MethodPost || r.Method == http.MethodPut) &&
 			!validateContentType(r, []string{"application/json"}) {
-			log.Printf("Request validation failed. Method: %s, URL: %s", r.Method, r.URL.Path)
+			logging.FromContext(r.Context()).Debug("unsupported content type", "content_type", r.Header.Get("Content-Type"))
 			w.WriteHeader(http.StatusUnsupportedMediaType)
 			if err := json.NewEncoder(w).Encode(JSONErrorResponse{
 				Error:       "Unsupported Media Type",
 				Description: "Content-Type must be application/json",
+				RequestID:   requestid.ID(r.Context()),
 			}); err != nil {
-				log.Printf("Error encoding response: %v", err)
+				logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
 			}
 			return
 		}
-		log.Printf("Request validation succeeded. Proceeding to next handler.")
 		next.ServeHTTP(w, r)
 	})
 }
 
 func errorHandlingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("Entering errorHandlingMiddleware. Method: %s, URL: %s", r.Method, r.URL.Path)
 		defer func() {
 			if rec := recover(); rec != nil {
-				log.Printf("Recovered from panic: %v. Method: %s, URL: %s, Headers: %v",
-					rec, r.Method, r.URL.Path, r.Header)
+				logging.FromContext(r.Context()).Error("recovered from panic", "panic", rec)
 				w.WriteHeader(http.StatusInternalServerError)
 				if err := json.NewEncoder(w).Encode(JSONErrorResponse{
 					Error:       "Internal Server Error",
 					Description: "A server error occurred. Please contact support.",
+					RequestID:   requestid.ID(r.Context()),
 				}); err != nil {
-					log.Printf("Error encoding response: %v", err)
+					logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
 				}
 			}
 		}()
-		log.Printf("Calling next handler in errorHandlingMiddleware.")
 		next.ServeHTTP(w, r)
-		log.Printf("Exiting errorHandlingMiddleware. Method: %s, URL: %s", r.Method, r.URL.Path)
 	})
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
+// requestIDMiddleware accepts the client's X-Request-ID or assigns one,
+// records the API Gateway and Lambda request IDs when running under Lambda,
+// and echoes the request ID in the response headers.
+func requestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		log.Printf("Entering loggingMiddleware. Method: %s, URL: %s, Headers: %v", r.Method, r.URL.Path, r.Header)
-
-		var requestBody bytes.Buffer
-		if r.Body != nil {
-			tee := io.TeeReader(r.Body, &requestBody)
-			r.Body = io.NopCloser(&requestBody)
-			bodyContent, err := io.ReadAll(tee)
-			if err != nil {
-				log.Printf("Error reading request body: %v", err)
-				return
-			}
-			log.Printf("Request Body: %s", string(bodyContent))
+		ctx := r.Context()
+		var ids requestid.IDs
+		if gw, ok := core.GetAPIGatewayContextFromContext(ctx); ok {
+			ids.APIGatewayRequestID = gw.RequestID
+		}
+		if lc, ok := core.GetRuntimeContextFromContext(ctx); ok && lc != nil {
+			ids.LambdaRequestID = lc.AwsRequestID
 		}
 
-		lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-		log.Printf("Calling next handler in loggingMiddleware.")
-		next.ServeHTTP(lrw, r)
+		switch id := r.Header.Get(requestid.Header); {
+		case requestid.Valid(id):
+			ids.RequestID = id
+		case ids.APIGatewayRequestID != "":
+			ids.RequestID = ids.APIGatewayRequestID
+		default:
+			ids.RequestID = requestid.New()
+		}
 
-		duration := time.Since(start)
-		log.Printf("Exiting loggingMiddleware. Method: %s, URL: %s, Status: %d, Duration: %v",
-			r.Method, r.URL.Path, lrw.statusCode, duration)
+		w.Header().Set(requestid.Header, ids.RequestID)
+		next.ServeHTTP(w, r.WithContext(requestid.WithIDs(ctx, ids)))
 	})
 }
 
-type loggingResponseWriter struct {
-	http.ResponseWriter
-	statusCode int
-	body       bytes.Buffer
-}
+// tracingMiddleware continues the caller's W3C or X-Ray trace, or starts a
+// new one, with a server span named after the route template.
+func tracingMiddleware(next http.Handler) http.Handler {
+	tracer := otel.Tracer(tracerName)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		ctx := tracing.Extract(r.Context(), r.Header)
+		ctx, span := tracer.Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("request.id", requestid.ID(r.Context())),
+			),
+		)
+		defer span.End()
 
-func (lrw *loggingResponseWriter) WriteHeader(code int) {
-	log.Printf("Writing response header. Status Code: %d", code)
-	lrw.statusCode = code
-	lrw.ResponseWriter.WriteHeader(code)
-}
+		lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(lrw, r.WithContext(ctx))
 
-func (lrw *loggingResponseWriter) Write(data []byte) (int, error) {
-	log.Printf("Writing response body. Data: %s", string(data))
-	lrw.body.Write(data)
-	return lrw.ResponseWriter.Write(data)
+		span.SetAttributes(attribute.Int("http.response.status_code", lrw.statusCode))
+		if lrw.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(lrw.statusCode))
+		}
+	})
 }
 
-func corsMiddleware(allowedOrigins []string, allowedMethods []string, allowedHeaders []string) func(http.Handler) http.Handler {
+// loggingMiddleware puts a request-scoped logger on the context and writes one
+// access-log line per request. Headers are logged at debug level and bodies
+// only when cfg.LogBodies is set, both after redaction.
+func loggingMiddleware(logger *slog.Logger, cfg logging.Config) func(http.Handler) http.Handler {
+	// Compiles the redaction pattern once, however cfg was built.
+	cfg = cfg.WithRedactFields()
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			log.Printf("Entering corsMiddleware. Method: %s, URL: %s, Origin: %s", r.Method, r.URL.Path, r.Header.Get("Origin"))
+			start := time.Now()
 
-			origin := r.Header.Get("Origin")
-			if origin == "" {
-				log.Printf("No Origin header found. Proceeding to next handler.")
-				next.ServeHTTP(w, r)
-				return
+			route := routeTemplate(r)
+			reqLogger := logger.With(requestid.FromContext(r.Context()).LogAttrs()...).With("method", r.Method, "route", route)
+			if traceID := tracing.TraceID(r.Context()); traceID != "" {
+				reqLogger = reqLogger.With("trace_id", traceID)
 			}
+			r = r.WithContext(logging.WithLogger(r.Context(), reqLogger))
 
-			allowed := false
-			for _, ao := range allowedOrigins {
-				if ao == "*" || ao == origin {
-					allowed = true
-					break
-				}
+			var requestBody *cappedBuffer
+			if cfg.LogBodies && r.Body != nil {
+				requestBody = &cappedBuffer{limit: cfg.MaxBodyBytes}
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.TeeReader(r.Body, requestBody), r.Body}
 			}
 
-			if !allowed {
-				log.Printf("Origin not allowed: %s", origin)
-				w.WriteHeader(http.StatusForbidden)
-				if err := json.NewEncoder(w).Encode(JSONErrorResponse{
-					Error:       "Forbidden",
-					Description: "Origin not allowed",
-				}); err != nil {
-					log.Printf("Error encoding response: %v", err)
-				}
-				return
+			lrw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			if cfg.LogBodies {
+				lrw.body = &cappedBuffer{limit: cfg.MaxBodyBytes}
 			}
+			next.ServeHTTP(lrw, r)
 
-			log.Printf("Setting CORS headers. Allowed Origin: %s", origin)
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-			w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
-			w.Header().Set("Access-Control-Allow-Headers", strings.Join(allowedHeaders, ", "))
-
-			if r.Method == http.MethodOptions {
-				log.Printf("Preflight request detected. Returning OK status.")
-				w.WriteHeader(http.StatusOK)
-				return
+			attrs := []any{
+				"path", r.URL.Path,
+				"status", lrw.statusCode,
+				"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+				"bytes", lrw.bytes,
 			}
+			if reqLogger.Enabled(r.Context(), slog.LevelDebug) {
+				attrs = append(attrs, "headers", cfg.RedactHeaderValues(r.Header))
+			}
+			if requestBody != nil {
+				attrs = append(attrs, "request_body", cfg.RedactBody(requestBody.Bytes()))
+			}
+			if lrw.body != nil {
+				attrs = append(attrs, "response_body", cfg.RedactBody(lrw.body.Bytes()))
+			}
+			reqLogger.Log(r.Context(), accessLogLevel(lrw.statusCode), "request", attrs...)
+		})
+	}
+}
 
-			log.Printf("Proceeding to next handler in corsMiddleware.")
-			next.ServeHTTP(w, r)
-			log.Printf("Exiting corsMiddleware. Method: %s, URL: %s", r.Method, r.URL.Path)
+// metricsMiddleware records request counts, latency and in-flight requests
+// per route template, and tags the context so DynamoDB calls made while
+// serving the request are attributed to its route.
+func metricsMiddleware(recorder metrics.Recorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			route := routeTemplate(r)
+
+			recorder.RequestStarted(route, r.Method)
+			sw := &loggingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() {
+				recorder.RequestFinished(route, r.Method, sw.statusCode, time.Since(start))
+			}()
+			next.ServeHTTP(sw, r.WithContext(metrics.WithRoute(r.Context(), route)))
 		})
 	}
 }
+
+// bearerAuth only lets requests through that carry "Authorization: Bearer <token>".
+func bearerAuth(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			if err := json.NewEncoder(w).Encode(JSONErrorResponse{
+				Error:     "Unauthorized",
+				RequestID: requestid.ID(r.Context()),
+			}); err != nil {
+				logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireAuth rejects requests without valid credentials and passes the
+// principal on in the request context.
+func requireAuth(authenticator *auth.Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			logging.FromContext(r.Context()).Debug("authentication failed", "error", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			if err := json.NewEncoder(w).Encode(JSONErrorResponse{
+				Error:       "Unauthorized",
+				Description: err.Error(),
+				RequestID:   requestid.ID(r.Context()),
+			}); err != nil {
+				logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
+			}
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// limitBody answers 413 when the declared body is larger than maxBytes and
+// caps the body of chunked requests.
+func limitBody(maxBytes int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			if err := json.NewEncoder(w).Encode(JSONErrorResponse{
+				Error:       "Request Entity Too Large",
+				Description: fmt.Sprintf("request body must not exceed %d bytes", maxBytes),
+				RequestID:   requestid.ID(r.Context()),
+			}); err != nil {
+				logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
+			}
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// routeTemplate returns the path template of the matched mux route, falling
+// back to the raw path outside of a router.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+func accessLogLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// cappedBuffer keeps the first limit bytes written to it, plus one byte so
+// that truncation can be detected, and discards the rest. A limit of zero or
+// less keeps everything.
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.Buffer.Write(p)
+	}
+	if room := b.limit + 1 - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	bytes      int
+	body       *cappedBuffer
+}
+
+func (lrw *loggingResponseWriter) WriteHeader(code int) {
+	lrw.statusCode = code
+	lrw.ResponseWriter.WriteHeader(code)
+}
+
+func (lrw *loggingResponseWriter) Write(data []byte) (int, error) {
+	if lrw.body != nil {
+		lrw.body.Write(data)
+	}
+	n, err := lrw.ResponseWriter.Write(data)
+	lrw.bytes += n
+	return n, err
+}
+
+// Flush lets streaming handlers flush through the wrapper.
+func (lrw *loggingResponseWriter) Flush() {
+	if flusher, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// extend the write deadline of a long-lived stream.
+func (lrw *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return lrw.ResponseWriter
+}
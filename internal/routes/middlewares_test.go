@@ -2,13 +2,31 @@ package routes
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"blog-api/internal/logging"
+	"blog-api/internal/metrics"
+	"blog-api/internal/requestid"
+	"blog-api/internal/tracing"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 func TestValidationMiddleware(t *testing.T) {
@@ -39,85 +57,231 @@ func TestValidationMiddleware(t *testing.T) {
 
 func TestLoggingMiddleware(t *testing.T) {
 	t.Run("Log Request", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
 		req := httptest.NewRequest("GET", "/v1/posts", nil)
 		rec := httptest.NewRecorder()
 
-		loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loggingMiddleware(logger, logging.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("[]"))
 		})).ServeHTTP(rec, req)
 
 		assert.Equal(t, http.StatusOK, rec.Code)
+
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, "INFO", entry["level"])
+		assert.Equal(t, "GET", entry["method"])
+		assert.Equal(t, "/v1/posts", entry["path"])
+		assert.Equal(t, float64(http.StatusOK), entry["status"])
+		assert.Equal(t, float64(2), entry["bytes"])
+		assert.Contains(t, entry, "latency_ms")
+		assert.NotContains(t, entry, "request_body")
 	})
-}
 
-func TestErrorHandlingMiddleware(t *testing.T) {
-	t.Run("Error Handling with Panic", func(t *testing.T) {
+	t.Run("Level Follows Status", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
+		req := httptest.NewRequest("GET", "/v1/posts/1", nil)
+		rec := httptest.NewRecorder()
+
+		loggingMiddleware(logger, logging.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})).ServeHTTP(rec, req)
+
+		assert.Contains(t, logs.String(), `"level":"WARN"`)
+	})
+
+	t.Run("Request Logger In Context", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, nil))
 		req := httptest.NewRequest("GET", "/v1/posts", nil)
 		rec := httptest.NewRecorder()
 
-		errorHandlingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			panic("unexpected error")
+		loggingMiddleware(logger, logging.Config{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logging.FromContext(r.Context()).Info("from handler")
 		})).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusInternalServerError, rec.Code)
-		var errorResponse JSONErrorResponse
-		err := json.NewDecoder(rec.Body).Decode(&errorResponse)
-		if err != nil {
-			return
-		}
-		assert.Equal(t, "Internal Server Error", errorResponse.Error)
-		assert.Contains(t, errorResponse.Description, "server error occurred")
+		lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.Contains(t, lines[0], `"msg":"from handler"`)
+		assert.Contains(t, lines[0], `"method":"GET"`)
 	})
-}
 
-func TestCORSMiddleware(t *testing.T) {
-	allowedOrigins := []string{"http://example.com", "http://localhost"}
-	allowedMethods := []string{"GET", "POST", "PUT", "DELETE"}
-	allowedHeaders := []string{"Content-Type", "Authorization"}
+	t.Run("Redacts Headers And Bodies", func(t *testing.T) {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		cfg := logging.DefaultConfig()
+		cfg.LogBodies = true
 
-	t.Run("Valid CORS Preflight Request", func(t *testing.T) {
-		req := httptest.NewRequest("OPTIONS", "/v1/posts", nil)
-		req.Header.Set("Origin", "http://example.com")
+		req := httptest.NewRequest("POST", "/v1/posts", strings.NewReader(`{"title":"t","password":"hunter2"}`))
+		req.Header.Set("Authorization", "Bearer abc123")
 		rec := httptest.NewRecorder()
 
-		corsMiddleware(allowedOrigins, allowedMethods, allowedHeaders)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
+		var received []byte
+		loggingMiddleware(logger, cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received, _ = io.ReadAll(r.Body)
+			_, _ = w.Write([]byte(`{"token":"xyz"}`))
 		})).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusOK, rec.Code)
-		assert.Equal(t, "http://example.com", rec.Header().Get("Access-Control-Allow-Origin"))
-		assert.Equal(t, strings.Join(allowedMethods, ", "), rec.Header().Get("Access-Control-Allow-Methods"))
-		assert.Equal(t, strings.Join(allowedHeaders, ", "), rec.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, `{"title":"t","password":"hunter2"}`, string(received))
+		assert.Equal(t, `{"token":"xyz"}`, rec.Body.String())
+		out := logs.String()
+		assert.NotContains(t, out, "hunter2")
+		assert.NotContains(t, out, "abc123")
+		assert.NotContains(t, out, "xyz")
+		assert.Contains(t, out, "[REDACTED]")
+		assert.Contains(t, out, `"request_body"`)
+		assert.Contains(t, out, `"response_body"`)
 	})
+}
 
-	t.Run("Invalid Origin", func(t *testing.T) {
-		req := httptest.NewRequest("OPTIONS", "/v1/posts", nil)
-		req.Header.Set("Origin", "http://unauthorized.com")
+func TestRequestIDMiddleware(t *testing.T) {
+	serve := func(req *http.Request) (*httptest.ResponseRecorder, requestid.IDs) {
+		var ids requestid.IDs
 		rec := httptest.NewRecorder()
+		requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ids = requestid.FromContext(r.Context())
+		})).ServeHTTP(rec, req)
+		return rec, ids
+	}
 
-		corsMiddleware(allowedOrigins, allowedMethods, allowedHeaders)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
+	t.Run("Generates ID", func(t *testing.T) {
+		rec, ids := serve(httptest.NewRequest("GET", "/v1/posts", nil))
+
+		assert.NotEmpty(t, ids.RequestID)
+		assert.Equal(t, ids.RequestID, rec.Header().Get(requestid.Header))
+	})
+
+	t.Run("Accepts Client ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/posts", nil)
+		req.Header.Set(requestid.Header, "client-123")
+		rec, ids := serve(req)
+
+		assert.Equal(t, "client-123", ids.RequestID)
+		assert.Equal(t, "client-123", rec.Header().Get(requestid.Header))
+	})
+
+	t.Run("Replaces Invalid Client ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/posts", nil)
+		req.Header.Set(requestid.Header, "bad id\r\nX-Injected: 1")
+		rec, ids := serve(req)
+
+		assert.NotEqual(t, "bad id\r\nX-Injected: 1", ids.RequestID)
+		assert.True(t, requestid.Valid(rec.Header().Get(requestid.Header)))
+	})
+
+	t.Run("Captures Lambda IDs", func(t *testing.T) {
+		ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"})
+		req, err := new(core.RequestAccessor).EventToRequestWithContext(ctx, events.APIGatewayProxyRequest{
+			HTTPMethod:     http.MethodGet,
+			Path:           "/v1/posts",
+			RequestContext: events.APIGatewayProxyRequestContext{RequestID: "gateway-1"},
+		})
+		assert.NoError(t, err)
+		rec, ids := serve(req)
+
+		assert.Equal(t, requestid.IDs{
+			RequestID:           "gateway-1",
+			APIGatewayRequestID: "gateway-1",
+			LambdaRequestID:     "lambda-1",
+		}, ids)
+		assert.Equal(t, "gateway-1", rec.Header().Get(requestid.Header))
+	})
+}
+
+type requestRecorder struct {
+	metrics.Nop
+	started  []string
+	finished []string
+	routes   []string
+}
+
+func (r *requestRecorder) RequestStarted(route, method string) {
+	r.started = append(r.started, method+" "+route)
+}
+
+func (r *requestRecorder) RequestFinished(route, method string, status int, _ time.Duration) {
+	r.finished = append(r.finished, method+" "+route+" "+metrics.StatusClass(status))
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	recorder := &requestRecorder{}
+	router := mux.NewRouter()
+	router.Use(metricsMiddleware(recorder))
+	router.HandleFunc("/v1/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		recorder.routes = append(recorder.routes, metrics.Route(r.Context()))
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/posts/123", nil))
+
+	assert.Equal(t, []string{"GET /v1/posts/{id}"}, recorder.started)
+	assert.Equal(t, []string{"GET /v1/posts/{id} 4xx"}, recorder.finished)
+	assert.Equal(t, []string{"/v1/posts/{id}"}, recorder.routes)
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(tracing.Propagator())
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var logs bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(tracingMiddleware)
+	router.Use(loggingMiddleware(slog.New(slog.NewJSONHandler(&logs, nil)), logging.Config{}))
+	router.HandleFunc("/v1/posts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/v1/posts/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	ended := spans.Ended()
+	assert.Len(t, ended, 1)
+	span := ended[0]
+	assert.Equal(t, "GET /v1/posts/{id}", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Contains(t, span.Attributes(), attribute.String("http.route", "/v1/posts/{id}"))
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	assert.Contains(t, logs.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+}
+
+func TestErrorHandlingMiddleware(t *testing.T) {
+	t.Run("Error Handling with Panic", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/v1/posts", nil)
+		rec := httptest.NewRecorder()
+
+		errorHandlingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("unexpected error")
 		})).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
 		var errorResponse JSONErrorResponse
 		err := json.NewDecoder(rec.Body).Decode(&errorResponse)
 		if err != nil {
 			return
 		}
-		assert.Equal(t, "Forbidden", errorResponse.Error)
-		assert.Contains(t, errorResponse.Description, "Origin not allowed")
+		assert.Equal(t, "Internal Server Error", errorResponse.Error)
+		assert.Contains(t, errorResponse.Description, "server error occurred")
 	})
 
-	t.Run("No Origin in Request", func(t *testing.T) {
+	t.Run("Includes Request ID", func(t *testing.T) {
 		req := httptest.NewRequest("GET", "/v1/posts", nil)
+		req = req.WithContext(requestid.WithIDs(req.Context(), requestid.IDs{RequestID: "trace-1"}))
 		rec := httptest.NewRecorder()
 
-		corsMiddleware(allowedOrigins, allowedMethods, allowedHeaders)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
+		errorHandlingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("unexpected error")
 		})).ServeHTTP(rec, req)
 
-		assert.Equal(t, http.StatusOK, rec.Code)
-		assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+		var errorResponse JSONErrorResponse
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&errorResponse))
+		assert.Equal(t, "trace-1", errorResponse.RequestID)
 	})
 }
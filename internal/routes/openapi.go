@@ -0,0 +1,413 @@
+package routes
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"log"
+	"net/http"
+
+	"blog-api/internal/graphql"
+	"blog-api/internal/handlers"
+	"blog-api/internal/health"
+	"blog-api/internal/logging"
+	"blog-api/internal/models"
+	"blog-api/internal/openapi"
+	"blog-api/internal/requestid"
+)
+
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUI holds the Swagger UI assets docs.html loads, vendored with
+// "make swagger-ui" so that /docs runs no script from elsewhere.
+//
+//go:embed swaggerui
+var swaggerUI embed.FS
+
+const (
+	jsonType   = "application/json"
+	ndjsonType = "application/x-ndjson"
+	xmlType    = "application/xml"
+)
+
+// OpenAPISpec describes every route mounted by SetupRouter. Request and
+// response schemas are derived from the model and handler types.
+func OpenAPISpec() *openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "Blog API",
+		Description: "CRUD, rendering, feeds and bulk operations for blog posts.",
+		Version:     "1.0.0",
+	})
+
+	post := b.Schema(models.Post{})
+	postResponse := b.Schema(handlers.PostResponse{})
+	errorResponse := b.Schema(JSONErrorResponse{})
+
+	jsonBody := func(schema *openapi.Schema) *openapi.RequestBody {
+		return &openapi.RequestBody{Required: true, Content: openapi.Content(jsonType, schema)}
+	}
+	ok := func(description, mediaType string, schema *openapi.Schema) openapi.Response {
+		return openapi.Response{Description: description, Content: openapi.Content(mediaType, schema)}
+	}
+	failure := func(description string) openapi.Response {
+		return ok(description, jsonType, errorResponse)
+	}
+	query := func(name, description string, schema *openapi.Schema) *openapi.Parameter {
+		return &openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+	}
+	unauthorized := failure("Credentials are required and were missing or invalid.")
+	tooLarge := failure("The request body is too large.")
+	str := &openapi.Schema{Type: "string"}
+	integer := &openapi.Schema{Type: "integer"}
+	fields := query("fields", "Comma-separated post fields to return, such as \"id,title,author\"; all by default.", str)
+
+	// Posts
+	b.Add(http.MethodGet, APIPrefix+PostsBase, &openapi.Operation{
+		OperationID: "listPosts",
+		Summary:     "List posts",
+		Tags:        []string{"posts"},
+		Parameters: []*openapi.Parameter{
+			query("page", "Page number, starting at 1.", integer),
+			query("limit", "Posts per page, 10 by default.", integer),
+			fields,
+		},
+		Responses: map[string]openapi.Response{
+			"200": ok("A page of posts.", jsonType, openapi.ArrayOf(post)),
+			"400": failure("A requested field is unknown."),
+			"500": failure("The posts could not be read."),
+		},
+	})
+	b.Add(http.MethodPost, APIPrefix+PostsBase, &openapi.Operation{
+		OperationID: "createPost",
+		Summary:     "Create a post",
+		Tags:        []string{"posts"},
+		RequestBody: jsonBody(post),
+		Responses: map[string]openapi.Response{
+			"201": ok("The created post.", jsonType, postResponse),
+			"400": failure("The post is invalid."),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+	b.Add(http.MethodGet, APIPrefix+PostWithID, &openapi.Operation{
+		OperationID: "getPost",
+		Summary:     "Get a post",
+		Tags:        []string{"posts"},
+		Parameters: []*openapi.Parameter{
+			query("render", "Set to \"html\" to include the rendered content. Cannot be combined with fields.", &openapi.Schema{Type: "string", Enum: []string{"html"}}),
+			fields,
+		},
+		Responses: map[string]openapi.Response{
+			"200": ok("The post.", jsonType, postResponse),
+			"400": failure("The request is invalid."),
+			"404": failure("The post does not exist."),
+		},
+	})
+	b.Add(http.MethodGet, APIPrefix+PostsEvents, &openapi.Operation{
+		OperationID: "streamPostEvents",
+		Summary:     "Stream post changes as server-sent events",
+		Tags:        []string{"posts"},
+		Parameters: []*openapi.Parameter{
+			query("author", "Only changes of posts by this author; deletions are always sent.", str),
+			query("tag", "Only changes of posts with this tag; deletions are always sent.", str),
+			{Name: "Last-Event-ID", In: "header", Description: "Resume after this event.", Schema: str},
+		},
+		Responses: map[string]openapi.Response{
+			"200": ok("An event stream of post.created, post.updated and post.deleted events carrying a StreamEvent, "+
+				"and a reset event when the events after Last-Event-ID are no longer kept. Only served by the standalone server.",
+				"text/event-stream", b.Schema(handlers.StreamEvent{})),
+		},
+	})
+	b.Add(http.MethodPut, APIPrefix+PostWithID, &openapi.Operation{
+		OperationID: "updatePost",
+		Summary:     "Replace a post",
+		Tags:        []string{"posts"},
+		RequestBody: jsonBody(post),
+		Responses: map[string]openapi.Response{
+			"200": ok("The updated post.", jsonType, postResponse),
+			"400": failure("The post is invalid or does not exist."),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+	b.Add(http.MethodPatch, APIPrefix+PostWithID, &openapi.Operation{
+		OperationID: "patchPost",
+		Summary:     "Update some fields of a post",
+		Tags:        []string{"posts"},
+		RequestBody: jsonBody(&openapi.Schema{Type: "object", AdditionalProperties: &openapi.Schema{}}),
+		Responses: map[string]openapi.Response{
+			"200": ok("The updated post.", jsonType, postResponse),
+			"400": failure("The update is invalid."),
+			"404": failure("The post does not exist."),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+	b.Add(http.MethodDelete, APIPrefix+PostWithID, &openapi.Operation{
+		OperationID: "deletePost",
+		Summary:     "Delete a post",
+		Tags:        []string{"posts"},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The post was deleted."},
+			"404": failure("The post does not exist."),
+			"401": unauthorized,
+		},
+	})
+
+	// Bulk operations
+	b.Add(http.MethodPost, APIPrefix+PostsImport, &openapi.Operation{
+		OperationID: "importPosts",
+		Summary:     "Import posts from newline-delimited JSON",
+		Tags:        []string{"bulk"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.Content(ndjsonType, post)},
+		Responses: map[string]openapi.Response{
+			"200": ok("The outcome of every line.", jsonType, b.Schema(handlers.ImportReport{})),
+			"400": failure("The body could not be read."),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+	b.Add(http.MethodGet, APIPrefix+PostsExport, &openapi.Operation{
+		OperationID: "exportPosts",
+		Summary:     "Export every post, drafts included, as newline-delimited JSON",
+		Tags:        []string{"bulk"},
+		Responses: map[string]openapi.Response{
+			"200": ok("One post per line.", ndjsonType, post),
+			"401": unauthorized,
+			"500": failure("The posts could not be read."),
+		},
+	})
+	b.Add(http.MethodPost, APIPrefix+PostsBatchGet, &openapi.Operation{
+		OperationID: "batchGetPosts",
+		Summary:     "Get up to 100 posts by ID",
+		Tags:        []string{"bulk"},
+		RequestBody: jsonBody(b.Schema(handlers.BatchGetRequest{})),
+		Responses: map[string]openapi.Response{
+			"200": ok("The posts in request order and the missing IDs.", jsonType, b.Schema(handlers.BatchGetResponse{})),
+			"400": failure("The request is invalid."),
+			"413": tooLarge,
+		},
+	})
+	batchDeleteResponse := b.Schema(handlers.BatchDeleteResponse{})
+	b.Add(http.MethodPost, APIPrefix+PostsBatchDelete, &openapi.Operation{
+		OperationID: "batchDeletePosts",
+		Summary:     "Delete up to 100 posts",
+		Tags:        []string{"bulk"},
+		RequestBody: jsonBody(b.Schema(handlers.BatchDeleteRequest{})),
+		Responses: map[string]openapi.Response{
+			"200": ok("The outcome for every ID.", jsonType, batchDeleteResponse),
+			"400": failure("The request is invalid."),
+			"409": ok("The transaction was aborted and nothing was deleted.", jsonType, batchDeleteResponse),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+
+	// GraphQL
+	b.Add(http.MethodPost, APIPrefix+GraphQL, &openapi.Operation{
+		OperationID: "graphql",
+		Summary:     "Query and change posts with GraphQL",
+		Tags:        []string{"graphql"},
+		RequestBody: jsonBody(b.Schema(graphql.Request{})),
+		Responses: map[string]openapi.Response{
+			"200": ok("The result of the operation, including its errors.", jsonType, b.Schema(graphql.Response{})),
+			"400": failure("The body is not a GraphQL request."),
+			"401": failure("The operation is a mutation and credentials were missing or invalid."),
+			"413": tooLarge,
+		},
+	})
+
+	// Webhooks
+	webhook := b.Schema(models.Webhook{})
+	delivery := b.Schema(models.WebhookDelivery{})
+	b.Add(http.MethodGet, APIPrefix+Webhooks, &openapi.Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List webhooks",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": ok("Every webhook, without its secret.", jsonType, openapi.ArrayOf(webhook)),
+			"401": unauthorized,
+			"500": failure("The webhooks could not be read."),
+		},
+	})
+	b.Add(http.MethodPost, APIPrefix+Webhooks, &openapi.Operation{
+		OperationID: "createWebhook",
+		Summary:     "Register a webhook",
+		Tags:        []string{"webhooks"},
+		RequestBody: jsonBody(webhook),
+		Responses: map[string]openapi.Response{
+			"201": ok("The webhook, including its secret; a secret is generated when none is given and is never returned again.", jsonType, webhook),
+			"400": failure("The webhook is invalid."),
+			"401": unauthorized,
+			"413": tooLarge,
+		},
+	})
+	b.Add(http.MethodGet, APIPrefix+WebhookWithID, &openapi.Operation{
+		OperationID: "getWebhook",
+		Summary:     "Get a webhook",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"200": ok("The webhook, without its secret.", jsonType, webhook),
+			"401": unauthorized,
+			"404": failure("The webhook does not exist."),
+		},
+	})
+	b.Add(http.MethodDelete, APIPrefix+WebhookWithID, &openapi.Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The webhook was deleted; its pending deliveries become dead."},
+			"401": unauthorized,
+			"404": failure("The webhook does not exist."),
+		},
+	})
+	b.Add(http.MethodGet, APIPrefix+WebhookDeliveries, &openapi.Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List a webhook's deliveries, newest first",
+		Tags:        []string{"webhooks"},
+		Parameters: []*openapi.Parameter{
+			query("limit", "Page size, 1 to 100; 20 by default.", integer),
+			query("cursor", "The nextCursor of the previous page.", str),
+		},
+		Responses: map[string]openapi.Response{
+			"200": ok("A page of deliveries.", jsonType, b.Schema(handlers.DeliveryPage{})),
+			"400": failure("The limit or cursor is invalid."),
+			"401": unauthorized,
+			"404": failure("The webhook does not exist."),
+		},
+	})
+	b.Add(http.MethodPost, APIPrefix+WebhookRedeliver, &openapi.Operation{
+		OperationID: "redeliverWebhookDelivery",
+		Summary:     "Send a delivery again",
+		Tags:        []string{"webhooks"},
+		Responses: map[string]openapi.Response{
+			"202": ok("The delivery, queued with a fresh set of attempts.", jsonType, delivery),
+			"401": unauthorized,
+			"404": failure("The webhook or delivery does not exist."),
+		},
+	})
+
+	// Feeds and sitemaps
+	feedResponses := map[string]openapi.Response{
+		"200": {Description: "An RSS 2.0, Atom or JSON Feed document.", Content: map[string]openapi.MediaType{
+			"application/rss+xml":   {},
+			"application/atom+xml":  {},
+			"application/feed+json": {},
+		}},
+		"304": {Description: "The feed has not changed."},
+	}
+	for _, route := range []struct{ template, id, summary string }{
+		{SiteFeed, "getSiteFeed", "Feed of the newest published posts"},
+		{AuthorFeed, "getAuthorFeed", "Feed of an author's newest published posts"},
+		{TagFeed, "getTagFeed", "Feed of the newest published posts with a tag"},
+	} {
+		b.Add(http.MethodGet, APIPrefix+route.template, &openapi.Operation{
+			OperationID: route.id,
+			Summary:     route.summary,
+			Tags:        []string{"feeds"},
+			Responses:   feedResponses,
+		})
+	}
+	b.Add(http.MethodGet, Sitemap, &openapi.Operation{
+		OperationID: "getSitemap",
+		Summary:     "Sitemap, or sitemap index once there are more than 50,000 posts",
+		Tags:        []string{"feeds"},
+		Responses:   map[string]openapi.Response{"200": ok("A sitemap document.", xmlType, str)},
+	})
+	b.Add(http.MethodGet, SitemapChunk, &openapi.Operation{
+		OperationID: "getSitemapChunk",
+		Summary:     "One chunk of a sitemap index",
+		Tags:        []string{"feeds"},
+		Responses: map[string]openapi.Response{
+			"200": ok("A sitemap document.", xmlType, str),
+			"404": failure("The chunk does not exist."),
+		},
+	})
+
+	// Health
+	b.Add(http.MethodGet, Healthz, &openapi.Operation{
+		OperationID: "getLiveness",
+		Summary:     "Liveness probe",
+		Tags:        []string{"health"},
+		Responses:   map[string]openapi.Response{"200": ok("The process is up.", jsonType, b.Schema(handlers.LivenessResponse{}))},
+	})
+	readiness := b.Schema(health.Report{})
+	b.Add(http.MethodGet, Readyz, &openapi.Operation{
+		OperationID: "getReadiness",
+		Summary:     "Readiness probe with per-dependency status",
+		Tags:        []string{"health"},
+		Responses: map[string]openapi.Response{
+			"200": ok("Every dependency is up.", jsonType, readiness),
+			"503": ok("At least one dependency is down.", jsonType, readiness),
+		},
+	})
+
+	// Documentation
+	b.Add(http.MethodGet, APIPrefix+OpenAPIDocument, &openapi.Operation{
+		OperationID: "getOpenAPIDocument",
+		Summary:     "This document",
+		Tags:        []string{"docs"},
+		Responses:   map[string]openapi.Response{"200": ok("The OpenAPI document.", jsonType, &openapi.Schema{Type: "object"})},
+	})
+	b.Add(http.MethodGet, Docs, &openapi.Operation{
+		OperationID: "getDocs",
+		Summary:     "Interactive API documentation",
+		Tags:        []string{"docs"},
+		Responses:   map[string]openapi.Response{"200": ok("The Swagger UI page.", "text/html", str)},
+	})
+	b.Add(http.MethodGet, DocsAsset, &openapi.Operation{
+		OperationID: "getDocsAsset",
+		Summary:     "A vendored Swagger UI asset",
+		Tags:        []string{"docs"},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The asset."},
+			"404": {Description: "No such asset."},
+		},
+	})
+
+	return b.Document()
+}
+
+// openAPIHandler serves the OpenAPI document, marshalled once.
+func openAPIHandler() http.HandlerFunc {
+	body, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		log.Fatalf("Failed to marshal OpenAPI document: %v", err)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", jsonType)
+		if _, err := w.Write(body); err != nil {
+			logging.FromContext(r.Context()).Error("failed to write OpenAPI document", "error", err)
+		}
+	}
+}
+
+// docsAssetHandler serves the vendored Swagger UI files.
+func docsAssetHandler() http.Handler {
+	assets, err := fs.Sub(swaggerUI, "swaggerui")
+	if err != nil {
+		log.Fatalf("Failed to open Swagger UI assets: %v", err)
+	}
+	return http.StripPrefix(Docs+"/", http.FileServer(http.FS(assets)))
+}
+
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+	if _, err := fs.Stat(swaggerUI, "swaggerui/swagger-ui-bundle.js"); err != nil {
+		w.Header().Set("Content-Type", jsonType)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		if err := json.NewEncoder(w).Encode(JSONErrorResponse{
+			Error:       "Service Unavailable",
+			Description: "Swagger UI is not vendored; run make swagger-ui",
+			RequestID:   requestid.ID(r.Context()),
+		}); err != nil {
+			logging.FromContext(r.Context()).Error("failed to encode response", "error", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(docsPage); err != nil {
+		logging.FromContext(r.Context()).Error("failed to write docs page", "error", err)
+	}
+}
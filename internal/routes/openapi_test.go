@@ -0,0 +1,97 @@
+package routes
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPISpecCoversRouter(t *testing.T) {
+	router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		GraphQL:  http.NotFoundHandler(),
+		Webhooks: new(MockWebhookHandler),
+		Events:   http.NotFoundHandler(),
+	})
+	spec := OpenAPISpec()
+
+	routed := 0
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // subrouter prefixes carry no methods
+		}
+		for _, method := range methods {
+			if method == http.MethodOptions {
+				continue // CORS preflight routes are mounted for every path
+			}
+			routed++
+			assert.True(t, spec.Has(method, template), "route %s %s is missing from the OpenAPI document", method, template)
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, routed, len(spec.Operations()), "the OpenAPI document describes routes the router does not serve")
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{})
+
+	t.Run("OpenAPI Document", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var doc struct {
+			OpenAPI    string `json:"openapi"`
+			Components struct {
+				Schemas map[string]struct {
+					Required []string `json:"required"`
+				} `json:"schemas"`
+			} `json:"components"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+		assert.Equal(t, "3.1.0", doc.OpenAPI)
+		assert.Equal(t, []string{"title", "content", "author"}, doc.Components.Schemas["Post"].Required)
+		assert.Contains(t, doc.Components.Schemas, "JSONErrorResponse")
+	})
+
+	t.Run("Swagger UI", func(t *testing.T) {
+		assert.NotContains(t, string(docsPage), "://", "docs.html must only load vendored assets")
+		if _, err := fs.Stat(swaggerUI, "swaggerui/swagger-ui-bundle.js"); err != nil {
+			t.Skip("Swagger UI is not vendored; run make swagger-ui")
+		}
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "/v1/openapi.json")
+
+		for _, asset := range []string{"/docs/swagger-ui.css", "/docs/swagger-ui-bundle.js"} {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, asset, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code, asset)
+			assert.Contains(t, string(docsPage), asset)
+		}
+	})
+
+	t.Run("Missing Docs Asset", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/nope.js", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
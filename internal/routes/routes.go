@@ -1,39 +1,192 @@
 package routes
 
 import (
+	"log/slog"
 	"net/http"
 
+	"blog-api/internal/auth"
 	"blog-api/internal/handlers"
+	"blog-api/internal/logging"
+	"blog-api/internal/metrics"
 	"github.com/gorilla/mux"
 )
 
 const (
-	APIPrefix  = "/v1"
-	PostsBase  = "/posts"
-	PostWithID = "/posts/{id}"
+	APIPrefix        = "/v1"
+	PostsBase        = "/posts"
+	PostWithID       = "/posts/{id}"
+	PostsImport      = "/posts:import"
+	PostsExport      = "/posts:export"
+	PostsBatchGet    = "/posts:batchGet"
+	PostsBatchDelete = "/posts:batchDelete"
+	PostsEvents      = "/posts/events"
+
+	feedFormats = "{format:rss|atom|json}"
+	SiteFeed    = "/feed." + feedFormats
+	AuthorFeed  = "/authors/{author}/feed." + feedFormats
+	TagFeed     = "/tags/{tag}/feed." + feedFormats
+
+	Sitemap      = "/sitemap.xml"
+	SitemapChunk = "/sitemap-{n:[0-9]+}.xml"
+
+	Healthz = "/healthz"
+	Readyz  = "/readyz"
+
+	GraphQL = "/graphql"
+
+	Webhooks          = "/webhooks"
+	WebhookWithID     = "/webhooks/{id}"
+	WebhookDeliveries = "/webhooks/{id}/deliveries"
+	WebhookRedeliver  = "/webhooks/{id}/deliveries/{deliveryId:[^/:]+}:redeliver"
+
+	OpenAPIDocument = "/openapi.json"
+	Docs            = "/docs"
+	DocsAsset       = "/docs/{asset}"
+
+	// Metrics is only mounted when Config.MetricsToken is set and is not part
+	// of the public API document.
+	Metrics = "/metrics"
 )
 
-func SetupRouter(postHandler handlers.PostHandlerInterface) *mux.Router {
+// Config holds the settings of the middleware chain. The zero value logs
+// through slog.Default() without bodies or redaction, records no metrics,
+// allows any origin, requires no credentials and does not limit bodies.
+type Config struct {
+	Logger  *slog.Logger
+	Logging logging.Config
+	Metrics metrics.Recorder
+	// MetricsHandler is served at /metrics behind a bearer token when both it
+	// and MetricsToken are set. Otherwise metrics are only reachable through a
+	// separate listener.
+	MetricsHandler http.Handler
+	MetricsToken   string
+	CORS           CORSConfig
+	// Auth guards the write endpoints when it has credentials configured.
+	Auth   *auth.Authenticator
+	Limits Limits
+	// GraphQL is served at /v1/graphql when set. It authenticates mutations
+	// itself, as only it can tell them from queries.
+	GraphQL http.Handler
+	// Webhooks is served at /v1/webhooks when set. Every webhook endpoint
+	// requires credentials, as webhooks carry signing secrets, and denies
+	// every request when Auth has none.
+	Webhooks handlers.WebhookHandlerInterface
+	// Events streams post events at /v1/posts/events when set, which only
+	// the standalone server can do.
+	Events http.Handler
+}
+
+// Limits bounds request bodies; zero means unlimited. MaxImportBytes applies
+// to imports and MaxBodyBytes to every other request.
+type Limits struct {
+	MaxBodyBytes   int64
+	MaxImportBytes int64
+}
+
+func SetupRouter(
+	postHandler handlers.PostHandlerInterface,
+	feedHandler handlers.FeedHandlerInterface,
+	sitemapHandler handlers.SitemapHandlerInterface,
+	healthHandler handlers.HealthHandlerInterface,
+	cfg Config,
+) *mux.Router {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	recorder := cfg.Metrics
+	if recorder == nil {
+		recorder = metrics.Nop{}
+	}
+
 	router := mux.NewRouter().StrictSlash(true)
 
 	router.SkipClean(true)
 
-	allowedOrigins := []string{"*"} // We can replace "*" with specific origins for production
-	allowedMethods := []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	allowedHeaders := []string{"Content-Type", "Authorization"}
-
-	router.Use(loggingMiddleware)
-	router.Use(corsMiddleware(allowedOrigins, allowedMethods, allowedHeaders))
+	router.Use(requestIDMiddleware)
+	router.Use(tracingMiddleware)
+	router.Use(loggingMiddleware(logger, cfg.Logging))
+	router.Use(metricsMiddleware(recorder))
+	router.Use(corsMiddleware(cfg.CORS))
 	router.Use(errorHandlingMiddleware)
 
+	router.HandleFunc(Healthz, healthHandler.Liveness).Methods(http.MethodGet)
+	router.HandleFunc(Readyz, healthHandler.Readiness).Methods(http.MethodGet)
+	router.HandleFunc(Docs, docsHandler).Methods(http.MethodGet)
+	router.Handle(DocsAsset, docsAssetHandler()).Methods(http.MethodGet)
+	if cfg.MetricsHandler != nil && cfg.MetricsToken != "" {
+		router.Handle(Metrics, bearerAuth(cfg.MetricsToken, cfg.MetricsHandler)).Methods(http.MethodGet)
+	}
+	router.HandleFunc(Sitemap, sitemapHandler.GetSitemap).Methods(http.MethodGet)
+	router.HandleFunc(SitemapChunk, sitemapHandler.GetSitemapChunk).Methods(http.MethodGet)
+
 	api := router.PathPrefix(APIPrefix).Subrouter()
 
+	// write wraps the handlers that change posts, and the export, which
+	// reads drafts too.
+	write := func(h http.HandlerFunc) http.Handler {
+		if cfg.Auth == nil || !cfg.Auth.Enabled() {
+			return h
+		}
+		return requireAuth(cfg.Auth, h)
+	}
+	// private wraps the webhook handlers, which always require credentials:
+	// without any configured every request is denied.
+	private := func(h http.HandlerFunc) http.Handler {
+		authenticator := cfg.Auth
+		if authenticator == nil {
+			authenticator = auth.New(auth.Config{})
+		}
+		return requireAuth(authenticator, h)
+	}
+	limit := func(maxBytes int64, h http.Handler) http.Handler {
+		if maxBytes <= 0 {
+			return h
+		}
+		return limitBody(maxBytes, h)
+	}
+	body := func(h http.Handler) http.Handler { return limit(cfg.Limits.MaxBodyBytes, h) }
+
+	api.HandleFunc(OpenAPIDocument, openAPIHandler()).Methods(http.MethodGet)
+
+	api.Handle(PostsImport, limit(cfg.Limits.MaxImportBytes, write(postHandler.ImportPosts))).Methods(http.MethodPost)
+	api.Handle(PostsExport, write(postHandler.ExportPosts)).Methods(http.MethodGet)
+	api.Handle(PostsBatchGet, body(http.HandlerFunc(postHandler.BatchGetPosts))).Methods(http.MethodPost)
+	api.Handle(PostsBatchDelete, body(write(postHandler.BatchDeletePosts))).Methods(http.MethodPost)
+	if cfg.Events != nil {
+		// Registered before PostWithID, which would match it too.
+		api.Handle(PostsEvents, cfg.Events).Methods(http.MethodGet)
+	}
 	api.HandleFunc(PostsBase, postHandler.GetAllPosts).Methods(http.MethodGet)
 	api.HandleFunc(PostWithID, postHandler.GetPostByID).Methods(http.MethodGet)
-	api.HandleFunc(PostsBase, postHandler.CreatePost).Methods(http.MethodPost)
-	api.HandleFunc(PostWithID, postHandler.UpdatePost).Methods(http.MethodPut)
-	api.HandleFunc(PostWithID, postHandler.PatchPost).Methods(http.MethodPatch)
-	api.HandleFunc(PostWithID, postHandler.DeletePost).Methods(http.MethodDelete)
+	api.Handle(PostsBase, body(write(postHandler.CreatePost))).Methods(http.MethodPost)
+	api.Handle(PostWithID, body(write(postHandler.UpdatePost))).Methods(http.MethodPut)
+	api.Handle(PostWithID, body(write(postHandler.PatchPost))).Methods(http.MethodPatch)
+	api.Handle(PostWithID, write(postHandler.DeletePost)).Methods(http.MethodDelete)
+
+	if cfg.GraphQL != nil {
+		api.Handle(GraphQL, body(cfg.GraphQL)).Methods(http.MethodPost)
+	}
+
+	if wh := cfg.Webhooks; wh != nil {
+		api.Handle(Webhooks, private(wh.ListWebhooks)).Methods(http.MethodGet)
+		api.Handle(Webhooks, body(private(wh.CreateWebhook))).Methods(http.MethodPost)
+		api.Handle(WebhookWithID, private(wh.GetWebhook)).Methods(http.MethodGet)
+		api.Handle(WebhookWithID, private(wh.DeleteWebhook)).Methods(http.MethodDelete)
+		api.Handle(WebhookDeliveries, private(wh.ListDeliveries)).Methods(http.MethodGet)
+		api.Handle(WebhookRedeliver, private(wh.RedeliverDelivery)).Methods(http.MethodPost)
+	}
+
+	api.HandleFunc(SiteFeed, feedHandler.GetFeed).Methods(http.MethodGet)
+	api.HandleFunc(AuthorFeed, feedHandler.GetFeed).Methods(http.MethodGet)
+	api.HandleFunc(TagFeed, feedHandler.GetFeed).Methods(http.MethodGet)
+
+	served := mountPreflight(router)
+	for template := range cfg.CORS.Routes {
+		if _, ok := served[template]; !ok {
+			logger.Warn("CORS policy for unknown route template is ignored", "route", template)
+		}
+	}
 
 	return router
 }
@@ -1,12 +1,23 @@
 package routes
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"blog-api/internal/auth"
+	"blog-api/internal/events"
+	"blog-api/internal/handlers"
+	"blog-api/internal/models"
+	"blog-api/internal/requestid"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockPostHandler struct {
@@ -63,9 +74,136 @@ func (m *MockPostHandler) DeletePost(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (m *MockPostHandler) ImportPosts(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("ImportPosts"))
+	if err != nil {
+		return
+	}
+}
+
+func (m *MockPostHandler) ExportPosts(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("ExportPosts"))
+	if err != nil {
+		return
+	}
+}
+
+func (m *MockPostHandler) BatchGetPosts(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("BatchGetPosts"))
+	if err != nil {
+		return
+	}
+}
+
+func (m *MockPostHandler) BatchDeletePosts(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("BatchDeletePosts"))
+	if err != nil {
+		return
+	}
+}
+
+type MockFeedHandler struct {
+	mock.Mock
+}
+
+func (m *MockFeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("GetFeed:" + mux.Vars(r)["format"]))
+	if err != nil {
+		return
+	}
+}
+
+type MockSitemapHandler struct {
+	mock.Mock
+}
+
+func (m *MockSitemapHandler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (m *MockSitemapHandler) GetSitemapChunk(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("GetSitemapChunk:" + mux.Vars(r)["n"]))
+	if err != nil {
+		return
+	}
+}
+
+type MockHealthHandler struct {
+	mock.Mock
+}
+
+func (m *MockHealthHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("Liveness"))
+	if err != nil {
+		return
+	}
+}
+
+func (m *MockHealthHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	m.Called(w, r)
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte("Readiness"))
+	if err != nil {
+		return
+	}
+}
+
+type MockWebhookHandler struct {
+	mock.Mock
+}
+
+func (m *MockWebhookHandler) serve(name string, w http.ResponseWriter, r *http.Request) {
+	m.MethodCalled(name, w, r)
+	w.WriteHeader(http.StatusOK)
+	vars := mux.Vars(r)
+	_, _ = w.Write([]byte(name + ":" + vars["id"] + ":" + vars["deliveryId"]))
+}
+
+func (m *MockWebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	m.serve("CreateWebhook", w, r)
+}
+
+func (m *MockWebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	m.serve("ListWebhooks", w, r)
+}
+
+func (m *MockWebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	m.serve("GetWebhook", w, r)
+}
+
+func (m *MockWebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	m.serve("DeleteWebhook", w, r)
+}
+
+func (m *MockWebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	m.serve("ListDeliveries", w, r)
+}
+
+func (m *MockWebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	m.serve("RedeliverDelivery", w, r)
+}
+
 func TestRoutes(t *testing.T) {
 	mockHandler := new(MockPostHandler)
-	router := SetupRouter(mockHandler)
+	mockFeedHandler := new(MockFeedHandler)
+	mockSitemapHandler := new(MockSitemapHandler)
+	mockHealthHandler := new(MockHealthHandler)
+	router := SetupRouter(mockHandler, mockFeedHandler, mockSitemapHandler, mockHealthHandler, Config{})
 
 	t.Run("Route GetAllPosts", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
@@ -79,6 +217,18 @@ func TestRoutes(t *testing.T) {
 		mockHandler.AssertExpectations(t)
 	})
 
+	t.Run("Route Echoes Request ID", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts", nil)
+		req.Header.Set(requestid.Header, "trace-1")
+		rec := httptest.NewRecorder()
+		mockHandler.On("GetAllPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, "trace-1", rec.Header().Get(requestid.Header))
+		mockHandler.AssertExpectations(t)
+	})
+
 	t.Run("Route GetPostByID", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/v1/posts/1", nil)
 		rec := httptest.NewRecorder()
@@ -125,4 +275,410 @@ func TestRoutes(t *testing.T) {
 		assert.Equal(t, http.StatusNoContent, rec.Code)
 		mockHandler.AssertExpectations(t)
 	})
+	t.Run("Route ImportPosts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/posts:import", nil)
+		rec := httptest.NewRecorder()
+		mockHandler.On("ImportPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ImportPosts", rec.Body.String())
+		mockHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route ExportPosts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts:export", nil)
+		rec := httptest.NewRecorder()
+		mockHandler.On("ExportPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "ExportPosts", rec.Body.String())
+		mockHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route BatchGetPosts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/posts:batchGet", nil)
+		rec := httptest.NewRecorder()
+		mockHandler.On("BatchGetPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "BatchGetPosts", rec.Body.String())
+		mockHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route BatchDeletePosts", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/posts:batchDelete", nil)
+		rec := httptest.NewRecorder()
+		mockHandler.On("BatchDeletePosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "BatchDeletePosts", rec.Body.String())
+		mockHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route Feeds", func(t *testing.T) {
+		for _, path := range []string{"/v1/feed.rss", "/v1/authors/jane/feed.atom", "/v1/tags/go/feed.json"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			rec := httptest.NewRecorder()
+			mockFeedHandler.On("GetFeed", mock.Anything, mock.Anything).Return().Once()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code, path)
+		}
+		mockFeedHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route Feed Unknown Format", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/feed.txt", nil)
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+	t.Run("Route Sitemap", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+		rec := httptest.NewRecorder()
+		mockSitemapHandler.On("GetSitemap", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockSitemapHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route Sitemap Chunk", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/sitemap-2.xml", nil)
+		rec := httptest.NewRecorder()
+		mockSitemapHandler.On("GetSitemapChunk", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "GetSitemapChunk:2", rec.Body.String())
+		mockSitemapHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route Liveness", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+		rec := httptest.NewRecorder()
+		mockHealthHandler.On("Liveness", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Liveness", rec.Body.String())
+		mockHealthHandler.AssertExpectations(t)
+	})
+
+	t.Run("Route Readiness", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rec := httptest.NewRecorder()
+		mockHealthHandler.On("Readiness", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "Readiness", rec.Body.String())
+		mockHealthHandler.AssertExpectations(t)
+	})
+}
+
+func TestMetricsRoute(t *testing.T) {
+	metricsHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("metrics"))
+	})
+
+	t.Run("Not Mounted Without Token", func(t *testing.T) {
+		router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{MetricsHandler: metricsHandler})
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Metrics, nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		MetricsHandler: metricsHandler,
+		MetricsToken:   "s3cret",
+	})
+
+	t.Run("Rejects Missing Token", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Metrics, nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Equal(t, "Bearer", rec.Header().Get("WWW-Authenticate"))
+	})
+
+	t.Run("Rejects Wrong Token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, Metrics, nil)
+		req.Header.Set("Authorization", "Bearer nope")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Serves With Token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, Metrics, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "metrics", rec.Body.String())
+	})
+}
+
+func TestWriteAuth(t *testing.T) {
+	mockHandler := new(MockPostHandler)
+	router := SetupRouter(mockHandler, new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		Auth: auth.New(auth.Config{APIKeys: []string{"0123456789abcdef"}}),
+	})
+
+	t.Run("Reads Stay Public", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mockHandler.On("GetAllPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/posts", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockHandler.AssertExpectations(t)
+	})
+
+	t.Run("Rejects Writes Without Credentials", func(t *testing.T) {
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodPost, "/v1/posts", nil),
+			httptest.NewRequest(http.MethodPut, "/v1/posts/1", nil),
+			httptest.NewRequest(http.MethodPatch, "/v1/posts/1", nil),
+			httptest.NewRequest(http.MethodDelete, "/v1/posts/1", nil),
+			httptest.NewRequest(http.MethodPost, "/v1/posts:import", nil),
+			httptest.NewRequest(http.MethodGet, "/v1/posts:export", nil),
+			httptest.NewRequest(http.MethodPost, "/v1/posts:batchDelete", nil),
+		} {
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code, req.Method+" "+req.URL.Path)
+			assert.Contains(t, rec.Body.String(), `"error":"Unauthorized"`)
+		}
+		mockHandler.AssertNotCalled(t, "CreatePost", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Accepts API Key", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/posts", nil)
+		req.Header.Set(auth.APIKeyHeader, "0123456789abcdef")
+		rec := httptest.NewRecorder()
+		mockHandler.On("CreatePost", mock.Anything, mock.MatchedBy(func(r *http.Request) bool {
+			p, ok := auth.FromContext(r.Context())
+			return ok && p.Method == auth.MethodAPIKey
+		})).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		mockHandler.AssertExpectations(t)
+	})
+}
+
+func TestWebhookRoutes(t *testing.T) {
+	t.Run("Not Mounted Without Handler", func(t *testing.T) {
+		router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{})
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	webhookHandler := new(MockWebhookHandler)
+	router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		Auth:     auth.New(auth.Config{APIKeys: []string{"0123456789abcdef"}}),
+		Webhooks: webhookHandler,
+	})
+
+	t.Run("Routes To Handlers", func(t *testing.T) {
+		for _, tc := range []struct{ method, path, want string }{
+			{http.MethodGet, "/v1/webhooks", "ListWebhooks::"},
+			{http.MethodPost, "/v1/webhooks", "CreateWebhook::"},
+			{http.MethodGet, "/v1/webhooks/w1", "GetWebhook:w1:"},
+			{http.MethodDelete, "/v1/webhooks/w1", "DeleteWebhook:w1:"},
+			{http.MethodGet, "/v1/webhooks/w1/deliveries", "ListDeliveries:w1:"},
+			{http.MethodPost, "/v1/webhooks/w1/deliveries/d1:redeliver", "RedeliverDelivery:w1:d1"},
+		} {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req.Header.Set(auth.APIKeyHeader, "0123456789abcdef")
+			rec := httptest.NewRecorder()
+			name := tc.want[:strings.Index(tc.want, ":")]
+			webhookHandler.On(name, mock.Anything, mock.Anything).Return().Once()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code, tc.method+" "+tc.path)
+			assert.Equal(t, tc.want, rec.Body.String())
+		}
+		webhookHandler.AssertExpectations(t)
+	})
+
+	t.Run("Requires Credentials For Reads", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("Denies Everything Without Credentials Configured", func(t *testing.T) {
+		for _, authenticator := range []*auth.Authenticator{nil, auth.New(auth.Config{})} {
+			handler := new(MockWebhookHandler)
+			router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+				Auth:     authenticator,
+				Webhooks: handler,
+			})
+			req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(`{}`))
+			req.Header.Set(auth.APIKeyHeader, "0123456789abcdef")
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			handler.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func TestBodyLimits(t *testing.T) {
+	mockHandler := new(MockPostHandler)
+	router := SetupRouter(mockHandler, new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		Limits: Limits{MaxBodyBytes: 8, MaxImportBytes: 64},
+	})
+
+	t.Run("Rejects Large Body", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(`{"title":"too long"}`)))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Contains(t, rec.Body.String(), "must not exceed 8 bytes")
+	})
+
+	t.Run("Import Has Its Own Limit", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		mockHandler.On("ImportPosts", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/posts:import", strings.NewReader(`{"title":"fits"}`)))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		mockHandler.AssertExpectations(t)
+	})
+}
+
+// postsByID serves the posts of a broker in the event stream tests.
+type postsByID map[string]*models.Post
+
+func (p postsByID) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
+	if post, ok := p[id]; ok {
+		return post, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// readEvent returns the next event or comment of an event stream, without
+// its trailing blank line.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestPostEventsRoute(t *testing.T) {
+	broker := events.NewBroker(postsByID{
+		"1": {ID: "1", Author: "ann", Version: 1},
+		"2": {ID: "2", Author: "bob", Version: 1},
+		"3": {ID: "3", Author: "ann", Version: 2},
+	}, 10)
+	broker.EnableReplay(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go broker.Run(ctx)
+
+	router := SetupRouter(new(MockPostHandler), new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{
+		Events: handlers.NewPostEventsHandler(broker, handlers.PostEventsConfig{}),
+	})
+	// Closed by a cleanup, so that it runs after the streams are closed.
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+
+	connect := func(t *testing.T, query, lastEventID string) *bufio.Reader {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/posts/events"+query, nil)
+		require.NoError(t, err)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		stream := bufio.NewReader(resp.Body)
+		assert.Equal(t, "retry: 3000\n", readEvent(t, stream))
+		return stream
+	}
+
+	stream := connect(t, "?author=ann", "")
+	broker.PostChanged(ctx, "1", events.TypeCreated)
+	broker.PostChanged(ctx, "2", events.TypeCreated)
+	broker.PostChanged(ctx, "3", events.TypeUpdated)
+
+	first := readEvent(t, stream)
+	assert.Contains(t, first, "event: post.created\ndata: {\"id\":\"1\"")
+	third := readEvent(t, stream)
+	assert.Contains(t, third, "event: post.updated\ndata: {\"id\":\"3\"", "post 2 is filtered out")
+	lastEventID := strings.TrimPrefix(strings.SplitN(first, "\n", 2)[0], "id: ")
+
+	t.Run("Resumes After Last Event ID", func(t *testing.T) {
+		resumed := connect(t, "", lastEventID)
+
+		assert.Contains(t, readEvent(t, resumed), "data: {\"id\":\"2\"")
+		assert.Contains(t, readEvent(t, resumed), "data: {\"id\":\"3\"")
+	})
+
+	t.Run("Resets Unknown Event IDs", func(t *testing.T) {
+		resumed := connect(t, "", "earlier-1")
+
+		assert.Equal(t, "event: reset\ndata: {}\n", readEvent(t, resumed))
+	})
+
+	t.Run("Not Mounted Without Handler", func(t *testing.T) {
+		postHandler := new(MockPostHandler)
+		router := SetupRouter(postHandler, new(MockFeedHandler), new(MockSitemapHandler), new(MockHealthHandler), Config{})
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts/events", nil)
+		rec := httptest.NewRecorder()
+		postHandler.On("GetPostByID", mock.Anything, mock.Anything).Return().Once()
+
+		router.ServeHTTP(rec, req)
+
+		assert.Equal(t, "GetPostByID", rec.Body.String())
+		postHandler.AssertExpectations(t)
+	})
 }
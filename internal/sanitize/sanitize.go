@@ -0,0 +1,261 @@
+// Package sanitize implements an allowlist-based HTML sanitizer for post content.
+package sanitize
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Removal kinds reported by Sanitize.
+const (
+	KindElement   = "element"   // a disallowed element that was dropped together with its content
+	KindTag       = "tag"       // a disallowed tag whose text content was kept
+	KindAttribute = "attribute" // a disallowed attribute, or one with a disallowed URL scheme
+	KindComment   = "comment"
+)
+
+// Policy describes which markup is allowed to survive sanitization.
+type Policy struct {
+	// AllowedTags maps each allowed tag to the attributes allowed on it.
+	AllowedTags map[string][]string
+	// GlobalAttributes are allowed on every allowed tag.
+	GlobalAttributes []string
+	// URLAttributes are attributes whose values are URLs and must use an allowed scheme.
+	URLAttributes []string
+	// AllowedSchemes lists the URL schemes accepted in URLAttributes. Relative URLs are always allowed.
+	AllowedSchemes []string
+	// DropContentTags are removed together with everything inside them.
+	DropContentTags []string
+	// NofollowExternalLinks adds rel="nofollow" to links that leave InternalHosts.
+	NofollowExternalLinks bool
+	// InternalHosts are hosts whose links are not considered external.
+	InternalHosts []string
+}
+
+// DefaultPolicy returns a policy suitable for user-generated blog content.
+func DefaultPolicy() *Policy {
+	return &Policy{
+		AllowedTags: map[string][]string{
+			"a":          {"href", "title", "rel", "class"},
+			"abbr":       {"title"},
+			"b":          nil,
+			"blockquote": {"cite"},
+			"br":         nil,
+			"code":       {"class"},
+			"dd":         nil,
+			"del":        nil,
+			"div":        nil,
+			"dl":         nil,
+			"dt":         nil,
+			"em":         nil,
+			"figcaption": nil,
+			"figure":     nil,
+			"h1":         {"id"},
+			"h2":         {"id"},
+			"h3":         {"id"},
+			"h4":         {"id"},
+			"h5":         {"id"},
+			"h6":         {"id"},
+			"hr":         nil,
+			"i":          nil,
+			"img":        {"src", "alt", "title", "width", "height"},
+			"input":      {"type", "checked", "disabled"},
+			"kbd":        nil,
+			"li":         nil,
+			"mark":       nil,
+			"ol":         {"start"},
+			"p":          nil,
+			"pre":        nil,
+			"q":          {"cite"},
+			"s":          nil,
+			"span":       nil,
+			"strong":     nil,
+			"sub":        nil,
+			"sup":        nil,
+			"table":      nil,
+			"tbody":      nil,
+			"td":         {"align"},
+			"th":         {"align"},
+			"thead":      nil,
+			"tr":         nil,
+			"ul":         nil,
+		},
+		URLAttributes:         []string{"href", "src", "cite"},
+		AllowedSchemes:        []string{"http", "https", "mailto"},
+		DropContentTags:       []string{"script", "style", "iframe", "object", "embed", "noscript", "template", "textarea", "select", "title"},
+		NofollowExternalLinks: true,
+	}
+}
+
+// Removal counts how many times a piece of markup was stripped.
+type Removal struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Report lists what Sanitize removed from its input.
+type Report struct {
+	Removed []Removal `json:"removed"`
+}
+
+// Changed reports whether anything was stripped.
+func (r *Report) Changed() bool {
+	return r != nil && len(r.Removed) > 0
+}
+
+// Sanitize strips everything the policy does not allow and reports what it removed.
+// The returned report is nil when nothing was stripped.
+func (p *Policy) Sanitize(input string) (string, *Report) {
+	var (
+		out       bytes.Buffer
+		skipDepth int
+		removed   = make(map[Removal]int)
+	)
+
+	z := html.NewTokenizer(strings.NewReader(input))
+	for {
+		// Reading from a string can only end in io.EOF.
+		if z.Next() == html.ErrorToken {
+			break
+		}
+		token := z.Token()
+
+		switch token.Type {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if skipDepth > 0 {
+				if token.Type == html.StartTagToken && p.dropsContent(token.Data) {
+					skipDepth++
+				}
+				continue
+			}
+			if p.dropsContent(token.Data) {
+				removed[Removal{Kind: KindElement, Name: token.Data}]++
+				if token.Type == html.StartTagToken {
+					skipDepth++
+				}
+				continue
+			}
+			if _, ok := p.AllowedTags[token.Data]; !ok {
+				removed[Removal{Kind: KindTag, Name: token.Data}]++
+				continue
+			}
+			token.Attr = p.filterAttributes(token.Data, token.Attr, removed)
+			out.WriteString(token.String())
+
+		case html.EndTagToken:
+			if skipDepth > 0 {
+				if p.dropsContent(token.Data) {
+					skipDepth--
+				}
+				continue
+			}
+			if _, ok := p.AllowedTags[token.Data]; ok {
+				out.WriteString(token.String())
+			}
+
+		case html.TextToken:
+			if skipDepth == 0 {
+				out.WriteString(token.String())
+			}
+
+		case html.CommentToken:
+			if skipDepth == 0 {
+				removed[Removal{Kind: KindComment, Name: "comment"}]++
+			}
+		}
+	}
+
+	return out.String(), newReport(removed)
+}
+
+func (p *Policy) filterAttributes(tag string, attrs []html.Attribute, removed map[Removal]int) []html.Attribute {
+	nofollow := p.NofollowExternalLinks && tag == "a" && p.isExternalLink(attrs)
+	allowed := make([]html.Attribute, 0, len(attrs)+1)
+
+	for _, attr := range attrs {
+		if !p.attributeAllowed(tag, attr.Key) {
+			removed[Removal{Kind: KindAttribute, Name: tag + "." + attr.Key}]++
+			continue
+		}
+		if contains(p.URLAttributes, attr.Key) {
+			if _, ok := p.parseURL(attr.Val); !ok {
+				removed[Removal{Kind: KindAttribute, Name: tag + "." + attr.Key}]++
+				continue
+			}
+		}
+		if nofollow && attr.Key == "rel" {
+			continue // replaced below
+		}
+		allowed = append(allowed, attr)
+	}
+
+	if nofollow {
+		allowed = append(allowed, html.Attribute{Key: "rel", Val: "nofollow"})
+	}
+	return allowed
+}
+
+// isExternalLink reports whether the href in attrs points outside InternalHosts.
+func (p *Policy) isExternalLink(attrs []html.Attribute) bool {
+	for _, attr := range attrs {
+		if attr.Key != "href" {
+			continue
+		}
+		u, ok := p.parseURL(attr.Val)
+		return ok && u.Host != "" && !contains(p.InternalHosts, u.Hostname())
+	}
+	return false
+}
+
+func (p *Policy) attributeAllowed(tag, attr string) bool {
+	return contains(p.AllowedTags[tag], attr) || contains(p.GlobalAttributes, attr)
+}
+
+// parseURL reports whether raw is a relative URL or uses an allowed scheme.
+func (p *Policy) parseURL(raw string) (*url.URL, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, false
+	}
+	if u.Scheme != "" && !contains(p.AllowedSchemes, strings.ToLower(u.Scheme)) {
+		return nil, false
+	}
+	return u, true
+}
+
+func (p *Policy) dropsContent(tag string) bool {
+	return contains(p.DropContentTags, tag)
+}
+
+func newReport(removed map[Removal]int) *Report {
+	if len(removed) == 0 {
+		return nil
+	}
+
+	report := &Report{Removed: make([]Removal, 0, len(removed))}
+	for r, count := range removed {
+		r.Count = count
+		report.Removed = append(report.Removed, r)
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		if report.Removed[i].Kind != report.Removed[j].Kind {
+			return report.Removed[i].Kind < report.Removed[j].Kind
+		}
+		return report.Removed[i].Name < report.Removed[j].Name
+	})
+	return report
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
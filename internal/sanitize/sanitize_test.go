@@ -0,0 +1,71 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.InternalHosts = []string{"blog.example.com"}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		removed []Removal
+	}{
+		{
+			name:  "Allowed Markup Is Kept",
+			input: `<p>Hello <strong>world</strong> &amp; <a href="/posts/1">friends</a></p>`,
+			want:  `<p>Hello <strong>world</strong> &amp; <a href="/posts/1">friends</a></p>`,
+		},
+		{
+			name:    "Script Dropped With Content",
+			input:   `<p>a</p><script>alert("x")</script><p>b</p>`,
+			want:    `<p>a</p><p>b</p>`,
+			removed: []Removal{{Kind: KindElement, Name: "script", Count: 1}},
+		},
+		{
+			name:    "Unknown Tag Unwrapped",
+			input:   `<marquee>moving</marquee>`,
+			want:    `moving`,
+			removed: []Removal{{Kind: KindTag, Name: "marquee", Count: 1}},
+		},
+		{
+			name:  "Event Handlers And Bad Schemes Removed",
+			input: `<img src="javascript:alert(1)" onerror="x()" alt="pic"><a href="JAVASCRIPT:evil()">x</a>`,
+			want:  `<img alt="pic"><a>x</a>`,
+			removed: []Removal{
+				{Kind: KindAttribute, Name: "a.href", Count: 1},
+				{Kind: KindAttribute, Name: "img.onerror", Count: 1},
+				{Kind: KindAttribute, Name: "img.src", Count: 1},
+			},
+		},
+		{
+			name:  "External Links Get Nofollow",
+			input: `<a href="https://elsewhere.com" rel="me">x</a><a href="https://blog.example.com/a">y</a>`,
+			want:  `<a href="https://elsewhere.com" rel="nofollow">x</a><a href="https://blog.example.com/a">y</a>`,
+		},
+		{
+			name:    "Comments Removed",
+			input:   `<p>a<!-- hidden --></p>`,
+			want:    `<p>a</p>`,
+			removed: []Removal{{Kind: KindComment, Name: "comment", Count: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, report := policy.Sanitize(tt.input)
+
+			assert.Equal(t, tt.want, got)
+			if tt.removed == nil {
+				assert.False(t, report.Changed(), "Expected nothing to be stripped")
+			} else {
+				assert.Equal(t, tt.removed, report.Removed)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Package search keeps the published posts in an Elasticsearch or OpenSearch
+// index, through the document API both share.
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"blog-api/internal/models"
+)
+
+// Config holds the index settings. Zero values select the defaults.
+type Config struct {
+	// Endpoint is the base URL of the cluster, such as
+	// https://search.example.com:9200.
+	Endpoint string
+	// Index is the name of the index; "posts" by default.
+	Index string
+	// APIKey is sent as "Authorization: ApiKey <key>" when set.
+	APIKey string
+	// Timeout bounds each request; 10s by default.
+	Timeout time.Duration
+}
+
+const (
+	defaultIndex   = "posts"
+	defaultTimeout = 10 * time.Second
+	// maxErrorBytes bounds the part of an error response quoted in errors.
+	maxErrorBytes = 512
+)
+
+// Indexer writes posts to the index as documents keyed by the post ID.
+type Indexer struct {
+	cfg    Config
+	client *http.Client
+}
+
+func NewIndexer(cfg Config) *Indexer {
+	if cfg.Index == "" {
+		cfg.Index = defaultIndex
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+	return &Indexer{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// IndexPost stores the post as its document. The post version is the
+// document's external version, so a stale change handled again, or late,
+// cannot replace a newer one; the index answers it with a conflict, which is
+// not an error.
+func (i *Indexer) IndexPost(ctx context.Context, post *models.Post) error {
+	body, err := json.Marshal(post)
+	if err != nil {
+		return fmt.Errorf("failed to encode post: %w", err)
+	}
+	query := url.Values{"version": {strconv.FormatInt(post.Version, 10)}, "version_type": {"external"}}
+	return i.do(ctx, http.MethodPut, post.ID, query, body, http.StatusConflict)
+}
+
+// RemovePost deletes the post's document. A document that does not exist is
+// not an error.
+func (i *Indexer) RemovePost(ctx context.Context, id string) error {
+	return i.do(ctx, http.MethodDelete, id, nil, nil, http.StatusNotFound)
+}
+
+// do sends a request for the document of the post with the given ID. Any
+// 2xx status and the listed statuses count as success.
+func (i *Indexer) do(ctx context.Context, method, id string, query url.Values, body []byte, accepted ...int) error {
+	target := fmt.Sprintf("%s/%s/_doc/%s", i.cfg.Endpoint, url.PathEscape(i.cfg.Index), url.PathEscape(id))
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if i.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+i.cfg.APIKey)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	for _, status := range accepted {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+	return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+}
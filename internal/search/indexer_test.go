@@ -0,0 +1,72 @@
+package search
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"blog-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRequest struct {
+	method, uri, auth, body string
+}
+
+func testIndexer(t *testing.T, status int) (*Indexer, *[]recordedRequest) {
+	t.Helper()
+	var requests []recordedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, recordedRequest{r.Method, r.URL.RequestURI(), r.Header.Get("Authorization"), string(body)})
+		w.WriteHeader(status)
+		io.WriteString(w, `{"error":"boom"}`)
+	}))
+	t.Cleanup(server.Close)
+	return NewIndexer(Config{Endpoint: server.URL + "/", APIKey: "key"}), &requests
+}
+
+func TestIndexer(t *testing.T) {
+	ctx := context.Background()
+	post := &models.Post{ID: "p 1", Title: "Hello", Version: 3}
+
+	t.Run("Indexes With The Post Version", func(t *testing.T) {
+		indexer, requests := testIndexer(t, http.StatusCreated)
+
+		err := indexer.IndexPost(ctx, post)
+
+		assert.NoError(t, err)
+		assert.Len(t, *requests, 1)
+		req := (*requests)[0]
+		assert.Equal(t, http.MethodPut, req.method)
+		assert.Equal(t, "/posts/_doc/p%201?version=3&version_type=external", req.uri)
+		assert.Equal(t, "ApiKey key", req.auth)
+		assert.Contains(t, req.body, `"title":"Hello"`)
+	})
+
+	t.Run("Ignores Stale Versions", func(t *testing.T) {
+		indexer, _ := testIndexer(t, http.StatusConflict)
+
+		assert.NoError(t, indexer.IndexPost(ctx, post))
+	})
+
+	t.Run("Removes Missing Documents", func(t *testing.T) {
+		indexer, requests := testIndexer(t, http.StatusNotFound)
+
+		err := indexer.RemovePost(ctx, "p1")
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.MethodDelete, (*requests)[0].method)
+		assert.Equal(t, "/posts/_doc/p1", (*requests)[0].uri)
+	})
+
+	t.Run("Reports Failures", func(t *testing.T) {
+		indexer, _ := testIndexer(t, http.StatusServiceUnavailable)
+
+		assert.ErrorContains(t, indexer.IndexPost(ctx, post), `unexpected response status 503: {"error":"boom"}`)
+		assert.ErrorContains(t, indexer.RemovePost(ctx, "p1"), "unexpected response status 503")
+	})
+}
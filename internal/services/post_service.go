@@ -1,29 +1,74 @@
 package services
 
 import (
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/events"
 	"blog-api/internal/handlers"
+	"blog-api/internal/logging"
 	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/sanitize"
+	"blog-api/internal/tracing"
 	"context"
 	"errors"
 	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("blog-api/internal/services")
+
 type Repository interface {
-	GetAll(ctx context.Context, page, limit int) ([]*models.Post, error)
+	GetAll(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error)
 	GetByID(ctx context.Context, id string) (*models.Post, error)
+	GetFields(ctx context.Context, id string, fields []string) (*models.Post, error)
 	Create(ctx context.Context, post *models.Post) (*models.Post, error)
 	Update(ctx context.Context, id string, updatedPost *models.Post) (*models.Post, error)
 	Delete(ctx context.Context, id string) error
+	ListPublished(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error)
+	ListPage(ctx context.Context, limit int, cursor string) ([]*models.Post, string, error)
+	BatchCreate(ctx context.Context, posts []*models.Post) []error
+	BatchGet(ctx context.Context, ids []string) (map[string]*models.Post, error)
+	TransactDelete(ctx context.Context, ids []string) error
+	BatchDelete(ctx context.Context, ids []string) []error
+}
+
+// ErrBatchAborted is reported for posts left untouched because a transactional batch was aborted.
+var ErrBatchAborted = custom_errors.ErrBatchAborted
+
+// exportPageSize is the number of posts read per repository call during an export.
+const exportPageSize = 100
+
+// ChangeListener is notified after a post has been created, updated or deleted.
+// eventType is events.TypeCreated, TypeUpdated or TypeDeleted.
+type ChangeListener interface {
+	PostChanged(ctx context.Context, id, eventType string)
 }
 
 var _ handlers.PostService = (*PostService)(nil)
 
 type PostService struct {
-	repo Repository
+	repo      Repository
+	renderer  *render.Renderer
+	policy    *sanitize.Policy
+	listeners []ChangeListener
+}
+
+func NewPostService(repo Repository, renderer *render.Renderer, policy *sanitize.Policy) *PostService {
+	return &PostService{repo: repo, renderer: renderer, policy: policy}
+}
+
+// AddChangeListener registers l to be notified of every successful write.
+func (s *PostService) AddChangeListener(l ChangeListener) {
+	s.listeners = append(s.listeners, l)
 }
 
-func NewPostService(repo Repository) *PostService {
-	return &PostService{repo: repo}
+func (s *PostService) notifyChanged(ctx context.Context, id, eventType string) {
+	for _, l := range s.listeners {
+		l.PostChanged(ctx, id, eventType)
+	}
 }
 
 type NotFoundError struct {
@@ -35,8 +80,19 @@ func (e *NotFoundError) Error() string {
 	return fmt.Sprintf("%s with ID %s not found", e.Resource, e.ID)
 }
 
-func (s *PostService) GetAllPosts(ctx context.Context, page, limit int) ([]*models.Post, error) {
-	posts, err := s.repo.GetAll(ctx, page, limit)
+// NotFound lets callers that cannot import this package recognise the error.
+func (e *NotFoundError) NotFound() bool {
+	return true
+}
+
+// GetAllPosts returns a page of posts with only the given fields set, or every
+// field when there are none.
+func (s *PostService) GetAllPosts(ctx context.Context, page, limit int, fields []string) (posts []*models.Post, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetAllPosts",
+		trace.WithAttributes(attribute.Int("page", page), attribute.Int("limit", limit), attribute.StringSlice("fields", fields)))
+	defer tracing.End(span, &err)
+
+	posts, err = s.repo.GetAll(ctx, page, limit, fields)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all posts: %w", err)
 	}
@@ -44,56 +100,346 @@ func (s *PostService) GetAllPosts(ctx context.Context, page, limit int) ([]*mode
 }
 
 func (s *PostService) GetPostByID(ctx context.Context, id string) (*models.Post, error) {
-	post, err := s.repo.GetByID(ctx, id)
-	if err != nil {
-		// Check if error is a not found scenario. If repository returns a generic error,
-		// you may need a custom error check here.
+	return s.GetPostFields(ctx, id, nil)
+}
+
+// GetPostFields returns a post with only the given fields set, or every field
+// when there are none.
+func (s *PostService) GetPostFields(ctx context.Context, id string, fields []string) (post *models.Post, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.GetPostByID",
+		trace.WithAttributes(postIDAttr(id), attribute.StringSlice("fields", fields)))
+	defer tracing.End(span, &err)
+
+	post, err = s.repo.GetFields(ctx, id, fields)
+	switch {
+	case errors.Is(err, custom_errors.ErrNotFound):
 		return nil, &NotFoundError{Resource: "Post", ID: id}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get post with ID=%s: %w", id, err)
 	}
 	return post, nil
 }
 
-func (s *PostService) CreatePost(ctx context.Context, post *models.Post) (*models.Post, error) {
+// CreatePost validates and stores a new post. HTML content is sanitized before
+// it is stored; the returned report lists what was stripped, or is nil.
+func (s *PostService) CreatePost(ctx context.Context, post *models.Post) (_ *models.Post, _ *sanitize.Report, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.CreatePost")
+	defer tracing.End(span, &err)
+
 	if err := post.Validate(); err != nil {
-		return nil, fmt.Errorf("post validation failed: %w", err)
+		return nil, nil, fmt.Errorf("post validation failed: %w", err)
 	}
+	report := s.normalize(post)
 
 	createdPost, err := s.repo.Create(ctx, post)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create post: %w", err)
+		return nil, nil, fmt.Errorf("failed to create post: %w", err)
+	}
+	if report.Changed() {
+		logging.FromContext(ctx).Info("sanitized post content", "id", createdPost.ID, "removed", report.Removed)
 	}
-	return createdPost, nil
+	span.SetAttributes(postIDAttr(createdPost.ID))
+	s.notifyChanged(ctx, createdPost.ID, events.TypeCreated)
+	return createdPost, report, nil
 }
 
-func (s *PostService) UpdatePost(ctx context.Context, id string, updatedPost *models.Post) (*models.Post, error) {
+// UpdatePost validates and replaces an existing post, sanitizing it like CreatePost.
+func (s *PostService) UpdatePost(ctx context.Context, id string, updatedPost *models.Post) (_ *models.Post, _ *sanitize.Report, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.UpdatePost", trace.WithAttributes(postIDAttr(id)))
+	defer tracing.End(span, &err)
+
 	if err := updatedPost.Validate(); err != nil {
-		return nil, fmt.Errorf("updated post validation failed: %w", err)
+		return nil, nil, fmt.Errorf("updated post validation failed: %w", err)
 	}
+	report := s.normalize(updatedPost)
 
 	// Check if the post exists before attempting the update
 	if _, err := s.repo.GetByID(ctx, id); err != nil {
-		return nil, &NotFoundError{Resource: "Post", ID: id}
+		logging.FromContext(ctx).Debug("post lookup failed", "id", id, "error", err)
+		return nil, nil, &NotFoundError{Resource: "Post", ID: id}
 	}
 
 	updated, err := s.repo.Update(ctx, id, updatedPost)
+	if errors.Is(err, custom_errors.ErrNotFound) {
+		// Deleted since the lookup above.
+		return nil, nil, &NotFoundError{Resource: "Post", ID: id}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to update post with ID=%s: %w", id, err)
+		return nil, nil, fmt.Errorf("failed to update post with ID=%s: %w", id, err)
+	}
+	if report.Changed() {
+		logging.FromContext(ctx).Info("sanitized post content", "id", id, "removed", report.Removed)
+	}
+	s.notifyChanged(ctx, id, events.TypeUpdated)
+	return updated, report, nil
+}
+
+// normalize fills in defaults and strips disallowed markup from HTML content.
+// Markdown and plain text are stored verbatim, since sanitizing them as HTML
+// would mangle code and entities, so their content is untrusted: only the
+// rendered document, which is always sanitized, is safe to embed in a page.
+func (s *PostService) normalize(post *models.Post) *sanitize.Report {
+	post.ContentFormat = post.Format()
+	if post.Status == "" {
+		post.Status = models.StatusPublished
+	}
+	if post.ContentFormat != models.ContentFormatHTML {
+		return nil
 	}
-	return updated, nil
+
+	var report *sanitize.Report
+	post.Content, report = s.policy.Sanitize(post.Content)
+	return report
 }
 
-func (s *PostService) DeletePost(ctx context.Context, id string) error {
+func (s *PostService) DeletePost(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "PostService.DeletePost", trace.WithAttributes(postIDAttr(id)))
+	defer tracing.End(span, &err)
+
 	// Check if the post exists before deleting
 	if _, err := s.repo.GetByID(ctx, id); err != nil {
+		logging.FromContext(ctx).Debug("post lookup failed", "id", id, "error", err)
 		return &NotFoundError{Resource: "Post", ID: id}
 	}
 
 	if err := s.repo.Delete(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete post with ID=%s: %w", id, err)
 	}
+	s.notifyChanged(ctx, id, events.TypeDeleted)
 	return nil
 }
 
+// ImportPosts validates, normalizes and stores posts in bulk. Posts keep their
+// ID if they have one, overwriting any stored post with the same ID; listeners
+// are told of every imported post as created either way. The returned slice
+// holds, for each post, nil or the reason it was not imported.
+func (s *PostService) ImportPosts(ctx context.Context, posts []*models.Post) []error {
+	ctx, span := tracer.Start(ctx, "PostService.ImportPosts", trace.WithAttributes(attribute.Int("posts.count", len(posts))))
+	defer span.End()
+
+	errs := make([]error, len(posts))
+	valid := make([]*models.Post, 0, len(posts))
+	indexes := make([]int, 0, len(posts))
+	seen := make(map[string]bool, len(posts))
+
+	for i, post := range posts {
+		if err := post.Validate(); err != nil {
+			errs[i] = fmt.Errorf("post validation failed: %w", err)
+			continue
+		}
+		if post.ID != "" {
+			if seen[post.ID] {
+				errs[i] = fmt.Errorf("duplicate post ID=%s in import", post.ID)
+				continue
+			}
+			seen[post.ID] = true
+		}
+		s.normalize(post)
+		valid = append(valid, post)
+		indexes = append(indexes, i)
+	}
+
+	for j, err := range s.repo.BatchCreate(ctx, valid) {
+		if err != nil {
+			errs[indexes[j]] = fmt.Errorf("failed to import post: %w", err)
+			continue
+		}
+		s.notifyChanged(ctx, valid[j].ID, events.TypeCreated)
+	}
+	return errs
+}
+
+// ExportPosts calls fn for every stored post, whatever its status, reading the
+// table page by page. It stops at the first error returned by fn.
+func (s *PostService) ExportPosts(ctx context.Context, fn func(*models.Post) error) (err error) {
+	ctx, span := tracer.Start(ctx, "PostService.ExportPosts")
+	defer tracing.End(span, &err)
+
+	cursor := ""
+	for {
+		posts, next, err := s.repo.ListPage(ctx, exportPageSize, cursor)
+		if err != nil {
+			return fmt.Errorf("failed to export posts: %w", err)
+		}
+		for _, post := range posts {
+			if err := fn(post); err != nil {
+				return err
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// ReindexResult is the outcome of reindexing one post. Err is set for posts
+// that fail validation or could not be rewritten.
+type ReindexResult struct {
+	ID      string
+	Changed bool
+	Err     error
+}
+
+// ReindexPosts validates and normalizes every stored post, as a write through
+// the API would, and rewrites those whose stored form differs, such as posts
+// missing the Status the status index is keyed on. With dryRun nothing is
+// written. fn is called for every post.
+func (s *PostService) ReindexPosts(ctx context.Context, dryRun bool, fn func(ReindexResult)) (err error) {
+	ctx, span := tracer.Start(ctx, "PostService.ReindexPosts", trace.WithAttributes(attribute.Bool("dry_run", dryRun)))
+	defer tracing.End(span, &err)
+
+	return s.ExportPosts(ctx, func(stored *models.Post) error {
+		result := ReindexResult{ID: stored.ID}
+		post := *stored
+		post.Tags = append([]string(nil), stored.Tags...)
+
+		if err := post.Validate(); err != nil {
+			result.Err = fmt.Errorf("post validation failed: %w", err)
+			fn(result)
+			return nil
+		}
+		s.normalize(&post)
+		result.Changed = post.Content != stored.Content ||
+			post.ContentFormat != stored.ContentFormat ||
+			post.Status != stored.Status
+		if result.Changed && !dryRun {
+			if _, err := s.repo.Update(ctx, post.ID, &post); err != nil {
+				result.Err = fmt.Errorf("failed to update post with ID=%s: %w", post.ID, err)
+			} else {
+				s.notifyChanged(ctx, post.ID, events.TypeUpdated)
+			}
+		}
+		fn(result)
+		return nil
+	})
+}
+
+// BatchGetPosts fetches posts by ID in a single round trip. Found posts are
+// returned in request order, followed by the IDs that do not exist.
+func (s *PostService) BatchGetPosts(ctx context.Context, ids []string) (_ []*models.Post, _ []string, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.BatchGetPosts", trace.WithAttributes(attribute.Int("ids.count", len(ids))))
+	defer tracing.End(span, &err)
+
+	found, err := s.repo.BatchGet(ctx, uniqueIDs(ids))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to batch get posts: %w", err)
+	}
+
+	posts := make([]*models.Post, 0, len(found))
+	var missing []string
+	for _, id := range ids {
+		if post, ok := found[id]; ok {
+			posts = append(posts, post)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	return posts, missing, nil
+}
+
+// BatchDeletePosts deletes posts by ID. In transactional mode either every
+// post is deleted or none is, and the posts that do not exist are reported as
+// not found. In best-effort mode each post is deleted independently, and
+// listeners are told only of the posts that existed. The returned slice
+// holds, for each ID, nil or the reason it was not deleted.
+func (s *PostService) BatchDeletePosts(ctx context.Context, ids []string, transactional bool) []error {
+	ctx, span := tracer.Start(ctx, "PostService.BatchDeletePosts", trace.WithAttributes(
+		attribute.Int("ids.count", len(ids)),
+		attribute.Bool("transactional", transactional),
+	))
+	defer span.End()
+
+	unique := uniqueIDs(ids)
+	results := make(map[string]error, len(unique))
+
+	if transactional {
+		err := s.repo.TransactDelete(ctx, unique)
+		var missing *custom_errors.MissingIDsError
+		switch {
+		case errors.As(err, &missing):
+			for _, id := range unique {
+				results[id] = ErrBatchAborted
+			}
+			for _, id := range missing.IDs {
+				results[id] = &NotFoundError{Resource: "Post", ID: id}
+			}
+		case err != nil:
+			for _, id := range unique {
+				results[id] = fmt.Errorf("failed to delete posts: %w", err)
+			}
+		}
+	} else {
+		for i, err := range s.repo.BatchDelete(ctx, unique) {
+			switch {
+			case errors.Is(err, custom_errors.ErrNotFound):
+				results[unique[i]] = &NotFoundError{Resource: "Post", ID: unique[i]}
+			case err != nil:
+				results[unique[i]] = fmt.Errorf("failed to delete post with ID=%s: %w", unique[i], err)
+			}
+		}
+	}
+
+	errs := make([]error, len(ids))
+	for i, id := range ids {
+		errs[i] = results[id]
+	}
+	for _, id := range unique {
+		if results[id] == nil {
+			s.notifyChanged(ctx, id, events.TypeDeleted)
+		}
+	}
+	return errs
+}
+
+func postIDAttr(id string) attribute.KeyValue {
+	return attribute.String("post.id", id)
+}
+
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// ListPublishedPosts returns a page of the newest published posts matching filter,
+// starting after cursor, and the cursor of the next page.
+func (s *PostService) ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) (posts []*models.Post, next string, err error) {
+	ctx, span := tracer.Start(ctx, "PostService.ListPublishedPosts", trace.WithAttributes(
+		attribute.String("filter.author", filter.Author),
+		attribute.String("filter.tag", filter.Tag),
+		attribute.Int("limit", limit),
+	))
+	defer tracing.End(span, &err)
+
+	posts, next, err = s.repo.ListPublished(ctx, filter, limit, cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list published posts: %w", err)
+	}
+	return posts, next, nil
+}
+
+// RenderPost returns the HTML form of the post's content.
+func (s *PostService) RenderPost(ctx context.Context, post *models.Post) (doc *render.Document, err error) {
+	_, span := tracer.Start(ctx, "PostService.RenderPost", trace.WithAttributes(
+		postIDAttr(post.ID),
+		attribute.String("post.format", post.Format()),
+	))
+	defer tracing.End(span, &err)
+
+	doc, err = s.renderer.Render(post)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render post with ID=%s: %w", post.ID, err)
+	}
+	return doc, nil
+}
+
 // If needed, you can implement an IsNotFound function to differentiate between not found and other errors
 func IsNotFound(err error) bool {
 	var nfe *NotFoundError
@@ -1,25 +1,32 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"testing"
 
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/events"
 	"blog-api/internal/models"
+	"blog-api/internal/render"
+	"blog-api/internal/sanitize"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockRepository struct {
 	mock.Mock
 }
 
-func (m *MockRepository) GetAll(page, limit int) ([]*models.Post, error) {
-	args := m.Called(page, limit)
+func (m *MockRepository) GetAll(ctx context.Context, page, limit int, fields []string) ([]*models.Post, error) {
+	args := m.Called(page, limit, fields)
 	return args.Get(0).([]*models.Post), args.Error(1)
 }
 
-func (m *MockRepository) GetByID(id int) (*models.Post, error) {
+func (m *MockRepository) GetByID(ctx context.Context, id string) (*models.Post, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -27,7 +34,15 @@ func (m *MockRepository) GetByID(id int) (*models.Post, error) {
 	return args.Get(0).(*models.Post), args.Error(1)
 }
 
-func (m *MockRepository) Create(post *models.Post) (*models.Post, error) {
+func (m *MockRepository) GetFields(ctx context.Context, id string, fields []string) (*models.Post, error) {
+	args := m.Called(id, fields)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Post), args.Error(1)
+}
+
+func (m *MockRepository) Create(ctx context.Context, post *models.Post) (*models.Post, error) {
 	args := m.Called(post)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -35,7 +50,7 @@ func (m *MockRepository) Create(post *models.Post) (*models.Post, error) {
 	return args.Get(0).(*models.Post), args.Error(1)
 }
 
-func (m *MockRepository) Update(id int, updatedPost *models.Post) (*models.Post, error) {
+func (m *MockRepository) Update(ctx context.Context, id string, updatedPost *models.Post) (*models.Post, error) {
 	args := m.Called(id, updatedPost)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -43,27 +58,89 @@ func (m *MockRepository) Update(id int, updatedPost *models.Post) (*models.Post,
 	return args.Get(0).(*models.Post), args.Error(1)
 }
 
-func (m *MockRepository) Delete(id int) error {
+func (m *MockRepository) Delete(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
 
+func (m *MockRepository) ListPublished(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error) {
+	args := m.Called(filter, limit, cursor)
+	posts, _ := args.Get(0).([]*models.Post)
+	return posts, args.String(1), args.Error(2)
+}
+
+func (m *MockRepository) ListPage(ctx context.Context, limit int, cursor string) ([]*models.Post, string, error) {
+	args := m.Called(limit, cursor)
+	posts, _ := args.Get(0).([]*models.Post)
+	return posts, args.String(1), args.Error(2)
+}
+
+func (m *MockRepository) BatchCreate(ctx context.Context, posts []*models.Post) []error {
+	args := m.Called(posts)
+	errs, _ := args.Get(0).([]error)
+	return errs
+}
+
+func (m *MockRepository) BatchGet(ctx context.Context, ids []string) (map[string]*models.Post, error) {
+	args := m.Called(ids)
+	posts, _ := args.Get(0).(map[string]*models.Post)
+	return posts, args.Error(1)
+}
+
+func (m *MockRepository) TransactDelete(ctx context.Context, ids []string) error {
+	args := m.Called(ids)
+	return args.Error(0)
+}
+
+func (m *MockRepository) BatchDelete(ctx context.Context, ids []string) []error {
+	args := m.Called(ids)
+	errs, _ := args.Get(0).([]error)
+	return errs
+}
+
+// recordingListener records the IDs and types of the changes it is notified of.
+type recordingListener struct {
+	changed []string
+	types   []string
+}
+
+func (l *recordingListener) PostChanged(ctx context.Context, id, eventType string) {
+	l.changed = append(l.changed, id)
+	l.types = append(l.types, eventType)
+}
+
+func newTestService(repo Repository) (*PostService, *recordingListener) {
+	policy := sanitize.DefaultPolicy()
+	service := NewPostService(repo, render.NewRenderer(16, policy), policy)
+	listener := &recordingListener{}
+	service.AddChangeListener(listener)
+	return service, listener
+}
+
 func TestPostService(t *testing.T) {
+	ctx := context.Background()
 	mockRepo := new(MockRepository)
-	service := NewPostService(mockRepo)
+	service, _ := newTestService(mockRepo)
 
 	t.Run("CreatePost - Validation Error", func(t *testing.T) {
 		invalidPost := &models.Post{Title: "", Content: "Content", Author: "Author"}
-		post, err := service.CreatePost(invalidPost)
+		post, _, err := service.CreatePost(ctx, invalidPost)
 		assert.Nil(t, post, "Expected no post to be created")
 		assert.Error(t, err, "Expected a validation error")
 	})
 
+	t.Run("CreatePost - Reserved ID", func(t *testing.T) {
+		reserved := &models.Post{ID: models.ReservedPostID, Title: "Title", Content: "Content", Author: "Author"}
+		post, _, err := service.CreatePost(ctx, reserved)
+		assert.Nil(t, post, "Expected no post to be created")
+		assert.ErrorContains(t, err, "is reserved")
+	})
+
 	t.Run("CreatePost - Success", func(t *testing.T) {
 		validPost := &models.Post{Title: "Title", Content: "Content", Author: "Author"}
 		mockRepo.On("Create", validPost).Return(validPost, nil)
 
-		post, err := service.CreatePost(validPost)
+		post, _, err := service.CreatePost(ctx, validPost)
 		assert.NoError(t, err, "Expected no error on CreatePost")
 		assert.Equal(t, validPost, post, "Created post mismatch")
 
@@ -71,9 +148,9 @@ func TestPostService(t *testing.T) {
 	})
 
 	t.Run("GetPostByID - Not Found", func(t *testing.T) {
-		mockRepo.On("GetByID", 99).Return(nil, errors.New("not found"))
+		mockRepo.On("GetFields", "99", []string(nil)).Return(nil, fmt.Errorf("post with ID=99: %w", custom_errors.ErrNotFound))
 
-		post, err := service.GetPostByID(99)
+		post, err := service.GetPostByID(ctx, "99")
 		assert.Nil(t, post, "Expected no post to be returned")
 		assert.Error(t, err, "Expected an error on GetPostByID")
 		assert.IsType(t, &NotFoundError{}, err, "Error type mismatch")
@@ -81,11 +158,22 @@ func TestPostService(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("GetPostByID - Lookup Failure", func(t *testing.T) {
+		mockRepo.On("GetFields", "98", []string(nil)).Return(nil, errors.New("throttled"))
+
+		post, err := service.GetPostByID(ctx, "98")
+		assert.Nil(t, post, "Expected no post to be returned")
+		assert.ErrorContains(t, err, "throttled")
+		assert.False(t, IsNotFound(err), "Only missing posts are reported as not found")
+
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("GetPostByID - Success", func(t *testing.T) {
-		expectedPost := &models.Post{ID: 1, Title: "Post Title", Content: "Content", Author: "Author"}
-		mockRepo.On("GetByID", 1).Return(expectedPost, nil)
+		expectedPost := &models.Post{ID: "1", Title: "Post Title", Content: "Content", Author: "Author"}
+		mockRepo.On("GetFields", "1", []string(nil)).Return(expectedPost, nil)
 
-		post, err := service.GetPostByID(1)
+		post, err := service.GetPostByID(ctx, "1")
 		assert.NoError(t, err, "Expected no error on GetPostByID")
 		assert.Equal(t, expectedPost, post, "Fetched post mismatch")
 
@@ -94,10 +182,10 @@ func TestPostService(t *testing.T) {
 
 	t.Run("UpdatePost - Success", func(t *testing.T) {
 		validPost := &models.Post{Title: "Updated", Content: "Updated Content", Author: "Author"}
-		mockRepo.On("GetByID", 1).Return(validPost, nil)
-		mockRepo.On("Update", 1, validPost).Return(validPost, nil)
+		mockRepo.On("GetByID", "1").Return(validPost, nil)
+		mockRepo.On("Update", "1", validPost).Return(validPost, nil)
 
-		post, err := service.UpdatePost(1, validPost)
+		post, _, err := service.UpdatePost(ctx, "1", validPost)
 		assert.NoError(t, err, "Expected no error on UpdatePost")
 		assert.Equal(t, validPost, post, "Updated post mismatch")
 
@@ -106,9 +194,9 @@ func TestPostService(t *testing.T) {
 
 	t.Run("UpdatePost - Not Found", func(t *testing.T) {
 		updatedPost := &models.Post{Title: "Updated", Content: "Updated Content", Author: "Author"}
-		mockRepo.On("GetByID", 99).Return(nil, errors.New("not found"))
+		mockRepo.On("GetByID", "99").Return(nil, errors.New("not found"))
 
-		post, err := service.UpdatePost(99, updatedPost)
+		post, _, err := service.UpdatePost(ctx, "99", updatedPost)
 		assert.Nil(t, post, "Expected no post to be updated")
 		assert.Error(t, err, "Expected an error on UpdatePost")
 		assert.IsType(t, &NotFoundError{}, err, "Error type mismatch")
@@ -116,23 +204,143 @@ func TestPostService(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 
+	t.Run("UpdatePost - Deleted During Update", func(t *testing.T) {
+		updatedPost := &models.Post{Title: "Updated", Content: "Updated Content", Author: "Author"}
+		mockRepo.On("GetByID", "98").Return(&models.Post{ID: "98"}, nil)
+		mockRepo.On("Update", "98", updatedPost).Return(nil, fmt.Errorf("post with ID=98: %w", custom_errors.ErrNotFound))
+
+		post, _, err := service.UpdatePost(ctx, "98", updatedPost)
+		assert.Nil(t, post)
+		assert.IsType(t, &NotFoundError{}, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+
 	t.Run("DeletePost - Success", func(t *testing.T) {
-		mockRepo.On("GetByID", 1).Return(&models.Post{ID: 1}, nil)
-		mockRepo.On("Delete", 1).Return(nil)
+		mockRepo.On("GetByID", "1").Return(&models.Post{ID: "1"}, nil)
+		mockRepo.On("Delete", "1").Return(nil)
 
-		err := service.DeletePost(1)
+		err := service.DeletePost(ctx, "1")
 		assert.NoError(t, err, "Expected no error on DeletePost")
 
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("DeletePost - Not Found", func(t *testing.T) {
-		mockRepo.On("GetByID", 99).Return(nil, errors.New("not found"))
+		mockRepo.On("GetByID", "99").Return(nil, errors.New("not found"))
 
-		err := service.DeletePost(99)
+		err := service.DeletePost(ctx, "99")
 		assert.Error(t, err, "Expected an error on DeletePost")
 		assert.IsType(t, &NotFoundError{}, err, "Error type mismatch")
 
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestPostServiceSanitization(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("CreatePost Sanitizes HTML", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service, listener := newTestService(mockRepo)
+		post := &models.Post{ID: "1", Title: "Title", Content: `<p>Hi</p><script>alert(1)</script>`, ContentFormat: models.ContentFormatHTML, Author: "Author"}
+		mockRepo.On("Create", mock.Anything).Return(post, nil)
+
+		created, report, err := service.CreatePost(ctx, post)
+
+		require.NoError(t, err)
+		assert.Equal(t, "<p>Hi</p>", created.Content)
+		assert.True(t, report.Changed())
+		assert.Equal(t, models.StatusPublished, created.Status)
+		assert.Equal(t, []string{"1"}, listener.changed)
+		assert.Equal(t, []string{events.TypeCreated}, listener.types)
+	})
+
+	t.Run("RenderPost Renders And Sanitizes Markdown", func(t *testing.T) {
+		service, _ := newTestService(new(MockRepository))
+		post := &models.Post{ID: "1", Version: 1, Content: "# Title\n\n<img src=x onerror=alert(1)>", ContentFormat: models.ContentFormatMarkdown}
+
+		doc, err := service.RenderPost(ctx, post)
+
+		require.NoError(t, err)
+		assert.Contains(t, doc.HTML, `<h1 id="title">`)
+		assert.NotContains(t, doc.HTML, "onerror")
+		assert.True(t, doc.Sanitization.Changed())
+	})
+}
+
+func TestImportPosts(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := new(MockRepository)
+	service, listener := newTestService(mockRepo)
+	posts := []*models.Post{
+		{ID: "a", Title: "First", Content: "Content", Author: "Author"},
+		{ID: "b", Title: "", Content: "Content", Author: "Author"},
+		{ID: "a", Title: "Again", Content: "Content", Author: "Author"},
+		{ID: "c", Title: "Third", Content: "Content", Author: "Author"},
+	}
+	mockRepo.On("BatchCreate", []*models.Post{posts[0], posts[3]}).Return([]error{nil, errors.New("throttled")})
+
+	errs := service.ImportPosts(ctx, posts)
+
+	require.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.ErrorContains(t, errs[1], "validation failed")
+	assert.ErrorContains(t, errs[2], "duplicate post ID=a")
+	assert.ErrorContains(t, errs[3], "throttled")
+	assert.Equal(t, []string{"a"}, listener.changed)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBatchDeletePosts(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Transactional Reports Missing Posts", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service, listener := newTestService(mockRepo)
+		mockRepo.On("TransactDelete", []string{"a", "b"}).Return(&custom_errors.MissingIDsError{IDs: []string{"b"}})
+
+		errs := service.BatchDeletePosts(ctx, []string{"a", "b", "a"}, true)
+
+		assert.ErrorIs(t, errs[0], ErrBatchAborted)
+		assert.True(t, IsNotFound(errs[1]))
+		assert.ErrorIs(t, errs[2], ErrBatchAborted)
+		assert.Empty(t, listener.changed)
+	})
+
+	t.Run("Transactional Deletes Every Post", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service, listener := newTestService(mockRepo)
+		mockRepo.On("TransactDelete", []string{"a", "b"}).Return(nil)
+
+		errs := service.BatchDeletePosts(ctx, []string{"a", "b"}, true)
+
+		assert.Equal(t, []error{nil, nil}, errs)
+		assert.Equal(t, []string{"a", "b"}, listener.changed)
+		assert.Equal(t, []string{events.TypeDeleted, events.TypeDeleted}, listener.types)
+	})
+
+	t.Run("Best Effort Deletes Independently", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service, listener := newTestService(mockRepo)
+		mockRepo.On("BatchDelete", []string{"a", "b"}).Return([]error{nil, errors.New("throttled")})
+
+		errs := service.BatchDeletePosts(ctx, []string{"a", "b"}, false)
+
+		assert.NoError(t, errs[0])
+		assert.ErrorContains(t, errs[1], "throttled")
+		assert.Equal(t, []string{"a"}, listener.changed)
+	})
+
+	t.Run("Best Effort Reports Missing Posts", func(t *testing.T) {
+		mockRepo := new(MockRepository)
+		service, listener := newTestService(mockRepo)
+		mockRepo.On("BatchDelete", []string{"a", "b"}).Return([]error{fmt.Errorf("post with ID=a: %w", custom_errors.ErrNotFound), nil})
+
+		errs := service.BatchDeletePosts(ctx, []string{"a", "b"}, false)
+
+		assert.True(t, IsNotFound(errs[0]))
+		assert.NoError(t, errs[1])
+		assert.Equal(t, []string{"b"}, listener.changed)
+	})
+}
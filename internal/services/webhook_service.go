@@ -0,0 +1,175 @@
+package services
+
+import (
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/handlers"
+	"blog-api/internal/models"
+	"blog-api/internal/tracing"
+	"blog-api/internal/webhooks"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID string, limit int, cursor string) ([]*models.WebhookDelivery, string, error)
+}
+
+// secretPrefix marks generated webhook secrets.
+const secretPrefix = "whsec_"
+
+var _ handlers.WebhookService = (*WebhookService)(nil)
+
+type WebhookService struct {
+	repo WebhookRepository
+	now  func() time.Time
+	// checkURL rejects URLs that do not lead to the public internet.
+	checkURL func(ctx context.Context, rawURL string) error
+}
+
+func NewWebhookService(repo WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo, now: time.Now, checkURL: webhooks.CheckURL}
+}
+
+// CreateWebhook validates and stores a webhook, generating its secret when it
+// has none. The returned webhook is the only one that includes the secret.
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook *models.Webhook) (_ *models.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer tracing.End(span, &err)
+
+	webhook.ID = ""
+	if webhook.Secret == "" {
+		secret := make([]byte, 24)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+		webhook.Secret = secretPrefix + hex.EncodeToString(secret)
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.checkURL(ctx, webhook.URL); err != nil {
+		return nil, fmt.Errorf("%w: url: %v", models.ErrInvalidWebhook, err)
+	}
+
+	if err := s.repo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	span.SetAttributes(webhookIDAttr(webhook.ID))
+	return webhook, nil
+}
+
+// ListWebhooks returns every webhook without its secret.
+func (s *WebhookService) ListWebhooks(ctx context.Context) (_ []*models.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListWebhooks")
+	defer tracing.End(span, &err)
+
+	webhooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook without its secret.
+func (s *WebhookService) GetWebhook(ctx context.Context, id string) (_ *models.Webhook, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook", trace.WithAttributes(webhookIDAttr(id)))
+	defer tracing.End(span, &err)
+
+	webhook, err := s.getWebhook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id string) (err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook", trace.WithAttributes(webhookIDAttr(id)))
+	defer tracing.End(span, &err)
+
+	if err := s.repo.DeleteWebhook(ctx, id); err != nil {
+		if errors.Is(err, custom_errors.ErrNotFound) {
+			return &NotFoundError{Resource: "Webhook", ID: id}
+		}
+		return fmt.Errorf("failed to delete webhook with ID=%s: %w", id, err)
+	}
+	return nil
+}
+
+// ListDeliveries returns up to limit deliveries of a webhook, newest first,
+// starting after cursor. The returned cursor is empty on the last page.
+func (s *WebhookService) ListDeliveries(ctx context.Context, webhookID string, limit int, cursor string) (_ []*models.WebhookDelivery, _ string, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.ListDeliveries", trace.WithAttributes(webhookIDAttr(webhookID)))
+	defer tracing.End(span, &err)
+
+	if _, err := s.getWebhook(ctx, webhookID); err != nil {
+		return nil, "", err
+	}
+	deliveries, next, err := s.repo.ListDeliveries(ctx, webhookID, limit, cursor)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list deliveries of webhook with ID=%s: %w", webhookID, err)
+	}
+	return deliveries, next, nil
+}
+
+// RedeliverDelivery queues a delivery of the webhook to be sent again right
+// away, with a fresh set of attempts, whatever its status.
+func (s *WebhookService) RedeliverDelivery(ctx context.Context, webhookID, deliveryID string) (_ *models.WebhookDelivery, err error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.RedeliverDelivery", trace.WithAttributes(
+		webhookIDAttr(webhookID),
+		attribute.String("webhook.delivery.id", deliveryID),
+	))
+	defer tracing.End(span, &err)
+
+	if _, err := s.getWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	delivery, err := s.repo.GetDelivery(ctx, deliveryID)
+	switch {
+	case errors.Is(err, custom_errors.ErrNotFound) || err == nil && delivery.WebhookID != webhookID:
+		return nil, &NotFoundError{Resource: "Delivery", ID: deliveryID}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get delivery with ID=%s: %w", deliveryID, err)
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = s.now().UTC()
+	delivery.ResponseStatus = 0
+	delivery.Error = ""
+	if err := s.repo.SaveDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to save delivery with ID=%s: %w", deliveryID, err)
+	}
+	return delivery, nil
+}
+
+func (s *WebhookService) getWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	webhook, err := s.repo.GetWebhook(ctx, id)
+	switch {
+	case errors.Is(err, custom_errors.ErrNotFound):
+		return nil, &NotFoundError{Resource: "Webhook", ID: id}
+	case err != nil:
+		return nil, fmt.Errorf("failed to get webhook with ID=%s: %w", id, err)
+	}
+	return webhook, nil
+}
+
+func webhookIDAttr(id string) attribute.KeyValue {
+	return attribute.String("webhook.id", id)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"blog-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createOnlyRepository stores the webhooks it is given and fails every other
+// call.
+type createOnlyRepository struct {
+	WebhookRepository
+	created []*models.Webhook
+}
+
+func (r *createOnlyRepository) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	webhook.ID = "w1"
+	r.created = append(r.created, webhook)
+	return nil
+}
+
+func TestCreateWebhook(t *testing.T) {
+	ctx := context.Background()
+	webhook := func(url string) *models.Webhook {
+		return &models.Webhook{URL: url, Events: []string{models.EventPostCreated}}
+	}
+
+	t.Run("Stores Public URLs", func(t *testing.T) {
+		repo := &createOnlyRepository{}
+		service := NewWebhookService(repo)
+
+		created, err := service.CreateWebhook(ctx, webhook("https://93.184.215.14/hooks"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "w1", created.ID)
+		assert.NotEmpty(t, created.Secret)
+		assert.Len(t, repo.created, 1)
+	})
+
+	t.Run("Rejects Non-Public URLs", func(t *testing.T) {
+		for _, url := range []string{
+			"http://127.0.0.1:8080/hooks",
+			"http://10.0.0.1/hooks",
+			"http://169.254.169.254/latest/meta-data",
+			"http://[::1]/hooks",
+			"http://localhost/hooks",
+		} {
+			repo := &createOnlyRepository{}
+			service := NewWebhookService(repo)
+
+			_, err := service.CreateWebhook(ctx, webhook(url))
+
+			assert.ErrorIs(t, err, models.ErrInvalidWebhook, url)
+			assert.Empty(t, repo.created, url)
+		}
+	})
+}
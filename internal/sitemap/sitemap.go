@@ -0,0 +1,224 @@
+// Package sitemap generates sitemaps.org documents listing every published post.
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"blog-api/internal/models"
+)
+
+const (
+	// MaxURLsPerSitemap is the sitemaps.org limit on URLs in a single file.
+	MaxURLsPerSitemap = 50000
+
+	xmlns = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	// pageSize is the number of posts read from the repository per request.
+	pageSize = 1000
+)
+
+// PostLister pages through published posts.
+type PostLister interface {
+	ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error)
+}
+
+// Config controls sitemap generation.
+type Config struct {
+	// BaseURL is the public URL of the site. Posts are listed as BaseURL + "/posts/{id}"
+	// and sitemap chunks as BaseURL + "/sitemap-{n}.xml".
+	BaseURL string
+	// ChunkSize is the number of URLs per sitemap file, at most MaxURLsPerSitemap.
+	ChunkSize int
+	// MaxAge bounds how long a generated sitemap is served before it is rebuilt,
+	// to pick up changes made through other instances.
+	MaxAge time.Duration
+}
+
+// Sitemap is a generated set of sitemap files.
+type Sitemap struct {
+	// Chunks are urlset documents, each listing at most ChunkSize URLs.
+	Chunks [][]byte
+	// Index is a sitemapindex document referencing every chunk.
+	Index []byte
+	// LastModified is the newest update time of any listed post.
+	LastModified time.Time
+
+	generatedAt time.Time
+}
+
+// Generator builds sitemaps by walking the published posts page by page and
+// caches the result until a post changes or MaxAge passes.
+type Generator struct {
+	posts  PostLister
+	config Config
+
+	// mu guards cached and generation only; it is never held while the posts
+	// are read, so invalidating never waits for a sitemap being built.
+	mu     sync.Mutex
+	cached *Sitemap
+	// generation counts invalidations, so that a sitemap built while a post
+	// changed is not cached.
+	generation uint64
+	// building lets one caller at a time walk the posts, and the others wait
+	// for its sitemap.
+	building sync.Mutex
+}
+
+func NewGenerator(posts PostLister, config Config) *Generator {
+	if config.ChunkSize <= 0 || config.ChunkSize > MaxURLsPerSitemap {
+		config.ChunkSize = MaxURLsPerSitemap
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &Generator{posts: posts, config: config}
+}
+
+// Sitemap returns the cached sitemap, generating it first if needed.
+func (g *Generator) Sitemap(ctx context.Context) (*Sitemap, error) {
+	if sm, _ := g.fresh(); sm != nil {
+		return sm, nil
+	}
+
+	g.building.Lock()
+	defer g.building.Unlock()
+	sm, generation := g.fresh()
+	if sm != nil {
+		return sm, nil // built while waiting
+	}
+
+	sm, err := g.generate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	if g.generation == generation {
+		g.cached = sm
+	}
+	g.mu.Unlock()
+	return sm, nil
+}
+
+// fresh returns the cached sitemap unless it is missing or too old, and the
+// current generation.
+func (g *Generator) fresh() (*Sitemap, uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.cached != nil && (g.config.MaxAge <= 0 || time.Since(g.cached.generatedAt) < g.config.MaxAge) {
+		return g.cached, g.generation
+	}
+	return nil, g.generation
+}
+
+// Invalidate drops the cached sitemap, and any sitemap being built.
+func (g *Generator) Invalidate() {
+	g.mu.Lock()
+	g.cached = nil
+	g.generation++
+	g.mu.Unlock()
+}
+
+// PostChanged invalidates the cache; it lets a Generator listen to post writes.
+func (g *Generator) PostChanged(ctx context.Context, id, eventType string) {
+	g.Invalidate()
+}
+
+type urlEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type urlSet struct {
+	XMLName xml.Name   `xml:"urlset"`
+	Xmlns   string     `xml:"xmlns,attr"`
+	URLs    []urlEntry `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name   `xml:"sitemapindex"`
+	Xmlns    string     `xml:"xmlns,attr"`
+	Sitemaps []urlEntry `xml:"sitemap"`
+}
+
+func (g *Generator) generate(ctx context.Context) (*Sitemap, error) {
+	var (
+		chunks     []urlSet
+		chunkMods  []time.Time
+		current    = urlSet{Xmlns: xmlns}
+		currentMod time.Time
+		cursor     string
+		sm         = &Sitemap{generatedAt: time.Now()}
+	)
+
+	for {
+		posts, next, err := g.posts.ListPublishedPosts(ctx, models.PostFilter{}, pageSize, cursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list posts for sitemap: %w", err)
+		}
+
+		for _, post := range posts {
+			current.URLs = append(current.URLs, urlEntry{
+				Loc:     g.config.BaseURL + "/posts/" + post.ID,
+				LastMod: formatLastMod(post.UpdatedAt),
+			})
+			if post.UpdatedAt.After(currentMod) {
+				currentMod = post.UpdatedAt
+			}
+			if len(current.URLs) == g.config.ChunkSize {
+				chunks = append(chunks, current)
+				chunkMods = append(chunkMods, currentMod)
+				current, currentMod = urlSet{Xmlns: xmlns}, time.Time{}
+			}
+		}
+
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	if len(current.URLs) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, current)
+		chunkMods = append(chunkMods, currentMod)
+	}
+
+	index := sitemapIndex{Xmlns: xmlns}
+	for i, chunk := range chunks {
+		body, err := marshalXML(chunk)
+		if err != nil {
+			return nil, err
+		}
+		sm.Chunks = append(sm.Chunks, body)
+
+		index.Sitemaps = append(index.Sitemaps, urlEntry{
+			Loc:     fmt.Sprintf("%s/sitemap-%d.xml", g.config.BaseURL, i+1),
+			LastMod: formatLastMod(chunkMods[i]),
+		})
+		if chunkMods[i].After(sm.LastModified) {
+			sm.LastModified = chunkMods[i]
+		}
+	}
+
+	body, err := marshalXML(index)
+	if err != nil {
+		return nil, err
+	}
+	sm.Index = body
+	return sm, nil
+}
+
+func marshalXML(v interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal sitemap: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func formatLastMod(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
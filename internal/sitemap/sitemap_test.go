@@ -0,0 +1,132 @@
+package sitemap
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPostLister struct {
+	mock.Mock
+}
+
+func (m *MockPostLister) ListPublishedPosts(ctx context.Context, filter models.PostFilter, limit int, cursor string) ([]*models.Post, string, error) {
+	args := m.Called(limit, cursor)
+	var posts []*models.Post
+	if args.Get(0) != nil {
+		posts = args.Get(0).([]*models.Post)
+	}
+	return posts, args.String(1), args.Error(2)
+}
+
+func makePosts(from, to int) []*models.Post {
+	posts := make([]*models.Post, 0, to-from)
+	for i := from; i < to; i++ {
+		posts = append(posts, &models.Post{
+			ID:        fmt.Sprint(i),
+			UpdatedAt: time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC),
+		})
+	}
+	return posts
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("Chunks Across Pages", func(t *testing.T) {
+		lister := new(MockPostLister)
+		lister.On("ListPublishedPosts", pageSize, "").Return(makePosts(0, 3), "next", nil).Once()
+		lister.On("ListPublishedPosts", pageSize, "next").Return(makePosts(3, 5), "", nil).Once()
+
+		gen := NewGenerator(lister, Config{BaseURL: "https://blog.example.com/", ChunkSize: 2})
+		sm, err := gen.Sitemap(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, sm.Chunks, 3)
+		assert.Equal(t, time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), sm.LastModified)
+
+		var set urlSet
+		assert.NoError(t, xml.Unmarshal(sm.Chunks[2], &set))
+		assert.Equal(t, []urlEntry{{Loc: "https://blog.example.com/posts/4", LastMod: "2024-01-05T00:00:00Z"}}, set.URLs)
+
+		var index sitemapIndex
+		assert.NoError(t, xml.Unmarshal(sm.Index, &index))
+		assert.Len(t, index.Sitemaps, 3)
+		assert.Equal(t, "https://blog.example.com/sitemap-1.xml", index.Sitemaps[0].Loc)
+		assert.Equal(t, "2024-01-02T00:00:00Z", index.Sitemaps[0].LastMod)
+		lister.AssertExpectations(t)
+	})
+
+	t.Run("Cached Until Invalidated", func(t *testing.T) {
+		lister := new(MockPostLister)
+		lister.On("ListPublishedPosts", pageSize, "").Return(makePosts(0, 1), "", nil).Twice()
+
+		gen := NewGenerator(lister, Config{BaseURL: "https://blog.example.com"})
+		first, err := gen.Sitemap(context.Background())
+		assert.NoError(t, err)
+		second, err := gen.Sitemap(context.Background())
+		assert.NoError(t, err)
+		assert.Same(t, first, second)
+
+		gen.PostChanged(context.Background(), "0", events.TypeUpdated)
+		third, err := gen.Sitemap(context.Background())
+		assert.NoError(t, err)
+		assert.NotSame(t, first, third)
+		lister.AssertExpectations(t)
+	})
+
+	t.Run("Invalidate Does Not Wait For Generation", func(t *testing.T) {
+		listing, release := make(chan struct{}), make(chan struct{})
+		lister := new(MockPostLister)
+		lister.On("ListPublishedPosts", pageSize, "").Return(makePosts(0, 1), "", nil).Run(func(mock.Arguments) {
+			listing <- struct{}{}
+			<-release
+		}).Once()
+		lister.On("ListPublishedPosts", pageSize, "").Return(makePosts(0, 2), "", nil).Once()
+
+		gen := NewGenerator(lister, Config{BaseURL: "https://blog.example.com"})
+		done := make(chan *Sitemap)
+		go func() {
+			sm, _ := gen.Sitemap(context.Background())
+			done <- sm
+		}()
+		<-listing
+
+		invalidated := make(chan struct{})
+		go func() {
+			gen.PostChanged(context.Background(), "1", events.TypeCreated)
+			close(invalidated)
+		}()
+		select {
+		case <-invalidated:
+		case <-time.After(time.Second):
+			t.Fatal("PostChanged waited for the sitemap being built")
+		}
+		close(release)
+		stale := <-done
+
+		// The sitemap built across the change is served once, not cached.
+		fresh, err := gen.Sitemap(context.Background())
+		assert.NoError(t, err)
+		assert.NotSame(t, stale, fresh)
+		assert.Contains(t, string(fresh.Chunks[0]), "/posts/1<")
+		lister.AssertExpectations(t)
+	})
+
+	t.Run("Empty Site", func(t *testing.T) {
+		lister := new(MockPostLister)
+		lister.On("ListPublishedPosts", pageSize, "").Return(nil, "", nil).Once()
+
+		sm, err := NewGenerator(lister, Config{BaseURL: "https://blog.example.com"}).Sitemap(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, sm.Chunks, 1)
+		assert.Contains(t, string(sm.Chunks[0]), "<urlset")
+	})
+}
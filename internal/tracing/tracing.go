@@ -0,0 +1,156 @@
+// Package tracing configures OpenTelemetry tracing and the propagators that
+// connect the service's spans to W3C and AWS X-Ray traces.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// xrayHeader is the header API Gateway and Lambda use for X-Ray trace context.
+const xrayHeader = "X-Amzn-Trace-Id"
+
+// lambdaTraceKey is the context key under which aws-lambda-go stores the
+// invocation's X-Ray trace header.
+const lambdaTraceKey = "x-amzn-trace-id"
+
+// Config selects the exporter and sampling.
+type Config struct {
+	// Exporter is ExporterNone, ExporterStdout or ExporterOTLP.
+	Exporter string
+	// Endpoint is the OTLP/HTTP collector address, e.g. "localhost:4318".
+	// When empty the exporter's defaults and OTEL_EXPORTER_OTLP_* apply.
+	Endpoint string
+	// Insecure disables TLS to the collector.
+	Insecure    bool
+	ServiceName string
+	// SampleRatio is the fraction of new traces to sample. Spans with a
+	// sampled parent are always sampled.
+	SampleRatio float64
+}
+
+// Provider flushes and stops the tracer provider installed by Setup.
+type Provider struct {
+	tp *sdktrace.TracerProvider
+}
+
+// ForceFlush exports all finished spans. Under Lambda it must be called
+// before the invocation returns.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
+
+// Shutdown flushes and stops the provider.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.tp == nil {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// Propagator extracts and injects W3C trace context and baggage as well as
+// the X-Ray trace header. W3C context wins when a request carries both.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(xray.Propagator{}, propagation.TraceContext{}, propagation.Baggage{})
+}
+
+// Setup installs the global propagator and, unless the exporter is
+// ExporterNone, a tracer provider exporting to the configured backend.
+// Stdout spans are written to w.
+func Setup(ctx context.Context, cfg Config, w io.Writer) (*Provider, error) {
+	otel.SetTextMapPropagator(Propagator())
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case ExporterNone, "":
+		return &Provider{}, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		// X-Ray requires trace IDs that start with a timestamp.
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	)
+	otel.SetTracerProvider(tp)
+	return &Provider{tp: tp}, nil
+}
+
+// Extract returns ctx carrying the remote span context found in h. When h
+// carries neither traceparent nor X-Amzn-Trace-Id, the X-Ray header of the
+// Lambda invocation is used, so spans join the trace Lambda started.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	propagator := otel.GetTextMapPropagator()
+	if h.Get("traceparent") == "" && h.Get(xrayHeader) == "" {
+		if lambdaTrace, ok := ctx.Value(lambdaTraceKey).(string); ok && lambdaTrace != "" {
+			h = h.Clone()
+			h.Set(xrayHeader, lambdaTrace)
+		}
+	}
+	return propagator.Extract(ctx, propagation.HeaderCarrier(h))
+}
+
+// End records err on span, if any, and ends it. It is meant to be deferred
+// with a pointer to a named error result.
+func End(span trace.Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+		span.SetStatus(codes.Error, (*err).Error())
+	}
+	span.End()
+}
+
+// TraceID returns the trace ID of the span in ctx, or "" if there is none.
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
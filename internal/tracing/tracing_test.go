@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtract(t *testing.T) {
+	otel.SetTextMapPropagator(Propagator())
+
+	t.Run("W3C Trace Context", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		sc := trace.SpanContextFromContext(Extract(context.Background(), h))
+
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+		assert.True(t, sc.IsRemote())
+		assert.True(t, sc.IsSampled())
+	})
+
+	t.Run("X-Ray Header", func(t *testing.T) {
+		h := http.Header{}
+		h.Set("X-Amzn-Trace-Id", "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+		sc := trace.SpanContextFromContext(Extract(context.Background(), h))
+
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", sc.TraceID().String())
+		assert.Equal(t, "53995c3f42cd8ad8", sc.SpanID().String())
+	})
+
+	t.Run("Lambda Trace Fallback", func(t *testing.T) {
+		//nolint:staticcheck // aws-lambda-go uses this plain string key
+		ctx := context.WithValue(context.Background(), lambdaTraceKey,
+			"Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+		sc := trace.SpanContextFromContext(Extract(ctx, http.Header{}))
+
+		assert.Equal(t, "5759e988bd862e3fe1be46a994272793", sc.TraceID().String())
+	})
+
+	t.Run("No Trace", func(t *testing.T) {
+		sc := trace.SpanContextFromContext(Extract(context.Background(), http.Header{}))
+
+		assert.False(t, sc.IsValid())
+	})
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	_, ok := tracer.Start(context.Background(), "ok")
+	var noErr error
+	End(ok, &noErr)
+
+	_, failed := tracer.Start(context.Background(), "failed")
+	err := errors.New("boom")
+	End(failed, &err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "boom", spans[1].Status().Description)
+}
+
+func TestSetup(t *testing.T) {
+	t.Run("None", func(t *testing.T) {
+		provider, err := Setup(context.Background(), Config{Exporter: ExporterNone}, nil)
+
+		assert.NoError(t, err)
+		assert.NoError(t, provider.ForceFlush(context.Background()))
+		assert.NoError(t, provider.Shutdown(context.Background()))
+	})
+
+	t.Run("Stdout", func(t *testing.T) {
+		var out bytes.Buffer
+		provider, err := Setup(context.Background(), Config{Exporter: ExporterStdout, ServiceName: "test", SampleRatio: 1}, &out)
+		assert.NoError(t, err)
+
+		ctx, span := otel.Tracer("test").Start(context.Background(), "work")
+		span.End()
+		assert.NoError(t, provider.ForceFlush(ctx))
+		assert.NoError(t, provider.Shutdown(ctx))
+
+		assert.Contains(t, out.String(), `"Name":"work"`)
+		assert.Contains(t, out.String(), `"Value":"test"`)
+		assert.Equal(t, 32, len(TraceID(trace.ContextWithSpan(context.Background(), span))))
+	})
+
+	t.Run("Unknown Exporter", func(t *testing.T) {
+		_, err := Setup(context.Background(), Config{Exporter: "zipkin"}, nil)
+
+		assert.Error(t, err)
+	})
+}
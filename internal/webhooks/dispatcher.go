@@ -0,0 +1,365 @@
+// Package webhooks delivers post events to the registered webhooks. Every
+// event is recorded as one delivery per subscribed webhook; deliveries are then
+// sent signed and retried with exponential backoff until they succeed or run
+// out of attempts and are dead.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+	"github.com/google/uuid"
+)
+
+// Store holds the webhooks and their deliveries.
+type Store interface {
+	ListWebhooks(ctx context.Context) ([]*models.Webhook, error)
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error)
+	ClaimDelivery(ctx context.Context, delivery *models.WebhookDelivery, until time.Time) (bool, error)
+}
+
+// Source provides the post events recorded by Watch.
+type Source interface {
+	Subscribe(buffer int) *events.Subscription
+}
+
+// Config holds the delivery settings. Zero values select the defaults.
+type Config struct {
+	// MaxAttempts is how often a delivery is sent before it is dead; 8 by
+	// default.
+	MaxAttempts int
+	// RetryBaseDelay is the wait after the first failed attempt, doubling
+	// with every further one up to six hours; 30s by default.
+	RetryBaseDelay time.Duration
+	// Timeout bounds each attempt; 10s by default.
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+const (
+	defaultMaxAttempts    = 8
+	defaultRetryBaseDelay = 30 * time.Second
+	defaultTimeout        = 10 * time.Second
+	maxRetryDelay         = 6 * time.Hour
+
+	// pollInterval is how often due deliveries are looked for when no new
+	// event arrives in between.
+	pollInterval = 5 * time.Second
+	// dueBatchSize is how many due deliveries are read at once, and
+	// maxConcurrentSends how many of them are sent at the same time.
+	dueBatchSize       = 25
+	maxConcurrentSends = 8
+	// deliveryRetention is how long the delivery log keeps a delivery.
+	deliveryRetention = 30 * 24 * time.Hour
+	// watchBuffer is how many events Watch may fall behind.
+	watchBuffer = 256
+	// maxDrainBytes bounds the response body read to reuse the connection.
+	maxDrainBytes = 64 << 10
+
+	userAgent = "blog-api-webhooks/1.0"
+)
+
+var eventTypes = map[string]string{
+	events.TypeCreated: models.EventPostCreated,
+	events.TypeUpdated: models.EventPostUpdated,
+	events.TypeDeleted: models.EventPostDeleted,
+}
+
+type Dispatcher struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+	logger *slog.Logger
+	now    func() time.Time
+	// wake asks Run to look for due deliveries before the next poll.
+	wake chan struct{}
+}
+
+func NewDispatcher(store Store, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBaseDelay <= 0 {
+		cfg.RetryBaseDelay = defaultRetryBaseDelay
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultTimeout
+	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Dispatcher{
+		store: store,
+		cfg:   cfg,
+		// Redirects are not followed, so a delivery only ever reaches the
+		// registered URL.
+		client: &http.Client{Transport: publicTransport(), CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+		logger: logger,
+		now:    time.Now,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Publish records a delivery of event for every webhook subscribed to its
+// type. The event ID of the payload is the DedupeID of event when it has one,
+// so receivers can ignore an event published twice.
+func (d *Dispatcher) Publish(ctx context.Context, event events.Event) error {
+	eventType, ok := eventTypes[event.Type]
+	if !ok {
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	var (
+		eventID string
+		payload []byte
+	)
+	now := d.now().UTC()
+	var errs []error
+	for _, webhook := range webhooks {
+		if !webhook.Subscribed(eventType) {
+			continue
+		}
+		if payload == nil {
+			eventID = event.DedupeID
+			if eventID == "" {
+				eventID = uuid.NewString()
+			}
+			payload, err = json.Marshal(models.WebhookEvent{
+				ID:        eventID,
+				Type:      eventType,
+				CreatedAt: event.Time.UTC(),
+				Data:      models.WebhookEventData{ID: event.ID, Post: event.Post, Fields: event.Fields},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to marshal webhook event: %w", err)
+			}
+		}
+		delivery := &models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			EventType:     eventType,
+			Payload:       payload,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			ExpiresAt:     now.Add(deliveryRetention).Unix(),
+		}
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			errs = append(errs, fmt.Errorf("failed to record delivery to webhook %s: %w", webhook.ID, err))
+		}
+	}
+	if payload != nil {
+		d.notify()
+	}
+	return errors.Join(errs...)
+}
+
+// Watch publishes the events of source until ctx is done or source stops.
+// When it falls behind, the missed events are logged and watching resumes.
+func (d *Dispatcher) Watch(ctx context.Context, source Source) {
+	for {
+		sub := source.Subscribe(watchBuffer)
+		err := d.consume(ctx, sub)
+		sub.Close()
+		if !errors.Is(err, events.ErrSlowSubscriber) {
+			return
+		}
+		d.logger.Error("webhook deliveries fell behind post events, some events were not delivered")
+	}
+}
+
+func (d *Dispatcher) consume(ctx context.Context, sub *events.Subscription) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return sub.Err()
+			}
+			if err := d.Publish(ctx, event); err != nil {
+				d.logger.Error("failed to record webhook deliveries", "id", event.ID, "type", event.Type, "error", err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends due deliveries until ctx is done. Any number of dispatchers may
+// share a store: each delivery is claimed by one of them before it is sent.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		d.SendDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// SendDue sends batches of due deliveries for as long as full batches are
+// claimed. Run calls it on every poll; processes without background work,
+// such as Lambda functions, call it directly.
+func (d *Dispatcher) SendDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.store.DueDeliveries(ctx, d.now(), dueBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error("failed to read due webhook deliveries", "error", err)
+			}
+			return
+		}
+
+		var (
+			wg      sync.WaitGroup
+			claimed atomic.Int32
+			slots   = make(chan struct{}, maxConcurrentSends)
+		)
+		for _, delivery := range due {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if d.attempt(ctx, delivery) {
+					claimed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if len(due) < dueBatchSize || claimed.Load() == 0 {
+			return
+		}
+	}
+}
+
+// attempt claims and sends a delivery and records the outcome. It reports
+// whether the delivery was claimed.
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) bool {
+	// The claim outlasts the attempt, so that a delivery whose outcome was
+	// not recorded is sent again once it lapses.
+	claimed, err := d.store.ClaimDelivery(ctx, delivery, d.now().Add(2*d.cfg.Timeout))
+	if err != nil {
+		d.logger.Error("failed to claim webhook delivery", "delivery", delivery.ID, "error", err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	logger := d.logger.With("delivery", delivery.ID, "webhook", delivery.WebhookID, "event", delivery.EventType)
+	// Outcomes are recorded even while shutting down.
+	saveCtx := context.WithoutCancel(ctx)
+
+	webhook, err := d.store.GetWebhook(ctx, delivery.WebhookID)
+	switch {
+	case errors.Is(err, custom_errors.ErrNotFound):
+		delivery.Status = models.DeliveryDead
+		delivery.Error = "webhook was deleted"
+		if err := d.store.SaveDelivery(saveCtx, delivery); err != nil {
+			logger.Error("failed to save webhook delivery", "error", err)
+		}
+		return true
+	case err != nil:
+		logger.Error("failed to get webhook", "error", err)
+		return true
+	}
+
+	now := d.now().UTC()
+	status, err := d.send(ctx, webhook, delivery, now)
+	if err != nil && ctx.Err() != nil {
+		return true // interrupted by shutdown, not the receiver's fault
+	}
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+	delivery.Error = ""
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		logger.Debug("delivered webhook", "attempt", delivery.Attempts, "status", status)
+	case delivery.Attempts >= d.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.Error = err.Error()
+		logger.Error("webhook delivery is dead", "attempts", delivery.Attempts, "error", err)
+	default:
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = now.Add(d.backoff(delivery.Attempts))
+		logger.Warn("webhook delivery failed, will retry", "attempt", delivery.Attempts, "retry_at", delivery.NextAttemptAt, "error", err)
+	}
+	if err := d.store.SaveDelivery(saveCtx, delivery); err != nil {
+		logger.Error("failed to save webhook delivery", "error", err)
+	}
+	return true
+}
+
+// send posts the payload to the webhook, returning the response status.
+// Anything but a 2xx response is an error.
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxDrainBytes))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.RetryBaseDelay
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"blog-api/internal/custom_errors"
+	"blog-api/internal/events"
+	"blog-api/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps webhooks and deliveries in memory, claiming deliveries
+// the way the DynamoDB repository does.
+type memoryStore struct {
+	mu         sync.Mutex
+	webhooks   []*models.Webhook
+	deliveries map[string]models.WebhookDelivery
+	nextID     int
+}
+
+func newMemoryStore(webhooks ...*models.Webhook) *memoryStore {
+	return &memoryStore{webhooks: webhooks, deliveries: make(map[string]models.WebhookDelivery)}
+}
+
+func (s *memoryStore) ListWebhooks(ctx context.Context) ([]*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*models.Webhook(nil), s.webhooks...), nil
+}
+
+func (s *memoryStore) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, webhook := range s.webhooks {
+		if webhook.ID == id {
+			return webhook, nil
+		}
+	}
+	return nil, custom_errors.ErrNotFound
+}
+
+func (s *memoryStore) SaveDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if delivery.ID == "" {
+		s.nextID++
+		delivery.ID = "d" + strconv.Itoa(s.nextID)
+	}
+	s.deliveries[delivery.ID] = *delivery
+	return nil
+}
+
+func (s *memoryStore) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due []*models.WebhookDelivery
+	for _, delivery := range s.deliveries {
+		if delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, &delivery)
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) ClaimDelivery(ctx context.Context, delivery *models.WebhookDelivery, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := s.deliveries[delivery.ID]
+	if stored.Status != models.DeliveryPending || !stored.NextAttemptAt.Equal(delivery.NextAttemptAt) {
+		return false, nil
+	}
+	stored.NextAttemptAt = until
+	s.deliveries[delivery.ID] = stored
+	delivery.NextAttemptAt = until
+	return true, nil
+}
+
+func (s *memoryStore) only(t *testing.T) models.WebhookDelivery {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Len(t, s.deliveries, 1)
+	for _, delivery := range s.deliveries {
+		return delivery
+	}
+	return models.WebhookDelivery{}
+}
+
+// newTestDispatcher returns a dispatcher at now that may deliver to the test
+// servers, which listen on loopback.
+func newTestDispatcher(store Store, cfg Config, now time.Time) *Dispatcher {
+	d := NewDispatcher(store, cfg)
+	d.now = func() time.Time { return now }
+	d.client.Transport = http.DefaultTransport
+	return d
+}
+
+func TestPublish(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	store := newMemoryStore(
+		&models.Webhook{ID: "w1", URL: "http://example.com", Events: []string{models.EventPostCreated}},
+		&models.Webhook{ID: "w2", URL: "http://example.com", Events: []string{models.EventPostDeleted}},
+	)
+	d := newTestDispatcher(store, Config{}, now)
+
+	t.Run("Records Deliveries For Subscribed Webhooks", func(t *testing.T) {
+		err := d.Publish(context.Background(), events.Event{
+			Type: events.TypeCreated,
+			ID:   "1",
+			Post: &models.Post{ID: "1", Title: "Hello"},
+			Time: now,
+		})
+
+		require.NoError(t, err)
+		delivery := store.only(t)
+		assert.Equal(t, "w1", delivery.WebhookID)
+		assert.Equal(t, models.EventPostCreated, delivery.EventType)
+		assert.Equal(t, models.DeliveryPending, delivery.Status)
+		assert.Equal(t, now, delivery.NextAttemptAt)
+		assert.Equal(t, now.Add(deliveryRetention).Unix(), delivery.ExpiresAt)
+
+		var event models.WebhookEvent
+		require.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		assert.Equal(t, delivery.EventID, event.ID)
+		assert.Equal(t, models.EventPostCreated, event.Type)
+		assert.Equal(t, "1", event.Data.ID)
+		assert.Equal(t, "Hello", event.Data.Post.Title)
+	})
+
+	t.Run("Keeps The Dedupe ID", func(t *testing.T) {
+		store := newMemoryStore(&models.Webhook{ID: "w1", URL: "http://example.com", Events: []string{models.EventPostDeleted}})
+		d := newTestDispatcher(store, Config{}, now)
+
+		err := d.Publish(context.Background(), events.Event{Type: events.TypeDeleted, ID: "1", Time: now, DedupeID: "m1"})
+
+		require.NoError(t, err)
+		assert.Equal(t, "m1", store.only(t).EventID)
+	})
+
+	t.Run("Includes Changed Fields", func(t *testing.T) {
+		store := newMemoryStore(&models.Webhook{ID: "w1", URL: "http://example.com", Events: []string{models.EventPostUpdated}})
+		d := newTestDispatcher(store, Config{}, now)
+
+		err := d.Publish(context.Background(), events.Event{Type: events.TypeUpdated, ID: "1", Fields: []string{"title", "tags"}, Time: now})
+
+		require.NoError(t, err)
+		var event models.WebhookEvent
+		require.NoError(t, json.Unmarshal(store.only(t).Payload, &event))
+		assert.Equal(t, []string{"title", "tags"}, event.Data.Fields)
+	})
+
+	t.Run("Rejects Unknown Event Types", func(t *testing.T) {
+		assert.Error(t, d.Publish(context.Background(), events.Event{Type: "moved"}))
+	})
+}
+
+func TestSendDue(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	webhook := func(url string) *models.Webhook {
+		return &models.Webhook{ID: "w1", URL: url, Events: []string{models.EventPostDeleted}, Secret: "whsec_0123456789abcdef"}
+	}
+	publish := func(t *testing.T, d *Dispatcher) {
+		t.Helper()
+		require.NoError(t, d.Publish(context.Background(), events.Event{Type: events.TypeDeleted, ID: "1", Time: now}))
+	}
+
+	t.Run("Sends Signed Deliveries", func(t *testing.T) {
+		var received *http.Request
+		var body []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			body, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		store := newMemoryStore(webhook(server.URL))
+		d := newTestDispatcher(store, Config{}, now)
+		publish(t, d)
+
+		d.SendDue(context.Background())
+
+		require.NotNil(t, received)
+		delivery := store.only(t)
+		assert.Equal(t, string(delivery.Payload), string(body))
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, models.EventPostDeleted, received.Header.Get(EventHeader))
+		assert.Equal(t, delivery.ID, received.Header.Get(DeliveryHeader))
+		assert.Equal(t, strconv.FormatInt(now.Unix(), 10), received.Header.Get(TimestampHeader))
+		assert.Equal(t, Sign("whsec_0123456789abcdef", now.Unix(), body), received.Header.Get(SignatureHeader))
+
+		assert.Equal(t, models.DeliverySucceeded, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusNoContent, delivery.ResponseStatus)
+		assert.Empty(t, delivery.Error)
+	})
+
+	t.Run("Refuses Non-Public Addresses", func(t *testing.T) {
+		called := false
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+		}))
+		defer server.Close()
+		store := newMemoryStore(webhook(server.URL))
+		d := NewDispatcher(store, Config{})
+		d.now = func() time.Time { return now }
+		publish(t, d)
+
+		d.SendDue(context.Background())
+
+		assert.False(t, called)
+		delivery := store.only(t)
+		assert.Equal(t, models.DeliveryPending, delivery.Status)
+		assert.Contains(t, delivery.Error, ErrNonPublicAddress.Error())
+	})
+
+	t.Run("Retries With Backoff Until Dead", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		store := newMemoryStore(webhook(server.URL))
+		d := newTestDispatcher(store, Config{MaxAttempts: 2, RetryBaseDelay: time.Minute}, now)
+		publish(t, d)
+
+		d.SendDue(context.Background())
+
+		delivery := store.only(t)
+		assert.Equal(t, models.DeliveryPending, delivery.Status)
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, now.Add(time.Minute), delivery.NextAttemptAt)
+		assert.Equal(t, http.StatusInternalServerError, delivery.ResponseStatus)
+		assert.Contains(t, delivery.Error, "unexpected response status 500")
+
+		d.SendDue(context.Background())
+		assert.Equal(t, 1, calls, "not due yet")
+
+		d.now = func() time.Time { return now.Add(time.Minute) }
+		d.SendDue(context.Background())
+
+		delivery = store.only(t)
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, models.DeliveryDead, delivery.Status)
+		assert.Equal(t, 2, delivery.Attempts)
+	})
+
+	t.Run("Does Not Follow Redirects", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("redirect was followed")
+		}))
+		defer target.Close()
+		server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+		defer server.Close()
+		store := newMemoryStore(webhook(server.URL))
+		d := newTestDispatcher(store, Config{}, now)
+		publish(t, d)
+
+		d.SendDue(context.Background())
+
+		delivery := store.only(t)
+		assert.Equal(t, models.DeliveryPending, delivery.Status)
+		assert.Equal(t, http.StatusTemporaryRedirect, delivery.ResponseStatus)
+	})
+
+	t.Run("Ends Deliveries Of Deleted Webhooks", func(t *testing.T) {
+		store := newMemoryStore(webhook("http://127.0.0.1:1"))
+		d := newTestDispatcher(store, Config{}, now)
+		publish(t, d)
+		store.webhooks = nil
+
+		d.SendDue(context.Background())
+
+		delivery := store.only(t)
+		assert.Equal(t, models.DeliveryDead, delivery.Status)
+		assert.Equal(t, 0, delivery.Attempts)
+		assert.Equal(t, "webhook was deleted", delivery.Error)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	d := NewDispatcher(newMemoryStore(), Config{RetryBaseDelay: time.Minute})
+
+	assert.Equal(t, time.Minute, d.backoff(1))
+	assert.Equal(t, 2*time.Minute, d.backoff(2))
+	assert.Equal(t, 8*time.Minute, d.backoff(4))
+	assert.Equal(t, maxRetryDelay, d.backoff(30))
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t,
+		"sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163",
+		Sign("secret", 1700000000, []byte("{}")),
+	)
+}
@@ -0,0 +1,101 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrNonPublicAddress is returned for webhook URLs and connections that reach
+// an address that is not on the public internet, such as loopback, private
+// networks or the instance metadata service.
+var ErrNonPublicAddress = errors.New("address is not public")
+
+// nonPublicPrefixes are special-purpose ranges that the netip predicates do
+// not cover.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// PublicAddress reports whether addr may receive webhook deliveries.
+func PublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL fails unless every address the host of rawURL resolves to is
+// public. Deliveries check the address again when they connect, as the host
+// may resolve differently by then.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse URL: %w", err)
+	}
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !PublicAddress(addr) {
+			return fmt.Errorf("%s: %w", host, ErrNonPublicAddress)
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if !PublicAddress(addr) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr, ErrNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// publicOnly is a net.Dialer Control function that refuses connections to
+// addresses that are not public, which also defeats DNS rebinding.
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse address %q: %w", address, err)
+	}
+	if !PublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%s: %w", addrPort.Addr(), ErrNonPublicAddress)
+	}
+	return nil
+}
+
+// publicTransport connects directly, without a proxy, and only to public
+// addresses.
+func publicTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}).DialContext
+	return transport
+}
@@ -0,0 +1,41 @@
+package webhooks
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicAddress(t *testing.T) {
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946", "8.8.8.8"} {
+		assert.True(t, PublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1",
+		"0.0.0.0", "224.0.0.1", "::1", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1", "::ffff:169.254.169.254",
+	} {
+		assert.False(t, PublicAddress(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Accepts Public Addresses", func(t *testing.T) {
+		assert.NoError(t, CheckURL(ctx, "https://93.184.216.34/hooks"))
+	})
+
+	t.Run("Rejects Non-Public Addresses", func(t *testing.T) {
+		for _, url := range []string{"http://169.254.169.254/latest/meta-data/", "http://[::1]:8080/", "http://localhost:8080/", "http://10.0.0.1/"} {
+			assert.ErrorIs(t, CheckURL(ctx, url), ErrNonPublicAddress, url)
+		}
+	})
+}
+
+func TestPublicOnly(t *testing.T) {
+	assert.NoError(t, publicOnly("tcp", "93.184.216.34:443", nil))
+	assert.ErrorIs(t, publicOnly("tcp", "127.0.0.1:80", nil), ErrNonPublicAddress)
+	assert.ErrorIs(t, publicOnly("tcp6", "[fe80::1]:443", nil), ErrNonPublicAddress)
+}
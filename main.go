@@ -1,93 +1,530 @@
 package main
 
 import (
+	"blog-api/internal/auth"
+	"blog-api/internal/cdc"
+	"blog-api/internal/config"
+	postevents "blog-api/internal/events"
+	"blog-api/internal/graphql"
+	"blog-api/internal/grpcapi"
 	"blog-api/internal/handlers"
+	"blog-api/internal/health"
+	"blog-api/internal/logging"
+	"blog-api/internal/metrics"
+	"blog-api/internal/migrate"
+	"blog-api/internal/outbox"
+	"blog-api/internal/render"
 	"blog-api/internal/repository"
 	"blog-api/internal/routes"
+	"blog-api/internal/search"
 	"blog-api/internal/services"
+	"blog-api/internal/sitemap"
+	"blog-api/internal/tracing"
+	"blog-api/internal/webhooks"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/awslabs/aws-lambda-go-api-proxy/httpadapter"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"google.golang.org/grpc"
 )
 
-// appConfig holds application-level configuration for DynamoDB.
-type appConfig struct {
-	DynamoDBEndpoint string
-	DynamoDBRegion   string
-	DynamoDBTable    string
-}
+// renderCacheSize bounds the number of rendered post documents kept in memory.
+const renderCacheSize = 512
+
+// sitemapMaxAge bounds how stale a cached sitemap can get when posts are
+// changed through another instance.
+const sitemapMaxAge = time.Hour
+
+// readinessTimeout bounds each readiness check, and readinessCacheTTL is how
+// long a readiness report is reused before DynamoDB is asked again.
+const (
+	readinessTimeout  = 2 * time.Second
+	readinessCacheTTL = 5 * time.Second
+)
+
+// eventQueueSize bounds the post changes waiting to be sent to gRPC watchers
+// and event streams, and eventReplaySize the events kept for event stream
+// clients that reconnect.
+const (
+	eventQueueSize  = 256
+	eventReplaySize = 1024
+)
 
 func main() {
 	// Set up application
-	appCfg, err := loadAppConfig()
+	appCfg, opts, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		log.Fatalf("Failed to load app configuration: %v", err)
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if opts.PrintConfig {
+		if err := config.Print(os.Stdout, appCfg); err != nil {
+			log.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	logger := logging.New(os.Stdout, appCfg.LoggingConfig())
+	slog.SetDefault(logger)
+	if opts.File != "" {
+		logger.Info("loaded config file", "path", opts.File)
 	}
 
-	dynamoClient, err := newDynamoDBClient(appCfg)
+	if len(opts.Args) > 0 {
+		if err := runCommand(appCfg, opts.Args); err != nil {
+			log.Fatalf("%s failed: %v", opts.Args[0], err)
+		}
+		return
+	}
+
+	traceProvider, err := tracing.Setup(context.Background(), appCfg.TracingConfig(), os.Stdout)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
+	var (
+		recorder       metrics.Recorder
+		emf            *metrics.EMF
+		metricsHandler http.Handler
+	)
+	if appCfg.Metrics.Backend == config.MetricsEMF {
+		emf = metrics.NewEMF(os.Stdout, appCfg.Metrics.Namespace, "blog-api")
+		recorder = emf
+	} else {
+		prom := metrics.NewPrometheus()
+		recorder, metricsHandler = prom, prom.Handler()
+		if appCfg.Metrics.Addr != "" {
+			go serveMetrics(appCfg.Metrics.Addr, metricsHandler)
+		}
+	}
+
+	dynamoClient, err := newDynamoDBClient(appCfg.Storage, recorder)
 	if err != nil {
 		log.Fatalf("Failed to create DynamoDB client: %v", err)
 	}
 
 	// Initialize repository, service, and handler
-	repo := repository.NewDynamoPostRepository(dynamoClient, appCfg.DynamoDBTable)
-	postService := services.NewPostService(repo)
+	repo := repository.NewDynamoPostRepository(dynamoClient, appCfg.Storage.Table)
+	policy := appCfg.SanitizePolicy()
+	postService := services.NewPostService(repo, render.NewRenderer(renderCacheSize, policy), policy)
 	postHandler := handlers.NewPostHandler(postService)
+	feedHandler := handlers.NewFeedHandler(postService, appCfg.FeedConfig())
+
+	sitemapGenerator := sitemap.NewGenerator(postService, sitemap.Config{
+		BaseURL: appCfg.Site.BaseURL,
+		MaxAge:  sitemapMaxAge,
+	})
+	postService.AddChangeListener(sitemapGenerator)
+	sitemapHandler := handlers.NewSitemapHandler(sitemapGenerator)
+
+	readiness := health.NewChecker(readinessTimeout, readinessCacheTTL)
+	readiness.Add("dynamodb", repo.CheckTable)
+
+	var (
+		webhookHandler    handlers.WebhookHandlerInterface
+		webhookDispatcher *webhooks.Dispatcher
+	)
+	if appCfg.Webhooks.Table != "" {
+		webhookRepo := repository.NewDynamoWebhookRepository(dynamoClient, appCfg.Webhooks.Table)
+		webhookHandler = handlers.NewWebhookHandler(services.NewWebhookService(webhookRepo))
+		webhookDispatcher = webhooks.NewDispatcher(webhookRepo, webhooks.Config{
+			MaxAttempts:    appCfg.Webhooks.MaxAttempts,
+			RetryBaseDelay: appCfg.Webhooks.RetryBaseDelay,
+			Timeout:        appCfg.Webhooks.Timeout,
+			Logger:         logger,
+		})
+		readiness.Add("webhooks", webhookRepo.CheckTable)
+	}
+
+	// With an outbox, webhook deliveries are recorded from it rather than
+	// from the in-process broker, unless the stream consumer records them.
+	var relay *outbox.Relay
+	if appCfg.Outbox.Table != "" {
+		outboxStore := repository.NewDynamoOutboxStore(dynamoClient, appCfg.Outbox.Table)
+		repo.OutboxTable = appCfg.Outbox.Table
+		publishers := outbox.Publishers{outbox.NewLogPublisher(logger)}
+		if webhookDispatcher != nil && !appCfg.Webhooks.FromStream {
+			publishers = append(publishers, outbox.NewEvents(webhookDispatcher))
+		}
+		relay = outbox.NewRelay(outboxStore, publishers, outbox.Config{MaxAttempts: appCfg.Outbox.MaxAttempts, Logger: logger})
+		readiness.Add("outbox", outboxStore.CheckTable)
+	}
+	healthHandler := handlers.NewHealthHandler(readiness)
+
+	authenticator := auth.New(auth.Config{
+		APIKeys:     appCfg.Auth.APIKeys,
+		JWTSecret:   appCfg.Auth.JWTSecret,
+		JWTIssuer:   appCfg.Auth.JWTIssuer,
+		JWTAudience: appCfg.Auth.JWTAudience,
+	})
+	graphqlHandler, err := graphql.NewHandler(postService, graphql.Config{
+		Auth:          authenticator,
+		MaxDepth:      appCfg.GraphQL.MaxDepth,
+		MaxComplexity: appCfg.GraphQL.MaxComplexity,
+	})
+	if err != nil {
+		log.Fatalf("Failed to create GraphQL handler: %v", err)
+	}
+
+	// Only the standalone server runs a broker, which feeds the event streams,
+	// the gRPC watch streams and, without an outbox or the stream consumer,
+	// webhook deliveries.
+	var (
+		broker        *postevents.Broker
+		eventsHandler http.Handler
+	)
+	if appCfg.Server.Mode == config.ModeStandalone {
+		broker = postevents.NewBroker(postService, eventQueueSize)
+		broker.EnableReplay(eventReplaySize)
+		postService.AddChangeListener(broker)
+		eventsHandler = handlers.NewPostEventsHandler(broker, handlers.PostEventsConfig{})
+	}
 
 	// Set up the HTTP router (using the project's internal routes)
-	router := routes.SetupRouter(postHandler)
+	router := routes.SetupRouter(postHandler, feedHandler, sitemapHandler, healthHandler, routes.Config{
+		Logger:         logger,
+		Logging:        appCfg.LoggingConfig(),
+		Metrics:        recorder,
+		MetricsHandler: metricsHandler,
+		MetricsToken:   appCfg.Metrics.Token,
+		CORS:           corsConfig(appCfg.CORS),
+		Auth:           authenticator,
+		Limits: routes.Limits{
+			MaxBodyBytes:   appCfg.Limits.MaxBodyBytes,
+			MaxImportBytes: appCfg.Limits.MaxImportBytes,
+		},
+		GraphQL:  graphqlHandler,
+		Webhooks: webhookHandler,
+		Events:   eventsHandler,
+	})
+
+	if appCfg.Server.Mode == config.ModeStandalone {
+		var rpc *rpcServer
+		recordWebhooks := webhookDispatcher != nil && !appCfg.Webhooks.FromStream && relay == nil
+		if appCfg.GRPC.Addr != "" {
+			rpc = &rpcServer{
+				addr:   appCfg.GRPC.Addr,
+				server: grpcapi.NewServer(postService, broker, grpcapi.Config{Logger: logger, Auth: authenticator}),
+			}
+		}
+		if relay != nil {
+			postService.AddChangeListener(relay)
+		}
+		w := workers{broker: broker, rpc: rpc, webhooks: webhookDispatcher, recordWebhooks: recordWebhooks, relay: relay}
+		if err := serve(appCfg.Server, router, w, traceProvider); err != nil {
+			log.Fatalf("Server failed: %v", err)
+		}
+		return
+	}
+
+	// Flush before returning from an invocation: the instance may be frozen
+	// right after.
+	flush := func(ctx context.Context) {
+		if emf != nil {
+			if err := emf.Flush(ctx); err != nil {
+				slog.Error("failed to flush metrics", "error", err)
+			}
+		}
+		if err := traceProvider.ForceFlush(ctx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}
+
+	if appCfg.Server.Mode == config.ModeStream {
+		sinks := []cdc.Sink{cdc.NewLogSink(logger)}
+		if webhookDispatcher != nil && appCfg.Webhooks.FromStream {
+			sinks = append(sinks, cdc.NewWebhookSink(webhookDispatcher))
+		}
+		if appCfg.Search.Endpoint != "" {
+			sinks = append(sinks, cdc.NewSearchSink(search.NewIndexer(appCfg.SearchConfig())))
+		}
+		var store cdc.IdempotencyStore
+		if appCfg.Streams.IdempotencyTable != "" {
+			store = repository.NewDynamoIdempotencyStore(dynamoClient, appCfg.Streams.IdempotencyTable)
+		}
+		consumer := cdc.NewConsumer(sinks, cdc.Config{Store: store, Logger: logger})
+
+		lambda.Start(func(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+			defer flush(ctx)
+			response, err := consumer.Handle(ctx, event)
+			if relay != nil {
+				// Every post write, and so every outbox message, also
+				// shows up in the stream, which makes this a timely place
+				// to relay them.
+				relay.Drain(ctx)
+			}
+			if webhookDispatcher != nil && (appCfg.Webhooks.FromStream || relay != nil) {
+				// Nothing runs between invocations, so deliveries, including
+				// due retries, are sent before returning.
+				webhookDispatcher.SendDue(ctx)
+			}
+			return response, err
+		})
+		return
+	}
 
-	// Wrap the router using lambda httpadapter
+	// Wrap the router using lambda httpadapter. Webhooks are managed and
+	// outbox messages written here, but both are sent on by a standalone
+	// server or the stream consumer, as Lambda freezes between invocations.
 	adapter := httpadapter.New(router)
 
 	// Start the Lambda function
 	lambda.Start(func(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		defer flush(ctx)
 		return adapter.ProxyWithContext(ctx, req)
 	})
 }
 
-// loadAppConfig loads and validates configuration from environment variables.
-func loadAppConfig() (appConfig, error) {
-	cfg := appConfig{
-		DynamoDBEndpoint: getEnv("DYNAMODB_ENDPOINT", "http://host.docker.internal:8000"),
-		DynamoDBRegion:   getEnv("DYNAMODB_REGION", "us-east-1"),
-		DynamoDBTable:    getEnv("DYNAMODB_TABLE", "TestTable"),
+// serveMetrics serves /metrics on its own listener, out of reach of API clients.
+func serveMetrics(addr string, handler http.Handler) {
+	mux := http.NewServeMux()
+	mux.Handle(routes.Metrics, handler)
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	if err := server.ListenAndServe(); err != nil {
+		slog.Error("metrics listener stopped", "addr", addr, "error", err)
 	}
-	return cfg, nil
 }
 
-// newDynamoDBClient sets up a new DynamoDB client using a custom endpoint resolver.
-func newDynamoDBClient(cfg appConfig) (*dynamodb.Client, error) {
-	customResolver := aws.EndpointResolverFunc(func(service, region string) (aws.Endpoint, error) {
-		if service == dynamodb.ServiceID {
-			return aws.Endpoint{
-				URL:           cfg.DynamoDBEndpoint,
-				SigningRegion: cfg.DynamoDBRegion,
-			}, nil
+// rpcServer is the gRPC API served next to the HTTP API in standalone mode.
+type rpcServer struct {
+	addr   string
+	server *grpc.Server
+}
+
+// workers are what the standalone server runs next to the HTTP API; each
+// may be nil. The broker feeds the event streams, the gRPC watch streams and,
+// when recordWebhooks is set, the webhook deliveries.
+type workers struct {
+	broker         *postevents.Broker
+	rpc            *rpcServer
+	webhooks       *webhooks.Dispatcher
+	recordWebhooks bool
+	relay          *outbox.Relay
+}
+
+// serve runs the API as a plain HTTP server until SIGINT or SIGTERM, then
+// stops accepting connections and waits for in-flight requests.
+func serve(cfg config.ServerConfig, handler http.Handler, w workers, traceProvider *tracing.Provider) error {
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 2)
+	go func() {
+		slog.Info("listening", "addr", cfg.Addr)
+		errCh <- server.ListenAndServe()
+	}()
+
+	// Event and watch streams end when the broker stops, so it stops before
+	// the servers drain. Deliveries cut short are sent again after a restart.
+	brokerCtx, stopBroker := context.WithCancel(context.Background())
+	defer stopBroker()
+	if w.broker != nil {
+		go w.broker.Run(brokerCtx)
+	}
+	if w.webhooks != nil {
+		if w.recordWebhooks {
+			go w.webhooks.Watch(brokerCtx, w.broker)
 		}
-		return aws.Endpoint{}, fmt.Errorf("unknown service: %s", service)
-	})
+		go w.webhooks.Run(brokerCtx)
+	}
+	if w.relay != nil {
+		go w.relay.Run(brokerCtx)
+	}
+	if rpc := w.rpc; rpc != nil {
+		listener, err := net.Listen("tcp", rpc.addr)
+		if err != nil {
+			return fmt.Errorf("failed to listen for gRPC: %w", err)
+		}
+		go func() {
+			slog.Info("listening for gRPC", "addr", rpc.addr)
+			errCh <- rpc.server.Serve(listener)
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	stopBroker()
+	if rpc := w.rpc; rpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			rpc.server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			rpc.server.Stop()
+		}
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down: %w", err)
+	}
+	if err := traceProvider.Shutdown(shutdownCtx); err != nil {
+		slog.Error("failed to shut down tracing", "error", err)
+	}
+	return nil
+}
+
+// runCommand runs the subcommand named by args[0] instead of the API.
+func runCommand(cfg config.Config, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runMigrate provisions the tables and applies pending migrations, or with
+// "status" lists the applied and pending migrations without changing anything.
+func runMigrate(appCfg config.Config, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	awsCfg, err := config.LoadDefaultConfig(context.TODO(), config.WithEndpointResolver(customResolver))
+	cfg := appCfg.Storage
+	client, err := newDynamoDBClient(cfg, metrics.Nop{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return err
 	}
+	migrator := migrate.NewMigrator(client, cfg.Table, migrate.Migrations())
 
-	return dynamodb.NewFromConfig(awsCfg), nil
+	switch strings.Join(args, " ") {
+	case "":
+		changes, err := repository.NewDynamoPostRepository(client, cfg.Table).EnsureTable(ctx)
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		if err != nil {
+			return err
+		}
+		if appCfg.Webhooks.Table != "" {
+			webhookChanges, err := repository.NewDynamoWebhookRepository(client, appCfg.Webhooks.Table).EnsureTable(ctx)
+			for _, change := range webhookChanges {
+				fmt.Println(change)
+			}
+			if err != nil {
+				return err
+			}
+			changes = append(changes, webhookChanges...)
+		}
+		if appCfg.Streams.IdempotencyTable != "" {
+			storeChanges, err := repository.NewDynamoIdempotencyStore(client, appCfg.Streams.IdempotencyTable).EnsureTable(ctx)
+			for _, change := range storeChanges {
+				fmt.Println(change)
+			}
+			if err != nil {
+				return err
+			}
+			changes = append(changes, storeChanges...)
+		}
+		if appCfg.Outbox.Table != "" {
+			outboxChanges, err := repository.NewDynamoOutboxStore(client, appCfg.Outbox.Table).EnsureTable(ctx)
+			for _, change := range outboxChanges {
+				fmt.Println(change)
+			}
+			if err != nil {
+				return err
+			}
+			changes = append(changes, outboxChanges...)
+		}
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied migration %d: %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 && len(applied) == 0 {
+			fmt.Println("tables are up to date")
+		}
+		return nil
+	case "status":
+		applied, pending, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tSTATUS\tDESCRIPTION")
+		for _, migration := range applied {
+			fmt.Fprintf(w, "%d\tapplied %s\t%s\n", migration.Version, migration.AppliedAt.Format(time.RFC3339), migration.Description)
+		}
+		for _, migration := range pending {
+			fmt.Fprintf(w, "%d\tpending\t%s\n", migration.Version, migration.Description)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown arguments %q, expected none or \"status\"", strings.Join(args, " "))
+	}
+}
+
+// corsConfig converts the configured CORS policy and its per-route overrides.
+func corsConfig(cfg config.CORSConfig) routes.CORSConfig {
+	policy := func(p config.CORSPolicy) routes.CORSConfig {
+		return routes.CORSConfig{
+			AllowedOrigins:   p.AllowedOrigins,
+			AllowedMethods:   p.AllowedMethods,
+			AllowedHeaders:   p.AllowedHeaders,
+			ExposedHeaders:   p.ExposedHeaders,
+			AllowCredentials: p.AllowCredentials,
+			MaxAge:           p.MaxAge,
+		}
+	}
+	out := policy(cfg.CORSPolicy)
+	if len(cfg.Routes) > 0 {
+		out.Routes = make(map[string]routes.CORSConfig, len(cfg.Routes))
+		for template, p := range cfg.Routes {
+			out.Routes[template] = policy(p)
+		}
+	}
+	return out
 }
 
-// getEnv retrieves an environment variable with a fallback value.
-func getEnv(key, fallback string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		return value
+// newDynamoDBClient sets up a DynamoDB client for the configured region, using
+// the endpoint override when one is set.
+func newDynamoDBClient(cfg config.StorageConfig, recorder metrics.Recorder) (*dynamodb.Client, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.TODO(), awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
-	return fallback
+
+	return dynamodb.NewFromConfig(awsCfg, func(o *dynamodb.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	}, repository.WithRequestCorrelation, repository.WithMetrics(recorder), repository.WithTracing), nil
 }